@@ -3,17 +3,15 @@ package v1
 import (
 	"os"
 	"net/http"
+	"yanblog/utils"
 	"yanblog/utils/errmsg"
 
 	"github.com/gin-gonic/gin"
 )
 
-// 关于页面内容文件路径
-const AboutFilePath = "./web/frontend/public/static/about.md"
-
 // GetAboutContent 获取关于页面内容
 func GetAboutContent(c *gin.Context) {
-	content, err := os.ReadFile(AboutFilePath)
+	content, err := os.ReadFile(utils.AboutFilePath)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  errmsg.ERROR,
@@ -44,7 +42,7 @@ func UpdateAboutContent(c *gin.Context) {
 	}
 
 	// 写入文件
-	err := os.WriteFile(AboutFilePath, []byte(data.Content), 0644)
+	err := os.WriteFile(utils.AboutFilePath, []byte(data.Content), 0644)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  errmsg.ERROR,
@@ -0,0 +1,266 @@
+package v1
+
+import (
+	"archive/zip"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"yanblog/model"
+	"yanblog/utils"
+	"yanblog/utils/errmsg"
+	"yanblog/utils/storage"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// 文章导出的 ZIP 与 ZIP 导入（processZipArticle）使用相同的结构，导出后可直接重新导入：
+//
+//	<slug>/<slug>.md      Front Matter + 正文，正文中的站内文件改为相对路径
+//	<slug>/assets/...     封面、正文引用的 /uploads 文件、PDF 文件
+
+// uploadRefRegex 匹配 Markdown 图片和链接中引用的站内上传文件，第 2 组为地址
+var uploadRefRegex = regexp.MustCompile(`(!?\[[^\]]*\]\()(/uploads/[^)\s]+)`)
+
+// unsafeAssetNameChars 导出文件名中需要替换的字符（空白和括号会破坏 Markdown 链接）
+var unsafeAssetNameChars = regexp.MustCompile(`[\s()\[\]"'<>]+`)
+
+// ExportArticle 导出单篇文章为 ZIP（包含草稿等所有状态）
+func ExportArticle(c *gin.Context) {
+	id, ok := utils.ParseIDParam(c)
+	if !ok {
+		return
+	}
+	art, code := model.GetArtInfoForAdmin(id)
+	if code != errmsg.SUCCESS {
+		utils.Error(c, code)
+		return
+	}
+
+	writeExportZip(c, articleExportDir(art)+".zip", func(zw *zip.Writer) error {
+		return writeArticleToZip(zw, art)
+	})
+}
+
+// ExportArticles 批量导出文章为 ZIP，每篇文章一个目录
+// 查询参数: ids - 逗号分隔的文章ID（为空时导出全部文章）, status - 按状态筛选（仅 ids 为空时生效）
+func ExportArticles(c *gin.Context) {
+	var ids []int
+	if raw := strings.TrimSpace(c.Query("ids")); raw != "" {
+		for _, s := range strings.Split(raw, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil || id <= 0 {
+				utils.BadRequest(c, "参数错误，ids 必须为逗号分隔的文章ID")
+				return
+			}
+			ids = append(ids, id)
+		}
+	} else {
+		status := c.Query("status")
+		if status != "" && !model.IsValidArtStatus(status) {
+			utils.Error(c, errmsg.ERROR_ART_STATUS_WRONG)
+			return
+		}
+		ids = model.GetArtIDs(status)
+	}
+	if len(ids) == 0 {
+		utils.Error(c, errmsg.ERROR_ART_NOT_EXIST)
+		return
+	}
+
+	filename := fmt.Sprintf("articles-%s.zip", time.Now().Format("20060102-150405"))
+	writeExportZip(c, filename, func(zw *zip.Writer) error {
+		// 逐篇查询，避免一次性加载所有文章内容
+		for _, id := range ids {
+			art, code := model.GetArtInfoForAdmin(id)
+			if code != errmsg.SUCCESS {
+				continue // 已删除的文章跳过
+			}
+			if err := writeArticleToZip(zw, art); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// writeExportZip 设置下载响应头，并将 write 生成的内容以 ZIP 流式写入响应
+func writeExportZip(c *gin.Context, filename string, write func(zw *zip.Writer) error) {
+	// 导出全部文章时需要读取大量文件，解除服务器的写超时
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q; filename*=UTF-8''%s", filename, url.PathEscape(filename)))
+	c.Status(http.StatusOK)
+
+	zw := zip.NewWriter(c.Writer)
+	if err := write(zw); err != nil {
+		// 响应已开始写入，无法再返回错误信息；不写入 ZIP 目录，客户端得到的压缩包无法打开
+		log.Printf("导出文章 %s 失败: %v", filename, err)
+		return
+	}
+	zw.Close()
+}
+
+// articleExportDir 文章在 ZIP 中的目录名（slug 全站唯一且只含 ASCII 字符）
+func articleExportDir(art model.Article) string {
+	if art.Slug != "" {
+		return art.Slug
+	}
+	return fmt.Sprintf("article-%d", art.ID)
+}
+
+// writeArticleToZip 将一篇文章及其引用的文件写入 ZIP
+func writeArticleToZip(zw *zip.Writer, art model.Article) error {
+	dir := articleExportDir(art)
+	assets := newExportAssets()
+
+	content := uploadRefRegex.ReplaceAllStringFunc(art.Content, func(m string) string {
+		sub := uploadRefRegex.FindStringSubmatch(m)
+		if rel, ok := assets.add(sub[2]); ok {
+			return sub[1] + rel
+		}
+		return m
+	})
+
+	fm := ArticleFrontMatter{
+		Title:    art.Title,
+		Slug:     art.Slug,
+		Date:     art.CreatedAt.Format(time.RFC3339),
+		Category: art.Category.Name,
+		Desc:     art.Desc,
+		Top:      art.Top,
+		Status:   art.Status,
+	}
+	for _, t := range strings.Split(art.Tags, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			fm.Tags = append(fm.Tags, t)
+		}
+	}
+	if art.PublishAt != nil && !art.PublishAt.IsZero() {
+		fm.PublishAt = art.PublishAt.Format(time.RFC3339)
+	}
+	// 封面和 PDF 为站外链接或文件已不存在时保留原地址
+	fm.Cover = art.Img
+	if rel, ok := assets.add(art.Img); ok {
+		fm.Cover = rel
+	}
+	if art.Type == 2 && art.PdfUrl != "" {
+		fm.Pdf = art.PdfUrl
+		if rel, ok := assets.add(art.PdfUrl); ok {
+			fm.Pdf = rel
+		}
+	}
+
+	header, err := yaml.Marshal(fm)
+	if err != nil {
+		return err
+	}
+	w, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     dir + "/" + dir + ".md",
+		Method:   zip.Deflate,
+		Modified: art.UpdatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	if _, err = io.WriteString(w, "---\n"+string(header)+"---\n\n"+content); err != nil {
+		return err
+	}
+
+	for _, asset := range assets.files {
+		if err := copyFileToZip(zw, asset.name, dir+"/"+asset.rel); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exportAsset 导出的文件：在存储中的路径和在文章目录中的相对路径
+type exportAsset struct {
+	name string
+	rel  string
+}
+
+// exportAssets 收集一篇文章引用的站内文件，同一地址只导出一次
+type exportAssets struct {
+	byURL map[string]string
+	names map[string]bool
+	files []exportAsset
+}
+
+func newExportAssets() *exportAssets {
+	return &exportAssets{byURL: make(map[string]string), names: make(map[string]bool)}
+}
+
+// add 登记一个站内文件地址（如 /uploads/article/a.png），返回其在文章目录中的相对路径
+// 非站内地址或文件不存在时返回 false
+func (a *exportAssets) add(ref string) (string, bool) {
+	if rel, ok := a.byURL[ref]; ok {
+		return rel, true
+	}
+	if !strings.HasPrefix(ref, "/uploads/") {
+		return "", false
+	}
+	p := ref
+	if i := strings.IndexAny(p, "?#"); i >= 0 {
+		p = p[:i]
+	}
+	p, err := url.PathUnescape(p)
+	if err != nil {
+		return "", false
+	}
+	stored, ok := safeUploadPath(strings.TrimPrefix(p, "/uploads/"))
+	if !ok || stored == "" {
+		return "", false
+	}
+	if info, err := storage.Current().Stat(stored); err != nil || info.IsDir {
+		return "", false
+	}
+
+	// 不同目录下的同名文件加序号区分
+	name := unsafeAssetNameChars.ReplaceAllString(path.Base(p), "_")
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 2; a.names[name]; i++ {
+		name = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+	a.names[name] = true
+
+	rel := "assets/" + name
+	a.byURL[ref] = rel
+	a.files = append(a.files, exportAsset{name: stored, rel: rel})
+	return rel, true
+}
+
+// copyFileToZip 将存储中的文件写入 ZIP 中的指定路径
+func copyFileToZip(zw *zip.Writer, stored string, name string) error {
+	store := storage.Current()
+	info, err := store.Stat(stored)
+	if err != nil {
+		return err
+	}
+	f, err := store.Get(stored)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: info.ModTime,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, f)
+	return err
+}
@@ -0,0 +1,535 @@
+package v1
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"yanblog/middlewares"
+	"yanblog/model"
+	"yanblog/utils"
+	"yanblog/utils/errmsg"
+	"yanblog/utils/importer"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 博客导入：上传 Hexo / Hugo / Jekyll 源码目录的 ZIP 或 WordPress 导出的 XML（也可以与 wp-content/uploads 一起打包成 ZIP）
+// 导入在后台执行，进度和每篇文章的结果通过上传任务（GET article/upload/:id 和 WebSocket）获取
+
+// 导入结果状态
+const (
+	importCreated   = "created"
+	importUpdated   = "updated"   // 覆盖了已有文章
+	importVersioned = "versioned" // 保存为已有文章的新修订版本
+	importFailed    = "failed"
+	importSkipped   = "skipped"
+)
+
+// 导入模式：导入的文章与已有文章匹配（见 model.FindArtForImport）时的处理方式，没有匹配时总是新建
+const (
+	importModeSkip      = "skip"      // 跳过，保留已有文章
+	importModeOverwrite = "overwrite" // 覆盖已有文章，保留文章ID和阅读量，清理不再引用的图片
+	importModeVersion   = "version"   // 保存为已有文章的新修订版本，不修改当前内容，可在修订历史中对比和恢复
+)
+
+// parseImportMode 读取表单中的导入模式，缺省为跳过
+func parseImportMode(c *gin.Context) (string, bool) {
+	mode := c.DefaultPostForm("mode", importModeSkip)
+	switch mode {
+	case importModeSkip, importModeOverwrite, importModeVersion:
+		return mode, true
+	}
+	utils.BadRequest(c, "不支持的导入模式: "+mode)
+	return "", false
+}
+
+// ImportReportItem 博客导入中每篇文章的结果
+type ImportReportItem struct {
+	Source    string `json:"source"` // 源文件路径或 WordPress 文章 ID
+	Title     string `json:"title"`
+	Status    string `json:"status"` // created, failed, skipped
+	ArticleID uint   `json:"article_id,omitempty"`
+	Slug      string `json:"slug,omitempty"`
+	Message   string `json:"message,omitempty"`
+}
+
+// importSite 解压后的待导入站点
+type importSite struct {
+	format  string
+	dir     string // 站点根目录（本地路径），单独上传 WXR 时为空
+	posts   []*importer.Post
+	failed  []ImportReportItem // 解析失败的文章
+	skipped int                // WordPress 中已删除和自动保存的草稿
+}
+
+// ImportBlog 导入其他博客系统的文章
+// 表单字段: format - 博客类型（auto、hexo、hugo、jekyll、wordpress，缺省自动识别）, mode - 导入模式,
+// file - ZIP 或 XML 文件，大文件可改用 upload_id 引用已完成的分片上传（type 为 import）
+func ImportBlog(c *gin.Context) {
+	http.NewResponseController(c.Writer).SetReadDeadline(time.Time{})
+
+	format := c.DefaultPostForm("format", "auto")
+	if format != "auto" && !importer.IsValidFormat(format) {
+		utils.BadRequest(c, "不支持的博客类型: "+format)
+		return
+	}
+	mode, ok := parseImportMode(c)
+	if !ok {
+		return
+	}
+	var file zipSource
+	if id := c.PostForm("upload_id"); id != "" {
+		upload, ok := completedChunkedUpload(c, id)
+		if !ok {
+			return
+		}
+		file = zipSource{name: upload.FileName, size: upload.Size, save: func(dst string) error {
+			return takeChunkedUpload(upload, dst)
+		}}
+	} else {
+		header, err := c.FormFile("file")
+		if err != nil {
+			utils.BadRequest(c, "请上传 ZIP 或 XML 文件")
+			return
+		}
+		file = zipSource{name: header.Filename, size: header.Size, save: func(dst string) error {
+			return c.SaveUploadedFile(header, dst)
+		}}
+	}
+	ext := strings.ToLower(filepath.Ext(file.name))
+	if ext != ".zip" && ext != ".xml" {
+		utils.BadRequest(c, "只支持 ZIP 和 XML 文件")
+		return
+	}
+
+	taskID := fmt.Sprintf("import_%d", time.Now().UnixNano())
+	os.MkdirAll("./temp_zip", 0755)
+	savePath := fmt.Sprintf("./temp_zip/%s%s", taskID, ext)
+	if err := file.save(savePath); err != nil {
+		utils.ErrorWithMessage(c, errmsg.ERROR, "保存文件失败: "+err.Error())
+		return
+	}
+
+	task := &UploadTaskV2{
+		ID:        taskID,
+		FileName:  file.name,
+		FileSize:  file.size,
+		Format:    format,
+		Mode:      mode,
+		Status:    "processing",
+		StartTime: time.Now(),
+		Errors:    make([]UploadError, 0),
+		Report:    make([]ImportReportItem, 0),
+		operator:  currentUsername(c),
+		source:    savePath,
+	}
+	addUploadTask(task)
+
+	go runImport(task, format, nil)
+
+	middlewares.AuditAfter(c, gin.H{"file": file.name, "format": format, "mode": mode, "task_id": taskID})
+	utils.Success(c, gin.H{"task_id": taskID})
+}
+
+// runImport 后台执行导入任务
+// 参数: done - 服务重启前已处理的文章（来源路径），继续处理中断的任务时跳过，新任务为 nil
+func runImport(task *UploadTaskV2, format string, done map[string]bool) {
+	taskSem.acquire(context.Background())
+	defer taskSem.release()
+	defer os.Remove(task.source)
+
+	tempDir := filepath.Join("./temp_zip", task.ID)
+	defer os.RemoveAll(tempDir)
+
+	site, err := loadImportSite(task.source, format, tempDir)
+	if err != nil {
+		updateTaskFailed(task, err.Error())
+		return
+	}
+
+	task.mu.Lock()
+	task.Format = site.format
+	task.TotalFiles = len(site.posts) + len(site.failed)
+	for _, item := range site.failed {
+		if !done[item.Source] {
+			task.Processed++
+			task.Failed++
+			task.recordImportFailure(item)
+		}
+	}
+	task.mu.Unlock()
+	broadcastProgress(task)
+
+	uploaded := make(map[string]string) // 本地文件 -> 站内地址，多篇文章引用同一文件时只上传一次
+	startTime := time.Now()
+	processed := 0 // 本次处理的文章数，用于计算速度
+	for _, post := range site.posts {
+		task.mu.Lock()
+		cancelled := task.Cancelled
+		task.mu.Unlock()
+		if cancelled {
+			break
+		}
+		if done[post.Source] {
+			continue
+		}
+
+		item := importPost(site, post, task.Mode, task.operator, uploaded)
+
+		task.mu.Lock()
+		task.Processed++
+		processed++
+		if item.Status != importFailed {
+			task.Success++
+			task.ProcessedFiles = append(task.ProcessedFiles, item.Source)
+			task.Report = append(task.Report, item)
+		} else {
+			task.Failed++
+			task.recordImportFailure(item)
+		}
+		if elapsed := time.Since(startTime).Seconds(); elapsed > 0 {
+			task.Speed = float64(processed) / elapsed
+			task.ETA = formatDuration(float64(task.TotalFiles-task.Processed) / task.Speed)
+		}
+		task.mu.Unlock()
+		broadcastProgress(task)
+	}
+
+	task.mu.Lock()
+	if site.skipped > 0 {
+		task.Report = append(task.Report, ImportReportItem{
+			Source:  "WordPress",
+			Status:  importSkipped,
+			Message: fmt.Sprintf("跳过 %d 篇已删除或自动保存的文章", site.skipped),
+		})
+	}
+	if task.Cancelled {
+		task.Status = "cancelled"
+	} else if task.TotalFiles == 0 {
+		task.Status = "failed"
+		task.Errors = append(task.Errors, UploadError{Error: "未找到可导入的文章"})
+	} else if task.Failed == task.TotalFiles {
+		task.Status = "failed"
+	} else {
+		task.Status = "completed"
+	}
+	now := time.Now()
+	task.EndTime = &now
+	task.mu.Unlock()
+
+	broadcastProgress(task)
+}
+
+// recordImportFailure 记录失败的文章（调用方持有 task.mu）
+func (task *UploadTaskV2) recordImportFailure(item ImportReportItem) {
+	task.Report = append(task.Report, item)
+	task.Errors = append(task.Errors, UploadError{FileName: item.Source, Error: item.Message})
+	task.FailedFiles = append(task.FailedFiles, item.Source)
+}
+
+// loadImportSite 解压并解析上传的文件
+// 参数: file - 上传的 ZIP 或 XML 文件, format - 指定的博客类型（auto 为自动识别）, tempDir - 解压目录
+func loadImportSite(file string, format string, tempDir string) (*importSite, error) {
+	if strings.HasSuffix(file, ".xml") {
+		if format != "auto" && format != importer.FormatWordPress {
+			return nil, fmt.Errorf("XML 文件只支持 WordPress 导出格式")
+		}
+		return loadWXR(file, "")
+	}
+
+	zr, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("ZIP文件损坏: %w", err)
+	}
+	defer zr.Close()
+
+	var paths []string
+	entries := make(map[string]*zip.File)
+	for _, f := range zr.File {
+		name := strings.TrimPrefix(f.Name, "./")
+		if f.FileInfo().IsDir() || importer.Skip(name) || strings.HasPrefix(name, "__MACOSX/") {
+			continue
+		}
+		paths = append(paths, name)
+		entries[name] = f
+	}
+
+	site := &importSite{format: format}
+	root := ""
+	wxrFile := ""
+	if format == "auto" || format == importer.FormatWordPress {
+		wxrFile = findWXR(paths, entries)
+	}
+	switch {
+	case wxrFile != "":
+		site.format = importer.FormatWordPress
+		root = path.Dir(wxrFile) + "/"
+		if root == "./" {
+			root = ""
+		}
+	case format == importer.FormatWordPress:
+		return nil, fmt.Errorf("ZIP 中未找到 WordPress 导出文件")
+	default:
+		detected, detectedRoot, ok := importer.Detect(paths)
+		if format == "auto" {
+			if !ok {
+				return nil, fmt.Errorf("无法识别博客类型，请确认 ZIP 包含 Hexo、Hugo 或 Jekyll 的源码目录")
+			}
+			site.format = detected
+		}
+		if ok && detected == site.format {
+			root = detectedRoot
+		}
+	}
+
+	// 解压站点根目录下的文件（文章和被引用的资源）
+	site.dir = tempDir
+	for _, name := range paths {
+		if !strings.HasPrefix(name, root) {
+			continue
+		}
+		destPath, ok := zipEntryPath(tempDir, filepath.FromSlash(strings.TrimPrefix(name, root)))
+		if !ok {
+			continue
+		}
+		os.MkdirAll(filepath.Dir(destPath), 0755)
+		if err := extractZipFile(entries[name], destPath); err != nil {
+			return nil, fmt.Errorf("解压 %s 失败: %w", name, err)
+		}
+	}
+
+	if site.format == importer.FormatWordPress {
+		return loadWXR(filepath.Join(tempDir, filepath.FromSlash(strings.TrimPrefix(wxrFile, root))), tempDir)
+	}
+
+	for _, src := range importer.PostFiles(site.format, root, paths) {
+		data, err := os.ReadFile(filepath.Join(tempDir, filepath.FromSlash(src.Path)))
+		if err == nil {
+			var post *importer.Post
+			if post, err = importer.ParsePost(site.format, src, data); err == nil {
+				site.posts = append(site.posts, post)
+				continue
+			}
+		}
+		site.failed = append(site.failed, ImportReportItem{Source: src.Path, Status: importFailed, Message: err.Error()})
+	}
+	return site, nil
+}
+
+// findWXR 查找 ZIP 中的 WordPress 导出文件
+func findWXR(paths []string, entries map[string]*zip.File) string {
+	for _, name := range paths {
+		if !strings.EqualFold(path.Ext(name), ".xml") {
+			continue
+		}
+		rc, err := entries[name].Open()
+		if err != nil {
+			continue
+		}
+		head := make([]byte, 4096)
+		n, _ := io.ReadFull(rc, head)
+		rc.Close()
+		if importer.IsWXR(head[:n]) {
+			return name
+		}
+	}
+	return ""
+}
+
+// loadWXR 解析 WordPress 导出文件，dir 为一起上传的 wp-content 所在目录
+func loadWXR(file string, dir string) (*importSite, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	head := make([]byte, 4096)
+	n, _ := io.ReadFull(f, head)
+	if !importer.IsWXR(head[:n]) {
+		return nil, fmt.Errorf("不是 WordPress 导出文件（WXR）")
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	posts, skipped, err := importer.ParseWXR(f)
+	if err != nil {
+		return nil, err
+	}
+	return &importSite{format: importer.FormatWordPress, dir: dir, posts: posts, skipped: skipped}, nil
+}
+
+// importPost 上传文章引用的本地文件并保存文章
+func importPost(site *importSite, post *importer.Post, mode string, username string, uploaded map[string]string) ImportReportItem {
+	item := ImportReportItem{Source: post.Source, Title: post.Title, Status: importFailed}
+	if strings.TrimSpace(post.Title) == "" {
+		item.Message = "缺少标题"
+		return item
+	}
+	match, ok := matchImportedArt(&item, 0, post.Slug, mode)
+	if !ok {
+		return item
+	}
+
+	content := importer.RewriteRefs(post.Content, func(ref string) (string, bool) {
+		return uploadImportAsset(site, post, ref, "article", uploaded)
+	})
+	cover, ok := uploadImportAsset(site, post, post.Cover, "cover", uploaded)
+	if !ok {
+		cover = ""
+		if _, _, local := importer.LocalRef(post.Cover, false); !local {
+			cover = post.Cover // 外链封面保持原样，找不到的本地文件不保留
+		}
+	}
+
+	cid := 1
+	if post.Category != "" {
+		cid = model.GetOrCreateCategory(post.Category)
+	}
+
+	article := &model.Article{
+		Title:   post.Title,
+		Slug:    post.Slug,
+		Cid:     cid,
+		Desc:    post.Desc,
+		Content: content,
+		Img:     cover,
+		Tags:    strings.Join(post.Tags, ","),
+		Status:  post.Status,
+	}
+	if !post.Date.IsZero() {
+		date := post.Date
+		article.CreatedAt = date
+		if post.Status != importer.StatusDraft {
+			article.PublishAt = &date
+		}
+	}
+
+	saveImportedArt(&item, article, match, mode, username)
+	return item
+}
+
+// matchImportedArt 查找与导入文章匹配的已有文章，跳过模式下匹配到时直接记录结果
+// 返回: 已有文章ID（0 表示新建）和是否需要继续导入
+func matchImportedArt(item *ImportReportItem, id uint, slug string, mode string) (uint, bool) {
+	match, by := model.FindArtForImport(id, slug, item.Title)
+	if match > 0 && mode == importModeSkip {
+		item.Status = importSkipped
+		item.ArticleID = match
+		item.Message = fmt.Sprintf("已存在（按%s匹配）", artMatchText[by])
+		return match, false
+	}
+	return match, true
+}
+
+// artMatchText 匹配方式的说明
+var artMatchText = map[string]string{
+	model.ArtMatchID:    " ID ",
+	model.ArtMatchSlug:  "别名",
+	model.ArtMatchTitle: "标题",
+}
+
+// saveImportedArt 按导入模式保存文章并记录结果
+// 参数: match - 匹配的已有文章ID（0 表示新建）, mode - 导入模式, username - 操作用户
+func saveImportedArt(item *ImportReportItem, article *model.Article, match uint, mode string, username string) {
+	item.Title = article.Title
+	if match == 0 {
+		slug := article.Slug
+		code := model.CreateArt(article)
+		if code == errmsg.ERROR_ART_SLUG_USED {
+			// 别名已被站内其他文章使用时改为根据标题生成
+			article.Slug = ""
+			code = model.CreateArt(article)
+			item.Message = "原别名 " + slug + " 已被占用，已重新生成"
+		}
+		if code != errmsg.SUCCESS {
+			item.Message = errmsg.GetErrMsg(code)
+			return
+		}
+		model.SaveArtRevision(int(article.ID), username, "导入文章")
+		item.Status = importCreated
+		item.ArticleID = article.ID
+		item.Slug = article.Slug
+		return
+	}
+
+	old, code := model.GetArtInfoForAdmin(int(match))
+	if code != errmsg.SUCCESS {
+		item.Message = errmsg.GetErrMsg(code)
+		return
+	}
+	if article.Type == 0 {
+		article.Type = 1
+	}
+	if article.Top == 0 {
+		article.Top = old.Top // 导入文件未指定置顶等级时保留原来的设置
+	}
+	item.ArticleID = match
+
+	if mode == importModeVersion {
+		if code := model.AddArtRevision(int(match), article, username, "导入的新版本（未应用）"); code != errmsg.SUCCESS {
+			item.Message = errmsg.GetErrMsg(code)
+			return
+		}
+		item.Status = importVersioned
+		item.Slug = old.Slug
+		item.Message = "已保存为新修订版本，可在修订历史中对比和恢复"
+		return
+	}
+
+	// 覆盖：状态、阅读量和评论保持不变，别名被其他文章占用时沿用原别名
+	code = applyArtEdit(int(match), article, username, "导入覆盖")
+	if code == errmsg.ERROR_ART_SLUG_USED {
+		article.Slug = old.Slug
+		code = applyArtEdit(int(match), article, username, "导入覆盖")
+	}
+	if code != errmsg.SUCCESS {
+		item.Message = errmsg.GetErrMsg(code)
+		return
+	}
+	item.Status = importUpdated
+	item.Slug = article.Slug
+}
+
+// uploadImportAsset 上传正文或封面引用的站点内文件，返回站内地址
+// 相对路径依次在文章所在目录（和 Hexo 的资源目录）中查找，站点绝对路径在静态文件目录中查找
+func uploadImportAsset(site *importSite, post *importer.Post, ref string, uploadType string, uploaded map[string]string) (string, bool) {
+	if site.dir == "" {
+		return "", false
+	}
+	p, absolute, ok := importer.LocalRef(ref, site.format == importer.FormatWordPress)
+	if !ok {
+		return "", false
+	}
+	dirs := post.AssetDirs
+	if absolute {
+		dirs = importer.StaticDirs(site.format)
+	}
+	for _, dir := range dirs {
+		local := filepath.Join(site.dir, filepath.FromSlash(path.Join(dir, p)))
+		if !isWithinDir(site.dir, local) {
+			continue
+		}
+		key := uploadType + ":" + local
+		if url, ok := uploaded[key]; ok {
+			return url, true
+		}
+		if info, err := os.Stat(local); err != nil || info.IsDir() {
+			continue
+		}
+		url, err := uploadLocalFile(local, uploadType)
+		if err != nil {
+			return "", false
+		}
+		uploaded[key] = url
+		return url, true
+	}
+	return "", false
+}
@@ -0,0 +1,158 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"yanblog/model"
+	"yanblog/utils"
+	"yanblog/utils/errmsg"
+
+	"github.com/gin-gonic/gin"
+)
+
+// revisionSnapshot 用于对比的修订快照
+type revisionSnapshot struct {
+	ID      uint   `json:"id"` // 0 表示文章当前内容
+	Title   string `json:"title"`
+	Desc    string `json:"desc"`
+	Tags    string `json:"tags"`
+	Content string `json:"-"`
+}
+
+// GetArtRevisions 获取文章的修订历史
+func GetArtRevisions(c *gin.Context) {
+	id, ok := utils.ParseIDParam(c)
+	if !ok {
+		return
+	}
+	if _, code := model.GetArtInfoForAdmin(id); code != errmsg.SUCCESS {
+		utils.Error(c, code)
+		return
+	}
+
+	pageSize, pageNum, _ := utils.ParsePageParams(c)
+	data, total := model.GetArtRevisions(id, pageSize, pageNum)
+	utils.SuccessWithTotal(c, data, total)
+}
+
+// GetArtRevision 获取单个修订的完整内容
+func GetArtRevision(c *gin.Context) {
+	id, ok := utils.ParseIDParam(c)
+	if !ok {
+		return
+	}
+	revID, err := strconv.Atoi(c.Param("rid"))
+	if err != nil {
+		utils.BadRequest(c, "无效的修订 ID")
+		return
+	}
+
+	rev, code := model.GetArtRevision(id, revID)
+	if code != errmsg.SUCCESS {
+		utils.Error(c, code)
+		return
+	}
+	utils.Success(c, rev)
+}
+
+// DiffArtRevisions 对比文章的两个修订版本
+// 查询参数: from - 旧版本ID, to - 新版本ID（为空或 0 表示文章当前内容）
+func DiffArtRevisions(c *gin.Context) {
+	id, ok := utils.ParseIDParam(c)
+	if !ok {
+		return
+	}
+	fromID, err := strconv.Atoi(c.Query("from"))
+	if err != nil || fromID <= 0 {
+		utils.BadRequest(c, "参数错误，需要 from 修订 ID")
+		return
+	}
+	toID, _ := strconv.Atoi(c.Query("to"))
+
+	from, code := loadRevisionSnapshot(id, fromID)
+	if code != errmsg.SUCCESS {
+		utils.Error(c, code)
+		return
+	}
+	to, code := loadRevisionSnapshot(id, toID)
+	if code != errmsg.SUCCESS {
+		utils.Error(c, code)
+		return
+	}
+
+	lines := utils.DiffLines(from.Content, to.Content)
+	added, removed := utils.DiffStats(lines)
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": errmsg.SUCCESS,
+		"data": gin.H{
+			"from":    from,
+			"to":      to,
+			"lines":   lines,
+			"added":   added,
+			"removed": removed,
+			"fields": gin.H{
+				"title": from.Title != to.Title,
+				"desc":  from.Desc != to.Desc,
+				"tags":  from.Tags != to.Tags,
+			},
+		},
+		"message": errmsg.GetErrMsg(errmsg.SUCCESS),
+	})
+}
+
+// RestoreArtRevision 将文章恢复到指定修订版本（走与编辑文章相同的校验流程）
+func RestoreArtRevision(c *gin.Context) {
+	id, ok := utils.ParseIDParam(c)
+	if !ok {
+		return
+	}
+	revID, err := strconv.Atoi(c.Param("rid"))
+	if err != nil {
+		utils.BadRequest(c, "无效的修订 ID")
+		return
+	}
+
+	rev, code := model.GetArtRevision(id, revID)
+	if code != errmsg.SUCCESS {
+		utils.Error(c, code)
+		return
+	}
+	current, code := model.GetArtInfoForAdmin(id)
+	if code != errmsg.SUCCESS {
+		utils.Error(c, code)
+		return
+	}
+
+	// 以当前文章为基础，覆盖修订中保存的字段（置顶等不属于修订内容的字段保持不变）
+	data := current
+	data.Title = rev.Title
+	data.Cid = rev.Cid
+	data.Desc = rev.Desc
+	data.Content = rev.Content
+	data.Img = rev.Img
+	data.Type = rev.Type
+	data.PdfUrl = rev.PdfUrl
+	data.Tags = rev.Tags
+
+	code = applyArtEdit(id, &data, currentUsername(c), fmt.Sprintf("恢复自版本 #%d", rev.ID))
+	utils.Error(c, code)
+}
+
+// loadRevisionSnapshot 加载修订快照，revID 为 0 时返回文章当前内容
+func loadRevisionSnapshot(artID int, revID int) (revisionSnapshot, int) {
+	if revID <= 0 {
+		art, code := model.GetArtInfoForAdmin(artID)
+		if code != errmsg.SUCCESS {
+			return revisionSnapshot{}, code
+		}
+		return revisionSnapshot{Title: art.Title, Desc: art.Desc, Tags: art.Tags, Content: art.Content}, errmsg.SUCCESS
+	}
+
+	rev, code := model.GetArtRevision(artID, revID)
+	if code != errmsg.SUCCESS {
+		return revisionSnapshot{}, code
+	}
+	return revisionSnapshot{ID: rev.ID, Title: rev.Title, Desc: rev.Desc, Tags: rev.Tags, Content: rev.Content}, errmsg.SUCCESS
+}
@@ -3,12 +3,11 @@ package v1
 import (
 	"archive/zip"
 	"context"
-	"encoding/json"
 	"fmt"
 	"io"
-	"mime/multipart"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -18,7 +17,6 @@ import (
 	"yanblog/utils/errmsg"
 
 	"github.com/gin-gonic/gin"
-	"github.com/gorilla/websocket"
 	"gopkg.in/yaml.v3"
 )
 
@@ -42,9 +40,16 @@ type UploadTaskV2 struct {
 	FailedFiles     []string         `json:"failed_files"`       // 失败文件列表（用于重试）
 	Speed           float64          `json:"speed"`              // 上传速度 (MB/s)
 	ETA             string           `json:"eta"`                // 预计剩余时间
-	Clients         []*websocket.Conn `json:"-"`                 // WebSocket 客户端
-	ClientsMu       sync.Mutex       `json:"-"`
+	Format          string           `json:"format,omitempty"`   // 博客导入的类型（hexo、hugo、jekyll、wordpress）
+	Mode            string           `json:"mode,omitempty"`     // 导入模式（skip、overwrite、version），重试时沿用
+	Report          []ImportReportItem `json:"report,omitempty"`  // 博客导入中每篇文章的结果
 	mu              sync.Mutex
+	clients         progressClients  // 订阅进度的 WebSocket 客户端
+	operator        string           // 发起上传的用户，记录在文章修订中
+	source          string           // 保存的原始文件，用于继续处理和重试
+	saveMu          sync.Mutex       // 保存到数据库的顺序锁，见 saveUploadTask
+	savedAt         time.Time        // 上次保存到数据库的时间
+	saved           string           // 上次保存时的任务状态
 }
 
 // UploadError 上传错误详情
@@ -54,49 +59,17 @@ type UploadError struct {
 	Retried  bool   `json:"retried"`  // 是否已重试
 }
 
-// UploadHistory 上传历史记录
-type UploadHistory struct {
-	TaskID      string    `json:"task_id"`
-	FileName    string    `json:"file_name"`
-	TotalFiles  int       `json:"total_files"`
-	Success     int       `json:"success"`
-	Failed      int       `json:"failed"`
-	Status      string    `json:"status"`
-	Duration    string    `json:"duration"`
-	CreatedAt   time.Time `json:"created_at"`
-}
-
-// WebSocket 升级器
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true // 生产环境应该检查 Origin
-	},
-}
-
-// 全局管理
+// 全局管理（同时运行的任务数由 taskSem 限制，见 task_v2.go）
 var (
-	uploadTasksV2   = make(map[string]*UploadTaskV2)
-	tasksMuV2       sync.RWMutex
-	maxConcurrentV2 = 3
-	semaphoreV2     = make(chan struct{}, maxConcurrentV2)
-	
-	uploadHistory   = make([]UploadHistory, 0, 100) // 保留最近100条
-	historyMu       sync.RWMutex
-	historyFile     = "./data/upload_history.json"
+	uploadTasksV2 = make(map[string]*UploadTaskV2) // 任务状态同时保存在数据库中，见 upload_task_v2.go
+	tasksMuV2     sync.RWMutex
 )
 
-// init 加载历史记录
-func init() {
-	loadUploadHistory()
-}
-
 // WebSocketProgress 通过 WebSocket 推送进度
 func WebSocketProgress(c *gin.Context) {
 	taskID := c.Param("id")
 	
-	tasksMuV2.RLock()
-	task, exists := uploadTasksV2[taskID]
-	tasksMuV2.RUnlock()
+	task, exists := findUploadTask(taskID)
 	
 	if !exists {
 		c.JSON(http.StatusNotFound, gin.H{
@@ -106,46 +79,16 @@ func WebSocketProgress(c *gin.Context) {
 		return
 	}
 	
-	// 升级为 WebSocket
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
-	if err != nil {
-		return
-	}
-	
-	// 添加客户端
-	task.ClientsMu.Lock()
-	task.Clients = append(task.Clients, conn)
-	task.ClientsMu.Unlock()
-	
-	// 立即发送当前进度
-	sendProgress(task, conn)
-	
-	// 保持连接
-	defer func() {
-		task.ClientsMu.Lock()
-		for i, client := range task.Clients {
-			if client == conn {
-				task.Clients = append(task.Clients[:i], task.Clients[i+1:]...)
-				break
-			}
-		}
-		task.ClientsMu.Unlock()
-		conn.Close()
-	}()
-	
-	// 读取消息（可选，用于接收取消命令）
-	for {
-		_, _, err := conn.ReadMessage()
-		if err != nil {
-			break
-		}
-	}
+	serveProgress(c, &task.clients, func() interface{} {
+		return uploadTaskProgress(task)
+	})
 }
 
-// sendProgress 发送进度到 WebSocket 客户端
-func sendProgress(task *UploadTaskV2, conn *websocket.Conn) {
+// uploadTaskProgress 推送到 WebSocket 客户端的进度
+func uploadTaskProgress(task *UploadTaskV2) gin.H {
 	task.mu.Lock()
-	progress := map[string]interface{}{
+	defer task.mu.Unlock()
+	return gin.H{
 		"type":          "progress",
 		"task_id":       task.ID,
 		"file_name":     task.FileName,
@@ -153,7 +96,7 @@ func sendProgress(task *UploadTaskV2, conn *websocket.Conn) {
 		"processed":     task.Processed,
 		"success":       task.Success,
 		"failed":        task.Failed,
-		"progress":      float64(task.Processed) / float64(task.TotalFiles) * 100,
+		"progress":      taskProgress(task),
 		"status":        task.Status,
 		"speed":         task.Speed,
 		"eta":           task.ETA,
@@ -161,21 +104,26 @@ func sendProgress(task *UploadTaskV2, conn *websocket.Conn) {
 		"errors":        task.Errors,
 		"start_time":    task.StartTime,
 		"end_time":      task.EndTime,
+		"format":        task.Format,
+		"mode":          task.Mode,
+		"report":        task.Report,
 	}
-	task.mu.Unlock()
-	
-	conn.WriteJSON(progress)
 }
 
-// broadcastProgress 广播进度到所有客户端
+// taskProgress 任务进度百分比（调用方持有 task.mu），文件总数未统计出来时为 0
+func taskProgress(task *UploadTaskV2) float64 {
+	if task.TotalFiles == 0 {
+		return 0
+	}
+	return float64(task.Processed) / float64(task.TotalFiles) * 100
+}
+
+// broadcastProgress 保存任务状态并广播进度到所有客户端
 func broadcastProgress(task *UploadTaskV2) {
-	task.ClientsMu.Lock()
-	clients := make([]*websocket.Conn, len(task.Clients))
-	copy(clients, task.Clients)
-	task.ClientsMu.Unlock()
-	
-	for _, conn := range clients {
-		sendProgress(task, conn)
+	saveUploadTask(task)
+
+	if !task.clients.empty() {
+		task.clients.send(uploadTaskProgress(task))
 	}
 }
 
@@ -183,9 +131,7 @@ func broadcastProgress(task *UploadTaskV2) {
 func GetUploadProgressV2(c *gin.Context) {
 	taskID := c.Param("id")
 	
-	tasksMuV2.RLock()
-	task, exists := uploadTasksV2[taskID]
-	tasksMuV2.RUnlock()
+	task, exists := findUploadTask(taskID)
 	
 	if !exists {
 		c.JSON(http.StatusNotFound, gin.H{
@@ -205,7 +151,7 @@ func GetUploadProgressV2(c *gin.Context) {
 		"processed":   task.Processed,
 		"success":     task.Success,
 		"failed":      task.Failed,
-		"progress":    float64(task.Processed) / float64(task.TotalFiles) * 100,
+		"progress":    taskProgress(task),
 		"task_status": task.Status,
 		"speed":       task.Speed,
 		"eta":         task.ETA,
@@ -213,6 +159,9 @@ func GetUploadProgressV2(c *gin.Context) {
 		"errors":      task.Errors,
 		"start_time":  task.StartTime,
 		"end_time":    task.EndTime,
+		"format":      task.Format,
+		"mode":        task.Mode,
+		"report":      task.Report,
 	}
 	task.mu.Unlock()
 	
@@ -223,9 +172,7 @@ func GetUploadProgressV2(c *gin.Context) {
 func CancelUploadV2(c *gin.Context) {
 	taskID := c.Param("id")
 	
-	tasksMuV2.RLock()
-	task, exists := uploadTasksV2[taskID]
-	tasksMuV2.RUnlock()
+	task, exists := findUploadTask(taskID)
 	
 	if !exists {
 		c.JSON(http.StatusNotFound, gin.H{
@@ -236,7 +183,7 @@ func CancelUploadV2(c *gin.Context) {
 	}
 	
 	task.mu.Lock()
-	if task.Status == "processing" || task.Status == "retrying" {
+	if model.IsUploadTaskRunning(task.Status) {
 		task.Cancelled = true
 		task.Status = "cancelled"
 		now := time.Now()
@@ -257,9 +204,7 @@ func CancelUploadV2(c *gin.Context) {
 func RetryFailedUpload(c *gin.Context) {
 	taskID := c.Param("id")
 	
-	tasksMuV2.RLock()
-	task, exists := uploadTasksV2[taskID]
-	tasksMuV2.RUnlock()
+	task, exists := findUploadTask(taskID)
 	
 	if !exists {
 		c.JSON(http.StatusNotFound, gin.H{
@@ -287,62 +232,104 @@ func RetryFailedUpload(c *gin.Context) {
 		})
 		return
 	}
+
+	// 博客导入任务的原始文件在导入结束后已删除
+	if _, err := os.Stat(task.source); task.Format != "" || task.source == "" || err != nil {
+		task.mu.Unlock()
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  errmsg.ERROR,
+			"message": "原始 ZIP 文件已清理，无法重试",
+		})
+		return
+	}
+
+	if task.RetryCount >= task.MaxRetries {
+		task.mu.Unlock()
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  errmsg.ERROR,
+			"message": fmt.Sprintf("已达到最大重试次数 %d", task.MaxRetries),
+		})
+		return
+	}
 	
-	// 重置状态
+	// 重置状态，失败的文章重新处理后再逐篇记录结果
+	failedFiles := task.FailedFiles
 	task.Status = "retrying"
 	task.RetryCount++
-	task.Processed = task.TotalFiles - len(task.FailedFiles)
-	task.Success = task.TotalFiles - len(task.FailedFiles)
+	task.Processed = task.TotalFiles - len(failedFiles)
+	task.Success = task.TotalFiles - len(failedFiles)
 	task.Failed = 0
 	task.Errors = make([]UploadError, 0)
+	task.FailedFiles = make([]string, 0)
+	task.EndTime = nil
+	retryCount := task.RetryCount
 	task.mu.Unlock()
 	
 	// 通知客户端
 	broadcastProgress(task)
 	
 	// 异步重试
-	go func() {
-		// TODO: 实现重试逻辑
-		// 这需要保存原始的 ZIP 文件或者文件路径
-	}()
+	retry := make(map[string]bool, len(failedFiles))
+	for _, name := range failedFiles {
+		retry[name] = true
+	}
+	go runZipTask(task, func(name string) bool { return retry[name] }, true)
 	
 	c.JSON(http.StatusOK, gin.H{
 		"status":  errmsg.SUCCESS,
-		"message": fmt.Sprintf("开始重试 %d 个失败文件", len(task.FailedFiles)),
+		"message": fmt.Sprintf("开始重试 %d 个失败文件", len(failedFiles)),
 		"data": gin.H{
-			"failed_files": task.FailedFiles,
-			"retry_count":  task.RetryCount,
+			"failed_files": failedFiles,
+			"retry_count":  retryCount,
 		},
 	})
 }
 
-// GetUploadHistory 获取上传历史
-func GetUploadHistory(c *gin.Context) {
-	page := 1
-	size := 20
-	
-	historyMu.RLock()
-	total := len(uploadHistory)
-	start := max(0, total-page*size)
-	end := min(total, start+size)
-	
-	history := make([]UploadHistory, 0)
-	if start < total {
-		history = uploadHistory[start:end]
+// runZipTask 后台处理保存的 ZIP 文件中 filter 选中的文章（新上传的任务、重试失败的文章，或继续处理服务重启前中断的任务）
+// 参数: filter - nil 表示处理全部, retried - 是否为重试，重试产生的错误会标记为已重试
+func runZipTask(task *UploadTaskV2, filter func(name string) bool, retried bool) {
+	taskSem.acquire(context.Background())
+	defer taskSem.release()
+
+	src, err := os.Open(task.source)
+	if err != nil {
+		updateTaskFailed(task, "打开文件失败: "+err.Error())
+		return
 	}
-	historyMu.RUnlock()
-	
-	c.JSON(http.StatusOK, gin.H{
-		"status":  errmsg.SUCCESS,
-		"total":   total,
-		"page":    page,
-		"size":    size,
-		"data":    history,
-	})
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		updateTaskFailed(task, "打开文件失败: "+err.Error())
+		return
+	}
+
+	tempDir := fmt.Sprintf("./temp_zip/%s_%d", task.ID, time.Now().UnixNano())
+	os.MkdirAll(tempDir, 0755)
+	defer os.RemoveAll(tempDir)
+
+	_, errors := processZipStreamV2(context.Background(), src, info.Size(), tempDir, task, time.Now(), filter)
+	for i := range errors {
+		errors[i].Retried = retried
+	}
+	finishZipTask(task, errors)
+}
+
+// zipSource 待处理的上传文件（ZIP 或 WordPress XML）：表单上传的文件或已完成的分片上传
+type zipSource struct {
+	name string
+	size int64
+	save func(dst string) error // 保存到 dst
 }
 
 // UploadArticleZipV2 增强版ZIP上传（支持单个和批量上传）
+// 表单字段 mode 指定与已有文章重复时的处理方式：skip（缺省）、overwrite、version
+// 大文件可先通过分片上传（type 为 import），再用 upload_id 字段（可多个）引用
+// 每个文件保存后创建一个任务并立即返回 task_id，文章在后台导入，进度通过 /article/upload/:id 或 WebSocket 查询
 func UploadArticleZipV2(c *gin.Context) {
+	mode, ok := parseImportMode(c)
+	if !ok {
+		return
+	}
 	form, err := c.MultipartForm()
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
@@ -354,28 +341,42 @@ func UploadArticleZipV2(c *gin.Context) {
 
 	files := form.File["files"]
 	if len(files) == 0 {
-		file, err := c.FormFile("file")
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"status":  errmsg.ERROR,
-				"message": "文件上传失败，请使用 file 或 files 字段",
-			})
+		files = form.File["file"]
+	}
+	sources := make([]zipSource, 0, len(files))
+	for _, file := range files {
+		sources = append(sources, zipSource{name: file.Filename, size: file.Size, save: func(dst string) error {
+			return c.SaveUploadedFile(file, dst)
+		}})
+	}
+	for _, id := range form.Value["upload_id"] {
+		upload, ok := completedChunkedUpload(c, id)
+		if !ok {
 			return
 		}
-		files = []*multipart.FileHeader{file}
+		sources = append(sources, zipSource{name: upload.FileName, size: upload.Size, save: func(dst string) error {
+			return takeChunkedUpload(upload, dst)
+		}})
+	}
+	if len(sources) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  errmsg.ERROR,
+			"message": "文件上传失败，请使用 file、files 或 upload_id 字段",
+		})
+		return
 	}
 
-	if len(files) == 1 {
-		result := processSingleZipFileSync(c, files[0])
+	if len(sources) == 1 {
+		result := startZipTask(c, sources[0], mode)
 		c.JSON(http.StatusOK, result)
 		return
 	}
 
-	results := make([]gin.H, 0, len(files))
+	results := make([]gin.H, 0, len(sources))
 	totalSuccess := 0
 
-	for _, file := range files {
-		result := processSingleZipFileSync(c, file)
+	for _, src := range sources {
+		result := startZipTask(c, src, mode)
 		results = append(results, result)
 		if result["status"] == errmsg.SUCCESS {
 			totalSuccess++
@@ -384,83 +385,62 @@ func UploadArticleZipV2(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":  errmsg.SUCCESS,
-		"total":   len(files),
+		"total":   len(sources),
 		"success": totalSuccess,
 		"results": results,
 	})
 }
 
-func processSingleZipFileSync(c *gin.Context, file *multipart.FileHeader) gin.H {
+// startZipTask 保存上传的文件并创建任务，文章由 runZipTask 在后台导入
+// 返回: 该文件的结果，成功时 data 中包含 task_id
+func startZipTask(c *gin.Context, file zipSource, mode string) gin.H {
 	taskID := fmt.Sprintf("upload_v2_%d", time.Now().UnixNano())
+	zipPath := fmt.Sprintf("./temp_zip/%s.zip", taskID)
 	task := &UploadTaskV2{
 		ID:         taskID,
-		FileName:   file.Filename,
-		FileSize:   file.Size,
+		FileName:   file.name,
+		FileSize:   file.size,
 		Status:     "processing",
 		StartTime:  time.Now(),
 		Errors:     make([]UploadError, 0),
 		MaxRetries: 3,
-		Clients:    make([]*websocket.Conn, 0),
-	}
-
-	tasksMuV2.Lock()
-	uploadTasksV2[taskID] = task
-	tasksMuV2.Unlock()
-
-	src, err := file.Open()
-	if err != nil {
-		updateTaskFailed(task, "打开文件失败: "+err.Error())
-		return gin.H{
-			"status":  errmsg.ERROR,
-			"message": "打开文件失败",
-			"file":    file.Filename,
-		}
+		Mode:       mode,
+		Report:     make([]ImportReportItem, 0),
+		operator:   currentUsername(c),
+		source:     zipPath,
 	}
-	defer src.Close()
+	addUploadTask(task)
 
-	zipPath := fmt.Sprintf("./temp_zip/%s.zip", task.ID)
 	os.MkdirAll("./temp_zip", 0755)
-
-	outFile, err := os.Create(zipPath)
-	if err != nil {
-		updateTaskFailed(task, "保存文件失败")
+	if err := file.save(zipPath); err != nil {
+		updateTaskFailed(task, "保存文件失败: "+err.Error())
 		return gin.H{
 			"status":  errmsg.ERROR,
 			"message": "保存文件失败",
-			"file":    file.Filename,
+			"file":    file.name,
 		}
 	}
 
-	io.Copy(outFile, src)
-	outFile.Close()
-
-	src, _ = os.Open(zipPath)
-	defer src.Close()
+	go runZipTask(task, nil, false)
 
-	tempDir := fmt.Sprintf("./temp_zip/%s", task.ID)
-	os.MkdirAll(tempDir, 0755)
-	defer os.RemoveAll(tempDir)
+	return gin.H{
+		"status":  errmsg.SUCCESS,
+		"message": "上传成功，正在后台导入",
+		"file":    file.name,
+		"data": gin.H{
+			"task_id": task.ID,
+		},
+	}
+}
 
-	startTime := time.Now()
-	successCount, errors := processZipStreamV2(c.Request.Context(), src, file.Size, tempDir, task, startTime)
-	
-	// 更新任务状态
+// finishZipTask 汇总 ZIP 上传（或重试）的结果，成功和失败数以逐篇统计的结果为准
+// 全部成功时删除保存的 ZIP 文件，否则保留用于重试失败的文章
+func finishZipTask(task *UploadTaskV2, errors []UploadError) {
 	task.mu.Lock()
 	task.Processed = task.TotalFiles
-	task.Success = successCount
-	task.Failed = task.TotalFiles - successCount
-	
-	for _, err := range errors {
-		task.Errors = append(task.Errors, UploadError{
-			FileName: err.FileName,
-			Error:    err.Error,
-			Retried:  false,
-		})
-		if err.FileName != "" {
-			task.FailedFiles = append(task.FailedFiles, err.FileName)
-		}
-	}
-	
+	task.Failed = task.TotalFiles - task.Success
+	task.Errors = append(task.Errors, errors...)
+
 	if task.Cancelled {
 		task.Status = "cancelled"
 	} else if task.Failed == task.TotalFiles {
@@ -471,43 +451,20 @@ func processSingleZipFileSync(c *gin.Context, file *multipart.FileHeader) gin.H
 	now := time.Now()
 	task.EndTime = &now
 	task.mu.Unlock()
-	
-	// 保存到历史
-	saveToHistory(task)
-	
-	// 通知所有客户端
-	broadcastProgress(task)
-	
-	// 清理 ZIP 文件（如果成功）
-	if task.Status == "completed" && task.Failed == 0 {
-		os.Remove(zipPath)
-	}
 
-	if task.Status == "failed" && task.Failed == task.TotalFiles {
-		return gin.H{
-			"status":  errmsg.ERROR,
-			"message": "上传失败",
-			"file":    file.Filename,
-			"errors":  task.Errors,
-		}
-	}
+	// 保存结果并通知所有客户端
+	broadcastProgress(task)
 
-	return gin.H{
-		"status":  errmsg.SUCCESS,
-		"message": fmt.Sprintf("上传完成，成功 %d/%d", task.Success, task.TotalFiles),
-		"file":    file.Filename,
-		"data": gin.H{
-			"total":    task.TotalFiles,
-			"success":  task.Success,
-			"failed":   task.Failed,
-			"errors":   task.Errors,
-			"task_id":  task.ID,
-		},
+	if task.Status == "completed" && task.Failed == 0 {
+		os.Remove(task.source)
 	}
 }
 
 // processZipStreamV2 增强版流式处理
-func processZipStreamV2(ctx context.Context, reader io.ReaderAt, size int64, tempDir string, task *UploadTaskV2, startTime time.Time) (int, []UploadError) {
+// ZIP 中的每个 Markdown 文件都作为一篇文章导入（每篇一个目录或平铺在同一目录均可），资源文件先全部解压，文章可引用 ZIP 内任意位置的文件
+// 参数: filter - 只处理返回 true 的文章（nil 表示处理全部，用于重试和继续处理中断的任务），文件总数保持首次上传时的统计
+// 返回: 本次成功的文章数和错误列表（逐篇的结果同时记录在任务的 ProcessedFiles / FailedFiles 中）
+func processZipStreamV2(ctx context.Context, reader io.ReaderAt, size int64, tempDir string, task *UploadTaskV2, startTime time.Time, filter func(name string) bool) (int, []UploadError) {
 	successCount := 0
 	errors := make([]UploadError, 0)
 	
@@ -520,19 +477,39 @@ func processZipStreamV2(ctx context.Context, reader io.ReaderAt, size int64, tem
 	// 统计文件数
 	mdCount := 0
 	for _, f := range zipReader.File {
-		if !f.FileInfo().IsDir() && strings.HasSuffix(strings.ToLower(f.Name), ".md") {
+		if isZipArticle(f) {
 			mdCount++
 		}
 	}
 	
-	task.mu.Lock()
-	task.TotalFiles = mdCount
-	task.mu.Unlock()
+	if filter == nil {
+		task.mu.Lock()
+		task.TotalFiles = mdCount
+		task.mu.Unlock()
+	}
 	
 	if mdCount == 0 {
 		errors = append(errors, UploadError{Error: "ZIP文件中未找到Markdown文件"})
 		return 0, errors
 	}
+
+	// 先解压图片等资源文件，供文章中的相对路径引用
+	for _, zipFile := range zipReader.File {
+		if zipFile.FileInfo().IsDir() || isZipArticle(zipFile) || strings.HasPrefix(zipFile.Name, "__MACOSX/") {
+			continue
+		}
+		destPath, ok := zipEntryPath(tempDir, zipFile.Name)
+		if !ok {
+			continue
+		}
+		os.MkdirAll(filepath.Dir(destPath), 0755)
+		if err := extractZipFile(zipFile, destPath); err != nil {
+			errors = append(errors, UploadError{
+				FileName: zipFile.Name,
+				Error:    "解压资源文件失败: " + err.Error(),
+			})
+		}
+	}
 	
 	// 处理每个文件
 	for _, zipFile := range zipReader.File {
@@ -550,15 +527,29 @@ func processZipStreamV2(ctx context.Context, reader io.ReaderAt, size int64, tem
 			return successCount, errors
 		}
 		
-		if zipFile.FileInfo().IsDir() || !strings.HasSuffix(strings.ToLower(zipFile.Name), ".md") {
+		if !isZipArticle(zipFile) || (filter != nil && !filter(zipFile.Name)) {
 			continue
 		}
 		
 		// 提取文件
-		fileDir := filepath.Join(tempDir, filepath.Dir(zipFile.Name))
-		os.MkdirAll(fileDir, 0755)
-		
-		destPath := filepath.Join(tempDir, zipFile.Name)
+		destPath, ok := zipEntryPath(tempDir, zipFile.Name)
+		if !ok {
+			task.mu.Lock()
+			task.Processed++
+			task.Failed++
+			errors = append(errors, UploadError{
+				FileName: zipFile.Name,
+				Error:    "非法的文件路径",
+			})
+			task.FailedFiles = append(task.FailedFiles, zipFile.Name)
+			task.Report = append(task.Report, ImportReportItem{Source: zipFile.Name, Status: importFailed, Message: "非法的文件路径"})
+			task.mu.Unlock()
+
+			broadcastProgress(task)
+			continue
+		}
+		os.MkdirAll(filepath.Dir(destPath), 0755)
+
 		if err := extractZipFile(zipFile, destPath); err != nil {
 			task.mu.Lock()
 			task.Processed++
@@ -568,15 +559,15 @@ func processZipStreamV2(ctx context.Context, reader io.ReaderAt, size int64, tem
 				Error:    "解压失败: " + err.Error(),
 			})
 			task.FailedFiles = append(task.FailedFiles, zipFile.Name)
+			task.Report = append(task.Report, ImportReportItem{Source: zipFile.Name, Status: importFailed, Message: "解压失败: " + err.Error()})
 			task.mu.Unlock()
 			
 			broadcastProgress(task)
 			continue
 		}
 		
-		// 处理文章（复用原有逻辑）
-		articleDir := filepath.Join(tempDir, filepath.Dir(zipFile.Name))
-		article, code := processZipArticle(articleDir)
+		item := processZipArticle(tempDir, destPath, task.Mode, task.operator)
+		item.Source = zipFile.Name
 		
 		// 计算速度和 ETA
 		task.mu.Lock()
@@ -592,13 +583,14 @@ func processZipStreamV2(ctx context.Context, reader io.ReaderAt, size int64, tem
 			}
 		}
 		
-		if code == errmsg.SUCCESS && article != nil {
+		task.Report = append(task.Report, item)
+		if item.Status != importFailed {
 			successCount++
 			task.Success++
 			task.ProcessedFiles = append(task.ProcessedFiles, zipFile.Name)
 		} else {
 			task.Failed++
-			errMsg := errmsg.GetErrMsg(code)
+			errMsg := item.Message
 			if errMsg == "" {
 				errMsg = "处理失败"
 			}
@@ -613,8 +605,8 @@ func processZipStreamV2(ctx context.Context, reader io.ReaderAt, size int64, tem
 		// 广播进度
 		broadcastProgress(task)
 		
-		// 清理
-		os.RemoveAll(articleDir)
+		// 清理已处理的 Markdown 文件（资源文件可能被其他文章引用，最后统一清理）
+		os.Remove(destPath)
 	}
 	
 	return successCount, errors
@@ -642,73 +634,6 @@ func formatDuration(seconds float64) string {
 	return fmt.Sprintf("%.1f小时", seconds/3600)
 }
 
-// saveToHistory 保存到历史记录
-func saveToHistory(task *UploadTaskV2) {
-	task.mu.Lock()
-	duration := "进行中"
-	if task.EndTime != nil {
-		duration = task.EndTime.Sub(task.StartTime).String()
-	}
-	
-	history := UploadHistory{
-		TaskID:     task.ID,
-		FileName:   task.FileName,
-		TotalFiles: task.TotalFiles,
-		Success:    task.Success,
-		Failed:     task.Failed,
-		Status:     task.Status,
-		Duration:   duration,
-		CreatedAt:  task.StartTime,
-	}
-	task.mu.Unlock()
-	
-	historyMu.Lock()
-	uploadHistory = append([]UploadHistory{history}, uploadHistory...)
-	if len(uploadHistory) > 100 {
-		uploadHistory = uploadHistory[:100]
-	}
-	historyMu.Unlock()
-	
-	// 持久化到文件
-	go persistHistory()
-}
-
-// persistHistory 持久化历史记录
-func persistHistory() {
-	historyMu.RLock()
-	data, _ := json.Marshal(uploadHistory)
-	historyMu.RUnlock()
-	
-	os.MkdirAll(filepath.Dir(historyFile), 0755)
-	os.WriteFile(historyFile, data, 0644)
-}
-
-// loadUploadHistory 加载历史记录
-func loadUploadHistory() {
-	data, err := os.ReadFile(historyFile)
-	if err != nil {
-		return
-	}
-	
-	historyMu.Lock()
-	json.Unmarshal(data, &uploadHistory)
-	historyMu.Unlock()
-}
-
-// ClearUploadHistory 清空历史记录
-func ClearUploadHistory(c *gin.Context) {
-	historyMu.Lock()
-	uploadHistory = make([]UploadHistory, 0, 100)
-	historyMu.Unlock()
-	
-	os.Remove(historyFile)
-	
-	c.JSON(http.StatusOK, gin.H{
-		"status":  errmsg.SUCCESS,
-		"message": "历史记录已清空",
-	})
-}
-
 // min/max 辅助函数
 func min(a, b int) int {
 	if a < b {
@@ -725,16 +650,23 @@ func max(a, b int) int {
 }
 
 type ArticleFrontMatter struct {
-	Title    string   `yaml:"title"`
-	Date     string   `yaml:"date"`
-	Tags     []string `yaml:"tags"`
-	Category string   `yaml:"category"`
-	Desc     string   `yaml:"desc"`
-	Cover    string   `yaml:"cover"`
+	ID        uint     `yaml:"id,omitempty"` // 站内文章ID，重复导入时用于匹配已有文章
+	Title     string   `yaml:"title"`
+	Slug      string   `yaml:"slug,omitempty"` // 文章别名，缺省时根据标题生成
+	Date      string   `yaml:"date,omitempty"`
+	Tags      []string `yaml:"tags,omitempty"`
+	Category  string   `yaml:"category,omitempty"`
+	Desc      string   `yaml:"desc,omitempty"`
+	Cover     string   `yaml:"cover,omitempty"`
+	Top       int      `yaml:"top,omitempty"`        // 置顶等级
+	Pdf       string   `yaml:"pdf,omitempty"`        // PDF 文章的文件（ZIP 内相对路径或链接）
+	Status    string   `yaml:"status,omitempty"`     // draft, published, scheduled, archived，缺省为 published
+	PublishAt string   `yaml:"publish_at,omitempty"` // 预约发布时间
 }
 
 func parseDate(s string) (time.Time, error) {
 	formats := []string{
+		time.RFC3339, // 导出的 ZIP 使用该格式
 		"2006-01-02 15:04:05",
 		"2006-01-02T15:04:05Z",
 		"2006-01-02T15:04:05+08:00",
@@ -753,6 +685,18 @@ func parseDate(s string) (time.Time, error) {
 	return time.Time{}, fmt.Errorf("无法解析日期: %s", s)
 }
 
+// zipEntryPath 计算 ZIP 条目解压后的路径，拒绝跳出解压目录的条目（如 "../x"）
+func zipEntryPath(baseDir string, name string) (string, bool) {
+	dest := filepath.Join(baseDir, name)
+	return dest, isWithinDir(baseDir, dest)
+}
+
+// isWithinDir 判断 path 是否位于 dir 目录内
+func isWithinDir(dir string, path string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
 func extractZipFile(zipFile *zip.File, destPath string) error {
 	outFile, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, zipFile.Mode())
 	if err != nil {
@@ -770,26 +714,21 @@ func extractZipFile(zipFile *zip.File, destPath string) error {
 	return err
 }
 
-func processZipArticle(unzipDir string) (*model.Article, int) {
-	var mdPath string
-	filepath.Walk(unzipDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if !info.IsDir() && strings.HasSuffix(strings.ToLower(info.Name()), ".md") {
-			mdPath = path
-			return io.EOF
-		}
-		return nil
-	})
-
-	if mdPath == "" {
-		return nil, errmsg.ERROR
-	}
+// isZipArticle 判断 ZIP 条目是否为文章（Markdown 文件），忽略 macOS 压缩时附带的 __MACOSX 目录和 ._ 元数据文件
+func isZipArticle(f *zip.File) bool {
+	return !f.FileInfo().IsDir() && strings.HasSuffix(strings.ToLower(f.Name), ".md") &&
+		!strings.HasPrefix(f.Name, "__MACOSX/") && !strings.HasPrefix(path.Base(f.Name), "._")
+}
 
+// processZipArticle 将解压后的一个 Markdown 文件导入为文章
+// 参数: unzipDir - ZIP 解压目录（引用的文件必须位于其中）, mdPath - Markdown 文件路径, mode - 导入模式, username - 操作用户
+// 返回: 导入结果（Source 由调用方填写）
+func processZipArticle(unzipDir string, mdPath string, mode string, username string) ImportReportItem {
+	item := ImportReportItem{Status: importFailed}
 	contentBytes, err := os.ReadFile(mdPath)
 	if err != nil {
-		return nil, errmsg.ERROR
+		item.Message = "读取文件失败: " + err.Error()
+		return item
 	}
 	contentStr := string(contentBytes)
 
@@ -802,7 +741,8 @@ func processZipArticle(unzipDir string) (*model.Article, int) {
 			if err := yaml.Unmarshal([]byte(parts[1]), &frontMatter); err != nil {
 				bodyContent = contentStr
 			} else {
-				bodyContent = parts[2]
+				// 去掉 Front Matter 结束标记后的空行
+				bodyContent = strings.TrimLeft(parts[2], "\r\n")
 			}
 		} else {
 			bodyContent = contentStr
@@ -815,35 +755,38 @@ func processZipArticle(unzipDir string) (*model.Article, int) {
 		base := filepath.Base(mdPath)
 		frontMatter.Title = strings.TrimSuffix(base, filepath.Ext(base))
 	}
+	item.Title = frontMatter.Title
 
-	imgRegex := regexp.MustCompile(`!\[(.*?)\]\((.*?)\)`)
-	matches := imgRegex.FindAllStringSubmatch(bodyContent, -1)
+	// 跳过模式下先匹配已有文章，避免上传用不到的文件
+	match, ok := matchImportedArt(&item, frontMatter.ID, frontMatter.Slug, mode)
+	if !ok {
+		return item
+	}
+
+	// 图片和附件链接中引用的本地文件上传后替换为站内地址
+	matches := markdownLinkRegex.FindAllStringSubmatch(bodyContent, -1)
 	processedContent := bodyContent
+	mdDir := filepath.Dir(mdPath)
+	uploaded := make(map[string]bool)
 
-	for _, match := range matches {
-		originalPath := match[2]
-		if strings.HasPrefix(originalPath, "http") || strings.HasPrefix(originalPath, "//") {
+	for _, link := range matches {
+		fields := strings.Fields(link[2]) // 去掉链接标题，如 (a.png "标题")
+		if len(fields) == 0 || uploaded[fields[0]] {
 			continue
 		}
-		mdDir := filepath.Dir(mdPath)
-		imageFullPath := filepath.Join(mdDir, originalPath)
-		if _, err := os.Stat(imageFullPath); err == nil {
-			newURL, err := uploadLocalFile(imageFullPath, "article")
-			if err == nil {
-				processedContent = strings.Replace(processedContent, originalPath, newURL, -1)
-			}
+		originalPath := fields[0]
+		if newURL, ok := uploadZipAsset(unzipDir, mdDir, originalPath, "article"); ok {
+			processedContent = strings.Replace(processedContent, "("+originalPath, "("+newURL, -1)
+			uploaded[originalPath] = true
 		}
 	}
 
-	if frontMatter.Cover != "" && !strings.HasPrefix(frontMatter.Cover, "http") {
-		mdDir := filepath.Dir(mdPath)
-		coverFullPath := filepath.Join(mdDir, frontMatter.Cover)
-		if _, err := os.Stat(coverFullPath); err == nil {
-			newCoverURL, err := uploadLocalFile(coverFullPath, "cover")
-			if err == nil {
-				frontMatter.Cover = newCoverURL
-			}
-		}
+	if newCoverURL, ok := uploadZipAsset(unzipDir, mdDir, frontMatter.Cover, "cover"); ok {
+		frontMatter.Cover = newCoverURL
+	}
+	pdfURL := frontMatter.Pdf
+	if newPdfURL, ok := uploadZipAsset(unzipDir, mdDir, frontMatter.Pdf, "article"); ok {
+		pdfURL = newPdfURL
 	}
 
 	var cid int
@@ -855,11 +798,18 @@ func processZipArticle(unzipDir string) (*model.Article, int) {
 
 	article := &model.Article{
 		Title:   frontMatter.Title,
+		Slug:    frontMatter.Slug,
 		Cid:     cid,
 		Desc:    frontMatter.Desc,
 		Content: processedContent,
 		Img:     frontMatter.Cover,
+		Top:     frontMatter.Top,
 		Tags:    strings.Join(frontMatter.Tags, ","),
+		Status:  frontMatter.Status,
+	}
+	if pdfURL != "" {
+		article.Type = 2
+		article.PdfUrl = pdfURL
 	}
 
 	if frontMatter.Date != "" {
@@ -867,45 +817,65 @@ func processZipArticle(unzipDir string) (*model.Article, int) {
 			article.CreatedAt = t
 		}
 	}
+	if frontMatter.PublishAt != "" {
+		if t, ok := parsePublishAt(frontMatter.PublishAt); ok {
+			article.PublishAt = t
+		}
+	}
+
+	saveImportedArt(&item, article, match, mode, username)
+	return item
+}
 
-	code := model.CreateArt(article)
-	return article, code
+// markdownLinkRegex 匹配 Markdown 图片和链接，第 2 组为地址
+var markdownLinkRegex = regexp.MustCompile(`!?\[(.*?)\]\((.*?)\)`)
+
+// uploadZipAsset 上传 Markdown 中以相对路径引用的 ZIP 内文件
+// 参数: unzipDir - 文章解压目录, mdDir - Markdown 文件所在目录, ref - 引用路径, uploadType - 上传类型
+// 返回: 站内地址和是否上传成功（外链、站内绝对路径和不存在的文件不处理）
+func uploadZipAsset(unzipDir string, mdDir string, ref string, uploadType string) (string, bool) {
+	if ref == "" || strings.HasPrefix(ref, "http") || strings.HasPrefix(ref, "//") ||
+		strings.HasPrefix(ref, "/") || strings.HasPrefix(ref, "#") || strings.Contains(ref, ":") ||
+		strings.HasSuffix(strings.ToLower(ref), ".md") {
+		return "", false
+	}
+	fullPath := filepath.Join(mdDir, filepath.FromSlash(ref))
+	if !isWithinDir(unzipDir, fullPath) {
+		return "", false
+	}
+	if info, err := os.Stat(fullPath); err != nil || info.IsDir() {
+		return "", false
+	}
+	newURL, err := uploadLocalFile(fullPath, uploadType)
+	if err != nil {
+		return "", false
+	}
+	return newURL, true
 }
 
-func uploadLocalFile(path string, uploadType string) (string, error) {
-	file, err := os.Open(path)
+func uploadLocalFile(localPath string, uploadType string) (string, error) {
+	file, err := os.Open(localPath)
 	if err != nil {
 		return "", err
 	}
 	defer file.Close()
-
-	baseDir := "./uploads"
-	targetDir := filepath.Join(baseDir, "article", "content", time.Now().Format("200601"))
-	if uploadType == "cover" {
-		targetDir = filepath.Join(baseDir, "article", "cover")
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
 	}
 
-	if _, err := os.Stat(targetDir); os.IsNotExist(err) {
-		_ = os.MkdirAll(targetDir, 0755)
+	targetDir := "article/content/" + time.Now().Format("200601")
+	if uploadType == "cover" {
+		targetDir = "article/cover"
 	}
 
-	ext := filepath.Ext(path)
-	newFileName := fmt.Sprintf("%d%s", time.Now().UnixNano(), ext)
-	newFilePath := filepath.Join(targetDir, newFileName)
-
-	out, err := os.Create(newFilePath)
+	name, created, err := model.StoreUpload(file, info.Size(), targetDir, filepath.Ext(localPath))
 	if err != nil {
 		return "", err
 	}
-	defer out.Close()
-
-	_, err = io.Copy(out, file)
-	if err != nil {
-		return "", err
+	if created {
+		model.ProcessUploadedImage(name)
 	}
 
-	relPath, _ := filepath.Rel(".", newFilePath)
-	url := "/" + filepath.ToSlash(relPath)
-
-	return url, nil
+	return "/uploads/" + name, nil
 }
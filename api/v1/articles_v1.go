@@ -3,15 +3,15 @@ package v1
 import (
 	"fmt"
 	"net/http"
-	"os"
-	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
+	"yanblog/middlewares"
 	"yanblog/model"
 	"yanblog/utils"
 	"yanblog/utils/errmsg"
+	"yanblog/utils/storage"
 
 	"github.com/gin-gonic/gin"
 )
@@ -27,10 +27,23 @@ func sanitizeTitle(title string) string {
 	return title
 }
 
+// removeArticleUploadDir 删除文章标题对应的上传目录（articles/<标题>）及其生成的图片尺寸
+func removeArticleUploadDir(title string) {
+	clean := sanitizeTitle(title)
+	if clean == "" {
+		return
+	}
+	dir := "articles/" + clean
+	if err := storage.Current().Delete(dir); err == nil {
+		model.RemoveImageVariants(dir)
+	}
+}
+
 // 添加文章
 func AddArticle(c *gin.Context) {
 	var input struct {
 		Title     string `json:"title"`
+		Slug      string `json:"slug"`
 		Cid       int    `json:"cid"`
 		Desc      string `json:"desc"`
 		Content   string `json:"content"`
@@ -40,12 +53,15 @@ func AddArticle(c *gin.Context) {
 		Type      int    `json:"type"`
 		PdfUrl    string `json:"pdf_url"`
 		CreatedAt string `json:"createdAt"`
+		Status    string `json:"status"`
+		PublishAt string `json:"publish_at"`
 	}
 	var code int
 	_ = c.ShouldBindJSON(&input)
 
 	data := model.Article{
 		Title:   input.Title,
+		Slug:    input.Slug,
 		Cid:     input.Cid,
 		Desc:    input.Desc,
 		Content: input.Content,
@@ -54,17 +70,29 @@ func AddArticle(c *gin.Context) {
 		Tags:    input.Tags,
 		Type:    input.Type,
 		PdfUrl:  input.PdfUrl,
+		Status:  input.Status,
 	}
 	if input.CreatedAt != "" {
 		if t, err := time.Parse("2006-01-02 15:04:05", input.CreatedAt); err == nil {
 			data.CreatedAt = t
 		}
 	}
+	if input.PublishAt != "" {
+		publishAt, ok := parsePublishAt(input.PublishAt)
+		if !ok {
+			utils.Error(c, errmsg.ERROR_ART_PUBLISH_TIME)
+			return
+		}
+		data.PublishAt = publishAt
+	}
 
 	code = model.CheckArtTitle(data.Title)
 	if code == errmsg.SUCCESS {
 		code = model.CreateArt(&data)
 	}
+	if code == errmsg.SUCCESS {
+		model.SaveArtRevision(int(data.ID), currentUsername(c), "创建文章")
+	}
 
 	utils.ErrorWithMessage(c, code, errmsg.GetErrMsg(code))
 }
@@ -105,6 +133,23 @@ func GetArtInfo(c *gin.Context) {
 	utils.Success(c, data)
 }
 
+// 通过 slug 查询单个文章信息，旧 slug 301 跳转到当前 slug
+func GetArtInfoBySlug(c *gin.Context) {
+	slug := c.Param("slug")
+	data, moved, code := model.GetArtInfoBySlug(slug)
+	if code != errmsg.SUCCESS {
+		utils.Error(c, code)
+		return
+	}
+	if moved {
+		c.Redirect(http.StatusMovedPermanently, "/api/v1/article/slug/"+data.Slug)
+		return
+	}
+
+	model.IncrementArtViews(int(data.ID))
+	utils.Success(c, data)
+}
+
 // 查询文章列表
 func GetArt(c *gin.Context) {
 	pageSize, pageNum, _ := utils.ParsePageParams(c)
@@ -124,6 +169,12 @@ func SearchArt(c *gin.Context) {
 	utils.SuccessWithTotal(c, data, total)
 }
 
+// RebuildSearchIndex 重建文章全文索引
+func RebuildSearchIndex(c *gin.Context) {
+	code := model.RebuildSearchIndex()
+	utils.Error(c, code)
+}
+
 // 查询置顶文章
 func GetTopArt(c *gin.Context) {
 	num, _ := strconv.Atoi(c.Query("num"))
@@ -197,33 +248,53 @@ func GetRelatedArt(c *gin.Context) {
 // 编辑文章
 func EditArt(c *gin.Context) {
 	var data model.Article
-	var code int
 	id, ok := utils.ParseIDParam(c)
 	if !ok {
 		return
 	}
 	_ = c.ShouldBindJSON(&data)
 
-	code = model.CheckArtTitleWithId(id, data.Title)
+	code := applyArtEdit(id, &data, currentUsername(c), "")
+	utils.Error(c, code)
+}
+
+// currentUsername 获取当前登录用户名（由 JwtToken 中间件写入）
+func currentUsername(c *gin.Context) string {
+	username, _ := c.Get("username")
+	name, _ := username.(string)
+	return name
+}
+
+// currentRole 获取当前登录用户角色（由 JwtToken 中间件从令牌声明写入）
+func currentRole(c *gin.Context) int {
+	return c.GetInt("role")
+}
+
+// applyArtEdit 校验并保存文章修改，同时记录修订版本（编辑和恢复修订共用）
+// 参数: id - 文章ID, data - 新的文章内容, username - 操作用户, note - 修订备注
+// 返回: 状态码
+func applyArtEdit(id int, data *model.Article, username string, note string) int {
+	code := model.CheckArtTitleWithId(id, data.Title)
 	if code != errmsg.SUCCESS {
-		utils.Error(c, code)
-		return
+		return code
 	}
 
 	// 获取旧文章信息，检查标题是否变更
-	oldArt, code := model.GetArtInfo(id)
+	oldArt, code := model.GetArtInfoForAdmin(id)
 	if code == errmsg.SUCCESS && oldArt.Title != data.Title {
 		oldTitleClean := sanitizeTitle(oldArt.Title)
 		newTitleClean := sanitizeTitle(data.Title)
 
-		oldDir := filepath.Join("uploads", "articles", oldTitleClean)
-		newDir := filepath.Join("uploads", "articles", newTitleClean)
+		oldDir := "articles/" + oldTitleClean
+		newDir := "articles/" + newTitleClean
 
-		if _, err := os.Stat(oldDir); err == nil {
-			err := os.Rename(oldDir, newDir)
+		store := storage.Current()
+		if storage.Exists(store, oldDir) && oldTitleClean != "" && newTitleClean != "" {
+			err := store.Move(oldDir, newDir)
 			if err == nil {
-				oldUrlPart := "/uploads/articles/" + filepath.ToSlash(oldTitleClean) + "/"
-				newUrlPart := "/uploads/articles/" + filepath.ToSlash(newTitleClean) + "/"
+				model.RemoveImageVariants(oldDir)
+				oldUrlPart := "/uploads/" + oldDir + "/"
+				newUrlPart := "/uploads/" + newDir + "/"
 
 				data.Content = strings.ReplaceAll(data.Content, oldUrlPart, newUrlPart)
 
@@ -234,8 +305,13 @@ func EditArt(c *gin.Context) {
 		}
 	}
 
-	code = model.EditArt(id, &data)
-	utils.Error(c, code)
+	// 保存修改前确保已有初始版本，修改成功后记录新版本
+	model.EnsureArtBaseRevision(id)
+	code = model.EditArt(id, data)
+	if code == errmsg.SUCCESS {
+		model.SaveArtRevision(id, username, note)
+	}
+	return code
 }
 
 // 删除文章
@@ -246,10 +322,12 @@ func DeleteArt(c *gin.Context) {
 	}
 
 	// 获取文章信息以找到对应的文件夹
-	data, code := model.GetArtInfo(id)
+	data, code := model.GetArtInfoForAdmin(id)
+	if code == errmsg.SUCCESS {
+		middlewares.AuditBefore(c, gin.H{"title": data.Title, "cid": data.Cid, "status": data.Status})
+	}
 	if code == errmsg.SUCCESS && data.Title != "" {
-		targetDir := filepath.Join("uploads", "articles", sanitizeTitle(data.Title))
-		_ = os.RemoveAll(targetDir)
+		removeArticleUploadDir(data.Title)
 	}
 
 	code = model.DeleteArt(id)
@@ -267,14 +345,18 @@ func BatchDeleteArt(c *gin.Context) {
 	}
 
 	// 先清理各文章的关联文件夹
+	titles := make(map[int]string, len(data.Ids))
 	for _, id := range data.Ids {
-		art, code := model.GetArtInfo(id)
+		art, code := model.GetArtInfoForAdmin(id)
+		if code == errmsg.SUCCESS {
+			titles[id] = art.Title
+		}
 		if code == errmsg.SUCCESS && art.Title != "" {
-			targetDir := filepath.Join("uploads", "articles", sanitizeTitle(art.Title))
-			_ = os.RemoveAll(targetDir)
+			removeArticleUploadDir(art.Title)
 		}
 	}
 
+	middlewares.AuditBefore(c, titles)
 	deleted, failed := model.BatchDeleteArts(data.Ids)
 
 	c.JSON(http.StatusOK, gin.H{
@@ -287,3 +369,71 @@ func BatchDeleteArt(c *gin.Context) {
 		"message": fmt.Sprintf("成功删除 %d 篇，失败 %d 篇", deleted, failed),
 	})
 }
+
+// parsePublishAt 解析发布时间（按服务器本地时区）
+func parsePublishAt(s string) (*time.Time, bool) {
+	for _, layout := range []string{"2006-01-02 15:04:05", "2006-01-02 15:04", time.RFC3339} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return &t, true
+		}
+	}
+	return nil, false
+}
+
+// GetAdminArt 后台查询文章列表（包含草稿、预约和归档文章）
+func GetAdminArt(c *gin.Context) {
+	pageSize, pageNum, _ := utils.ParsePageParams(c)
+	status := c.Query("status")
+	if status != "" && !model.IsValidArtStatus(status) {
+		utils.Error(c, errmsg.ERROR_ART_STATUS_WRONG)
+		return
+	}
+
+	data, _, total := model.GetArtForAdmin(status, c.Query("keyword"), pageSize, pageNum)
+	utils.SuccessWithTotal(c, data, total)
+}
+
+// GetAdminArtInfo 后台查询单个文章（不限状态，不增加阅读量）
+func GetAdminArtInfo(c *gin.Context) {
+	id, ok := utils.ParseIDParam(c)
+	if !ok {
+		return
+	}
+
+	data, code := model.GetArtInfoForAdmin(id)
+	if code != errmsg.SUCCESS {
+		utils.Error(c, code)
+		return
+	}
+	utils.Success(c, data)
+}
+
+// UpdateArtStatus 修改文章状态（发布、存为草稿、预约发布、归档）
+func UpdateArtStatus(c *gin.Context) {
+	id, ok := utils.ParseIDParam(c)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Status    string `json:"status"`
+		PublishAt string `json:"publish_at"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil || input.Status == "" {
+		utils.BadRequest(c, "参数错误，需要 status")
+		return
+	}
+
+	var publishAt *time.Time
+	if input.PublishAt != "" {
+		t, ok := parsePublishAt(input.PublishAt)
+		if !ok {
+			utils.Error(c, errmsg.ERROR_ART_PUBLISH_TIME)
+			return
+		}
+		publishAt = t
+	}
+
+	code := model.UpdateArtStatus(id, input.Status, publishAt)
+	utils.Error(c, code)
+}
@@ -0,0 +1,112 @@
+package v1
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"yanblog/model"
+	"yanblog/utils"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetAuditLogs 分页查询审计日志
+// 查询参数: username - 操作人, action - 路由关键词, keyword - 全文关键词,
+// result - success / fail, start / end - 时间范围（2006-01-02 或 RFC3339）
+func GetAuditLogs(c *gin.Context) {
+	q, ok := parseAuditQuery(c)
+	if !ok {
+		return
+	}
+	pageSize, pageNum, _ := utils.ParsePageParams(c)
+
+	data, total := model.GetAuditLogs(q, pageSize, pageNum)
+	utils.SuccessWithTotal(c, data, total)
+}
+
+// ExportAuditLogs 按查询条件导出审计日志 CSV（查询参数同 GetAuditLogs）
+func ExportAuditLogs(c *gin.Context) {
+	q, ok := parseAuditQuery(c)
+	if !ok {
+		return
+	}
+
+	filename := fmt.Sprintf("audit-%s.csv", time.Now().Format("20060102-150405"))
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	// 写入 UTF-8 BOM，Excel 打开时中文不乱码
+	c.Writer.WriteString("\xEF\xBB\xBF")
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"ID", "时间", "操作人", "IP", "操作", "请求路径", "操作对象", "修改前", "修改后", "HTTP状态", "业务状态"})
+	model.EachAuditLog(q, func(log model.AuditLog) bool {
+		w.Write([]string{
+			strconv.Itoa(int(log.ID)),
+			log.CreatedAt.Format("2006-01-02 15:04:05"),
+			csvSafe(log.Username),
+			log.IP,
+			log.Action,
+			csvSafe(log.Path),
+			csvSafe(log.Target),
+			csvSafe(log.Before),
+			csvSafe(log.After),
+			strconv.Itoa(log.HTTPStatus),
+			strconv.Itoa(log.Status),
+		})
+		return w.Error() == nil
+	})
+	w.Flush()
+}
+
+// parseAuditQuery 解析审计日志查询参数
+func parseAuditQuery(c *gin.Context) (model.AuditQuery, bool) {
+	q := model.AuditQuery{
+		Username: strings.TrimSpace(c.Query("username")),
+		Action:   strings.TrimSpace(c.Query("action")),
+		Keyword:  strings.TrimSpace(c.Query("keyword")),
+		Result:   c.Query("result"),
+	}
+	if q.Result != "" && q.Result != "success" && q.Result != "fail" {
+		utils.BadRequest(c, "参数错误，result 必须为 success 或 fail")
+		return q, false
+	}
+
+	var ok bool
+	if q.Start, ok = parseAuditTime(c.Query("start"), false); !ok {
+		utils.BadRequest(c, "start 时间格式错误")
+		return q, false
+	}
+	if q.End, ok = parseAuditTime(c.Query("end"), true); !ok {
+		utils.BadRequest(c, "end 时间格式错误")
+		return q, false
+	}
+	return q, true
+}
+
+// parseAuditTime 解析时间参数；只给日期时，作为结束时间表示包含当天
+func parseAuditTime(s string, isEnd bool) (time.Time, bool) {
+	if s == "" {
+		return time.Time{}, true
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, true
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if isEnd {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, true
+}
+
+// csvSafe 防止以 = + - @ 开头的单元格被表格软件当作公式执行
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
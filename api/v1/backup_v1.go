@@ -0,0 +1,157 @@
+package v1
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+	"yanblog/middlewares"
+	"yanblog/model"
+	"yanblog/utils"
+	"yanblog/utils/errmsg"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 恢复分两步：先上传备份并预览差异，确认后再用返回的 ID 执行恢复
+// 上传的备份暂存在 pendingBackupDir，同一时间只保留最近上传的一个
+
+// pendingBackupDir 待恢复备份的暂存目录
+const pendingBackupDir = "./data/backup"
+
+// pendingBackupIDRegex 待恢复备份 ID 格式
+var pendingBackupIDRegex = regexp.MustCompile(`^restore_\d+$`)
+
+// CreateBackup 下载全站备份（数据库、上传文件、关于页、前后端配置、导入历史）
+func CreateBackup(c *gin.Context) {
+	if !model.AcquireBackupLock() {
+		utils.Error(c, errmsg.ERROR_BACKUP_BUSY)
+		return
+	}
+	defer model.ReleaseBackupLock()
+
+	// 上传目录可能很大，解除服务器的写超时
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	filename := fmt.Sprintf("yanblog-backup-%s.zip", time.Now().Format("20060102-150405"))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q; filename*=UTF-8''%s", filename, url.PathEscape(filename)))
+	c.Status(http.StatusOK)
+
+	manifest, err := model.WriteBackup(c.Writer)
+	if err != nil {
+		// 响应已开始写入，无法再返回错误信息；客户端得到的压缩包缺少清单，恢复时会被拒绝
+		log.Printf("生成备份失败: %v", err)
+		return
+	}
+	middlewares.AuditAfter(c, gin.H{"file": filename, "tables": manifest.Tables, "files": len(manifest.Files)})
+}
+
+// UploadRestoreBackup 上传备份文件，校验后返回与当前数据的差异（不修改任何数据）
+func UploadRestoreBackup(c *gin.Context) {
+	http.NewResponseController(c.Writer).SetReadDeadline(time.Time{})
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		utils.BadRequest(c, "请上传备份文件")
+		return
+	}
+	if !model.AcquireBackupLock() {
+		utils.Error(c, errmsg.ERROR_BACKUP_BUSY)
+		return
+	}
+	defer model.ReleaseBackupLock()
+
+	id := fmt.Sprintf("restore_%d", time.Now().UnixNano())
+	os.RemoveAll(pendingBackupDir) // 丢弃之前上传但未恢复的备份
+	if err := os.MkdirAll(pendingBackupDir, 0755); err != nil {
+		utils.ErrorWithMessage(c, errmsg.ERROR, err.Error())
+		return
+	}
+	file := pendingBackupPath(id)
+	if err := c.SaveUploadedFile(fileHeader, file); err != nil {
+		utils.ErrorWithMessage(c, errmsg.ERROR, err.Error())
+		return
+	}
+
+	archive, err := model.OpenBackup(file)
+	if err != nil {
+		os.Remove(file)
+		utils.ErrorWithMessage(c, errmsg.ERROR_BACKUP_INVALID, err.Error())
+		return
+	}
+	defer archive.Close()
+
+	diff, err := archive.Diff()
+	if err != nil {
+		utils.ErrorWithMessage(c, errmsg.ERROR, err.Error())
+		return
+	}
+	utils.Success(c, gin.H{"id": id, "diff": diff})
+}
+
+// ApplyRestoreBackup 用已上传的备份替换当前数据
+func ApplyRestoreBackup(c *gin.Context) {
+	file, ok := pendingBackupFile(c)
+	if !ok {
+		return
+	}
+	if !model.AcquireBackupLock() {
+		utils.Error(c, errmsg.ERROR_BACKUP_BUSY)
+		return
+	}
+	defer model.ReleaseBackupLock()
+
+	// 上传后文件可能被改动，恢复前重新校验
+	archive, err := model.OpenBackup(file)
+	if err != nil {
+		utils.ErrorWithMessage(c, errmsg.ERROR_BACKUP_INVALID, err.Error())
+		return
+	}
+	diff, err := archive.Diff()
+	if err == nil {
+		err = archive.Restore()
+	}
+	archive.Close()
+	if err != nil {
+		utils.ErrorWithMessage(c, errmsg.ERROR, "恢复失败: "+err.Error())
+		return
+	}
+	os.RemoveAll(pendingBackupDir)
+
+	middlewares.AuditAfter(c, gin.H{"backup_created_at": diff.CreatedAt, "tables": diff.Tables,
+		"added": len(diff.Added), "removed": len(diff.Removed), "changed": len(diff.Changed)})
+	utils.Success(c, diff)
+}
+
+// DiscardRestoreBackup 放弃恢复，删除已上传的备份
+func DiscardRestoreBackup(c *gin.Context) {
+	if _, ok := pendingBackupFile(c); !ok {
+		return
+	}
+	os.RemoveAll(pendingBackupDir)
+	utils.Success(c, nil)
+}
+
+func pendingBackupPath(id string) string {
+	return filepath.Join(pendingBackupDir, id+".zip")
+}
+
+// pendingBackupFile 根据路由参数查找已上传的备份，不存在时直接返回错误响应
+func pendingBackupFile(c *gin.Context) (string, bool) {
+	id := c.Param("id")
+	if !pendingBackupIDRegex.MatchString(id) {
+		utils.Error(c, errmsg.ERROR_BACKUP_NOT_EXIST)
+		return "", false
+	}
+	file := pendingBackupPath(id)
+	if _, err := os.Stat(file); err != nil {
+		utils.Error(c, errmsg.ERROR_BACKUP_NOT_EXIST)
+		return "", false
+	}
+	return file, true
+}
@@ -2,8 +2,6 @@ package v1
 
 import (
 	"net/http"
-	"os"
-	"path/filepath"
 	"strconv"
 	"yanblog/model"
 	"yanblog/utils"
@@ -23,7 +21,7 @@ func AddCategory(c *gin.Context) {
 		if data.Top < 0 {
 			data.Top = 0
 		}
-		model.CreateCate(&data)
+		code = model.CreateCate(&data)
 	}
 	if code == errmsg.ERROR_CATENAME_USED {
 		code = errmsg.ERROR_CATENAME_USED
@@ -52,6 +50,21 @@ func GetCateInfo(c *gin.Context) {
 	})
 }
 
+// 通过 slug 查询分类信息，旧 slug 301 跳转到当前 slug
+func GetCateInfoBySlug(c *gin.Context) {
+	data, moved, code := model.GetCateInfoBySlug(c.Param("slug"))
+	if moved {
+		c.Redirect(http.StatusMovedPermanently, "/api/v1/category/slug/"+data.Slug)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"data":    data,
+		"message": errmsg.GetErrMsg(code),
+	})
+}
+
 // 查询分类列表
 func GetCate(c *gin.Context) {
 	pageSize, pageNum, _ := utils.ParsePageParams(c)
@@ -118,8 +131,8 @@ func DeleteCate(c *gin.Context) {
 
 	var code int
 
-	// 1. 获取分类信息（用于删除封面图）
-	cate, code := model.GetCateInfo(id)
+	// 1. 检查分类是否存在
+	_, code = model.GetCateInfo(id)
 	if code != errmsg.SUCCESS {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  code,
@@ -135,27 +148,14 @@ func DeleteCate(c *gin.Context) {
 		for _, art := range articles {
 			// 删除文章文件夹
 			if art.Title != "" {
-				targetDir := filepath.Join("uploads", "articles", filepath.Clean(art.Title))
-				_ = os.RemoveAll(targetDir)
+				removeArticleUploadDir(art.Title)
 			}
 			// 删除文章记录
 			model.DeleteArt(int(art.ID))
 		}
 	}
 
-	// 3. 删除分类封面图 (如果是本地文件)
-	if cate.Img != "" {
-		// 简单判断是否在 uploads 下
-		// 实际路径可能是 /uploads/category/123.jpg
-		// os.Remove 需要相对路径 uploads/category/123.jpg
-		// 去掉第一个 /
-		if len(cate.Img) > 0 && cate.Img[0] == '/' {
-			_ = os.Remove("." + cate.Img)
-		} else {
-			_ = os.Remove(cate.Img)
-		}
-	}
-
+	// 3. 删除分类（同时释放分类封面图）
 	code = model.DeleteCate(id)
 
 	c.JSON(http.StatusOK, gin.H{
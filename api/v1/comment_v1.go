@@ -0,0 +1,169 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+	"yanblog/model"
+	"yanblog/utils"
+	"yanblog/utils/errmsg"
+	"yanblog/utils/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AddComment 读者发表评论（公开接口，经评论限流中间件保护）
+func AddComment(c *gin.Context) {
+	var input struct {
+		ArticleID uint   `json:"article_id"`
+		ParentID  uint   `json:"parent_id"`
+		Nickname  string `json:"nickname"`
+		Email     string `json:"email"`
+		Website   string `json:"website"`
+		Content   string `json:"content"`
+	}
+	if err := c.ShouldBindJSON(&input); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+
+	data := model.Comment{
+		ArticleID: input.ArticleID,
+		ParentID:  input.ParentID,
+		Nickname:  strings.TrimSpace(input.Nickname),
+		Email:     strings.TrimSpace(input.Email),
+		Website:   strings.TrimSpace(input.Website),
+		Content:   strings.TrimSpace(input.Content),
+		IP:        c.ClientIP(),
+		UserAgent: truncateRunes(c.Request.UserAgent(), 250),
+	}
+
+	msg, code := validator.Validate(&data)
+	if code != errmsg.SUCCESS {
+		utils.ErrorWithMessage(c, code, msg)
+		return
+	}
+	if utf8.RuneCountInString(data.Content) > model.CommentMaxLength() {
+		utils.Error(c, errmsg.ERROR_COMMENT_TOO_LONG)
+		return
+	}
+
+	code = model.CreateComment(&data)
+	if code != errmsg.SUCCESS {
+		utils.Error(c, code)
+		return
+	}
+
+	// 只返回审核状态，不回显 IP 等信息
+	utils.Success(c, gin.H{
+		"id":     data.ID,
+		"status": data.Status,
+	})
+}
+
+// GetArtComments 查询文章下已通过审核的评论（公开接口）
+func GetArtComments(c *gin.Context) {
+	id, ok := utils.ParseIDParam(c)
+	if !ok {
+		return
+	}
+	pageSize, pageNum, _ := utils.ParsePageParams(c)
+
+	data, total := model.GetArtComments(id, pageSize, pageNum)
+	utils.SuccessWithTotal(c, data, total)
+}
+
+// GetAdminComments 后台评论列表（审核队列）
+// 查询参数: status - 状态筛选, article_id - 文章ID
+func GetAdminComments(c *gin.Context) {
+	status := c.Query("status")
+	if status != "" && !model.IsValidCommentStatus(status) {
+		utils.Error(c, errmsg.ERROR_COMMENT_STATUS_WRONG)
+		return
+	}
+	artID, _ := strconv.Atoi(c.Query("article_id"))
+	pageSize, pageNum, _ := utils.ParsePageParams(c)
+
+	data, total := model.GetCommentsForAdmin(status, artID, pageSize, pageNum)
+	utils.SuccessWithTotal(c, data, total)
+}
+
+// ApproveComment 审核通过评论
+func ApproveComment(c *gin.Context) {
+	setCommentStatus(c, model.CommentStatusApproved)
+}
+
+// RejectComment 驳回评论（标记为垃圾评论）
+func RejectComment(c *gin.Context) {
+	setCommentStatus(c, model.CommentStatusSpam)
+}
+
+// DeleteComment 删除评论（连同其下的回复）
+func DeleteComment(c *gin.Context) {
+	id, ok := utils.ParseIDParam(c)
+	if !ok {
+		return
+	}
+	deleted, code := model.DeleteComments([]int{id})
+	if code == errmsg.SUCCESS && deleted == 0 {
+		code = errmsg.ERROR_COMMENT_NOT_EXIST
+	}
+	utils.Error(c, code)
+}
+
+// BatchModerateComments 批量审核评论
+// 请求体: {"ids": [1, 2], "action": "approve" | "reject" | "pending" | "delete"}
+func BatchModerateComments(c *gin.Context) {
+	var data struct {
+		Ids    []int  `json:"ids"`
+		Action string `json:"action"`
+	}
+	if err := c.ShouldBindJSON(&data); err != nil || len(data.Ids) == 0 {
+		utils.BadRequest(c, "参数错误，需要 ids 数组")
+		return
+	}
+
+	var affected int64
+	var code int
+	switch data.Action {
+	case "approve":
+		affected, code = model.UpdateCommentStatus(data.Ids, model.CommentStatusApproved)
+	case "reject":
+		affected, code = model.UpdateCommentStatus(data.Ids, model.CommentStatusSpam)
+	case "pending":
+		affected, code = model.UpdateCommentStatus(data.Ids, model.CommentStatusPending)
+	case "delete":
+		affected, code = model.DeleteComments(data.Ids)
+	default:
+		utils.BadRequest(c, "参数错误，action 必须为 approve、reject、pending 或 delete")
+		return
+	}
+	if code != errmsg.SUCCESS {
+		utils.Error(c, code)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status": errmsg.SUCCESS,
+		"data": gin.H{
+			"affected": affected,
+			"total":    len(data.Ids),
+		},
+		"message": fmt.Sprintf("已处理 %d 条评论", affected),
+	})
+}
+
+// setCommentStatus 修改单条评论状态
+func setCommentStatus(c *gin.Context, status string) {
+	id, ok := utils.ParseIDParam(c)
+	if !ok {
+		return
+	}
+	affected, code := model.UpdateCommentStatus([]int{id}, status)
+	if code == errmsg.SUCCESS && affected == 0 {
+		code = errmsg.ERROR_COMMENT_NOT_EXIST
+	}
+	utils.Error(c, code)
+}
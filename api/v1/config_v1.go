@@ -160,6 +160,8 @@ func UpdateBackendConfig(c *gin.Context) {
 		}
 	}
 
+	middlewares.AuditBefore(c, safeBackendConfig(utils.GetConfig()))
+
 	// 1. 读取现有 YAML 配置，通过 Config struct 解析（保证键名统一）
 	var cfg utils.Config
 	found := false
@@ -210,6 +212,7 @@ func UpdateBackendConfig(c *gin.Context) {
 	// 重新加载配置到内存，使修改即时生效
 	_ = utils.ReloadConfig()
 	middlewares.RefreshJwtKey()
+	middlewares.AuditAfter(c, safeBackendConfig(utils.GetConfig()))
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":  errmsg.SUCCESS,
@@ -236,11 +239,9 @@ func ReloadConfig(c *gin.Context) {
 	})
 }
 
-func GetAllConfig(c *gin.Context) {
-	backendConfig := utils.GetConfig()
-
-	// 过滤敏感字段，防止密码和密钥泄露
-	safeBackend := map[string]interface{}{
+// safeBackendConfig 过滤敏感字段后的后端配置，防止密码和密钥泄露（也用于审计日志）
+func safeBackendConfig(backendConfig utils.Config) map[string]interface{} {
+	return map[string]interface{}{
 		"server": map[string]interface{}{
 			"AppMode":  backendConfig.Server.AppMode,
 			"HttpPort": backendConfig.Server.HttpPort,
@@ -256,6 +257,10 @@ func GetAllConfig(c *gin.Context) {
 		"weather":            backendConfig.Weather,
 		"FrontEndConfigPath": backendConfig.FrontEndConfigPath,
 	}
+}
+
+func GetAllConfig(c *gin.Context) {
+	safeBackend := safeBackendConfig(utils.GetConfig())
 	
 	configPath := utils.GetFrontEndConfigPath()
 	frontendContent, err := os.ReadFile(configPath)
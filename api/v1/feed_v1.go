@@ -0,0 +1,511 @@
+package v1
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"yanblog/model"
+	"yanblog/utils"
+	"yanblog/utils/errmsg"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v3"
+)
+
+// 订阅源输出模式
+const (
+	feedModeFull    = "full"    // 全文
+	feedModeSummary = "summary" // 仅摘要
+
+	defaultFeedLimit  = 20
+	feedSummaryLength = 200 // 没有摘要时从正文截取的字符数
+)
+
+// feedItem 各种订阅格式共用的文章条目
+type feedItem struct {
+	ID        string
+	Url       string
+	Title     string
+	Summary   string // 纯文本
+	Content   string // HTML，仅全文模式
+	Image     string
+	Tags      []string
+	Published time.Time
+	Updated   time.Time
+}
+
+// feedData 各种订阅格式共用的订阅源信息
+type feedData struct {
+	Title       string
+	Description string
+	Author      string
+	HomeUrl     string
+	SelfUrl     string
+	Updated     time.Time
+	Items       []feedItem
+}
+
+// ===== RSS 2.0 =====
+
+type rssCData struct {
+	Text string `xml:",cdata"`
+}
+
+type rssAtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssGuid struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string    `xml:"title"`
+	Link        string    `xml:"link"`
+	Guid        rssGuid   `xml:"guid"`
+	PubDate     string    `xml:"pubDate"`
+	Description rssCData  `xml:"description"`
+	Content     *rssCData `xml:"content:encoded,omitempty"`
+	Categories  []string  `xml:"category"`
+}
+
+type rssChannel struct {
+	Title         string      `xml:"title"`
+	Link          string      `xml:"link"`
+	Description   string      `xml:"description"`
+	Language      string      `xml:"language"`
+	LastBuildDate string      `xml:"lastBuildDate,omitempty"`
+	AtomLink      rssAtomLink `xml:"atom:link"`
+	Items         []rssItem   `xml:"item"`
+}
+
+type rssFeed struct {
+	XMLName      xml.Name   `xml:"rss"`
+	Version      string     `xml:"version,attr"`
+	XmlnsAtom    string     `xml:"xmlns:atom,attr"`
+	XmlnsContent string     `xml:"xmlns:content,attr"`
+	Channel      rssChannel `xml:"channel"`
+}
+
+// ===== Atom =====
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+}
+
+type atomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Link       atomLink       `xml:"link"`
+	Published  string         `xml:"published"`
+	Updated    string         `xml:"updated"`
+	Summary    *atomText      `xml:"summary,omitempty"`
+	Content    *atomText      `xml:"content,omitempty"`
+	Categories []atomCategory `xml:"category"`
+}
+
+type atomFeed struct {
+	XMLName  xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title    string      `xml:"title"`
+	Subtitle string      `xml:"subtitle,omitempty"`
+	ID       string      `xml:"id"`
+	Updated  string      `xml:"updated"`
+	Links    []atomLink  `xml:"link"`
+	Author   atomAuthor  `xml:"author"`
+	Entries  []atomEntry `xml:"entry"`
+}
+
+// ===== JSON Feed 1.1 =====
+
+type jsonFeedAuthor struct {
+	Name string `json:"name"`
+}
+
+type jsonFeedItem struct {
+	ID            string   `json:"id"`
+	Url           string   `json:"url"`
+	Title         string   `json:"title"`
+	ContentHTML   string   `json:"content_html,omitempty"`
+	Summary       string   `json:"summary,omitempty"`
+	Image         string   `json:"image,omitempty"`
+	DatePublished string   `json:"date_published"`
+	DateModified  string   `json:"date_modified"`
+	Tags          []string `json:"tags,omitempty"`
+}
+
+type jsonFeed struct {
+	Version     string           `json:"version"`
+	Title       string           `json:"title"`
+	HomePageUrl string           `json:"home_page_url"`
+	FeedUrl     string           `json:"feed_url"`
+	Description string           `json:"description,omitempty"`
+	Language    string           `json:"language"`
+	Authors     []jsonFeedAuthor `json:"authors,omitempty"`
+	Items       []jsonFeedItem   `json:"items"`
+}
+
+// GetRSSFeed 生成 RSS 2.0 订阅源
+// 路由: /feed.xml, /feed/category/:id/feed.xml, /feed/tag/:name/feed.xml
+func GetRSSFeed(c *gin.Context) {
+	feed, ok := loadFeed(c)
+	if !ok {
+		return
+	}
+
+	channel := rssChannel{
+		Title:       feed.Title,
+		Link:        feed.HomeUrl,
+		Description: feed.Description,
+		Language:    "zh-CN",
+		AtomLink:    rssAtomLink{Href: feed.SelfUrl, Rel: "self", Type: "application/rss+xml"},
+		Items:       make([]rssItem, 0, len(feed.Items)),
+	}
+	if !feed.Updated.IsZero() {
+		channel.LastBuildDate = feed.Updated.Format(time.RFC1123Z)
+	}
+	for _, item := range feed.Items {
+		ri := rssItem{
+			Title:       item.Title,
+			Link:        item.Url,
+			Guid:        rssGuid{IsPermaLink: true, Value: item.Url},
+			PubDate:     item.Published.Format(time.RFC1123Z),
+			Description: rssCData{Text: item.Summary}, // CDATA 中不转义，encoding/xml 会拆分其中的 "]]>"
+			Categories:  item.Tags,
+		}
+		if item.Content != "" {
+			ri.Content = &rssCData{Text: item.Content}
+		}
+		channel.Items = append(channel.Items, ri)
+	}
+
+	body, err := xml.MarshalIndent(rssFeed{
+		Version:      "2.0",
+		XmlnsAtom:    "http://www.w3.org/2005/Atom",
+		XmlnsContent: "http://purl.org/rss/1.0/modules/content/",
+		Channel:      channel,
+	}, "", "  ")
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	writeFeed(c, "application/rss+xml; charset=utf-8", append([]byte(xml.Header), body...), feed.Updated)
+}
+
+// GetAtomFeed 生成 Atom 订阅源
+// 路由: /atom.xml, /feed/category/:id/atom.xml, /feed/tag/:name/atom.xml
+func GetAtomFeed(c *gin.Context) {
+	feed, ok := loadFeed(c)
+	if !ok {
+		return
+	}
+
+	af := atomFeed{
+		Title:    feed.Title,
+		Subtitle: feed.Description,
+		ID:       feed.SelfUrl,
+		Updated:  atomTime(feed.Updated),
+		Links: []atomLink{
+			{Href: feed.HomeUrl, Rel: "alternate", Type: "text/html"},
+			{Href: feed.SelfUrl, Rel: "self", Type: "application/atom+xml"},
+		},
+		Author:  atomAuthor{Name: feed.Author},
+		Entries: make([]atomEntry, 0, len(feed.Items)),
+	}
+	for _, item := range feed.Items {
+		entry := atomEntry{
+			Title:     item.Title,
+			ID:        item.ID,
+			Link:      atomLink{Href: item.Url, Rel: "alternate", Type: "text/html"},
+			Published: atomTime(item.Published),
+			Updated:   atomTime(item.Updated),
+			Summary:   &atomText{Type: "text", Body: item.Summary},
+		}
+		if item.Content != "" {
+			entry.Content = &atomText{Type: "html", Body: item.Content}
+		}
+		for _, tag := range item.Tags {
+			entry.Categories = append(entry.Categories, atomCategory{Term: tag})
+		}
+		af.Entries = append(af.Entries, entry)
+	}
+
+	body, err := xml.MarshalIndent(af, "", "  ")
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	writeFeed(c, "application/atom+xml; charset=utf-8", append([]byte(xml.Header), body...), feed.Updated)
+}
+
+// GetJSONFeed 生成 JSON Feed 1.1 订阅源
+// 路由: /feed.json, /feed/category/:id/feed.json, /feed/tag/:name/feed.json
+func GetJSONFeed(c *gin.Context) {
+	feed, ok := loadFeed(c)
+	if !ok {
+		return
+	}
+
+	jf := jsonFeed{
+		Version:     "https://jsonfeed.org/version/1.1",
+		Title:       feed.Title,
+		HomePageUrl: feed.HomeUrl,
+		FeedUrl:     feed.SelfUrl,
+		Description: feed.Description,
+		Language:    "zh-CN",
+		Items:       make([]jsonFeedItem, 0, len(feed.Items)),
+	}
+	if feed.Author != "" {
+		jf.Authors = []jsonFeedAuthor{{Name: feed.Author}}
+	}
+	for _, item := range feed.Items {
+		jf.Items = append(jf.Items, jsonFeedItem{
+			ID:            item.ID,
+			Url:           item.Url,
+			Title:         item.Title,
+			ContentHTML:   item.Content,
+			Summary:       item.Summary,
+			Image:         item.Image,
+			DatePublished: atomTime(item.Published),
+			DateModified:  atomTime(item.Updated),
+			Tags:          item.Tags,
+		})
+	}
+
+	body, err := json.MarshalIndent(jf, "", "  ")
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	writeFeed(c, "application/feed+json; charset=utf-8", body, feed.Updated)
+}
+
+// loadFeed 根据路由参数（分类 / 标签）和查询参数 mode 组装订阅源数据
+// 分类或标签不存在时直接返回 404
+func loadFeed(c *gin.Context) (feedData, bool) {
+	baseUrl := siteBaseUrl(c)
+	site := loadFeedSiteInfo()
+
+	feed := feedData{
+		Title:       site.BlogName,
+		Description: site.AuthorBio,
+		Author:      site.AuthorName,
+		HomeUrl:     baseUrl + "/",
+		SelfUrl:     baseUrl + c.Request.URL.Path,
+	}
+
+	cid := 0
+	tag := ""
+	if idStr := c.Param("id"); idStr != "" {
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			c.Status(http.StatusNotFound)
+			return feed, false
+		}
+		cate, code := model.GetCateInfo(id)
+		if code != errmsg.SUCCESS {
+			c.Status(http.StatusNotFound)
+			return feed, false
+		}
+		cid = id
+		feed.Title = fmt.Sprintf("%s - %s", feed.Title, cate.Name)
+		feed.HomeUrl = categoryUrl(baseUrl, cate)
+	}
+	if name := c.Param("name"); name != "" {
+		if model.CheckTagExist(name) != errmsg.ERROR_TAG_EXIST {
+			c.Status(http.StatusNotFound)
+			return feed, false
+		}
+		tag = name
+		feed.Title = fmt.Sprintf("%s - #%s", feed.Title, name)
+		feed.HomeUrl = baseUrl + "/articles"
+	}
+
+	limit := utils.ServerConfig.Feed.Limit
+	if limit <= 0 {
+		limit = defaultFeedLimit
+	}
+	articles, code := model.GetFeedArticles(cid, tag, limit)
+	if code != errmsg.SUCCESS {
+		c.Status(http.StatusInternalServerError)
+		return feed, false
+	}
+
+	full := feedMode(c) == feedModeFull
+	feed.Items = make([]feedItem, 0, len(articles))
+	for _, art := range articles {
+		item := buildFeedItem(art, baseUrl, full)
+		if item.Updated.After(feed.Updated) {
+			feed.Updated = item.Updated
+		}
+		feed.Items = append(feed.Items, item)
+	}
+	return feed, true
+}
+
+// buildFeedItem 将文章转换为订阅条目
+func buildFeedItem(art model.Article, baseUrl string, full bool) feedItem {
+	link := articleUrl(baseUrl, art)
+
+	published := art.CreatedAt
+	if art.PublishAt != nil && !art.PublishAt.IsZero() {
+		published = *art.PublishAt
+	}
+	updated := art.UpdatedAt
+	if updated.Before(published) {
+		updated = published
+	}
+
+	item := feedItem{
+		ID:        link,
+		Url:       link,
+		Title:     art.Title,
+		Summary:   art.Desc,
+		Published: published,
+		Updated:   updated,
+	}
+	if item.Summary == "" {
+		item.Summary = truncateRunes(art.Content, feedSummaryLength)
+	}
+	if art.Img != "" {
+		item.Image = absoluteUrl(art.Img, baseUrl)
+	}
+	for _, t := range strings.Split(art.Tags, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			item.Tags = append(item.Tags, t)
+		}
+	}
+
+	if full {
+		content := art.Content
+		if art.Type == 2 && art.PdfUrl != "" {
+			// PDF 文章没有正文，提供原文件链接
+			content = fmt.Sprintf("[%s](%s)\n\n%s", art.Title, art.PdfUrl, art.Desc)
+		}
+		item.Content = utils.AbsolutizeLinks(utils.RenderMarkdown(content), baseUrl)
+	}
+	return item
+}
+
+// writeFeed 写出订阅源，并处理 ETag / Last-Modified 条件请求
+func writeFeed(c *gin.Context, contentType string, body []byte, lastModified time.Time) {
+	sum := sha1.Sum(body)
+	etag := `W/"` + hex.EncodeToString(sum[:]) + `"`
+	c.Header("ETag", etag)
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		if strings.Contains(match, etag) || strings.TrimSpace(match) == "*" {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	} else if since := c.GetHeader("If-Modified-Since"); since != "" && !lastModified.IsZero() {
+		if t, err := http.ParseTime(since); err == nil && !lastModified.Truncate(time.Second).After(t) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+	}
+
+	c.Data(http.StatusOK, contentType, body)
+}
+
+// feedMode 获取输出模式，查询参数 mode 优先于配置文件
+func feedMode(c *gin.Context) string {
+	mode := c.Query("mode")
+	if mode == "" {
+		mode = utils.ServerConfig.Feed.Mode
+	}
+	if mode == feedModeSummary {
+		return feedModeSummary
+	}
+	return feedModeFull
+}
+
+// feedSiteInfo 订阅源使用的站点信息（取自前端配置文件）
+type feedSiteInfo struct {
+	BlogName   string `yaml:"blog_name"`
+	AuthorName string `yaml:"author_name"`
+	AuthorBio  string `yaml:"author_bio"`
+}
+
+func loadFeedSiteInfo() feedSiteInfo {
+	var info feedSiteInfo
+	if content, err := os.ReadFile(utils.GetFrontEndConfigPath()); err == nil {
+		_ = yaml.Unmarshal(content, &info)
+	}
+	if utils.ServerConfig.Feed.Title != "" {
+		info.BlogName = utils.ServerConfig.Feed.Title
+	}
+	if info.BlogName == "" {
+		info.BlogName = "YanBlog"
+	}
+	if info.AuthorName == "" {
+		info.AuthorName = info.BlogName
+	}
+	return info
+}
+
+// siteBaseUrl 获取站点根地址，未配置 SiteUrl 时根据请求推断
+func siteBaseUrl(c *gin.Context) string {
+	if baseUrl := utils.ServerConfig.Server.SiteUrl; baseUrl != "" {
+		return strings.TrimRight(baseUrl, "/")
+	}
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s", scheme, c.Request.Host)
+}
+
+// absoluteUrl 将站内相对地址转换为绝对地址
+func absoluteUrl(u string, baseUrl string) string {
+	if parsed, err := url.Parse(u); err == nil && parsed.IsAbs() {
+		return u
+	}
+	if !strings.HasPrefix(u, "/") {
+		u = "/" + u
+	}
+	return baseUrl + u
+}
+
+func atomTime(t time.Time) string {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	return t.Format(time.RFC3339)
+}
+
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
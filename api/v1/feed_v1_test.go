@@ -0,0 +1,119 @@
+package v1
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"yanblog/model"
+	"yanblog/utils"
+	"yanblog/utils/errmsg"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// openTestDB 在临时目录中初始化 SQLite 数据库，测试结束后还原配置
+func openTestDB(t *testing.T) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	old := utils.ServerConfig
+	t.Cleanup(func() {
+		if sqlDB, err := model.GetDB().DB(); err == nil {
+			sqlDB.Close()
+		}
+		utils.ServerConfig = old
+	})
+	utils.ServerConfig.Database.Db = "sqlite"
+	utils.ServerConfig.Database.DbName = "test.db"
+	model.InitDB()
+	// 去掉首次运行时创建的演示文章
+	model.GetDB().Session(&gorm.Session{AllowGlobalUpdate: true}).Unscoped().Delete(&model.Article{})
+}
+
+func TestFeed(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	openTestDB(t)
+	utils.ServerConfig.Server.SiteUrl = "https://blog.example.com"
+
+	summary := `a & b <c> ]]> "end"`
+	future := time.Now().Add(24 * time.Hour)
+	articles := []model.Article{
+		{Title: "已发布", Desc: summary, Content: "正文 **加粗**", Tags: "go", Status: model.ArticleStatusPublished},
+		{Title: "草稿", Desc: "draft", Content: "x", Tags: "go", Status: model.ArticleStatusDraft},
+		{Title: "已归档", Desc: "archived", Content: "x", Status: model.ArticleStatusArchived},
+		{Title: "预约发布", Desc: "scheduled", Content: "x", Status: model.ArticleStatusScheduled, PublishAt: &future},
+	}
+	cid := model.GetOrCreateCategory("技术")
+	for i := range articles {
+		articles[i].Cid = cid
+		if code := model.CreateArt(&articles[i]); code != errmsg.SUCCESS {
+			t.Fatalf("创建文章 %s 失败: %d", articles[i].Title, code)
+		}
+	}
+
+	r := gin.New()
+	r.GET("feed.xml", GetRSSFeed)
+	r.GET("atom.xml", GetAtomFeed)
+	r.GET("feed/tag/:name/feed.xml", GetRSSFeed)
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	for _, path := range []string{"/feed.xml", "/feed/tag/go/feed.xml"} {
+		var rss struct {
+			Items []struct {
+				Title       string `xml:"title"`
+				Description string `xml:"description"`
+				Content     string `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+			} `xml:"channel>item"`
+		}
+		w := get(path)
+		if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/rss+xml") {
+			t.Fatalf("%s: %d %s", path, w.Code, w.Header().Get("Content-Type"))
+		}
+		if err := xml.Unmarshal(w.Body.Bytes(), &rss); err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		if len(rss.Items) != 1 || rss.Items[0].Title != "已发布" {
+			t.Fatalf("%s: 应只包含已发布的文章: %+v", path, rss.Items)
+		}
+		if rss.Items[0].Description != summary {
+			t.Errorf("%s: description = %q, want %q", path, rss.Items[0].Description, summary)
+		}
+		if !strings.Contains(rss.Items[0].Content, "<strong>加粗</strong>") {
+			t.Errorf("%s: content = %q", path, rss.Items[0].Content)
+		}
+		if strings.Contains(w.Body.String(), "&amp;amp;") || strings.Contains(w.Body.String(), "&amp;lt;") {
+			t.Errorf("%s: 重复转义: %s", path, w.Body.String())
+		}
+	}
+
+	w := get("/atom.xml")
+	var atom struct {
+		Entries []struct {
+			Title   string `xml:"title"`
+			Summary string `xml:"summary"`
+			Link    struct {
+				Href string `xml:"href,attr"`
+			} `xml:"link"`
+		} `xml:"entry"`
+	}
+	if err := xml.Unmarshal(w.Body.Bytes(), &atom); err != nil {
+		t.Fatal(err)
+	}
+	if len(atom.Entries) != 1 || atom.Entries[0].Title != "已发布" || atom.Entries[0].Summary != summary {
+		t.Errorf("atom entries = %+v", atom.Entries)
+	}
+	if !strings.HasPrefix(atom.Entries[0].Link.Href, "https://blog.example.com/") {
+		t.Errorf("atom link = %q", atom.Entries[0].Link.Href)
+	}
+
+	if w := get("/feed/tag/none/feed.xml"); w.Code != http.StatusNotFound {
+		t.Errorf("不存在的标签: %d, want 404", w.Code)
+	}
+}
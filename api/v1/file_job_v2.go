@@ -0,0 +1,422 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+	"yanblog/utils"
+	"yanblog/utils/errmsg"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 文件管理的耗时操作（压缩、解压、复制目录、统计）可以作为后台任务运行，避免超过请求的写超时
+// 接口带上 ?async=1 时立即返回任务 ID，之后通过 files/jobs/:id 查询进度和结果，或通过 files/jobs/:id/ws 接收进度推送
+// 任务只保存在内存中，结束的任务保留 fileJobTTL 后清理
+
+// 文件任务状态
+const (
+	fileJobQueued    = "queued"
+	fileJobRunning   = "running"
+	fileJobCompleted = "completed"
+	fileJobFailed    = "failed"
+	fileJobCancelled = "cancelled"
+)
+
+const (
+	// fileJobTTL 结束的任务保留的时间
+	fileJobTTL = 24 * time.Hour
+	// maxFinishedFileJobs 最多保留的已结束任务数
+	maxFinishedFileJobs = 100
+	// fileJobNotifyInterval 处理中两次推送进度的最小间隔
+	fileJobNotifyInterval = 200 * time.Millisecond
+)
+
+// FileJob 文件管理的后台任务
+type FileJob struct {
+	ID        string
+	Type      string // compress, extract, copy, stats
+	Status    string
+	Username  string
+	Params    interface{} // 请求参数
+	Total     int64       // 需要处理的文件数，未知时为 0
+	Done      int64       // 已处理的文件数
+	Current   string      // 正在处理的文件
+	Message   string
+	Result    interface{}
+	Error     string
+	CreatedAt time.Time
+	StartTime *time.Time
+	EndTime   *time.Time
+	mu        sync.Mutex
+	clients   progressClients // 订阅进度的 WebSocket 客户端
+	ctx       context.Context
+	cancel    context.CancelFunc
+	notified  time.Time // 上次推送进度的时间
+}
+
+// fileJobFunc 任务的执行函数，返回结果数据和提示信息；应在处理每个文件前检查 job.cancelled()
+type fileJobFunc func(job *FileJob) (interface{}, string, error)
+
+// 全局管理（与 ZIP 导入共用 taskSem 限制同时运行的任务数，见 task_v2.go）
+var (
+	fileJobs    = make(map[string]*FileJob)
+	fileJobsMu  sync.RWMutex
+	fileJobSeq  int64
+	fileJobSeqM sync.Mutex
+)
+
+// fileOpError 文件操作失败，同步执行时按 HTTP 状态码返回
+type fileOpError struct {
+	code    int
+	message string
+}
+
+func (e *fileOpError) Error() string {
+	return e.message
+}
+
+func newFileOpError(code int, format string, args ...interface{}) error {
+	return &fileOpError{code: code, message: fmt.Sprintf(format, args...)}
+}
+
+// respondFileOpError 返回同步执行的文件操作的错误
+func respondFileOpError(c *gin.Context, err error) {
+	code := http.StatusInternalServerError
+	if e, ok := err.(*fileOpError); ok {
+		code = e.code
+	}
+	c.JSON(code, gin.H{
+		"status":  errmsg.ERROR,
+		"message": err.Error(),
+	})
+}
+
+// wantAsync 请求是否要求以后台任务运行（?async=1 或 ?async=true）
+func wantAsync(c *gin.Context) bool {
+	switch c.Query("async") {
+	case "1", "true":
+		return true
+	}
+	return false
+}
+
+// runFileOp 执行文件操作：要求后台运行时创建任务并返回任务 ID，否则直接执行并返回结果
+func runFileOp(c *gin.Context, jobType string, params interface{}, run fileJobFunc) {
+	if wantAsync(c) {
+		job := startFileJob(jobType, currentUsername(c), params, run)
+		c.JSON(http.StatusOK, gin.H{
+			"status":  errmsg.SUCCESS,
+			"message": "任务已开始",
+			"data":    gin.H{"job_id": job.ID, "job_status": job.Status},
+		})
+		return
+	}
+
+	data, message, err := run(nil)
+	if err != nil {
+		respondFileOpError(c, err)
+		return
+	}
+	resp := gin.H{
+		"status": errmsg.SUCCESS,
+		"data":   data,
+	}
+	if message != "" {
+		resp["message"] = message
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// startFileJob 创建任务并在后台排队执行
+func startFileJob(jobType string, username string, params interface{}, run fileJobFunc) *FileJob {
+	fileJobSeqM.Lock()
+	fileJobSeq++
+	id := fmt.Sprintf("job_%d_%d", time.Now().UnixNano(), fileJobSeq)
+	fileJobSeqM.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &FileJob{
+		ID:        id,
+		Type:      jobType,
+		Status:    fileJobQueued,
+		Username:  username,
+		Params:    params,
+		CreatedAt: time.Now(),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+
+	fileJobsMu.Lock()
+	pruneFileJobs()
+	fileJobs[id] = job
+	fileJobsMu.Unlock()
+
+	go job.run(run)
+	return job
+}
+
+// run 等待空闲的执行位置后执行任务
+func (job *FileJob) run(fn fileJobFunc) {
+	defer job.cancel()
+
+	if !taskSem.acquire(job.ctx) {
+		job.finish(nil, "", nil)
+		return
+	}
+	defer taskSem.release()
+
+	job.mu.Lock()
+	if job.Status == fileJobQueued {
+		now := time.Now()
+		job.Status = fileJobRunning
+		job.StartTime = &now
+	}
+	job.mu.Unlock()
+	job.broadcast(true)
+
+	result, message, err := fn(job)
+	job.finish(result, message, err)
+}
+
+// finish 记录任务结果并推送最终状态
+func (job *FileJob) finish(result interface{}, message string, err error) {
+	job.mu.Lock()
+	now := time.Now()
+	job.EndTime = &now
+	if job.Current != "" && err == nil && job.ctx.Err() == nil {
+		job.Done++
+	}
+	job.Current = ""
+	job.Result = result
+	job.Message = message
+	switch {
+	case job.ctx.Err() != nil:
+		job.Status = fileJobCancelled
+		job.Message = "任务已取消"
+	case err != nil:
+		job.Status = fileJobFailed
+		job.Error = err.Error()
+	default:
+		job.Status = fileJobCompleted
+	}
+	job.mu.Unlock()
+	job.broadcast(true)
+}
+
+// cancelled 任务是否已被取消（同步执行时 job 为 nil，总是返回 false）
+func (job *FileJob) cancelled() bool {
+	return job != nil && job.ctx.Err() != nil
+}
+
+// setTotal 设置需要处理的文件数
+func (job *FileJob) setTotal(total int64) {
+	if job == nil {
+		return
+	}
+	job.mu.Lock()
+	job.Total = total
+	job.mu.Unlock()
+	job.broadcast(false)
+}
+
+// step 记录开始处理一个文件，前一个文件计为已处理
+func (job *FileJob) step(current string) {
+	if job == nil {
+		return
+	}
+	job.mu.Lock()
+	if job.Current != "" {
+		job.Done++
+	}
+	job.Current = current
+	job.mu.Unlock()
+	job.broadcast(false)
+}
+
+// reader 包装任务中读取的数据，任务取消后读取立即失败，避免大文件复制无法中断
+func (job *FileJob) reader(r io.Reader) io.Reader {
+	if job == nil {
+		return r
+	}
+	return &fileJobReader{r: r, ctx: job.ctx}
+}
+
+type fileJobReader struct {
+	r   io.Reader
+	ctx context.Context
+}
+
+func (r *fileJobReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// view 任务的当前状态
+func (job *FileJob) view() gin.H {
+	job.mu.Lock()
+	defer job.mu.Unlock()
+
+	progress := 0.0
+	switch {
+	case job.Status == fileJobCompleted:
+		progress = 100
+	case job.Total > 0:
+		progress = float64(job.Done) / float64(job.Total) * 100
+	}
+	return gin.H{
+		"id":         job.ID,
+		"job_type":   job.Type,
+		"job_status": job.Status,
+		"username":   job.Username,
+		"params":     job.Params,
+		"total":      job.Total,
+		"done":       job.Done,
+		"current":    job.Current,
+		"progress":   progress,
+		"message":    job.Message,
+		"result":     job.Result,
+		"error":      job.Error,
+		"created_at": job.CreatedAt,
+		"start_time": job.StartTime,
+		"end_time":   job.EndTime,
+	}
+}
+
+// broadcast 推送进度到所有客户端，处理中最多每 fileJobNotifyInterval 推送一次，force 为 true 时立即推送
+func (job *FileJob) broadcast(force bool) {
+	job.mu.Lock()
+	if !force && time.Since(job.notified) < fileJobNotifyInterval {
+		job.mu.Unlock()
+		return
+	}
+	job.notified = time.Now()
+	job.mu.Unlock()
+
+	if !job.clients.empty() {
+		job.clients.send(job.progress())
+	}
+}
+
+// progress 推送到 WebSocket 客户端的进度
+func (job *FileJob) progress() gin.H {
+	msg := job.view()
+	msg["type"] = "progress"
+	return msg
+}
+
+// isFinished 任务是否已结束（调用方持有 job.mu）
+func (job *FileJob) isFinished() bool {
+	return job.Status != fileJobQueued && job.Status != fileJobRunning
+}
+
+// pruneFileJobs 清理过期的已结束任务，并限制保留的数量（调用方持有 fileJobsMu）
+func pruneFileJobs() {
+	var finished []*FileJob
+	for id, job := range fileJobs {
+		job.mu.Lock()
+		done, end := job.isFinished(), job.EndTime
+		job.mu.Unlock()
+		if !done {
+			continue
+		}
+		if end != nil && time.Since(*end) > fileJobTTL {
+			delete(fileJobs, id)
+			continue
+		}
+		finished = append(finished, job)
+	}
+	if len(finished) < maxFinishedFileJobs {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].CreatedAt.Before(finished[j].CreatedAt)
+	})
+	for _, job := range finished[:len(finished)-maxFinishedFileJobs+1] {
+		delete(fileJobs, job.ID)
+	}
+}
+
+func findFileJob(id string) (*FileJob, bool) {
+	fileJobsMu.RLock()
+	defer fileJobsMu.RUnlock()
+	job, ok := fileJobs[id]
+	return job, ok
+}
+
+// GetFileJobs 获取文件任务列表（按创建时间倒序）
+// 查询参数: type - 任务类型, status - 任务状态
+func GetFileJobs(c *gin.Context) {
+	jobType, status := c.Query("type"), c.Query("status")
+
+	fileJobsMu.RLock()
+	jobs := make([]*FileJob, 0, len(fileJobs))
+	for _, job := range fileJobs {
+		jobs = append(jobs, job)
+	}
+	fileJobsMu.RUnlock()
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+	})
+
+	data := make([]gin.H, 0, len(jobs))
+	for _, job := range jobs {
+		v := job.view()
+		if (jobType != "" && v["job_type"] != jobType) || (status != "" && v["job_status"] != status) {
+			continue
+		}
+		data = append(data, v)
+	}
+	utils.Success(c, data)
+}
+
+// GetFileJob 获取任务的进度和结果
+func GetFileJob(c *gin.Context) {
+	job, ok := findFileJob(c.Param("id"))
+	if !ok {
+		utils.NotFound(c, "任务不存在")
+		return
+	}
+	utils.Success(c, job.view())
+}
+
+// CancelFileJob 取消排队中或正在运行的任务
+// 已处理的文件不会回滚（如解压、复制到一半时已写入的文件），压缩任务不会保存未完成的压缩包
+func CancelFileJob(c *gin.Context) {
+	job, ok := findFileJob(c.Param("id"))
+	if !ok {
+		utils.NotFound(c, "任务不存在")
+		return
+	}
+	job.mu.Lock()
+	finished := job.isFinished()
+	job.mu.Unlock()
+	if finished {
+		utils.ErrorWithMessage(c, errmsg.ERROR, "任务已结束")
+		return
+	}
+	job.cancel()
+	c.JSON(http.StatusOK, gin.H{
+		"status":  errmsg.SUCCESS,
+		"message": "任务已取消",
+	})
+}
+
+// FileJobProgress 通过 WebSocket 推送任务进度
+func FileJobProgress(c *gin.Context) {
+	job, ok := findFileJob(c.Param("id"))
+	if !ok {
+		utils.NotFound(c, "任务不存在")
+		return
+	}
+
+	serveProgress(c, &job.clients, func() interface{} {
+		return job.progress()
+	})
+}
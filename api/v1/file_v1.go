@@ -1,28 +1,24 @@
 package v1
 
 import (
-	"io"
+	"mime/multipart"
 	"net/http"
-	"os"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
+	"yanblog/model"
 	"yanblog/utils/errmsg"
+	"yanblog/utils/imaging"
+	"yanblog/utils/storage"
 
 	"github.com/gin-gonic/gin"
 )
 
-// safeUploadPath 验证并返回安全的文件路径，防止路径遍历攻击
+// safeUploadPath 验证并返回文件在存储中的路径（空字符串为根目录），防止路径遍历攻击
 func safeUploadPath(userPath string) (string, bool) {
-	cleaned := filepath.Clean(filepath.Join("uploads", userPath))
-	// 确保路径在 uploads 目录内
-	absBase, _ := filepath.Abs("uploads")
-	absTarget, _ := filepath.Abs(cleaned)
-	if !strings.HasPrefix(absTarget, absBase) {
-		return "", false
-	}
-	return cleaned, true
+	return storage.Clean(userPath)
 }
 
 // FileInfo 文件信息结构体
@@ -39,23 +35,18 @@ type FileInfo struct {
 
 // GetFileList 获取文件列表
 func GetFileList(c *gin.Context) {
-	reqPath := c.Query("path")
-	if reqPath == "" {
-		reqPath = "uploads"
-	} else {
-		var ok bool
-		reqPath, ok = safeUploadPath(reqPath)
-		if !ok {
-			c.JSON(http.StatusForbidden, gin.H{
-				"status":  errmsg.ERROR,
-				"message": "非法路径",
-				"data":    []FileInfo{},
-			})
-			return
-		}
+	reqPath, ok := safeUploadPath(c.Query("path"))
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  errmsg.ERROR,
+			"message": "非法路径",
+			"data":    []FileInfo{},
+		})
+		return
 	}
 
-	if _, err := os.Stat(reqPath); os.IsNotExist(err) {
+	files, err := storage.Current().List(reqPath)
+	if storage.IsNotExist(err) {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  errmsg.ERROR,
 			"message": "目录不存在",
@@ -64,7 +55,6 @@ func GetFileList(c *gin.Context) {
 		return
 	}
 
-	files, err := os.ReadDir(reqPath)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  errmsg.ERROR,
@@ -76,32 +66,30 @@ func GetFileList(c *gin.Context) {
 
 	fileList := make([]FileInfo, 0)
 	for _, f := range files {
-		info, err := f.Info()
-		if err != nil {
+		// 自动生成的图片尺寸不在文件管理中显示
+		if f.Path == model.ImageVariantDir {
 			continue
 		}
-		fullPath := filepath.Join(reqPath, f.Name())
-		relPath, err := filepath.Rel("uploads", fullPath)
-		if err != nil {
-			continue
-		}
-		relPath = filepath.ToSlash(relPath)
+		relPath := f.Path
 
-		ext := filepath.Ext(f.Name())
+		ext := filepath.Ext(f.Name)
 		isImage := isImageFile(ext)
 
 		fileInfo := FileInfo{
-			Name:    f.Name(),
-			IsDir:   f.IsDir(),
+			Name:    f.Name,
+			IsDir:   f.IsDir,
 			Path:    relPath,
-			Size:    info.Size(),
+			Size:    f.Size,
 			Ext:     ext,
-			ModTime: info.ModTime(),
+			ModTime: f.ModTime,
 			IsImage: isImage,
 		}
 
-		if isImage && !f.IsDir() {
+		if isImage && !f.IsDir {
 			fileInfo.Thumbnail = "/uploads/" + relPath
+			if imaging.IsSupported(ext) {
+				fileInfo.Thumbnail = "/img/thumb/" + relPath
+			}
 		}
 
 		fileList = append(fileList, fileInfo)
@@ -141,7 +129,7 @@ func DeleteFile(c *gin.Context) {
 
 	// 安全路径验证，防止路径遍历攻击
 	targetPath, ok := safeUploadPath(path)
-	if !ok {
+	if !ok || targetPath == "" {
 		c.JSON(http.StatusForbidden, gin.H{
 			"status":  errmsg.ERROR,
 			"message": "非法路径",
@@ -149,7 +137,8 @@ func DeleteFile(c *gin.Context) {
 		return
 	}
 
-	if _, err := os.Stat(targetPath); os.IsNotExist(err) {
+	store := storage.Current()
+	if !storage.Exists(store, targetPath) {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  errmsg.ERROR,
 			"message": "文件或目录不存在",
@@ -157,7 +146,7 @@ func DeleteFile(c *gin.Context) {
 		return
 	}
 
-	err := os.RemoveAll(targetPath)
+	err := store.Delete(targetPath)
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  errmsg.ERROR,
@@ -165,6 +154,7 @@ func DeleteFile(c *gin.Context) {
 		})
 		return
 	}
+	model.RemoveImageVariants(targetPath)
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":  errmsg.SUCCESS,
@@ -186,18 +176,9 @@ func CreateDir(c *gin.Context) {
 		return
 	}
 
-	data.Path = filepath.Clean(data.Path)
-	if data.Path == "." {
-		data.Path = ""
-	}
-
-	targetPath := filepath.Join("uploads", data.Path, data.Name)
-	targetPath = filepath.Clean(targetPath)
-
 	// 安全路径验证，防止路径遍历攻击
-	absBase, _ := filepath.Abs("uploads")
-	absTarget, _ := filepath.Abs(targetPath)
-	if !strings.HasPrefix(absTarget, absBase) {
+	targetPath, ok := safeUploadPath(path.Join(data.Path, data.Name))
+	if !ok || targetPath == "" {
 		c.JSON(http.StatusForbidden, gin.H{
 			"status":  errmsg.ERROR,
 			"message": "非法路径",
@@ -205,7 +186,8 @@ func CreateDir(c *gin.Context) {
 		return
 	}
 
-	if _, err := os.Stat(targetPath); !os.IsNotExist(err) {
+	store := storage.Current()
+	if storage.Exists(store, targetPath) {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  errmsg.ERROR,
 			"message": "目录已存在",
@@ -213,7 +195,7 @@ func CreateDir(c *gin.Context) {
 		return
 	}
 
-	if err := os.MkdirAll(targetPath, 0755); err != nil {
+	if err := store.Mkdir(targetPath); err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  errmsg.ERROR,
 			"message": "创建目录失败: " + err.Error(),
@@ -249,18 +231,10 @@ func RenameFile(c *gin.Context) {
 		return
 	}
 
-	oldPath := filepath.Join("uploads", data.Path)
-	dir := filepath.Dir(oldPath)
-	newPath := filepath.Join(dir, data.NewName)
-
-	oldPath = filepath.Clean(oldPath)
-	newPath = filepath.Clean(newPath)
-
 	// 安全路径验证，防止路径遍历攻击
-	absBase, _ := filepath.Abs("uploads")
-	absOld, _ := filepath.Abs(oldPath)
-	absNew, _ := filepath.Abs(newPath)
-	if !strings.HasPrefix(absOld, absBase) || !strings.HasPrefix(absNew, absBase) {
+	oldPath, okOld := safeUploadPath(data.Path)
+	newPath, okNew := safeUploadPath(path.Join(path.Dir(oldPath), data.NewName))
+	if !okOld || !okNew || oldPath == "" || newPath == "" {
 		c.JSON(http.StatusForbidden, gin.H{
 			"status":  errmsg.ERROR,
 			"message": "非法路径",
@@ -268,13 +242,30 @@ func RenameFile(c *gin.Context) {
 		return
 	}
 
-	if err := os.Rename(oldPath, newPath); err != nil {
+	store := storage.Current()
+	if _, err := store.Stat(oldPath); err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  errmsg.ERROR,
 			"message": "重命名失败: " + err.Error(),
 		})
 		return
 	}
+	if storage.Exists(store, newPath) {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  errmsg.ERROR,
+			"message": "重命名失败: 目标已存在",
+		})
+		return
+	}
+
+	if err := store.Move(oldPath, newPath); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  errmsg.ERROR,
+			"message": "重命名失败: " + err.Error(),
+		})
+		return
+	}
+	model.RemoveImageVariants(oldPath)
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":  errmsg.SUCCESS,
@@ -304,17 +295,10 @@ func MoveFile(c *gin.Context) {
 		return
 	}
 
-	source := filepath.Join("uploads", data.SourcePath)
-	targetDir := filepath.Join("uploads", data.TargetPath)
-
-	source = filepath.Clean(source)
-	targetDir = filepath.Clean(targetDir)
-
 	// 安全路径验证，防止路径遍历攻击
-	absBase, _ := filepath.Abs("uploads")
-	absSrc, _ := filepath.Abs(source)
-	absDst, _ := filepath.Abs(targetDir)
-	if !strings.HasPrefix(absSrc, absBase) || !strings.HasPrefix(absDst, absBase) {
+	source, okSrc := safeUploadPath(data.SourcePath)
+	targetDir, okDst := safeUploadPath(data.TargetPath)
+	if !okSrc || !okDst || source == "" {
 		c.JSON(http.StatusForbidden, gin.H{
 			"status":  errmsg.ERROR,
 			"message": "非法路径",
@@ -322,7 +306,9 @@ func MoveFile(c *gin.Context) {
 		return
 	}
 
-	if _, err := os.Stat(source); os.IsNotExist(err) {
+	store := storage.Current()
+
+	if !storage.Exists(store, source) {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  errmsg.ERROR,
 			"message": "源文件不存在",
@@ -330,20 +316,18 @@ func MoveFile(c *gin.Context) {
 		return
 	}
 
-	if _, err := os.Stat(targetDir); os.IsNotExist(err) {
-		if err := os.MkdirAll(targetDir, 0755); err != nil {
-			c.JSON(http.StatusOK, gin.H{
-				"status":  errmsg.ERROR,
-				"message": "创建目标目录失败: " + err.Error(),
-			})
-			return
-		}
+	if err := store.Mkdir(targetDir); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  errmsg.ERROR,
+			"message": "创建目标目录失败: " + err.Error(),
+		})
+		return
 	}
 
-	fileName := filepath.Base(source)
-	target := filepath.Join(targetDir, fileName)
+	fileName := path.Base(source)
+	target := path.Join(targetDir, fileName)
 
-	if _, err := os.Stat(target); !os.IsNotExist(err) {
+	if storage.Exists(store, target) {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  errmsg.ERROR,
 			"message": "目标文件已存在",
@@ -351,13 +335,14 @@ func MoveFile(c *gin.Context) {
 		return
 	}
 
-	if err := os.Rename(source, target); err != nil {
+	if err := store.Move(source, target); err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  errmsg.ERROR,
 			"message": "移动失败: " + err.Error(),
 		})
 		return
 	}
+	model.RemoveImageVariants(source)
 
 	c.JSON(http.StatusOK, gin.H{
 		"status":  errmsg.SUCCESS,
@@ -365,7 +350,8 @@ func MoveFile(c *gin.Context) {
 	})
 }
 
-// CopyFile 复制文件
+// CopyFile 复制文件或目录
+// 复制较大的目录时可带上 ?async=1 以后台任务运行，立即返回任务 ID
 func CopyFile(c *gin.Context) {
 	var data struct {
 		SourcePath string `json:"sourcePath"`
@@ -387,17 +373,10 @@ func CopyFile(c *gin.Context) {
 		return
 	}
 
-	source := filepath.Join("uploads", data.SourcePath)
-	targetDir := filepath.Join("uploads", data.TargetPath)
-
-	source = filepath.Clean(source)
-	targetDir = filepath.Clean(targetDir)
-
 	// 安全路径验证，防止路径遍历攻击
-	absBase, _ := filepath.Abs("uploads")
-	absSrc, _ := filepath.Abs(source)
-	absDst, _ := filepath.Abs(targetDir)
-	if !strings.HasPrefix(absSrc, absBase) || !strings.HasPrefix(absDst, absBase) {
+	source, okSrc := safeUploadPath(data.SourcePath)
+	targetDir, okDst := safeUploadPath(data.TargetPath)
+	if !okSrc || !okDst || source == "" {
 		c.JSON(http.StatusForbidden, gin.H{
 			"status":  errmsg.ERROR,
 			"message": "非法路径",
@@ -405,8 +384,10 @@ func CopyFile(c *gin.Context) {
 		return
 	}
 
-	sourceInfo, err := os.Stat(source)
-	if os.IsNotExist(err) {
+	store := storage.Current()
+
+	sourceInfo, err := store.Stat(source)
+	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  errmsg.ERROR,
 			"message": "源文件不存在",
@@ -414,65 +395,96 @@ func CopyFile(c *gin.Context) {
 		return
 	}
 
-	if sourceInfo.IsDir() {
+	// 不能将目录复制到其自身或子目录中
+	if sourceInfo.IsDir && (targetDir == source || strings.HasPrefix(targetDir, source+"/")) {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  errmsg.ERROR,
-			"message": "暂不支持复制目录",
+			"message": "不能将目录复制到自身或其子目录中",
 		})
 		return
 	}
 
-	if _, err := os.Stat(targetDir); os.IsNotExist(err) {
-		if err := os.MkdirAll(targetDir, 0755); err != nil {
-			c.JSON(http.StatusOK, gin.H{
-				"status":  errmsg.ERROR,
-				"message": "创建目标目录失败: " + err.Error(),
-			})
-			return
+	runFileOp(c, "copy", data, func(job *FileJob) (interface{}, string, error) {
+		target, fileCount, err := copyInStorage(job, sourceInfo, targetDir)
+		if err != nil {
+			return nil, "", err
 		}
+		return gin.H{
+			"target":     target,
+			"file_count": fileCount,
+		}, "复制成功", nil
+	})
+}
+
+// copyInStorage 将文件或目录复制到 targetDir 中，目标已存在时命名为 <原名称>_copy<扩展名>（job 为 nil 时同步执行）
+// 返回复制后的路径和复制的文件数；任务取消时已复制的文件会保留
+func copyInStorage(job *FileJob, source storage.FileInfo, targetDir string) (string, int, error) {
+	store := storage.Current()
+	if err := store.Mkdir(targetDir); err != nil {
+		return "", 0, newFileOpError(http.StatusOK, "创建目标目录失败: %v", err)
 	}
 
-	fileName := filepath.Base(source)
-	target := filepath.Join(targetDir, fileName)
+	fileName := path.Base(source.Path)
+	target := path.Join(targetDir, fileName)
 
-	if _, err := os.Stat(target); !os.IsNotExist(err) {
-		ext := filepath.Ext(fileName)
+	if storage.Exists(store, target) {
+		ext := path.Ext(fileName)
+		if source.IsDir {
+			ext = ""
+		}
 		name := fileName[:len(fileName)-len(ext)]
-		target = filepath.Join(targetDir, name+"_copy"+ext)
+		target = path.Join(targetDir, name+"_copy"+ext)
 	}
 
-	srcFile, err := os.Open(source)
-	if err != nil {
-		c.JSON(http.StatusOK, gin.H{
-			"status":  errmsg.ERROR,
-			"message": "打开源文件失败: " + err.Error(),
-		})
-		return
+	if !source.IsDir {
+		job.setTotal(1)
+		job.step(source.Path)
+		if err := store.Copy(source.Path, target); err != nil {
+			return "", 0, newFileOpError(http.StatusOK, "复制失败: %v", err)
+		}
+		return target, 1, nil
 	}
-	defer srcFile.Close()
 
-	dstFile, err := os.Create(target)
+	// 先列出目录中的所有项目，避免复制过程中新建的文件被再次遍历
+	var entries []storage.FileInfo
+	var files int64
+	err := storage.Walk(store, source.Path, func(info storage.FileInfo) error {
+		if job.cancelled() {
+			return job.ctx.Err()
+		}
+		entries = append(entries, info)
+		if !info.IsDir {
+			files++
+		}
+		return nil
+	})
 	if err != nil {
-		c.JSON(http.StatusOK, gin.H{
-			"status":  errmsg.ERROR,
-			"message": "创建目标文件失败: " + err.Error(),
-		})
-		return
+		return "", 0, newFileOpError(http.StatusOK, "复制失败: %v", err)
 	}
-	defer dstFile.Close()
+	job.setTotal(files)
 
-	if _, err := io.Copy(dstFile, srcFile); err != nil {
-		c.JSON(http.StatusOK, gin.H{
-			"status":  errmsg.ERROR,
-			"message": "复制失败: " + err.Error(),
-		})
-		return
+	if err := store.Mkdir(target); err != nil {
+		return "", 0, newFileOpError(http.StatusOK, "复制失败: %v", err)
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"status":  errmsg.SUCCESS,
-		"message": "复制成功",
-	})
+	fileCount := 0
+	for _, info := range entries {
+		if job.cancelled() {
+			return target, fileCount, job.ctx.Err()
+		}
+		dest := path.Join(target, strings.TrimPrefix(info.Path, source.Path+"/"))
+		if info.IsDir {
+			if err := store.Mkdir(dest); err != nil {
+				return target, fileCount, newFileOpError(http.StatusOK, "复制失败: %v", err)
+			}
+			continue
+		}
+		job.step(info.Path)
+		if err := store.Copy(info.Path, dest); err != nil {
+			return target, fileCount, newFileOpError(http.StatusOK, "复制失败: %v", err)
+		}
+		fileCount++
+	}
+	return target, fileCount, nil
 }
 
 // BatchDeleteFiles 批量删除文件
@@ -503,18 +515,19 @@ func BatchDeleteFiles(c *gin.Context) {
 	for _, path := range data.Paths {
 		// 安全路径验证
 		safePath, ok := safeUploadPath(path)
-		if !ok {
+		if !ok || safePath == "" {
 			failCount++
 			failMessages = append(failMessages, path+": 非法路径")
 			continue
 		}
 		targetPath := safePath
 
-		if err := os.RemoveAll(targetPath); err != nil {
+		if err := storage.Current().Delete(targetPath); err != nil {
 			failCount++
 			failMessages = append(failMessages, path+": "+err.Error())
 		} else {
 			successCount++
+			model.RemoveImageVariants(targetPath)
 		}
 	}
 
@@ -562,16 +575,14 @@ func BatchUploadFiles(c *gin.Context) {
 		return
 	}
 	targetPath := safeTargetDir
-	targetPath = filepath.Clean(targetPath)
 
-	if _, err := os.Stat(targetPath); os.IsNotExist(err) {
-		if err := os.MkdirAll(targetPath, 0755); err != nil {
-			c.JSON(http.StatusOK, gin.H{
-				"status":  errmsg.ERROR,
-				"message": "创建目录失败: " + err.Error(),
-			})
-			return
-		}
+	store := storage.Current()
+	if err := store.Mkdir(targetPath); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  errmsg.ERROR,
+			"message": "创建目录失败: " + err.Error(),
+		})
+		return
 	}
 
 	results := make([]map[string]interface{}, 0)
@@ -594,9 +605,9 @@ func BatchUploadFiles(c *gin.Context) {
 		}
 
 		newFileName := strconv.FormatInt(time.Now().UnixNano(), 10) + ext
-		dstPath := filepath.Join(targetPath, newFileName)
+		dstPath := path.Join(targetPath, newFileName)
 
-		if err := c.SaveUploadedFile(file, dstPath); err != nil {
+		if err := saveUploadedFile(store, file, dstPath); err != nil {
 			failCount++
 			results = append(results, map[string]interface{}{
 				"name":    fileName,
@@ -605,8 +616,8 @@ func BatchUploadFiles(c *gin.Context) {
 			})
 		} else {
 			successCount++
-			relPath, _ := filepath.Rel("uploads", dstPath)
-			relPath = filepath.ToSlash(relPath)
+			model.ProcessUploadedImage(dstPath)
+			relPath := dstPath
 			results = append(results, map[string]interface{}{
 				"name":    fileName,
 				"success": true,
@@ -625,22 +636,28 @@ func BatchUploadFiles(c *gin.Context) {
 	})
 }
 
+// saveUploadedFile 将表单中的文件写入存储
+func saveUploadedFile(store storage.Storage, file *multipart.FileHeader, name string) error {
+	src, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	return store.Put(name, src, file.Size)
+}
+
 // GetStorageStats 获取存储统计信息
 func GetStorageStats(c *gin.Context) {
-	rootDir := "uploads"
 	var totalFiles int
 	var totalSize int64
-	var totalDirs int
+	totalDirs := 1 // 根目录
 
-	err := filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-		if info.IsDir() {
+	err := storage.Walk(storage.Current(), "", func(info storage.FileInfo) error {
+		if info.IsDir {
 			totalDirs++
 		} else {
 			totalFiles++
-			totalSize += info.Size()
+			totalSize += info.Size
 		}
 		return nil
 	})
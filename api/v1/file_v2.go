@@ -1,18 +1,22 @@
 package v1
 
 import (
-	"archive/zip"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
-	"time"
+	"yanblog/middlewares"
+	"yanblog/model"
+	"yanblog/utils"
+	"yanblog/utils/archive"
 	"yanblog/utils/errmsg"
+	"yanblog/utils/storage"
 
 	"github.com/gin-gonic/gin"
 )
@@ -44,75 +48,66 @@ type FileSearchRequest struct {
 	PageSize int    `json:"page_size"` // 每页数量
 }
 
-// RecycleBinItem 回收站项目
-type RecycleBinItem struct {
-	OriginalPath string    `json:"original_path"`
-	RecyclePath  string    `json:"recycle_path"`
-	Name         string    `json:"name"`
-	Size         int64     `json:"size"`
-	DeletedAt    time.Time `json:"deleted_at"`
-	IsDir        bool      `json:"is_dir"`
-}
-
 // GetFileStats 获取文件统计信息
+// 文件较多时可带上 ?async=1 以后台任务运行，结果通过 files/jobs/:id 获取
 func GetFileStats(c *gin.Context) {
-	basePath := "uploads"
-	
-	stats := &FileStats{}
+	runFileOp(c, "stats", nil, func(job *FileJob) (interface{}, string, error) {
+		stats, err := computeFileStats(job)
+		if err != nil {
+			return nil, "", newFileOpError(http.StatusOK, "统计失败")
+		}
+		return stats, "", nil
+	})
+}
+
+// computeFileStats 遍历存储统计文件数量和大小（job 为 nil 时同步执行）
+func computeFileStats(job *FileJob) (*FileStats, error) {
+	stats := &FileStats{TotalDirs: 1} // 根目录
 	var largestSize int64
 	var largestFile string
-	
-	err := filepath.Walk(basePath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
+
+	err := storage.Walk(storage.Current(), "", func(info storage.FileInfo) error {
+		if job.cancelled() {
+			return job.ctx.Err()
 		}
-		
-		if info.IsDir() {
+		if info.IsDir {
 			stats.TotalDirs++
 			return nil
 		}
-		
+		job.step(info.Path)
+
 		stats.TotalFiles++
-		stats.TotalSize += info.Size()
-		
+		stats.TotalSize += info.Size
+
 		// 统计最大文件
-		if info.Size() > largestSize {
-			largestSize = info.Size()
-			largestFile = path
+		if info.Size > largestSize {
+			largestSize = info.Size
+			largestFile = info.Path
 		}
-		
+
 		// 按类型统计
-		ext := strings.ToLower(filepath.Ext(info.Name()))
+		ext := strings.ToLower(path.Ext(info.Name))
 		switch {
 		case isImageFile(ext):
 			stats.ImageCount++
 		case isDocumentFile(ext):
 			stats.DocumentCount++
-		case isArchiveFile(ext):
+		case isArchiveFile(info.Name):
 			stats.ArchiveCount++
 		default:
 			stats.OtherCount++
 		}
-		
+
 		return nil
 	})
-	
 	if err != nil {
-		c.JSON(http.StatusOK, gin.H{
-			"status":  errmsg.ERROR,
-			"message": "统计失败",
-		})
-		return
+		return nil, err
 	}
-	
+
 	stats.TotalSizeMB = float64(stats.TotalSize) / 1024 / 1024
 	stats.LargestFile = largestFile
 	stats.LargestSize = largestSize
-	
-	c.JSON(http.StatusOK, gin.H{
-		"status":  errmsg.SUCCESS,
-		"data":    stats,
-	})
+	return stats, nil
 }
 
 // SearchFiles 搜索文件
@@ -136,55 +131,54 @@ func SearchFiles(c *gin.Context) {
 	req.Page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
 	req.PageSize, _ = strconv.Atoi(c.DefaultQuery("page_size", "20"))
 	
-	if req.Path == "" {
-		req.Path = "uploads"
-	} else {
-		var ok bool
-		req.Path, ok = safeUploadPath(req.Path)
-		if !ok {
-			c.JSON(http.StatusForbidden, gin.H{
-				"status":  errmsg.ERROR,
-				"message": "非法路径",
-			})
-			return
-		}
+	var ok bool
+	req.Path, ok = safeUploadPath(req.Path)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"status":  errmsg.ERROR,
+			"message": "非法路径",
+		})
+		return
 	}
 	
 	// 执行搜索
 	var results []FileInfo
-	filepath.Walk(req.Path, func(path string, info os.FileInfo, err error) error {
-		if err != nil || info.IsDir() {
+	storage.Walk(storage.Current(), req.Path, func(info storage.FileInfo) error {
+		if info.IsDir {
+			// 自动生成的图片尺寸不参与搜索
+			if info.Path == model.ImageVariantDir {
+				return storage.SkipDir
+			}
 			return nil
 		}
 		
 		// 关键词过滤
-		if req.Keyword != "" && !strings.Contains(strings.ToLower(info.Name()), strings.ToLower(req.Keyword)) {
+		if req.Keyword != "" && !strings.Contains(strings.ToLower(info.Name), strings.ToLower(req.Keyword)) {
 			return nil
 		}
 		
 		// 扩展名过滤
-		if req.Ext != "" && strings.ToLower(filepath.Ext(info.Name())) != strings.ToLower(req.Ext) {
+		if req.Ext != "" && strings.ToLower(path.Ext(info.Name)) != strings.ToLower(req.Ext) {
 			return nil
 		}
 		
 		// 大小过滤
-		if req.MinSize > 0 && info.Size() < req.MinSize {
+		if req.MinSize > 0 && info.Size < req.MinSize {
 			return nil
 		}
-		if req.MaxSize > 0 && info.Size() > req.MaxSize {
+		if req.MaxSize > 0 && info.Size > req.MaxSize {
 			return nil
 		}
 		
-		relPath, _ := filepath.Rel("uploads", path)
-		ext := filepath.Ext(info.Name())
+		ext := path.Ext(info.Name)
 		
 		results = append(results, FileInfo{
-			Name:    info.Name(),
+			Name:    info.Name,
 			IsDir:   false,
-			Path:    filepath.ToSlash(relPath),
-			Size:    info.Size(),
+			Path:    info.Path,
+			Size:    info.Size,
 			Ext:     ext,
-			ModTime: info.ModTime(),
+			ModTime: info.ModTime,
 			IsImage: isImageFile(ext),
 		})
 		
@@ -235,13 +229,15 @@ func SearchFiles(c *gin.Context) {
 }
 
 // CompressFiles 压缩文件/目录
+// 压缩包格式由文件名的扩展名决定（.zip、.tar、.tar.gz/.tgz，不是压缩包的扩展名按 ZIP 处理）
+// 带上 ?async=1 时以后台任务运行，立即返回任务 ID
 func CompressFiles(c *gin.Context) {
 	var req struct {
-		Paths    []string `json:"paths" binding:"required"`    // 要压缩的文件/目录
-		ZipName  string   `json:"zip_name" binding:"required"` // ZIP文件名
-		ZipPath  string   `json:"zip_path"`                    // ZIP文件保存路径
+		Paths   []string `json:"paths" binding:"required"`    // 要压缩的文件/目录
+		ZipName string   `json:"zip_name" binding:"required"` // 压缩包文件名
+		ZipPath string   `json:"zip_path"`                    // 压缩包保存路径
 	}
-	
+
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"status":  errmsg.ERROR,
@@ -249,105 +245,139 @@ func CompressFiles(c *gin.Context) {
 		})
 		return
 	}
-	
-	// 确定ZIP路径
-	if req.ZipPath == "" {
-		req.ZipPath = "uploads"
-	}
-	
-	zipPath, ok := safeUploadPath(filepath.Join(req.ZipPath, req.ZipName))
-	if !ok {
+
+	// 确定压缩包路径（默认为根目录）
+	zipPath, ok := safeUploadPath(path.Join(req.ZipPath, req.ZipName))
+	if !ok || zipPath == "" {
 		c.JSON(http.StatusForbidden, gin.H{
 			"status":  errmsg.ERROR,
 			"message": "非法路径",
 		})
 		return
 	}
-	
-	// 创建ZIP文件
-	zipFile, err := os.Create(zipPath)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+
+	format := archive.Detect(zipPath)
+	if format == "" && !archive.IsOther(zipPath) {
+		format = archive.FormatZip
+	}
+	if !archive.CanCreate(format) {
+		c.JSON(http.StatusBadRequest, gin.H{
 			"status":  errmsg.ERROR,
-			"message": "创建ZIP失败",
+			"message": "不支持创建该格式的压缩包，请使用 .zip、.tar 或 .tar.gz",
 		})
 		return
 	}
-	defer zipFile.Close()
-	
-	zipWriter := zip.NewWriter(zipFile)
-	defer zipWriter.Close()
-	
-	// 添加文件到ZIP
-	fileCount := 0
-	for _, path := range req.Paths {
-		safePath, ok := safeUploadPath(path)
+
+	runFileOp(c, "compress", req, func(job *FileJob) (interface{}, string, error) {
+		fileCount, zipSize, err := compressToStorage(job, req.Paths, zipPath, format)
+		if err != nil {
+			return nil, "", err
+		}
+		return gin.H{
+			"zip_path":   zipPath,
+			"format":     format,
+			"file_count": fileCount,
+			"zip_size":   zipSize,
+		}, fmt.Sprintf("压缩成功，共 %d 个文件", fileCount), nil
+	})
+}
+
+// compressToStorage 将存储中的文件/目录压缩为 zipPath（job 为 nil 时同步执行）
+// 先在本地临时文件中生成压缩包，完成后写入存储；任务取消时不保存压缩包
+func compressToStorage(job *FileJob, paths []string, zipPath string, format string) (int, int64, error) {
+	store := storage.Current()
+
+	// 先列出所有文件，以便报告进度
+	type zipEntry struct {
+		info storage.FileInfo
+		name string
+	}
+	var entries []zipEntry
+	for _, p := range paths {
+		safePath, ok := safeUploadPath(p)
 		if !ok {
 			continue
 		}
-		
-		err := filepath.Walk(safePath, func(filePath string, info os.FileInfo, err error) error {
-			if err != nil || info.IsDir() {
-				return nil
-			}
-			
-			relPath, _ := filepath.Rel(filepath.Dir(safePath), filePath)
-			
-			header, err := zip.FileInfoHeader(info)
-			if err != nil {
-				return err
-			}
-			header.Name = filepath.ToSlash(relPath)
-			header.Method = zip.Deflate
-			
-			writer, err := zipWriter.CreateHeader(header)
-			if err != nil {
-				return err
+		err := walkStorageFiles(store, safePath, func(info storage.FileInfo) error {
+			if job.cancelled() {
+				return job.ctx.Err()
 			}
-			
-			file, err := os.Open(filePath)
-			if err != nil {
-				return err
+			// 保留所选项目本身的名称
+			relPath := info.Path
+			if dir := path.Dir(safePath); dir != "." {
+				relPath = strings.TrimPrefix(info.Path, dir+"/")
 			}
-			
-			_, err = io.Copy(writer, file)
-			file.Close()
+			entries = append(entries, zipEntry{info: info, name: relPath})
+			return nil
+		})
+		if err != nil {
+			return 0, 0, newFileOpError(http.StatusInternalServerError, "压缩失败: %v", err)
+		}
+	}
+	job.setTotal(int64(len(entries)))
+
+	zipFile, err := os.CreateTemp("", "compress-*"+archive.Ext(format))
+	if err != nil {
+		return 0, 0, newFileOpError(http.StatusInternalServerError, "创建压缩包失败")
+	}
+	defer os.Remove(zipFile.Name())
+	defer zipFile.Close()
+
+	writer, err := archive.NewWriter(zipFile, format)
+	if err != nil {
+		return 0, 0, newFileOpError(http.StatusBadRequest, "创建压缩包失败: %v", err)
+	}
+	fileCount := 0
+	for _, entry := range entries {
+		if job.cancelled() {
+			return 0, 0, job.ctx.Err()
+		}
+		job.step(entry.info.Path)
+		err := func() error {
+			file, err := store.Get(entry.info.Path)
 			if err != nil {
 				return err
 			}
-			
-			fileCount++
-			return nil
-		})
-		
+			defer file.Close()
+			return writer.Add(entry.name, entry.info.Size, entry.info.ModTime, job.reader(file))
+		}()
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"status":  errmsg.ERROR,
-				"message": fmt.Sprintf("压缩失败: %v", err),
-			})
-			os.Remove(zipPath)
-			return
+			return 0, 0, newFileOpError(http.StatusInternalServerError, "压缩失败: %v", err)
 		}
+		fileCount++
 	}
-	
-	c.JSON(http.StatusOK, gin.H{
-		"status":  errmsg.SUCCESS,
-		"message": fmt.Sprintf("压缩成功，共 %d 个文件", fileCount),
-		"data": gin.H{
-			"zip_path":    filepath.ToSlash(strings.TrimPrefix(zipPath, "uploads/")),
-			"file_count":  fileCount,
-			"zip_size":    getFileSize(zipPath),
-		},
-	})
+
+	if err := writer.Close(); err != nil {
+		return 0, 0, newFileOpError(http.StatusInternalServerError, "压缩失败: %v", err)
+	}
+	if job.cancelled() {
+		return 0, 0, job.ctx.Err()
+	}
+	zipSize, err := zipFile.Seek(0, io.SeekCurrent)
+	if err == nil {
+		_, err = zipFile.Seek(0, io.SeekStart)
+	}
+	if err == nil {
+		err = store.Put(zipPath, job.reader(zipFile), zipSize)
+	}
+	if job.cancelled() {
+		return 0, 0, job.ctx.Err()
+	}
+	if err != nil {
+		return 0, 0, newFileOpError(http.StatusInternalServerError, "保存压缩包失败: %v", err)
+	}
+	return fileCount, zipSize, nil
 }
 
-// ExtractZip 解压ZIP文件
+// ExtractZip 解压压缩包（.zip、.tar、.tar.gz/.tgz、.tar.bz2/.tbz2）
+// 条目数、解压后的总大小和压缩比超过 archive.DefaultLimits 时停止解压
+// 带上 ?async=1 时以后台任务运行，立即返回任务 ID
 func ExtractZip(c *gin.Context) {
 	var req struct {
-		ZipPath   string `json:"zip_path" binding:"required"`   // ZIP文件路径
-		ExtractTo string `json:"extract_to"`                    // 解压目标目录
+		ZipPath   string `json:"zip_path" binding:"required"` // 压缩包路径
+		ExtractTo string `json:"extract_to"`                  // 解压目标目录
 	}
-	
+
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"status":  errmsg.ERROR,
@@ -355,7 +385,7 @@ func ExtractZip(c *gin.Context) {
 		})
 		return
 	}
-	
+
 	safeZipPath, ok := safeUploadPath(req.ZipPath)
 	if !ok {
 		c.JSON(http.StatusForbidden, gin.H{
@@ -364,9 +394,18 @@ func ExtractZip(c *gin.Context) {
 		})
 		return
 	}
-	
+
+	format := archive.Detect(safeZipPath)
+	if format == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  errmsg.ERROR,
+			"message": "不支持的压缩格式，仅支持 .zip、.tar、.tar.gz 和 .tar.bz2",
+		})
+		return
+	}
+
 	if req.ExtractTo == "" {
-		req.ExtractTo = filepath.Dir(safeZipPath)
+		req.ExtractTo, _ = safeUploadPath(path.Dir(safeZipPath))
 	} else {
 		var ok bool
 		req.ExtractTo, ok = safeUploadPath(req.ExtractTo)
@@ -378,64 +417,112 @@ func ExtractZip(c *gin.Context) {
 			return
 		}
 	}
-	
-	// 打开ZIP
-	zipReader, err := zip.OpenReader(safeZipPath)
+
+	runFileOp(c, "extract", req, func(job *FileJob) (interface{}, string, error) {
+		fileCount, err := extractToStorage(job, safeZipPath, format, req.ExtractTo)
+		if err != nil {
+			return nil, "", err
+		}
+		return gin.H{
+			"file_count": fileCount,
+			"extract_to": req.ExtractTo,
+		}, fmt.Sprintf("解压成功，共 %d 个文件", fileCount), nil
+	})
+}
+
+// openArchive 打开存储中的压缩包
+// ZIP 需要随机读取，非本地存储先下载到临时文件；tar 格式直接从存储中按顺序读取
+func openArchive(store storage.Storage, name string, format string) (*archive.Reader, func(), error) {
+	if format == archive.FormatZip {
+		localZip, cleanup, err := localCopy(store, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		r, err := archive.OpenFile(localZip, format, archive.DefaultLimits)
+		if err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+		return r, func() { r.Close(); cleanup() }, nil
+	}
+
+	info, err := store.Stat(name)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"status":  errmsg.ERROR,
-			"message": "打开ZIP失败",
-		})
-		return
+		return nil, nil, err
 	}
-	defer zipReader.Close()
-	
+	src, err := store.Get(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	r, err := archive.NewTarReader(src, info.Size, format, archive.DefaultLimits)
+	if err != nil {
+		src.Close()
+		return nil, nil, err
+	}
+	return r, func() { r.Close(); src.Close() }, nil
+}
+
+// extractToStorage 将存储中的压缩包解压到 extractTo 目录（job 为 nil 时同步执行）
+// 超过解压限制或任务取消时停止，已解压的文件会保留
+func extractToStorage(job *FileJob, zipPath string, format string, extractTo string) (int, error) {
+	store := storage.Current()
+	reader, closeArchive, err := openArchive(store, zipPath, format)
+	if err != nil {
+		if archive.IsLimit(err) {
+			return 0, newFileOpError(http.StatusBadRequest, "拒绝解压: %v", err)
+		}
+		return 0, newFileOpError(http.StatusBadRequest, "打开压缩包失败")
+	}
+	defer closeArchive()
+	job.setTotal(int64(reader.Len()))
+
 	// 解压
 	fileCount := 0
-	for _, file := range zipReader.File {
-		destPath := filepath.Join(req.ExtractTo, file.Name)
-		
-		// 防止 Zip Slip
-		if !strings.HasPrefix(destPath, filepath.Clean(req.ExtractTo)+string(os.PathSeparator)) {
-			continue
+	for {
+		if job.cancelled() {
+			return fileCount, job.ctx.Err()
 		}
-		
-		if file.FileInfo().IsDir() {
-			os.MkdirAll(destPath, 0755)
-			continue
+		entry, data, err := reader.Next()
+		if err == io.EOF {
+			break
 		}
-		
-		os.MkdirAll(filepath.Dir(destPath), 0755)
-		
-		destFile, err := os.OpenFile(destPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, file.Mode())
 		if err != nil {
+			if archive.IsLimit(err) {
+				return fileCount, newFileOpError(http.StatusBadRequest, "解压已停止: %v", err)
+			}
+			return fileCount, newFileOpError(http.StatusBadRequest, "读取压缩包失败: %v", err)
+		}
+		job.step(entry.Name)
+
+		// 防止 Zip Slip：条目名不能包含 ..，绝对路径按相对于解压目录处理
+		name, ok := safeUploadPath(entry.Name)
+		if !ok || name == "" {
 			continue
 		}
-		
-		srcFile, err := file.Open()
+		destPath := path.Join(extractTo, name)
+
+		if entry.IsDir {
+			store.Mkdir(destPath)
+			continue
+		}
+
+		err = store.Put(destPath, job.reader(data), entry.Size)
+		if limitErr := reader.Exceeded(); limitErr != nil {
+			return fileCount, newFileOpError(http.StatusBadRequest, "解压已停止: %v", limitErr)
+		}
 		if err != nil {
-			destFile.Close()
 			continue
 		}
-		
-		io.Copy(destFile, srcFile)
-		destFile.Close()
-		srcFile.Close()
-		
+
 		fileCount++
 	}
-	
-	c.JSON(http.StatusOK, gin.H{
-		"status":  errmsg.SUCCESS,
-		"message": fmt.Sprintf("解压成功，共 %d 个文件", fileCount),
-		"data": gin.H{
-			"file_count": fileCount,
-			"extract_to": filepath.ToSlash(strings.TrimPrefix(req.ExtractTo, "uploads/")),
-		},
-	})
+	return fileCount, nil
 }
 
-// MoveToRecycleBin 移动到回收站
+// recycleBinDir 回收站在存储中的目录
+const recycleBinDir = model.RecycleBinDir
+
+// MoveToRecycleBin 移动到回收站，记录原位置和删除的用户
 func MoveToRecycleBin(c *gin.Context) {
 	var req struct {
 		Paths []string `json:"paths" binding:"required"`
@@ -449,40 +536,21 @@ func MoveToRecycleBin(c *gin.Context) {
 		return
 	}
 	
-	recycleDir := "uploads/.recycle"
-	os.MkdirAll(recycleDir, 0755)
+	username := currentUsername(c)
+	movedItems := make([]model.RecycleItem, 0)
 	
-	movedItems := make([]RecycleBinItem, 0)
-	
-	for _, path := range req.Paths {
-		safePath, ok := safeUploadPath(path)
-		if !ok {
+	for _, p := range req.Paths {
+		safePath, ok := safeUploadPath(p)
+		if !ok || safePath == "" || safePath == recycleBinDir {
 			continue
 		}
 		
-		info, err := os.Stat(safePath)
-		if err != nil {
-			continue
-		}
-		
-		// 生成回收站路径
-		timestamp := time.Now().Format("20060102_150405")
-		name := filepath.Base(safePath)
-		recyclePath := filepath.Join(recycleDir, fmt.Sprintf("%s_%s", timestamp, name))
-		
 		// 移动到回收站
-		if err := os.Rename(safePath, recyclePath); err != nil {
+		item, err := model.RecycleUpload(safePath, username)
+		if err != nil {
 			continue
 		}
-		
-		movedItems = append(movedItems, RecycleBinItem{
-			OriginalPath: path,
-			RecyclePath:  filepath.ToSlash(strings.TrimPrefix(recyclePath, "uploads/")),
-			Name:         name,
-			Size:         info.Size(),
-			DeletedAt:    time.Now(),
-			IsDir:        info.IsDir(),
-		})
+		movedItems = append(movedItems, item)
 	}
 	
 	c.JSON(http.StatusOK, gin.H{
@@ -492,19 +560,9 @@ func MoveToRecycleBin(c *gin.Context) {
 	})
 }
 
-// GetRecycleBin 获取回收站列表
+// GetRecycleBin 获取回收站列表（含原位置、删除的用户和自动清理时间）
 func GetRecycleBin(c *gin.Context) {
-	recycleDir := "uploads/.recycle"
-	
-	if _, err := os.Stat(recycleDir); os.IsNotExist(err) {
-		c.JSON(http.StatusOK, gin.H{
-			"status":  errmsg.SUCCESS,
-			"data":    []RecycleBinItem{},
-		})
-		return
-	}
-	
-	files, err := os.ReadDir(recycleDir)
+	items, err := model.ListRecycleItems()
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  errmsg.ERROR,
@@ -513,86 +571,110 @@ func GetRecycleBin(c *gin.Context) {
 		return
 	}
 	
-	items := make([]RecycleBinItem, 0)
-	for _, f := range files {
-		info, err := f.Info()
-		if err != nil {
-			continue
-		}
-		
-		items = append(items, RecycleBinItem{
-			RecyclePath: filepath.ToSlash(filepath.Join(".recycle", f.Name())),
-			Name:        f.Name(),
-			Size:        info.Size(),
-			DeletedAt:   info.ModTime(),
-			IsDir:       f.IsDir(),
-		})
-	}
-	
 	c.JSON(http.StatusOK, gin.H{
-		"status":  errmsg.SUCCESS,
-		"data":    items,
+		"status":         errmsg.SUCCESS,
+		"data":           items,
+		"retention_days": utils.GetConfig().RecycleBin.RetentionDays,
 	})
 }
 
-// RestoreFromRecycleBin 从回收站恢复
+// restoreResult 单个项目的恢复结果
+type restoreResult struct {
+	ID           uint   `json:"id"`
+	RecyclePath  string `json:"recycle_path"`
+	RestoredPath string `json:"restored_path,omitempty"`
+	Status       int    `json:"status"`
+	Message      string `json:"message"`
+}
+
+// RestoreFromRecycleBin 从回收站恢复到原位置
+// 请求体: ids 或 recycle_paths - 要恢复的项目, conflict - 原位置已存在同名文件时的处理方式：rename（默认，恢复为 <原名称>_restored）、overwrite（原位置的文件移入回收站）、skip（不恢复）
 func RestoreFromRecycleBin(c *gin.Context) {
 	var req struct {
-		RecyclePaths []string `json:"recycle_paths" binding:"required"`
+		IDs          []uint   `json:"ids"`
+		RecyclePaths []string `json:"recycle_paths"`
+		Conflict     string   `json:"conflict"`
 	}
 	
-	if err := c.ShouldBindJSON(&req); err != nil {
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.IDs)+len(req.RecyclePaths) == 0 {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"status":  errmsg.ERROR,
 			"message": "参数错误",
 		})
 		return
 	}
+	switch req.Conflict {
+	case "":
+		req.Conflict = model.RestoreRename
+	case model.RestoreRename, model.RestoreOverwrite, model.RestoreSkip:
+	default:
+		utils.BadRequest(c, "conflict 只能是 rename、overwrite 或 skip")
+		return
+	}
 	
-	restored := 0
-	for _, recyclePath := range req.RecyclePaths {
-		fullPath := filepath.Join("uploads", recyclePath)
-		
-		if _, err := os.Stat(fullPath); os.IsNotExist(err) {
+	items := make([]model.RecycleItem, 0, len(req.IDs)+len(req.RecyclePaths))
+	results := make([]restoreResult, 0, cap(items))
+	for _, id := range req.IDs {
+		item, code := model.GetRecycleItem(id, "")
+		if code != errmsg.SUCCESS {
+			results = append(results, restoreResult{ID: id, Status: code, Message: errmsg.GetErrMsg(code)})
 			continue
 		}
-		
-		// 解析原始路径（从文件名中提取）
-		name := filepath.Base(fullPath)
-		parts := strings.SplitN(name, "_", 2)
-		if len(parts) != 2 {
+		items = append(items, item)
+	}
+	for _, recyclePath := range req.RecyclePaths {
+		fullPath, ok := safeUploadPath(recyclePath)
+		if !ok || path.Dir(fullPath) != recycleBinDir {
+			results = append(results, restoreResult{RecyclePath: recyclePath, Status: errmsg.ERROR_RECYCLE_NOT_EXIST, Message: errmsg.GetErrMsg(errmsg.ERROR_RECYCLE_NOT_EXIST)})
 			continue
 		}
-		
-		originalName := parts[1]
-		originalPath := filepath.Join("uploads", originalName)
-		
-		// 处理冲突
-		if _, err := os.Stat(originalPath); err == nil {
-			// 文件已存在，添加后缀
-			ext := filepath.Ext(originalName)
-			base := strings.TrimSuffix(originalName, ext)
-			originalPath = filepath.Join("uploads", fmt.Sprintf("%s_restored_%s", base, ext))
-		}
-		
-		if err := os.Rename(fullPath, originalPath); err != nil {
+		item, code := model.GetRecycleItem(0, fullPath)
+		if code != errmsg.SUCCESS {
+			results = append(results, restoreResult{RecyclePath: recyclePath, Status: code, Message: errmsg.GetErrMsg(code)})
 			continue
 		}
-		
-		restored++
+		items = append(items, item)
 	}
 	
+	restored := 0
+	username := currentUsername(c)
+	for _, item := range items {
+		target, code := model.RestoreRecycleItem(item, req.Conflict, username)
+		result := restoreResult{ID: item.ID, RecyclePath: item.RecyclePath, Status: code, Message: errmsg.GetErrMsg(code)}
+		if code == errmsg.SUCCESS {
+			result.RestoredPath = target
+			restored++
+		}
+		results = append(results, result)
+	}
+	middlewares.AuditAfter(c, gin.H{"restored": restored, "conflict": req.Conflict, "results": results})
+	
 	c.JSON(http.StatusOK, gin.H{
 		"status":  errmsg.SUCCESS,
 		"message": fmt.Sprintf("已恢复 %d 个项目", restored),
+		"data":    results,
 	})
 }
 
+// DeleteRecycleItem 永久删除回收站中的单个项目
+func DeleteRecycleItem(c *gin.Context) {
+	id, ok := utils.ParseIDParam(c)
+	if !ok {
+		return
+	}
+	item, code := model.GetRecycleItem(uint(id), "")
+	if code != errmsg.SUCCESS {
+		utils.Error(c, code)
+		return
+	}
+	middlewares.AuditBefore(c, item)
+	utils.Error(c, model.DeleteRecycleItem(item))
+}
+
 // EmptyRecycleBin 清空回收站
 func EmptyRecycleBin(c *gin.Context) {
-	recycleDir := "uploads/.recycle"
-	
-	if err := os.RemoveAll(recycleDir); err != nil {
+	items, err := model.EmptyRecycleBin()
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  errmsg.ERROR,
 			"message": "清空回收站失败",
@@ -600,6 +682,13 @@ func EmptyRecycleBin(c *gin.Context) {
 		return
 	}
 	
+	// 审计日志记录被清空的条目
+	names := make([]string, 0, len(items))
+	for _, item := range items {
+		names = append(names, item.OriginalPath)
+	}
+	middlewares.AuditBefore(c, gin.H{"count": len(names), "items": names})
+	
 	c.JSON(http.StatusOK, gin.H{
 		"status":  errmsg.SUCCESS,
 		"message": "回收站已清空",
@@ -626,7 +715,8 @@ func GetFilePreview(c *gin.Context) {
 		return
 	}
 	
-	info, err := os.Stat(safePath)
+	store := storage.Current()
+	info, err := store.Stat(safePath)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{
 			"status":  errmsg.ERROR,
@@ -635,7 +725,7 @@ func GetFilePreview(c *gin.Context) {
 		return
 	}
 	
-	if info.IsDir() {
+	if info.IsDir {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"status":  errmsg.ERROR,
 			"message": "无法预览目录",
@@ -644,7 +734,7 @@ func GetFilePreview(c *gin.Context) {
 	}
 	
 	// 文件大小限制（1MB）
-	if info.Size() > 1<<20 {
+	if info.Size > 1<<20 {
 		c.JSON(http.StatusBadRequest, gin.H{
 			"status":  errmsg.ERROR,
 			"message": "文件过大，无法预览",
@@ -656,7 +746,7 @@ func GetFilePreview(c *gin.Context) {
 	
 	// 文本文件预览
 	if isTextFile(ext) {
-		content, err := os.ReadFile(safePath)
+		content, err := storage.ReadFile(store, safePath)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{
 				"status":  errmsg.ERROR,
@@ -679,8 +769,8 @@ func GetFilePreview(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  errmsg.SUCCESS,
 			"type":    "image",
-			"url":     "/uploads/" + filepath.ToSlash(strings.TrimPrefix(safePath, "uploads/")),
-			"size":    info.Size(),
+			"url":     "/uploads/" + safePath,
+			"size":    info.Size,
 		})
 		return
 	}
@@ -701,12 +791,9 @@ func isDocumentFile(ext string) bool {
 	return docs[ext]
 }
 
-func isArchiveFile(ext string) bool {
-	archives := map[string]bool{
-		".zip": true, ".rar": true, ".7z": true,
-		".tar": true, ".gz": true,
-	}
-	return archives[ext]
+// isArchiveFile 是否是能够解压的压缩包（按文件名判断，.tar.gz 等需要完整的文件名）
+func isArchiveFile(name string) bool {
+	return archive.Detect(name) != ""
 }
 
 func isTextFile(ext string) bool {
@@ -719,12 +806,48 @@ func isTextFile(ext string) bool {
 	return texts[ext]
 }
 
-func getFileSize(path string) int64 {
-	info, err := os.Stat(path)
+// walkStorageFiles 对文件本身或目录中的每个文件调用 fn
+func walkStorageFiles(store storage.Storage, name string, fn func(info storage.FileInfo) error) error {
+	info, err := store.Stat(name)
 	if err != nil {
-		return 0
+		return err
 	}
-	return info.Size()
+	if !info.IsDir {
+		return fn(info)
+	}
+	return storage.Walk(store, name, func(info storage.FileInfo) error {
+		if info.IsDir {
+			return nil
+		}
+		return fn(info)
+	})
+}
+
+// localCopy 返回存储中文件的本地路径（需要随机读取时使用，如解压 ZIP）
+// 本地存储直接返回文件路径，其他存储下载到临时文件，调用方使用完后调用 cleanup
+func localCopy(store storage.Storage, name string) (string, func(), error) {
+	if l, ok := store.(storage.Localer); ok {
+		return l.LocalPath(name), func() {}, nil
+	}
+	src, err := store.Get(name)
+	if err != nil {
+		return "", nil, err
+	}
+	defer src.Close()
+	tmp, err := os.CreateTemp("", "storage-*"+path.Ext(name))
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.Remove(tmp.Name()) }
+	_, err = io.Copy(tmp, src)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return tmp.Name(), cleanup, nil
 }
 
 // FormatSize 格式化文件大小
@@ -755,7 +878,7 @@ func SaveFileMetadata(c *gin.Context) {
 	}
 	
 	safePath, ok := safeUploadPath(req.Path)
-	if !ok {
+	if !ok || safePath == "" {
 		c.JSON(http.StatusForbidden, gin.H{
 			"status":  errmsg.ERROR,
 			"message": "非法路径",
@@ -766,7 +889,7 @@ func SaveFileMetadata(c *gin.Context) {
 	// 保存元数据到 .meta.json 文件
 	metaPath := safePath + ".meta.json"
 	data, _ := json.Marshal(req.Metadata)
-	os.WriteFile(metaPath, data, 0644)
+	storage.WriteFile(storage.Current(), metaPath, data)
 	
 	c.JSON(http.StatusOK, gin.H{
 		"status":  errmsg.SUCCESS,
@@ -796,7 +919,8 @@ func GetFileMetadata(c *gin.Context) {
 	
 	metaPath := safePath + ".meta.json"
 	
-	if _, err := os.Stat(metaPath); os.IsNotExist(err) {
+	data, err := storage.ReadFile(storage.Current(), metaPath)
+	if storage.IsNotExist(err) {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  errmsg.SUCCESS,
 			"data":    map[string]string{},
@@ -804,7 +928,6 @@ func GetFileMetadata(c *gin.Context) {
 		return
 	}
 	
-	data, err := os.ReadFile(metaPath)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"status":  errmsg.ERROR,
@@ -0,0 +1,133 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"yanblog/model"
+	"yanblog/utils"
+	"yanblog/utils/errmsg"
+	"yanblog/utils/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 上传图片的缩略图和不同宽度通过固定地址访问：/img/<尺寸>/<上传路径>
+// 如 /uploads/article/cover/1.jpg 的 640 宽版本为 /img/w640/article/cover/1.jpg，缩略图为 /img/thumb/article/cover/1.jpg
+// 浏览器支持 WebP（Accept 头）或指定 ?format=webp 时优先返回 WebP，?format=original 返回原格式
+
+// imageBackfill 补生成已有图片各尺寸的后台任务状态（同一时间只运行一个）
+var imageBackfill struct {
+	sync.Mutex
+	running    bool
+	processed  int
+	startedAt  time.Time
+	finishedAt *time.Time
+	result     *model.ImageBackfillResult
+}
+
+// GetImageVariant 返回上传图片的指定尺寸，还没有生成时立即生成
+func GetImageVariant(c *gin.Context) {
+	size, ok := model.FindImageSize(c.Param("size"))
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	rel := strings.TrimPrefix(c.Param("filepath"), "/")
+	path, ok := safeUploadPath(rel)
+	if !ok || path == "" || !isImageFile(strings.ToLower(filepath.Ext(rel))) {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	// 隐藏目录（已生成的尺寸、回收站）不对外提供
+	for _, part := range strings.Split(path, "/") {
+		if strings.HasPrefix(part, ".") {
+			c.Status(http.StatusNotFound)
+			return
+		}
+	}
+
+	format := c.Query("format")
+	webp := format == "webp" || (format == "" && strings.Contains(c.GetHeader("Accept"), "image/webp"))
+	file, err := model.ImageVariant(path, size, webp)
+	if err != nil {
+		if storage.IsNotExist(err) {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		// 无法解码等情况返回原图，不影响页面显示
+		fmt.Printf("生成图片尺寸失败 %s: %v\n", path, err)
+		file = path
+	}
+
+	c.Header("Vary", "Accept")
+	c.Header("Cache-Control", "public, max-age=86400")
+	serveStorageFile(c, file)
+}
+
+// StartImageBackfill 在后台为 uploads 中已有的图片补生成各尺寸
+// 请求体: force - 重新生成已有的尺寸, strip_exif - 同时去除原图的 EXIF 等元数据
+func StartImageBackfill(c *gin.Context) {
+	var req struct {
+		Force     bool `json:"force"`
+		StripExif bool `json:"strip_exif"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+
+	imageBackfill.Lock()
+	if imageBackfill.running {
+		imageBackfill.Unlock()
+		utils.ErrorWithMessage(c, errmsg.ERROR, "正在生成图片尺寸，请等待完成")
+		return
+	}
+	imageBackfill.running = true
+	imageBackfill.processed = 0
+	imageBackfill.startedAt = time.Now()
+	imageBackfill.finishedAt = nil
+	imageBackfill.result = nil
+	imageBackfill.Unlock()
+
+	go func() {
+		result := model.BackfillImageVariants(req.Force, req.StripExif, func(string) {
+			imageBackfill.Lock()
+			imageBackfill.processed++
+			imageBackfill.Unlock()
+		})
+		fmt.Printf("图片尺寸生成完成: %d 张图片，生成 %d 个尺寸，失败 %d 张\n", result.Images, result.Generated, result.Failed)
+
+		imageBackfill.Lock()
+		now := time.Now()
+		imageBackfill.running = false
+		imageBackfill.finishedAt = &now
+		imageBackfill.result = &result
+		imageBackfill.Unlock()
+	}()
+
+	utils.Success(c, gin.H{"started": true})
+}
+
+// GetImageBackfill 查询补生成任务的进度和结果，以及当前配置的图片尺寸
+func GetImageBackfill(c *gin.Context) {
+	imageBackfill.Lock()
+	defer imageBackfill.Unlock()
+
+	data := gin.H{
+		"running":   imageBackfill.running,
+		"processed": imageBackfill.processed,
+		"result":    imageBackfill.result,
+		"sizes":     model.ImageSizes(),
+	}
+	if !imageBackfill.startedAt.IsZero() {
+		data["started_at"] = imageBackfill.startedAt
+		data["finished_at"] = imageBackfill.finishedAt
+	}
+	utils.Success(c, data)
+}
@@ -12,6 +12,7 @@ import (
 
 // Login 用户登录接口
 // 处理用户登录请求，验证用户名和密码，成功后生成JWT token
+// 已启用两步验证的用户需再调用 LoginTwoFactor 提交验证码
 func Login(c *gin.Context) {
 	var data model.User
 
@@ -34,8 +35,68 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// 登录成功：生成 token
-	token, tokenCode := middlewares.SetToken(data.Username)
+	// 已启用两步验证：返回登录挑战令牌，等待提交第二因素
+	if userID, enabled := model.IsTwoFactorEnabled(data.Username); enabled {
+		code = errmsg.ERROR_TOTP_REQUIRED
+		c.JSON(http.StatusOK, gin.H{
+			"status":     code,
+			"message":    errmsg.GetErrMsg(code),
+			"mfa_token":  model.CreateLoginChallenge(data.Username, userID),
+			"expires_in": int(model.LoginChallengeTTL().Seconds()),
+		})
+		return
+	}
+
+	issueLoginTokens(c, data.Username)
+}
+
+// LoginTwoFactor 两步登录的第二步：提交 TOTP 验证码或恢复码
+// 每个登录挑战有单独的次数限制（见 middlewares.TwoFactorRateLimit），不占用登录接口的次数
+func LoginTwoFactor(c *gin.Context) {
+	var data struct {
+		MfaToken string `json:"mfa_token"`
+		Code     string `json:"code"`
+	}
+	if err := c.ShouldBindJSON(&data); err != nil || data.MfaToken == "" || data.Code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	username, code := model.CompleteLoginChallenge(data.MfaToken, data.Code)
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+
+	issueLoginTokens(c, username)
+}
+
+// issueLoginTokens 登录成功：创建会话并签发访问令牌和刷新令牌
+func issueLoginTokens(c *gin.Context, username string) {
+	session, refreshToken, code := model.CreateSession(username, c.ClientIP(), c.Request.UserAgent(), middlewares.RefreshTokenTTL())
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+	user, code := model.GetLoginUser(username)
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+
+	token, tokenCode := middlewares.SetToken(user, session.SessionID)
 	if tokenCode != errmsg.SUCCESS {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  tokenCode,
@@ -44,15 +105,75 @@ func Login(c *gin.Context) {
 		return
 	}
 
-	// 获取用户角色
-	role := model.GetUserRole(data.Username)
-
 	// 只返回一次成功响应（包含 token 和用户信息）
 	c.JSON(http.StatusOK, gin.H{
-		"status":   code,
-		"message":  errmsg.GetErrMsg(code),
-		"token":    token,
-		"username": data.Username,
-		"role":     role,
+		"status":        code,
+		"message":       errmsg.GetErrMsg(code),
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_in":    int(middlewares.AccessTokenTTL().Seconds()),
+		"username":      user.Username,
+		"role":          user.Role,
+		"permissions":   model.RolePermissions(user.Role),
+		// 为 true 时前端应跳转到修改密码页面，修改前其他接口都会返回 ERROR_PASSWORD_CHANGE
+		"must_change_password": user.MustChangePassword,
+	})
+}
+
+// RefreshToken 使用刷新令牌换取新的访问令牌（刷新令牌同时轮换）
+func RefreshToken(c *gin.Context) {
+	var data struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := c.ShouldBindJSON(&data); err != nil || data.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"status":  400,
+			"message": "请求参数错误",
+		})
+		return
+	}
+
+	session, refreshToken, code := model.RotateSession(data.RefreshToken, middlewares.RefreshTokenTTL())
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+	// 角色或密码变更会撤销会话，这里读取的用户信息与会话创建时一致
+	user, code := model.GetLoginUser(session.Username)
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+	token, code := middlewares.SetToken(user, session.SessionID)
+	if code != errmsg.SUCCESS {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  code,
+			"message": errmsg.GetErrMsg(code),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"status":        code,
+		"message":       errmsg.GetErrMsg(code),
+		"token":         token,
+		"refresh_token": refreshToken,
+		"expires_in":    int(middlewares.AccessTokenTTL().Seconds()),
+	})
+}
+
+// Logout 退出登录，撤销当前会话（该会话签发的访问令牌和刷新令牌立即失效）
+func Logout(c *gin.Context) {
+	sessionID := c.GetString("session_id")
+	code := model.RevokeSession(sessionID)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  code,
+		"message": errmsg.GetErrMsg(code),
 	})
 }
@@ -6,7 +6,6 @@ import (
 	"net/http"
 	"time"
 	"yanblog/model"
-	"yanblog/utils"
 	"yanblog/utils/errmsg"
 
 	"github.com/gin-gonic/gin"
@@ -35,15 +34,8 @@ func GetSitemap(c *gin.Context) {
 		return
 	}
 
-	baseUrl := utils.ServerConfig.Server.SiteUrl
 	// 如果未配置 SiteUrl，使用请求中的 Host 自动推断
-	if baseUrl == "" {
-		scheme := "http"
-		if c.Request.TLS != nil {
-			scheme = "https"
-		}
-		baseUrl = fmt.Sprintf("%s://%s", scheme, c.Request.Host)
-	}
+	baseUrl := siteBaseUrl(c)
 
 	urlSet := UrlSet{
 		Xmlns: "http://www.sitemaps.org/schemas/sitemap/0.9",
@@ -61,17 +53,44 @@ func GetSitemap(c *gin.Context) {
 		})
 	}
 
-	// 2. 添加所有文章页面
+	// 2. 添加所有文章页面（使用 slug 链接）
 	for _, art := range articles {
 		urlSet.Urls = append(urlSet.Urls, Url{
-			Loc:        fmt.Sprintf("%s/article/info/%d", baseUrl, art.ID),
+			Loc:        articleUrl(baseUrl, art),
 			LastMod:    art.UpdatedAt.Format("2006-01-02"),
 			ChangeFreq: "weekly",
 			Priority:   0.6,
 		})
 	}
 
+	// 3. 添加所有分类页面
+	cates, _ := model.GetCate(-1, -1)
+	for _, cate := range cates {
+		urlSet.Urls = append(urlSet.Urls, Url{
+			Loc:        categoryUrl(baseUrl, cate),
+			LastMod:    cate.UpdatedAt.Format("2006-01-02"),
+			ChangeFreq: "weekly",
+			Priority:   0.5,
+		})
+	}
+
 	// 生成 XML
 	c.Header("Content-Type", "application/xml")
 	c.XML(http.StatusOK, urlSet)
 }
+
+// articleUrl 生成文章的前台链接，优先使用 slug
+func articleUrl(baseUrl string, art model.Article) string {
+	if art.Slug != "" {
+		return fmt.Sprintf("%s/article/%s", baseUrl, art.Slug)
+	}
+	return fmt.Sprintf("%s/article/%d", baseUrl, art.ID)
+}
+
+// categoryUrl 生成分类的前台链接，优先使用 slug
+func categoryUrl(baseUrl string, cate model.Category) string {
+	if cate.Slug != "" {
+		return fmt.Sprintf("%s/category/%s", baseUrl, cate.Slug)
+	}
+	return fmt.Sprintf("%s/category/%d", baseUrl, cate.ID)
+}
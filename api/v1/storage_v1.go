@@ -0,0 +1,66 @@
+package v1
+
+import (
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"yanblog/utils/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ServeUpload 提供 /uploads 下的文件（使用远程存储时注册，本地存储直接由静态文件服务提供）
+func ServeUpload(c *gin.Context) {
+	name, ok := safeUploadPath(strings.TrimPrefix(c.Param("filepath"), "/"))
+	if !ok || name == "" {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	serveStorageFile(c, name)
+}
+
+// serveStorageFile 返回存储中的文件：本地存储直接发送文件，配置了公开访问地址时重定向，否则从存储读取后转发
+func serveStorageFile(c *gin.Context, name string) {
+	store := storage.Current()
+	if l, ok := store.(storage.Localer); ok {
+		c.File(l.LocalPath(name))
+		return
+	}
+	if url := storage.PublicURL(name); url != "" {
+		c.Redirect(http.StatusFound, url)
+		return
+	}
+
+	info, err := store.Stat(name)
+	if err != nil || info.IsDir {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	r, err := store.Get(name)
+	if err != nil {
+		c.Status(http.StatusBadGateway)
+		return
+	}
+	defer r.Close()
+
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	headers := map[string]string{}
+	if !info.ModTime.IsZero() {
+		headers["Last-Modified"] = info.ModTime.UTC().Format(http.TimeFormat)
+	}
+	if c.Request.Method == http.MethodHead {
+		c.Header("Content-Type", contentType)
+		c.Header("Content-Length", strconv.FormatInt(info.Size, 10))
+		for k, v := range headers {
+			c.Header(k, v)
+		}
+		c.Status(http.StatusOK)
+		return
+	}
+	c.DataFromReader(http.StatusOK, info.Size, contentType, r, headers)
+}
@@ -0,0 +1,100 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+// 后台任务的公共部分，ZIP 导入（UploadTaskV2）和文件管理任务（FileJob）共用：
+// 同时运行的任务数限制，以及通过 WebSocket 推送进度
+
+// maxConcurrentTasks 同时运行的后台任务数，其余任务排队
+const maxConcurrentTasks = 3
+
+// taskSem 所有后台任务共用的执行位置
+var taskSem = make(taskSemaphore, maxConcurrentTasks)
+
+// taskSemaphore 限制同时运行的任务数
+type taskSemaphore chan struct{}
+
+// acquire 等待空闲的执行位置，ctx 先结束时返回 false（此时不需要 release）
+func (s taskSemaphore) acquire(ctx context.Context) bool {
+	select {
+	case s <- struct{}{}:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// release 释放 acquire 获得的执行位置
+func (s taskSemaphore) release() {
+	<-s
+}
+
+// WebSocket 升级器
+var upgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool {
+		return true // 生产环境应该检查 Origin
+	},
+}
+
+// progressClients 订阅任务进度的 WebSocket 客户端
+// 同一连接不能并发写入，推送时持有锁
+type progressClients struct {
+	mu    sync.Mutex
+	conns []*websocket.Conn
+}
+
+// empty 是否没有客户端，没有时可以跳过生成进度
+func (p *progressClients) empty() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.conns) == 0
+}
+
+// send 推送消息到所有客户端
+func (p *progressClients) send(msg interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, conn := range p.conns {
+		conn.WriteJSON(msg)
+	}
+}
+
+func (p *progressClients) remove(conn *websocket.Conn) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for i, client := range p.conns {
+		if client == conn {
+			p.conns = append(p.conns[:i], p.conns[i+1:]...)
+			break
+		}
+	}
+}
+
+// serveProgress 将请求升级为 WebSocket，立即推送 current 返回的当前进度，之后接收 send 的推送，直到客户端断开
+func serveProgress(c *gin.Context, p *progressClients, current func() interface{}) {
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	p.mu.Lock()
+	p.conns = append(p.conns, conn)
+	conn.WriteJSON(current())
+	p.mu.Unlock()
+	defer p.remove(conn)
+
+	// 读取消息直到连接关闭（客户端不需要发送消息）
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			break
+		}
+	}
+}
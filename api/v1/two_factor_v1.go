@@ -0,0 +1,129 @@
+package v1
+
+import (
+	"encoding/base64"
+	"yanblog/model"
+	"yanblog/utils"
+	"yanblog/utils/errmsg"
+
+	"github.com/gin-gonic/gin"
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// defaultTOTPIssuer 验证器中显示的默认签发方名称
+const defaultTOTPIssuer = "YanBlog"
+
+// GetTwoFactorStatus 查询当前用户的两步验证状态
+func GetTwoFactorStatus(c *gin.Context) {
+	utils.Success(c, model.GetTwoFactorStatus(c.GetUint("user_id")))
+}
+
+// SetupTwoFactor 生成 TOTP 密钥，返回 otpauth 链接和二维码供验证器扫码
+// 扫码后需调用 EnableTwoFactor 提交一次验证码才会启用
+func SetupTwoFactor(c *gin.Context) {
+	secret, code := model.SetupTwoFactor(c.GetUint("user_id"))
+	if code != errmsg.SUCCESS {
+		utils.Error(c, code)
+		return
+	}
+
+	issuer := utils.ServerConfig.Auth.TOTPIssuer
+	if issuer == "" {
+		issuer = defaultTOTPIssuer
+	}
+	uri := utils.TOTPURI(issuer, currentUsername(c), secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		utils.Error(c, errmsg.ERROR)
+		return
+	}
+
+	utils.Success(c, gin.H{
+		"secret":      secret,
+		"otpauth_uri": uri,
+		"qr_code":     "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	})
+}
+
+// EnableTwoFactor 提交验证码确认并启用两步验证，返回恢复码（只显示这一次）
+func EnableTwoFactor(c *gin.Context) {
+	var data struct {
+		Code string `json:"code"`
+	}
+	if err := c.ShouldBindJSON(&data); err != nil || data.Code == "" {
+		utils.BadRequest(c, "请输入验证码")
+		return
+	}
+
+	codes, code := model.EnableTwoFactor(c.GetUint("user_id"), data.Code)
+	if code != errmsg.SUCCESS {
+		utils.Error(c, code)
+		return
+	}
+	utils.Success(c, gin.H{"recovery_codes": codes})
+}
+
+// DisableTwoFactor 关闭两步验证，需要同时提交密码和验证码（或恢复码）
+func DisableTwoFactor(c *gin.Context) {
+	var data struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if err := c.ShouldBindJSON(&data); err != nil || data.Password == "" || data.Code == "" {
+		utils.BadRequest(c, "请输入密码和验证码")
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if code := model.CheckLogin(currentUsername(c), data.Password); code != errmsg.SUCCESS {
+		utils.Error(c, code)
+		return
+	}
+	if code := model.VerifySecondFactor(userID, data.Code); code != errmsg.SUCCESS {
+		utils.Error(c, code)
+		return
+	}
+	utils.Error(c, model.DisableTwoFactor(userID))
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码，需要提交当前验证码
+func RegenerateRecoveryCodes(c *gin.Context) {
+	var data struct {
+		Code string `json:"code"`
+	}
+	if err := c.ShouldBindJSON(&data); err != nil || data.Code == "" {
+		utils.BadRequest(c, "请输入验证码")
+		return
+	}
+
+	userID := c.GetUint("user_id")
+	if code := model.VerifySecondFactor(userID, data.Code); code != errmsg.SUCCESS {
+		utils.Error(c, code)
+		return
+	}
+	codes, code := model.RegenerateRecoveryCodes(userID)
+	if code != errmsg.SUCCESS {
+		utils.Error(c, code)
+		return
+	}
+	utils.Success(c, gin.H{"recovery_codes": codes})
+}
+
+// ResetUserTwoFactor 管理员为丢失验证器的用户重置两步验证
+func ResetUserTwoFactor(c *gin.Context) {
+	id, ok := utils.ParseIDParam(c)
+	if !ok {
+		return
+	}
+
+	var targetUser model.User
+	if err := model.GetDB().Where("id = ?", id).First(&targetUser).Error; err != nil {
+		utils.Error(c, errmsg.ERROR_USER_NOT_EXIST)
+		return
+	}
+	if !model.CanManageRole(currentRole(c), targetUser.Role) {
+		utils.ErrorWithMessage(c, errmsg.ERROR_USER_NO_RIGHT, "无权重置该用户的两步验证")
+		return
+	}
+	utils.Error(c, model.DisableTwoFactor(targetUser.ID))
+}
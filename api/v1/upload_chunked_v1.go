@@ -0,0 +1,413 @@
+package v1
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"yanblog/middlewares"
+	"yanblog/model"
+	"yanblog/utils"
+	"yanblog/utils/errmsg"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 可断点续传的分片上传（参照 tus 协议）：
+//
+//	POST   /upload/chunked               新建上传，返回上传 ID（Location 头为上传地址）
+//	PATCH  /upload/chunked/:id           追加一个分片，请求头 Upload-Offset 为分片的起始位置，
+//	                                     Content-Type 为 application/offset+octet-stream，
+//	                                     可选的 Upload-Checksum（如 "sha256 <base64>"）校验本分片
+//	HEAD   /upload/chunked/:id           查询已接收的字节数（Upload-Offset）和文件大小（Upload-Length）
+//	POST   /upload/chunked/:id/complete  接收完成后校验整个文件并保存到与 UpLoad 相同的位置
+//	DELETE /upload/chunked/:id           放弃上传
+//
+// import 类型的上传完成后保留在临时目录，通过 upload_id 表单字段交给 ZIP 上传和博客导入使用
+
+const (
+	chunkedUploadType = "import"
+	// 单个文件最大大小（1GB）
+	maxChunkedFileSize = 1 << 30
+	// 建议的分片大小，单个分片不能超过 maxChunkSize
+	chunkSize    = 8 << 20
+	maxChunkSize = 32 << 20
+	// 分片请求的 Content-Type
+	chunkContentType = "application/offset+octet-stream"
+)
+
+// checksumAlgorithms 分片校验支持的算法（Upload-Checksum 头）
+var checksumAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+}
+
+// chunkLocks 上传 ID -> 写入锁，同一上传同时只能写入一个分片
+var chunkLocks sync.Map
+
+func chunkLock(id string) *sync.Mutex {
+	mu, _ := chunkLocks.LoadOrStore(id, &sync.Mutex{})
+	return mu.(*sync.Mutex)
+}
+
+// chunkedUploadOffset 已接收的字节数
+func chunkedUploadOffset(id string) (int64, error) {
+	info, err := os.Stat(model.ChunkedUploadPath(id))
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// CreateChunkedUpload 新建分片上传
+// 请求体: filename - 文件名, size - 文件大小, type - 上传类型（同 UpLoad，import 用于 ZIP 上传和博客导入）,
+// key / id - 同 UpLoad, checksum - 整个文件的 SHA-256（十六进制，可选）
+func CreateChunkedUpload(c *gin.Context) {
+	var req struct {
+		FileName string `json:"filename"`
+		Size     int64  `json:"size"`
+		Type     string `json:"type"`
+		Key      string `json:"key"`
+		ID       int    `json:"id"`
+		Checksum string `json:"checksum"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误: "+err.Error())
+		return
+	}
+	req.FileName = filepath.Base(strings.TrimSpace(req.FileName))
+	req.Checksum = strings.ToLower(strings.TrimSpace(req.Checksum))
+	if req.FileName == "" || req.FileName == "." || len(req.FileName) > 255 {
+		utils.BadRequest(c, "文件名不能为空")
+		return
+	}
+	if req.Size <= 0 || req.Size > maxChunkedFileSize {
+		utils.ErrorWithMessage(c, errmsg.ERROR_FILE_TOO_LARGE, "文件大小必须在 1 字节到 1GB 之间")
+		return
+	}
+	if len(req.Type) > 20 || len(req.Key) > 100 {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+	if _, err := hex.DecodeString(req.Checksum); err != nil || (req.Checksum != "" && len(req.Checksum) != sha256.Size*2) {
+		utils.BadRequest(c, "checksum 必须为 SHA-256 的十六进制值")
+		return
+	}
+
+	ext := strings.ToLower(filepath.Ext(req.FileName))
+	if req.Type == chunkedUploadType {
+		if ext != ".zip" && ext != ".xml" {
+			utils.BadRequest(c, "只支持 ZIP 和 XML 文件")
+			return
+		}
+	} else if !allowedExtensions[ext] && ext != "" {
+		utils.BadRequest(c, "不支持的文件类型: "+ext)
+		return
+	}
+	// 与 UpLoad 相同：新增文章时标题已被其他文章使用则不允许上传图片
+	if req.Type == "article" && req.Key != "" && req.Key != "default" &&
+		model.CheckArtTitle(req.Key) == errmsg.ERROR_ART_TITLE_USED && !model.CheckUploadPermission(req.Key, strconv.Itoa(req.ID)) {
+		utils.ErrorWithMessage(c, errmsg.ERROR_ART_TITLE_USED, "文章标题已存在，请更换标题后再上传图片")
+		return
+	}
+
+	upload := model.ChunkedUpload{
+		FileName:  req.FileName,
+		Size:      req.Size,
+		Type:      req.Type,
+		Key:       req.Key,
+		ArticleID: req.ID,
+		Checksum:  req.Checksum,
+		Username:  currentUsername(c),
+	}
+	if code := model.CreateChunkedUpload(&upload); code != errmsg.SUCCESS {
+		utils.Error(c, code)
+		return
+	}
+
+	c.Header("Location", "/api/v1/upload/chunked/"+upload.ID)
+	c.Header("Upload-Offset", "0")
+	middlewares.AuditAfter(c, gin.H{"id": upload.ID, "file": upload.FileName, "size": upload.Size, "type": upload.Type})
+	utils.Success(c, gin.H{
+		"id":             upload.ID,
+		"offset":         0,
+		"size":           upload.Size,
+		"chunk_size":     chunkSize,
+		"max_chunk_size": maxChunkSize,
+	})
+}
+
+// findChunkedUpload 查找当前用户的分片上传，不存在时写入 404 响应
+func findChunkedUpload(c *gin.Context) (model.ChunkedUpload, bool) {
+	upload, code := model.GetChunkedUpload(c.Param("id"), currentUsername(c))
+	if code != errmsg.SUCCESS {
+		if c.Request.Method == http.MethodHead {
+			c.Status(http.StatusNotFound)
+		} else {
+			c.JSON(http.StatusNotFound, gin.H{"status": code, "message": errmsg.GetErrMsg(code)})
+		}
+		return upload, false
+	}
+	return upload, true
+}
+
+// GetChunkedUploadOffset 查询已接收的字节数（HEAD），客户端中断后据此继续上传
+func GetChunkedUploadOffset(c *gin.Context) {
+	upload, ok := findChunkedUpload(c)
+	if !ok {
+		return
+	}
+	offset, err := chunkedUploadOffset(upload.ID)
+	if err != nil {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	c.Header("Upload-Offset", strconv.FormatInt(offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+}
+
+// UploadChunk 追加一个分片（PATCH）
+// 分片的起始位置必须等于已接收的字节数，否则返回 409 和当前的 Upload-Offset
+// 没有 Upload-Checksum 时中途断开已写入的数据会保留；校验失败（460）时本分片的数据全部丢弃
+func UploadChunk(c *gin.Context) {
+	// 慢速网络下单个分片的传输时间可能超过服务器的读超时；写超时从读取请求头时开始计算，也需要取消，否则数据写入后响应被截断
+	rc := http.NewResponseController(c.Writer)
+	rc.SetReadDeadline(time.Time{})
+	rc.SetWriteDeadline(time.Time{})
+
+	upload, ok := findChunkedUpload(c)
+	if !ok {
+		return
+	}
+	if upload.Completed {
+		utils.BadRequest(c, "上传已完成")
+		return
+	}
+	if c.ContentType() != chunkContentType {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"status": errmsg.ERROR, "message": "Content-Type 必须为 " + chunkContentType})
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		utils.BadRequest(c, "Upload-Offset 格式错误")
+		return
+	}
+	var checksum hash.Hash
+	var expected []byte
+	if header := c.GetHeader("Upload-Checksum"); header != "" {
+		algo, value, _ := strings.Cut(header, " ")
+		newHash, ok := checksumAlgorithms[strings.ToLower(algo)]
+		if expected, err = base64.StdEncoding.DecodeString(value); !ok || err != nil {
+			utils.BadRequest(c, "Upload-Checksum 格式错误，应为 \"<算法> <Base64>\"，支持 md5、sha1、sha256")
+			return
+		}
+		checksum = newHash()
+	}
+
+	mu := chunkLock(upload.ID)
+	if !mu.TryLock() {
+		c.JSON(http.StatusLocked, gin.H{"status": errmsg.ERROR, "message": "该文件的上一个分片仍在写入"})
+		return
+	}
+	defer mu.Unlock()
+
+	current, err := chunkedUploadOffset(upload.ID)
+	if err != nil {
+		utils.Error(c, errmsg.ERROR_CHUNK_NOT_EXIST)
+		return
+	}
+	c.Header("Upload-Offset", strconv.FormatInt(current, 10))
+	if offset != current {
+		c.JSON(http.StatusConflict, gin.H{"status": errmsg.ERROR_CHUNK_OFFSET, "message": errmsg.GetErrMsg(errmsg.ERROR_CHUNK_OFFSET), "offset": current})
+		return
+	}
+
+	f, err := os.OpenFile(model.ChunkedUploadPath(upload.ID), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		utils.Error(c, errmsg.ERROR)
+		return
+	}
+	defer f.Close()
+
+	limit := upload.Size - current
+	if limit > maxChunkSize {
+		limit = maxChunkSize
+	}
+	var w io.Writer = f
+	if checksum != nil {
+		w = io.MultiWriter(f, checksum)
+	}
+	// 多读一个字节，用于判断分片是否超出文件大小或分片上限
+	n, copyErr := io.Copy(w, io.LimitReader(c.Request.Body, limit+1))
+
+	// 需要丢弃本分片时截断回分片开始的位置
+	rollback := func() {
+		f.Truncate(current)
+		n = 0
+	}
+	switch {
+	case n > limit:
+		rollback()
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"status": errmsg.ERROR_FILE_TOO_LARGE, "message": "分片超出文件大小或单个分片上限", "offset": current})
+		return
+	case checksum != nil && copyErr != nil:
+		rollback()
+		utils.ErrorWithMessage(c, errmsg.ERROR, "接收分片失败: "+copyErr.Error())
+		return
+	case checksum != nil && !bytes.Equal(checksum.Sum(nil), expected):
+		rollback()
+		c.JSON(460, gin.H{"status": errmsg.ERROR_CHECKSUM_MISMATCH, "message": "分片校验失败", "offset": current})
+		return
+	}
+
+	model.TouchChunkedUpload(upload.ID)
+	offset = current + n
+	c.Header("Upload-Offset", strconv.FormatInt(offset, 10))
+	if copyErr != nil {
+		// 已写入的数据保留，客户端查询偏移量后继续
+		utils.ErrorWithMessage(c, errmsg.ERROR, "接收分片中断: "+copyErr.Error())
+		return
+	}
+	// 分片写入不单独记录请求体
+	middlewares.AuditAfter(c, gin.H{"offset": current, "length": n})
+	utils.Success(c, gin.H{"offset": offset, "size": upload.Size})
+}
+
+// CompleteChunkedUpload 完成上传：校验整个文件后保存到与 UpLoad 相同的位置
+// import 类型的上传只做校验，保留给 ZIP 上传和博客导入使用
+func CompleteChunkedUpload(c *gin.Context) {
+	// 大文件计算校验和的时间可能超过服务器的写超时
+	http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+
+	upload, ok := findChunkedUpload(c)
+	if !ok {
+		return
+	}
+	mu := chunkLock(upload.ID)
+	if !mu.TryLock() {
+		c.JSON(http.StatusLocked, gin.H{"status": errmsg.ERROR, "message": "该文件的上一个分片仍在写入"})
+		return
+	}
+	defer mu.Unlock()
+
+	offset, err := chunkedUploadOffset(upload.ID)
+	if err != nil {
+		utils.Error(c, errmsg.ERROR_CHUNK_NOT_EXIST)
+		return
+	}
+	if offset != upload.Size {
+		c.JSON(http.StatusOK, gin.H{"status": errmsg.ERROR_UPLOAD_INCOMPLETE, "message": errmsg.GetErrMsg(errmsg.ERROR_UPLOAD_INCOMPLETE), "offset": offset})
+		return
+	}
+
+	path := model.ChunkedUploadPath(upload.ID)
+	if !upload.Completed && upload.Checksum != "" {
+		sum, err := fileSHA256(path)
+		if err != nil {
+			utils.Error(c, errmsg.ERROR)
+			return
+		}
+		if sum != upload.Checksum {
+			// 无法确定出错的分片，需要重新上传
+			model.DeleteChunkedUpload(upload.ID)
+			chunkLocks.Delete(upload.ID)
+			utils.ErrorWithMessage(c, errmsg.ERROR_CHECKSUM_MISMATCH, "文件校验失败，请重新上传")
+			return
+		}
+	}
+
+	if upload.Type == chunkedUploadType {
+		if code := model.CompleteChunkedUpload(upload.ID); code != errmsg.SUCCESS {
+			utils.Error(c, code)
+			return
+		}
+		middlewares.AuditAfter(c, gin.H{"id": upload.ID, "file": upload.FileName, "size": upload.Size})
+		utils.Success(c, gin.H{"upload_id": upload.ID, "file_name": upload.FileName, "size": upload.Size})
+		return
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		utils.Error(c, errmsg.ERROR)
+		return
+	}
+	url, code := model.UpLoadFile(f, &multipart.FileHeader{Filename: upload.FileName, Size: upload.Size}, upload.Type, upload.Key)
+	f.Close()
+	if code != errmsg.SUCCESS {
+		utils.Error(c, code)
+		return
+	}
+	model.DeleteChunkedUpload(upload.ID)
+	chunkLocks.Delete(upload.ID)
+
+	middlewares.AuditAfter(c, gin.H{"file": upload.FileName, "size": upload.Size, "type": upload.Type, "url": url})
+	utils.Success(c, gin.H{"url": url})
+}
+
+// AbortChunkedUpload 放弃上传，删除已接收的数据
+func AbortChunkedUpload(c *gin.Context) {
+	upload, ok := findChunkedUpload(c)
+	if !ok {
+		return
+	}
+	mu := chunkLock(upload.ID)
+	mu.Lock()
+	model.DeleteChunkedUpload(upload.ID)
+	mu.Unlock()
+	chunkLocks.Delete(upload.ID)
+	utils.Success(c, nil)
+}
+
+// completedChunkedUpload 查找当前用户已完成的 import 类型分片上传（表单字段 upload_id），不可用时写入错误响应
+func completedChunkedUpload(c *gin.Context, id string) (model.ChunkedUpload, bool) {
+	upload, code := model.GetChunkedUpload(id, currentUsername(c))
+	if code != errmsg.SUCCESS {
+		utils.ErrorWithMessage(c, code, errmsg.GetErrMsg(code)+": "+id)
+		return upload, false
+	}
+	if upload.Type != chunkedUploadType || !upload.Completed {
+		utils.ErrorWithMessage(c, errmsg.ERROR_UPLOAD_INCOMPLETE, "文件尚未上传完成: "+upload.FileName)
+		return upload, false
+	}
+	return upload, true
+}
+
+// takeChunkedUpload 将已完成的分片上传移动到 dst，并删除上传记录
+func takeChunkedUpload(upload model.ChunkedUpload, dst string) error {
+	if err := os.Rename(model.ChunkedUploadPath(upload.ID), dst); err != nil {
+		return errors.New("移动上传文件失败: " + err.Error())
+	}
+	model.DeleteChunkedUpload(upload.ID)
+	chunkLocks.Delete(upload.ID)
+	return nil
+}
+
+// fileSHA256 计算文件的 SHA-256（十六进制）
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,103 @@
+package v1
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+	"yanblog/model"
+	"yanblog/utils"
+	"yanblog/utils/errmsg"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 上传文件按内容哈希保存，相同内容只保存一份（见 model.StoreUpload）
+// 升级前已上传的重复文件可通过去重迁移合并
+
+// uploadDedup 合并已有重复文件的后台任务状态（同一时间只运行一个）
+var uploadDedup struct {
+	sync.Mutex
+	running    bool
+	processed  int
+	startedAt  time.Time
+	finishedAt *time.Time
+	result     *model.DedupResult
+}
+
+// GetDedupStats 查询去重节省的存储空间
+// 参数: limit - 返回节省空间最多的文件数（默认 20，最大 100）
+func GetDedupStats(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	stats, err := model.GetDedupStats(limit)
+	if err != nil {
+		utils.Error(c, errmsg.ERROR)
+		return
+	}
+	utils.Success(c, stats)
+}
+
+// StartUploadDedup 在后台合并 uploads 中内容相同的文件，引用改为指向保留的文件
+// 请求体: dry_run - 只统计重复文件和引用，不做修改
+func StartUploadDedup(c *gin.Context) {
+	var req struct {
+		DryRun bool `json:"dry_run"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+
+	uploadDedup.Lock()
+	if uploadDedup.running {
+		uploadDedup.Unlock()
+		utils.ErrorWithMessage(c, errmsg.ERROR, "正在合并重复文件，请等待完成")
+		return
+	}
+	uploadDedup.running = true
+	uploadDedup.processed = 0
+	uploadDedup.startedAt = time.Now()
+	uploadDedup.finishedAt = nil
+	uploadDedup.result = nil
+	uploadDedup.Unlock()
+
+	go func() {
+		result := model.DedupUploads(req.DryRun, func(string) {
+			uploadDedup.Lock()
+			uploadDedup.processed++
+			uploadDedup.Unlock()
+		})
+		fmt.Printf("重复文件合并完成: 扫描 %d 个文件，重复 %d 个，节省 %d 字节\n", result.Files, result.Duplicates, result.SavedBytes)
+
+		uploadDedup.Lock()
+		now := time.Now()
+		uploadDedup.running = false
+		uploadDedup.finishedAt = &now
+		uploadDedup.result = &result
+		uploadDedup.Unlock()
+	}()
+
+	utils.Success(c, gin.H{"started": true, "dry_run": req.DryRun})
+}
+
+// GetUploadDedup 查询合并任务的进度和结果
+func GetUploadDedup(c *gin.Context) {
+	uploadDedup.Lock()
+	defer uploadDedup.Unlock()
+
+	data := gin.H{
+		"running":   uploadDedup.running,
+		"processed": uploadDedup.processed,
+		"result":    uploadDedup.result,
+	}
+	if !uploadDedup.startedAt.IsZero() {
+		data["started_at"] = uploadDedup.startedAt
+		data["finished_at"] = uploadDedup.finishedAt
+	}
+	utils.Success(c, data)
+}
@@ -0,0 +1,88 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"yanblog/middlewares"
+	"yanblog/model"
+	"yanblog/utils"
+	"yanblog/utils/errmsg"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultOrphanMinAge 默认不处理最近 24 小时内上传的文件（编辑中的文章可能还未保存）
+const defaultOrphanMinAge = 24
+
+// ScanUploads 扫描上传目录，报告没有被引用的文件和指向不存在文件的引用（只读，不做修改）
+// 参数: min_age_hours - 修改时间在该小时数以内的文件不算作未引用（默认 24）
+func ScanUploads(c *gin.Context) {
+	hours, err := strconv.Atoi(c.DefaultQuery("min_age_hours", strconv.Itoa(defaultOrphanMinAge)))
+	if err != nil || hours < 0 {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+	result, err := model.ScanUploads(time.Duration(hours) * time.Hour)
+	if err != nil {
+		utils.ErrorWithMessage(c, errmsg.ERROR, "扫描上传文件失败")
+		return
+	}
+	utils.Success(c, result)
+}
+
+// RecycleOrphanUploads 将没有被引用的文件移入回收站
+// 请求体: paths - 只处理其中列出的文件（为空时处理全部未引用的文件）, min_age_hours - 同 ScanUploads, dry_run - 只返回将移动的文件
+func RecycleOrphanUploads(c *gin.Context) {
+	var req struct {
+		Paths       []string `json:"paths"`
+		MinAgeHours *int     `json:"min_age_hours"`
+		DryRun      bool     `json:"dry_run"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+	hours := defaultOrphanMinAge
+	if req.MinAgeHours != nil {
+		hours = *req.MinAgeHours
+	}
+	if hours < 0 {
+		utils.BadRequest(c, "参数错误")
+		return
+	}
+	paths := make([]string, 0, len(req.Paths))
+	for _, p := range req.Paths {
+		if name, ok := safeUploadPath(p); ok && name != "" {
+			paths = append(paths, name)
+		}
+	}
+	if len(req.Paths) > 0 && len(paths) == 0 {
+		utils.BadRequest(c, "无效的路径")
+		return
+	}
+
+	moved, errs, err := model.RecycleOrphanUploads(time.Duration(hours)*time.Hour, paths, req.DryRun, currentUsername(c))
+	if err != nil {
+		utils.ErrorWithMessage(c, errmsg.ERROR, "扫描上传文件失败")
+		return
+	}
+	var size int64
+	for _, item := range moved {
+		size += item.Size
+	}
+	if !req.DryRun {
+		middlewares.AuditAfter(c, gin.H{"count": len(moved), "size": size, "failed": len(errs)})
+	}
+
+	message := fmt.Sprintf("已将 %d 个未引用的文件移入回收站", len(moved))
+	if req.DryRun {
+		message = fmt.Sprintf("将移入回收站 %d 个未引用的文件", len(moved))
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"status":  errmsg.SUCCESS,
+		"message": message,
+		"data":    gin.H{"dry_run": req.DryRun, "items": moved, "size": size, "errors": errs},
+	})
+}
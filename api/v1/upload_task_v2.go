@@ -0,0 +1,229 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"yanblog/model"
+	"yanblog/utils"
+	"yanblog/utils/errmsg"
+	"yanblog/utils/importer"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 上传任务的状态保存在数据库中（model.UploadTask），内存中只保留正在处理和最近查询过的任务
+// 处理过程中每处理完一篇文章保存一次进度（每秒最多一次，状态变化时立即保存），服务重启后可从数据库恢复
+
+// saveInterval 处理中的任务两次保存进度的最小间隔
+const saveInterval = time.Second
+
+// record 任务的数据库记录（调用方持有 task.mu）
+func (task *UploadTaskV2) record() model.UploadTask {
+	return model.UploadTask{
+		ID:             task.ID,
+		FileName:       task.FileName,
+		FileSize:       task.FileSize,
+		Format:         task.Format,
+		Mode:           task.Mode,
+		Status:         task.Status,
+		TotalFiles:     task.TotalFiles,
+		Processed:      task.Processed,
+		Success:        task.Success,
+		Failed:         task.Failed,
+		RetryCount:     task.RetryCount,
+		MaxRetries:     task.MaxRetries,
+		Operator:       task.operator,
+		SourceFile:     task.source,
+		Errors:         marshalTaskField(task.Errors),
+		ProcessedFiles: marshalTaskField(task.ProcessedFiles),
+		FailedFiles:    marshalTaskField(task.FailedFiles),
+		Report:         marshalTaskField(task.Report),
+		StartTime:      task.StartTime,
+		EndTime:        task.EndTime,
+	}
+}
+
+func marshalTaskField(v interface{}) string {
+	data, _ := json.Marshal(v)
+	return string(data)
+}
+
+// taskFromRecord 从数据库记录还原任务
+func taskFromRecord(rec model.UploadTask) *UploadTaskV2 {
+	task := &UploadTaskV2{
+		ID:         rec.ID,
+		FileName:   rec.FileName,
+		FileSize:   rec.FileSize,
+		Format:     rec.Format,
+		Mode:       rec.Mode,
+		Status:     rec.Status,
+		TotalFiles: rec.TotalFiles,
+		Processed:  rec.Processed,
+		Success:    rec.Success,
+		Failed:     rec.Failed,
+		RetryCount: rec.RetryCount,
+		MaxRetries: rec.MaxRetries,
+		StartTime:  rec.StartTime,
+		EndTime:    rec.EndTime,
+		operator:   rec.Operator,
+		source:     rec.SourceFile,
+		savedAt:    time.Now(),
+		saved:      rec.Status,
+	}
+	// 字段为空（如旧版本迁移的历史记录）时保持零值
+	json.Unmarshal([]byte(rec.Errors), &task.Errors)
+	json.Unmarshal([]byte(rec.ProcessedFiles), &task.ProcessedFiles)
+	json.Unmarshal([]byte(rec.FailedFiles), &task.FailedFiles)
+	json.Unmarshal([]byte(rec.Report), &task.Report)
+	if task.Mode == "" {
+		task.Mode = importModeSkip
+	}
+	return task
+}
+
+// saveUploadTask 将任务的当前状态保存到数据库
+// 处理中的任务距上次保存不足 saveInterval 时跳过，状态变化时总是保存
+func saveUploadTask(task *UploadTaskV2) {
+	// 保证按状态变化的先后顺序写入
+	task.saveMu.Lock()
+	defer task.saveMu.Unlock()
+
+	task.mu.Lock()
+	if model.IsUploadTaskRunning(task.Status) && task.saved == task.Status && time.Since(task.savedAt) < saveInterval {
+		task.mu.Unlock()
+		return
+	}
+	rec := task.record()
+	task.savedAt = time.Now()
+	task.saved = task.Status
+	task.mu.Unlock()
+
+	if code := model.SaveUploadTask(&rec); code != errmsg.SUCCESS {
+		fmt.Printf("保存上传任务 %s 失败\n", task.ID)
+	}
+}
+
+// addUploadTask 登记新任务并保存到数据库
+func addUploadTask(task *UploadTaskV2) {
+	tasksMuV2.Lock()
+	uploadTasksV2[task.ID] = task
+	tasksMuV2.Unlock()
+	saveUploadTask(task)
+}
+
+// findUploadTask 查找任务，内存中没有时（如服务重启后）从数据库加载
+func findUploadTask(id string) (*UploadTaskV2, bool) {
+	tasksMuV2.RLock()
+	task, exists := uploadTasksV2[id]
+	tasksMuV2.RUnlock()
+	if exists {
+		return task, true
+	}
+
+	rec, code := model.GetUploadTask(id)
+	if code != errmsg.SUCCESS {
+		return nil, false
+	}
+	tasksMuV2.Lock()
+	defer tasksMuV2.Unlock()
+	if task, exists := uploadTasksV2[id]; exists {
+		return task, true
+	}
+	task = taskFromRecord(rec)
+	uploadTasksV2[id] = task
+	return task, true
+}
+
+// handledFiles 已处理（成功或失败）的文章（调用方持有 task.mu）
+func (task *UploadTaskV2) handledFiles() map[string]bool {
+	done := make(map[string]bool, len(task.ProcessedFiles)+len(task.FailedFiles))
+	for _, name := range task.ProcessedFiles {
+		done[name] = true
+	}
+	for _, name := range task.FailedFiles {
+		done[name] = true
+	}
+	return done
+}
+
+// RecoverUploadTasks 恢复服务重启前未完成的任务（服务启动时调用）
+// 保存的原始文件还在时在后台继续处理尚未处理的文章，否则将任务标记为失败
+func RecoverUploadTasks() {
+	for _, rec := range model.GetRunningUploadTasks() {
+		task := taskFromRecord(rec)
+		tasksMuV2.Lock()
+		uploadTasksV2[task.ID] = task
+		tasksMuV2.Unlock()
+
+		if _, err := os.Stat(task.source); task.source == "" || err != nil {
+			updateTaskFailed(task, "服务重启，任务中断且原始文件已不存在")
+			continue
+		}
+
+		done := task.handledFiles()
+		fmt.Printf("继续处理中断的上传任务 %s（%s），已处理 %d 篇\n", task.ID, task.FileName, len(done))
+		if task.Format != "" {
+			go runImport(task, task.Format, done)
+		} else {
+			go runZipTask(task, func(name string) bool { return !done[name] }, false)
+		}
+	}
+}
+
+// GetUploadHistory 分页查询上传历史
+// 查询参数: status - 任务状态, format - 博客类型（zip 表示 ZIP 上传）, keyword - 文件名关键词,
+// start / end - 开始时间范围（RFC3339 或 2006-01-02）, pagesize / pagenum - 分页
+func GetUploadHistory(c *gin.Context) {
+	q := model.UploadTaskQuery{
+		Status:  c.Query("status"),
+		Format:  c.Query("format"),
+		Keyword: strings.TrimSpace(c.Query("keyword")),
+	}
+	switch q.Status {
+	case "", model.UploadStatusProcessing, model.UploadStatusRetrying, model.UploadStatusCompleted, model.UploadStatusFailed, model.UploadStatusCancelled:
+	default:
+		utils.BadRequest(c, "不支持的任务状态: "+q.Status)
+		return
+	}
+	if q.Format != "" && q.Format != "zip" && !importer.IsValidFormat(q.Format) {
+		utils.BadRequest(c, "不支持的博客类型: "+q.Format)
+		return
+	}
+	var ok bool
+	if q.Start, ok = parseAuditTime(c.Query("start"), false); !ok {
+		utils.BadRequest(c, "start 时间格式错误")
+		return
+	}
+	if q.End, ok = parseAuditTime(c.Query("end"), true); !ok {
+		utils.BadRequest(c, "end 时间格式错误")
+		return
+	}
+	pageSize, pageNum, _ := utils.ParsePageParams(c)
+
+	data, total := model.GetUploadTasks(q, pageSize, pageNum)
+	utils.SuccessWithTotal(c, data, total)
+}
+
+// ClearUploadHistory 清空已结束任务的历史记录，同时删除为重试保留的原始文件
+func ClearUploadHistory(c *gin.Context) {
+	files, count := model.ClearUploadTasks()
+	for _, file := range files {
+		os.Remove(file)
+	}
+
+	tasksMuV2.Lock()
+	for id, task := range uploadTasksV2 {
+		task.mu.Lock()
+		running := model.IsUploadTaskRunning(task.Status)
+		task.mu.Unlock()
+		if !running {
+			delete(uploadTasksV2, id)
+		}
+	}
+	tasksMuV2.Unlock()
+
+	utils.Success(c, gin.H{"deleted": count})
+}
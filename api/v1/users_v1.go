@@ -1,11 +1,13 @@
 package v1
 
 import (
+	"yanblog/middlewares"
 	"yanblog/model"
 	"yanblog/utils"
 	"yanblog/utils/errmsg"
 	"yanblog/utils/validator"
 
+	"fmt"
 	"net/http"
 	"strconv"
 
@@ -20,34 +22,21 @@ func AddUser(c *gin.Context) {
 
 	_ = c.ShouldBindJSON(&data)
 
-	// 获取当前操作用户的用户名和角色
-	currentUsername, _ := c.Get("username")
-	currentUserRole := model.GetUserRole(currentUsername.(string))
+	// 获取当前操作用户的角色（来自令牌声明）
+	currentUserRole := currentRole(c)
 
-	// 权限检查
-	if currentUserRole == 1 {
-		// 超级管理员可以创建管理员(2)和普通用户(3)
-		if data.Role == 1 {
-			c.JSON(http.StatusOK, gin.H{
-				"status":  errmsg.ERROR_USER_NO_RIGHT,
-				"message": "无权创建超级管理员",
-			})
-			return
-		}
-	} else if currentUserRole == 2 {
-		// 管理员只能创建普通用户(3)
-		if data.Role != 3 {
-			c.JSON(http.StatusOK, gin.H{
-				"status":  errmsg.ERROR_USER_NO_RIGHT,
-				"message": "管理员只能创建普通用户",
-			})
-			return
-		}
-	} else {
-		// 普通用户无权创建用户
+	// 权限检查：不能创建超级管理员；管理员只能创建等级低于自己的用户（编辑、普通用户）
+	if data.Role == model.RoleSuperAdmin {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  errmsg.ERROR_USER_NO_RIGHT,
-			"message": "无权创建用户",
+			"message": "无权创建超级管理员",
+		})
+		return
+	}
+	if !model.CanManageRole(currentUserRole, data.Role) {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  errmsg.ERROR_USER_NO_RIGHT,
+			"message": "无权创建该角色的用户",
 		})
 		return
 	}
@@ -62,6 +51,11 @@ func AddUser(c *gin.Context) {
 		return
 	}
 
+	if msg := utils.CheckPasswordPolicy(data.Password, data.Username); msg != "" {
+		utils.ErrorWithMessage(c, errmsg.ERROR_PASSWORD_WEAK, msg)
+		return
+	}
+
 	code = model.CheckUser(data.Username)
 	if code == errmsg.SUCCESS {
 		model.CreateUser(&data)
@@ -86,9 +80,8 @@ func GetUsers(c *gin.Context) {
 	pageSize, pageNum, _ := utils.ParsePageParams(c)
 	code := errmsg.SUCCESS
 
-	// 获取当前操作用户的用户名和角色
-	currentUsername, _ := c.Get("username")
-	currentUserRole := model.GetUserRole(currentUsername.(string))
+	// 获取当前操作用户的角色
+	currentUserRole := currentRole(c)
 
 	data, total := model.GetUsers(pageSize, pageNum, currentUserRole)
 	stripPasswords(data)
@@ -111,9 +104,8 @@ func SearchUsers(c *gin.Context) {
 		role, _ = strconv.Atoi(roleStr)
 	}
 
-	// 获取当前操作用户的用户名和角色
-	currentUsername, _ := c.Get("username")
-	currentUserRole := model.GetUserRole(currentUsername.(string))
+	// 获取当前操作用户的角色
+	currentUserRole := currentRole(c)
 
 	data, total := model.SearchUser(keyword, role, pageSize, pageNum, currentUserRole)
 	stripPasswords(data)
@@ -140,20 +132,25 @@ func EditUser(c *gin.Context) {
 	// fmt.Printf("EditUser received data: %+v\n", data)
 
 	// 获取当前操作用户的用户名和角色
-	currentUsername, _ := c.Get("username")
-	currentUserRole := model.GetUserRole(currentUsername.(string))
+	self := currentUsername(c)
+	currentUserRole := currentRole(c)
 
 	// 获取目标用户的信息
 	var targetUser model.User
 	model.GetDB().Where("id = ?", id).First(&targetUser)
+	middlewares.AuditBefore(c, gin.H{"username": targetUser.Username, "role": targetUser.Role})
+	isSelf := targetUser.Username == self
+	if data.Role == 0 {
+		data.Role = targetUser.Role
+	}
 
 	// 权限检查
-	// 1. 超级管理员(1)可以修改任何人，但不能修改自己的角色
-	// 2. 管理员(2)可以修改自己和普通用户(3)，不能修改超级管理员(1)和其他管理员(2)
-	// 3. 普通用户(3)只能修改自己，且不能修改角色
+	// 1. 超级管理员可以修改任何人，但不能修改自己的角色
+	// 2. 拥有 user:write 权限的角色可以修改自己和等级低于自己的用户，角色只能调整为等级低于自己的角色
+	// 3. 其他角色只能修改自己，且不能修改角色
 
-	// 禁止将用户角色修改为超级管理员(1)
-	if data.Role == 1 && targetUser.Role != 1 {
+	// 禁止将用户角色修改为超级管理员
+	if data.Role == model.RoleSuperAdmin && targetUser.Role != model.RoleSuperAdmin {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  errmsg.ERROR_USER_NO_RIGHT,
 			"message": "无权将用户提升为超级管理员",
@@ -162,55 +159,49 @@ func EditUser(c *gin.Context) {
 	}
 
 	// 禁止修改其他超级管理员的角色（防止降权）
-	if targetUser.Role == 1 && targetUser.Username != currentUsername.(string) {
-		if data.Role != 1 {
-			c.JSON(http.StatusOK, gin.H{
-				"status":  errmsg.ERROR_USER_NO_RIGHT,
-				"message": "无权修改其他超级管理员的角色",
-			})
-			return
-		}
+	if targetUser.Role == model.RoleSuperAdmin && !isSelf && data.Role != model.RoleSuperAdmin {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  errmsg.ERROR_USER_NO_RIGHT,
+			"message": "无权修改其他超级管理员的角色",
+		})
+		return
 	}
 
-	if currentUserRole == 1 {
-		// 超级管理员
-		if targetUser.Username == currentUsername.(string) {
+	switch {
+	case currentUserRole == model.RoleSuperAdmin:
+		if isSelf {
 			// 修改自己，强制保持超级管理员角色（防止降权）
-			data.Role = 1
+			data.Role = model.RoleSuperAdmin
 		}
-		// 可以修改其他任何数据
-	} else if currentUserRole == 2 {
-		// 管理员
-		if targetUser.Role == 1 {
-			// 不能修改超级管理员
-			c.JSON(http.StatusOK, gin.H{
-				"status":  errmsg.ERROR_USER_NO_RIGHT,
-				"message": "无权修改超级管理员信息",
-			})
-			return
-		}
-		if targetUser.Role == 2 && targetUser.Username != currentUsername.(string) {
-			// 不能修改其他管理员
-			c.JSON(http.StatusOK, gin.H{
-				"status":  errmsg.ERROR_USER_NO_RIGHT,
-				"message": "无权修改其他管理员信息",
-			})
-			return
-		}
-		// 修改自己或普通用户，不能修改角色
+	case isSelf:
+		// 修改自己，不能修改角色
 		data.Role = targetUser.Role
-	} else {
-		// 普通用户
-		if targetUser.Username != currentUsername.(string) {
-			// 不能修改他人
-			c.JSON(http.StatusOK, gin.H{
-				"status":  errmsg.ERROR_USER_NO_RIGHT,
-				"message": "无权修改他人信息",
-			})
+	case !model.HasPermission(currentUserRole, model.PermUserWrite):
+		c.JSON(http.StatusOK, gin.H{
+			"status":  errmsg.ERROR_USER_NO_RIGHT,
+			"message": "无权修改他人信息",
+		})
+		return
+	case !model.CanManageRole(currentUserRole, targetUser.Role):
+		c.JSON(http.StatusOK, gin.H{
+			"status":  errmsg.ERROR_USER_NO_RIGHT,
+			"message": "无权修改该用户信息",
+		})
+		return
+	case data.Role != targetUser.Role && !model.CanManageRole(currentUserRole, data.Role):
+		c.JSON(http.StatusOK, gin.H{
+			"status":  errmsg.ERROR_USER_NO_RIGHT,
+			"message": "无权将用户调整为该角色",
+		})
+		return
+	}
+
+	// 修改密码时检查密码策略
+	if data.Password != "" {
+		if msg := utils.CheckPasswordPolicy(data.Password, data.Username); msg != "" {
+			utils.ErrorWithMessage(c, errmsg.ERROR_PASSWORD_WEAK, msg)
 			return
 		}
-		// 修改自己，不能修改角色
-		data.Role = targetUser.Role
 	}
 
 	code = model.CheckUser(data.Username)
@@ -244,38 +235,26 @@ func DeleteUser(c *gin.Context) {
 	}
 
 	// 获取当前操作用户的用户名和角色
-	currentUsername, _ := c.Get("username")
-	currentUserRole := model.GetUserRole(currentUsername.(string))
+	self := currentUsername(c)
+	currentUserRole := currentRole(c)
 
 	// 获取目标用户的信息
 	var targetUser model.User
 	model.GetDB().Where("id = ?", id).First(&targetUser)
+	middlewares.AuditBefore(c, gin.H{"username": targetUser.Username, "role": targetUser.Role})
 
-	// 权限检查
-	if currentUserRole == 1 {
-		// 超级管理员不能删除自己
-		if targetUser.Username == currentUsername.(string) {
-			c.JSON(http.StatusOK, gin.H{
-				"status":  errmsg.ERROR_USER_NO_RIGHT,
-				"message": "超级管理员不能删除自己",
-			})
-			return
-		}
-		// 超级管理员可以删除其他任何人
-	} else if currentUserRole == 2 {
-		// 管理员只能删除普通用户
-		if targetUser.Role != 3 {
-			c.JSON(http.StatusOK, gin.H{
-				"status":  errmsg.ERROR_USER_NO_RIGHT,
-				"message": "无权删除该用户",
-			})
-			return
-		}
-	} else {
-		// 普通用户不能删除任何人
+	// 权限检查：不能删除自己；只能删除等级低于自己的用户
+	if targetUser.Username == self {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  errmsg.ERROR_USER_NO_RIGHT,
+			"message": "不能删除自己",
+		})
+		return
+	}
+	if !model.CanManageRole(currentUserRole, targetUser.Role) {
 		c.JSON(http.StatusOK, gin.H{
 			"status":  errmsg.ERROR_USER_NO_RIGHT,
-			"message": "无权执行删除操作",
+			"message": "无权删除该用户",
 		})
 		return
 	}
@@ -289,3 +268,88 @@ func DeleteUser(c *gin.Context) {
 		"message": errmsg.GetErrMsg(code),
 	})
 }
+
+// ChangePassword 修改自己的密码（必须修改密码期间唯一可用的业务接口）
+// 修改成功后旧会话全部失效，直接返回新的令牌
+func ChangePassword(c *gin.Context) {
+	var data struct {
+		OldPassword string `json:"old_password"`
+		NewPassword string `json:"new_password"`
+	}
+	if err := c.ShouldBindJSON(&data); err != nil || data.OldPassword == "" || data.NewPassword == "" {
+		utils.BadRequest(c, "请输入原密码和新密码")
+		return
+	}
+
+	username := currentUsername(c)
+	if msg := utils.CheckPasswordPolicy(data.NewPassword, username); msg != "" {
+		utils.ErrorWithMessage(c, errmsg.ERROR_PASSWORD_WEAK, msg)
+		return
+	}
+	if code := model.ChangePassword(c.GetUint("user_id"), data.OldPassword, data.NewPassword); code != errmsg.SUCCESS {
+		utils.Error(c, code)
+		return
+	}
+
+	issueLoginTokens(c, username)
+}
+
+// GetUserSessions 查询用户当前有效的登录会话
+func GetUserSessions(c *gin.Context) {
+	id, ok := utils.ParseIDParam(c)
+	if !ok {
+		return
+	}
+	if code, msg := checkSessionRight(c, id); code != errmsg.SUCCESS {
+		utils.ErrorWithMessage(c, code, msg)
+		return
+	}
+	utils.Success(c, model.GetUserSessions(id))
+}
+
+// RevokeUserSessions 撤销用户的全部登录会话（强制下线）
+func RevokeUserSessions(c *gin.Context) {
+	id, ok := utils.ParseIDParam(c)
+	if !ok {
+		return
+	}
+	if code, msg := checkSessionRight(c, id); code != errmsg.SUCCESS {
+		utils.ErrorWithMessage(c, code, msg)
+		return
+	}
+
+	revoked := model.RevokeUserSessions(id)
+	c.JSON(http.StatusOK, gin.H{
+		"status":  errmsg.SUCCESS,
+		"data":    gin.H{"revoked": revoked},
+		"message": fmt.Sprintf("已撤销 %d 个会话", revoked),
+	})
+}
+
+// checkSessionRight 检查当前用户是否有权管理目标用户的会话
+// 用户可以管理自己的会话；拥有 user:write 权限时还可以管理等级低于自己的用户
+func checkSessionRight(c *gin.Context, id int) (int, string) {
+	self := currentUsername(c)
+	currentUserRole := currentRole(c)
+
+	var targetUser model.User
+	if err := model.GetDB().Where("id = ?", id).First(&targetUser).Error; err != nil {
+		return errmsg.ERROR_USER_NOT_EXIST, errmsg.GetErrMsg(errmsg.ERROR_USER_NOT_EXIST)
+	}
+	if targetUser.Username == self ||
+		(model.HasPermission(currentUserRole, model.PermUserWrite) && model.CanManageRole(currentUserRole, targetUser.Role)) {
+		return errmsg.SUCCESS, ""
+	}
+	return errmsg.ERROR_USER_NO_RIGHT, "无权管理该用户的会话"
+}
+
+// GetMyPermissions 查询当前登录用户的角色和权限列表（前端据此控制菜单和按钮）
+func GetMyPermissions(c *gin.Context) {
+	role := currentRole(c)
+	utils.Success(c, gin.H{
+		"user_id":     c.GetUint("user_id"),
+		"username":    currentUsername(c),
+		"role":        role,
+		"permissions": model.RolePermissions(role),
+	})
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"yanblog/model"
+)
+
+// 命令行子命令：
+//
+//	yanblog backup [-o 文件]                 生成全站备份
+//	yanblog restore [-dry-run] [-y] 文件     校验备份、显示差异并恢复
+//	yanblog images [-force] [-strip-exif]    为已上传的图片补生成缩略图和不同宽度
+//	yanblog dedup [-dry-run]                 合并已上传的重复文件
+//	yanblog gc [-recycle] [-min-age 24h]     查找未被引用的上传文件和失效的引用
+
+// runCommand 执行子命令，返回进程退出码
+func runCommand(args []string) int {
+	switch args[0] {
+	case "backup":
+		return runBackup(args[1:])
+	case "restore":
+		return runRestore(args[1:])
+	case "images":
+		return runImages(args[1:])
+	case "dedup":
+		return runDedup(args[1:])
+	case "gc":
+		return runGC(args[1:])
+	case "help", "-h", "-help", "--help":
+		printUsage()
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "未知命令: %s\n\n", args[0])
+	printUsage()
+	return 2
+}
+
+func printUsage() {
+	fmt.Println("用法:")
+	fmt.Println("  yanblog                                 启动服务")
+	fmt.Println("  yanblog backup [-o 文件]                生成全站备份（数据库、上传文件、关于页、前后端配置、导入历史）")
+	fmt.Println("  yanblog restore [-dry-run] [-y] 文件    校验备份并恢复，-dry-run 只显示差异，-y 跳过确认")
+	fmt.Println("  yanblog images [-force] [-strip-exif]   为已上传的图片补生成缩略图和不同宽度，-force 重新生成已有的尺寸")
+	fmt.Println("  yanblog dedup [-dry-run]                合并已上传的重复文件并更新引用，-dry-run 只显示将合并的文件")
+	fmt.Println("  yanblog gc [-recycle] [-min-age 24h]    查找未被引用的上传文件和失效的引用，-recycle 将未引用的文件移入回收站")
+}
+
+// runImages 为 uploads 中已有的图片补生成各尺寸，可同时去除 EXIF 等元数据
+func runImages(args []string) int {
+	fs := flag.NewFlagSet("images", flag.ExitOnError)
+	force := fs.Bool("force", false, "重新生成已有的尺寸")
+	stripExif := fs.Bool("strip-exif", false, "同时去除原图中的 EXIF 等元数据（会修改原图）")
+	fs.Parse(args)
+
+	count := 0
+	result := model.BackfillImageVariants(*force, *stripExif, func(path string) {
+		if count++; count%100 == 0 {
+			fmt.Printf("已处理 %d 张图片\n", count)
+		}
+	})
+	for _, e := range result.Errors {
+		fmt.Fprintf(os.Stderr, "  ✗ %s\n", e)
+	}
+	fmt.Printf("✅ 处理完成: %d 张图片，生成 %d 个尺寸，去除元数据 %d 张，失败 %d 张\n", result.Images, result.Generated, result.Stripped, result.Failed)
+	if result.Failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runDedup 合并 uploads 中内容相同的文件，引用改为指向保留的文件
+func runDedup(args []string) int {
+	fs := flag.NewFlagSet("dedup", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "只显示将合并的文件，不做修改")
+	fs.Parse(args)
+
+	model.InitDB()
+	result := model.DedupUploads(*dryRun, nil)
+	for _, group := range result.Groups {
+		fmt.Printf("  %s\n", group.Keep)
+		for _, name := range group.Removed {
+			fmt.Printf("    ← %s\n", name)
+		}
+	}
+	for _, e := range result.Errors {
+		fmt.Fprintf(os.Stderr, "  ✗ %s\n", e)
+	}
+	action := "已合并"
+	if *dryRun {
+		action = "可合并"
+	}
+	fmt.Printf("✅ 扫描 %d 个文件，%s %d 个重复文件，节省 %d 字节，更新 %d 处引用\n", result.Files, action, result.Duplicates, result.SavedBytes, result.References)
+	if len(result.Errors) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runGC 对照数据库和 uploads 目录，报告未被引用的文件和失效的引用，默认只报告不修改
+func runGC(args []string) int {
+	fs := flag.NewFlagSet("gc", flag.ExitOnError)
+	recycle := fs.Bool("recycle", false, "将未被引用的文件移入回收站（uploads/.recycle）")
+	minAge := fs.Duration("min-age", 24*time.Hour, "修改时间在该时长以内的文件不算作未引用")
+	fs.Parse(args)
+
+	model.InitDB()
+	result, err := model.ScanUploads(*minAge)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ 扫描失败: %v\n", err)
+		return 1
+	}
+	for _, ref := range result.Broken {
+		sources := make([]string, 0, len(ref.Sources))
+		for _, s := range ref.Sources {
+			if s.ID != 0 {
+				sources = append(sources, fmt.Sprintf("%s#%d.%s", s.Type, s.ID, s.Field))
+			} else {
+				sources = append(sources, s.Type)
+			}
+		}
+		fmt.Printf("  ✗ 失效引用 %s（%s）\n", ref.URL, strings.Join(sources, ", "))
+	}
+	for _, orphan := range result.Orphans {
+		fmt.Printf("  ? 未引用 %s（%d 字节）\n", orphan.Name, orphan.Size)
+	}
+	fmt.Printf("扫描 %d 个文件：被引用 %d 个，未引用 %d 个（%d 字节），最近上传跳过 %d 个；失效引用 %d 个\n",
+		result.Files, result.Referenced, len(result.Orphans), result.OrphanBytes, result.Recent, len(result.Broken))
+
+	if !*recycle || len(result.Orphans) == 0 {
+		return 0
+	}
+	moved, errs, err := model.RecycleOrphanUploads(*minAge, nil, false, "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ 扫描失败: %v\n", err)
+		return 1
+	}
+	for _, e := range errs {
+		fmt.Fprintf(os.Stderr, "  ✗ %s\n", e)
+	}
+	fmt.Printf("✅ 已将 %d 个未引用的文件移入回收站\n", len(moved))
+	if len(errs) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runBackup 生成全站备份，先写入临时文件，完成后再重命名，避免留下不完整的备份
+func runBackup(args []string) int {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	output := fs.String("o", fmt.Sprintf("yanblog-backup-%s.zip", time.Now().Format("20060102-150405")), "备份文件路径")
+	fs.Parse(args)
+
+	model.InitDB()
+	model.AcquireBackupLock()
+	defer model.ReleaseBackupLock()
+
+	tmp := *output + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ 创建备份文件失败: %v\n", err)
+		return 1
+	}
+	manifest, err := model.WriteBackup(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp, *output)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		fmt.Fprintf(os.Stderr, "❌ 备份失败: %v\n", err)
+		return 1
+	}
+
+	var rows int64
+	for _, n := range manifest.Tables {
+		rows += n
+	}
+	fmt.Printf("✅ 备份完成: %s（%d 张数据表共 %d 条记录，%d 个文件）\n", *output, len(manifest.Tables), rows, len(manifest.Files))
+	return 0
+}
+
+// runRestore 校验备份并显示与当前数据的差异，确认后恢复
+func runRestore(args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "只校验备份并显示差异，不修改任何数据")
+	yes := fs.Bool("y", false, "跳过确认")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		printUsage()
+		return 2
+	}
+
+	model.InitDB()
+	model.AcquireBackupLock()
+	defer model.ReleaseBackupLock()
+
+	archive, err := model.OpenBackup(fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		return 1
+	}
+	defer archive.Close()
+
+	diff, err := archive.Diff()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ 对比数据失败: %v\n", err)
+		return 1
+	}
+	printBackupDiff(diff)
+	if *dryRun {
+		return 0
+	}
+
+	if !*yes {
+		fmt.Println("\n⚠️  恢复将替换当前所有数据，请先停止正在运行的服务。")
+		fmt.Print("输入 yes 继续: ")
+		line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+		if strings.TrimSpace(line) != "yes" {
+			fmt.Println("已取消")
+			return 1
+		}
+	}
+	if err := archive.Restore(); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ 恢复失败: %v\n", err)
+		return 1
+	}
+	fmt.Println("✅ 恢复完成")
+	return 0
+}
+
+// printBackupDiff 打印恢复前后的差异，每类文件最多列出 20 个
+func printBackupDiff(diff *model.BackupDiff) {
+	fmt.Printf("备份时间: %s（%s）\n\n", diff.CreatedAt.Local().Format("2006-01-02 15:04:05"), diff.Database)
+	fmt.Printf("%-22s %10s %10s\n", "数据表", "当前", "备份")
+	for _, t := range diff.Tables {
+		fmt.Printf("%-22s %10d %10d\n", t.Table, t.Current, t.Backup)
+	}
+
+	fmt.Printf("\n文件: 新增 %d，删除 %d，替换 %d，不变 %d\n", len(diff.Added), len(diff.Removed), len(diff.Changed), diff.Unchanged)
+	for _, group := range []struct {
+		mark  string
+		files []string
+	}{{"+", diff.Added}, {"-", diff.Removed}, {"~", diff.Changed}} {
+		for i, name := range group.files {
+			if i == 20 {
+				fmt.Printf("  %s ... 等 %d 个\n", group.mark, len(group.files))
+				break
+			}
+			fmt.Printf("  %s %s\n", group.mark, name)
+		}
+	}
+}
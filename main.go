@@ -3,10 +3,12 @@ package main
 import (
 	"fmt"
 	"os"
+	v1 "yanblog/api/v1"
 	"yanblog/middlewares"
 	"yanblog/model"
 	"yanblog/routers"
 	"yanblog/utils"
+	"yanblog/utils/storage"
 )
 
 func main() {
@@ -22,6 +24,17 @@ func main() {
 	// 注册配置重载回调
 	utils.OnConfigReloaded = middlewares.RefreshJwtKey
 
+	// 初始化上传文件存储（本地、S3 兼容对象存储或 WebDAV）
+	if err := storage.Init(); err != nil {
+		fmt.Fprintf(os.Stderr, "❌ 存储配置错误：%v\n", err)
+		os.Exit(1)
+	}
+
+	// 命令行子命令（如 backup / restore），执行完成后直接退出
+	if len(os.Args) > 1 {
+		os.Exit(runCommand(os.Args[1:]))
+	}
+
 	// 打印启动信息
 	utils.PrintStartupInfo()
 
@@ -37,6 +50,21 @@ func main() {
 	// 启动 API 限流清理
 	go middlewares.CleanupAPIRateLimits()
 
+	// 启动预约文章发布调度
+	go model.RunArticleScheduler()
+
+	// 启动过期会话清理
+	go model.RunSessionCleanup()
+
+	// 启动过期分片上传清理
+	go model.RunChunkedUploadCleanup()
+
+	// 启动回收站过期项目清理
+	go model.RunRecycleBinCleanup()
+
+	// 恢复服务重启前中断的上传任务
+	v1.RecoverUploadTasks()
+
 	// 初始化路由
 	routers.InitRouter()
 }
\ No newline at end of file
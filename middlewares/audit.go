@@ -0,0 +1,247 @@
+package middlewares
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"unicode/utf8"
+	"yanblog/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	auditBodyLimit     = 64 << 10 // 超过该大小的请求体不读取，只记录长度
+	auditSummaryLimit  = 4000     // 摘要最大字符数
+	auditResponseLimit = 4 << 10  // 解析业务状态码时最多缓存的响应字节数
+
+	auditTargetKey = "audit_target"
+	auditBeforeKey = "audit_before"
+	auditAfterKey  = "audit_after"
+)
+
+// auditSensitiveKeys 请求体中需要脱敏的字段（不区分大小写，包含即脱敏）
+var auditSensitiveKeys = []string{"password", "secret", "token", "jwtkey", "accesskey", "apikey"}
+
+// AuditTarget 由接口指定审计日志中的操作对象（默认使用路由参数，如 "id=5"）
+func AuditTarget(c *gin.Context, target string) {
+	c.Set(auditTargetKey, target)
+}
+
+// AuditBefore 由接口记录修改前的摘要（如被删除文章的标题、修改前的配置）
+func AuditBefore(c *gin.Context, v interface{}) {
+	c.Set(auditBeforeKey, auditSummary(v))
+}
+
+// AuditAfter 由接口记录修改后的摘要（默认使用脱敏后的请求体）
+func AuditAfter(c *gin.Context, v interface{}) {
+	c.Set(auditAfterKey, auditSummary(v))
+}
+
+// auditWriter 缓存响应开头部分，用于解析业务状态码
+type auditWriter struct {
+	gin.ResponseWriter
+	buf bytes.Buffer
+}
+
+func (w *auditWriter) Write(b []byte) (int, error) {
+	if room := auditResponseLimit - w.buf.Len(); room > 0 {
+		if len(b) < room {
+			room = len(b)
+		}
+		w.buf.Write(b[:room])
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap 供 http.ResponseController 访问底层连接（如下载大文件时解除写超时）
+func (w *auditWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *auditWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+// Audit 审计日志中间件，记录所有写操作（POST / PUT / PATCH / DELETE）
+// 必须放在 JwtToken 之后使用，操作人从令牌声明中读取
+func Audit() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method == http.MethodGet || c.Request.Method == http.MethodHead || c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+
+		body := readAuditBody(c)
+		writer := &auditWriter{ResponseWriter: c.Writer}
+		c.Writer = writer
+
+		c.Next()
+
+		log := model.AuditLog{
+			UserID:     c.GetUint("user_id"),
+			Username:   c.GetString("username"),
+			IP:         c.ClientIP(),
+			Method:     c.Request.Method,
+			Action:     truncateAudit(c.Request.Method+" "+c.FullPath(), 150),
+			Path:       truncateAudit(c.Request.URL.RequestURI(), 500),
+			Target:     c.GetString(auditTargetKey),
+			Before:     c.GetString(auditBeforeKey),
+			After:      c.GetString(auditAfterKey),
+			HTTPStatus: writer.Status(),
+			Status:     responseStatus(writer.buf.Bytes(), writer.Status()),
+		}
+		if log.Target == "" {
+			log.Target = paramsTarget(c)
+		}
+		if log.After == "" {
+			log.After = body
+		}
+		if c.Request.MultipartForm != nil {
+			log.After = multipartSummary(c)
+		}
+		model.CreateAuditLog(&log)
+	}
+}
+
+// readAuditBody 读取并还原请求体，返回脱敏后的摘要
+// 只解析 JSON 和表单（application/x-www-form-urlencoded），其他类型（如分片上传的二进制数据）只记录类型和长度
+func readAuditBody(c *gin.Context) string {
+	if c.Request.Body == nil || c.Request.ContentLength == 0 {
+		return ""
+	}
+	contentType := c.ContentType()
+	if strings.HasPrefix(contentType, "multipart/") {
+		return "" // 上传的文件在处理完成后从 MultipartForm 中记录
+	}
+	isJSON := contentType == "" || contentType == "application/json" || strings.HasSuffix(contentType, "+json")
+	isForm := contentType == "application/x-www-form-urlencoded"
+	if !isJSON && !isForm {
+		return auditBodyInfo(contentType, c.Request.ContentLength)
+	}
+	if c.Request.ContentLength < 0 || c.Request.ContentLength > auditBodyLimit {
+		return fmt.Sprintf("(请求体 %d 字节，未记录)", c.Request.ContentLength)
+	}
+
+	raw, err := io.ReadAll(c.Request.Body)
+	c.Request.Body = io.NopCloser(bytes.NewReader(raw))
+	if err != nil || len(raw) == 0 {
+		return ""
+	}
+
+	if isForm {
+		values, err := url.ParseQuery(string(raw))
+		if err != nil {
+			return auditBodyInfo(contentType, int64(len(raw)))
+		}
+		return auditSummary(values)
+	}
+	var v interface{}
+	if json.Unmarshal(raw, &v) != nil {
+		return auditBodyInfo(contentType, int64(len(raw)))
+	}
+	return auditSummary(v)
+}
+
+// auditBodyInfo 不记录内容的请求体：只记录类型和长度
+func auditBodyInfo(contentType string, length int64) string {
+	if contentType == "" {
+		contentType = "未知类型"
+	}
+	return fmt.Sprintf("(%s 请求体 %d 字节，未记录)", contentType, length)
+}
+
+// auditSummary 将对象序列化为脱敏后的 JSON 摘要
+func auditSummary(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return truncateAudit(s, auditSummaryLimit)
+	}
+	// 先转为通用结构，便于按字段名脱敏
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	var generic interface{}
+	if json.Unmarshal(raw, &generic) != nil {
+		return ""
+	}
+	out, _ := json.Marshal(redactAudit(generic))
+	return truncateAudit(string(out), auditSummaryLimit)
+}
+
+// redactAudit 递归替换敏感字段的值
+func redactAudit(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for k, item := range val {
+			if isSensitiveAuditKey(k) {
+				val[k] = "******"
+			} else {
+				val[k] = redactAudit(item)
+			}
+		}
+	case []interface{}:
+		for i, item := range val {
+			val[i] = redactAudit(item)
+		}
+	}
+	return v
+}
+
+func isSensitiveAuditKey(key string) bool {
+	lower := strings.ToLower(key)
+	if lower == "code" || lower == "recovery_code" || lower == "mfa_token" {
+		return true
+	}
+	for _, s := range auditSensitiveKeys {
+		if strings.Contains(lower, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// paramsTarget 用路由参数作为默认的操作对象
+func paramsTarget(c *gin.Context) string {
+	parts := make([]string, 0, len(c.Params))
+	for _, p := range c.Params {
+		parts = append(parts, p.Key+"="+p.Value)
+	}
+	return truncateAudit(strings.Join(parts, ","), 255)
+}
+
+// multipartSummary 记录上传的文件名和大小
+func multipartSummary(c *gin.Context) string {
+	var files []string
+	for field, headers := range c.Request.MultipartForm.File {
+		for _, h := range headers {
+			files = append(files, fmt.Sprintf("%s:%s(%d)", field, h.Filename, h.Size))
+		}
+	}
+	sort.Strings(files)
+	return auditSummary(gin.H{"files": files, "form": c.Request.MultipartForm.Value})
+}
+
+// responseStatus 从响应 JSON 中解析业务状态码，解析失败时使用 HTTP 状态码
+func responseStatus(body []byte, httpStatus int) int {
+	var resp struct {
+		Status *int `json:"status"`
+	}
+	if json.Unmarshal(body, &resp) == nil && resp.Status != nil {
+		return *resp.Status
+	}
+	return httpStatus
+}
+
+func truncateAudit(s string, max int) string {
+	if utf8.RuneCountInString(s) <= max {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:max]) + "..."
+}
@@ -0,0 +1,65 @@
+package middlewares
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAuditSummaryRedactsSecrets(t *testing.T) {
+	summary := auditSummary(map[string]interface{}{
+		"username": "editor1",
+		"password": "Edit#2026ok",
+		"database": map[string]interface{}{"DbPassWord": "root123", "DbHost": "localhost"},
+		"code":     "123456",
+	})
+
+	for _, secret := range []string{"Edit#2026ok", "root123", "123456"} {
+		if strings.Contains(summary, secret) {
+			t.Fatalf("summary leaks %q: %s", secret, summary)
+		}
+	}
+	if !strings.Contains(summary, "editor1") || !strings.Contains(summary, "localhost") {
+		t.Fatalf("summary should keep non-sensitive fields: %s", summary)
+	}
+}
+
+func TestReadAuditBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		want        []string // 摘要中应包含的内容
+		secrets     []string // 摘要中不能出现的内容
+	}{
+		{"JSON", "application/json", `{"username":"admin","password":"Secret#1"}`, []string{"admin"}, []string{"Secret#1"}},
+		{"表单", "application/x-www-form-urlencoded", "username=admin&password=Secret%231&refresh_token=abc123", []string{"admin"}, []string{"Secret#1", "Secret%231", "abc123"}},
+		{"二进制", "application/offset+octet-stream", "password=Secret#1", []string{"application/offset+octet-stream", "17 字节"}, []string{"Secret#1"}},
+		{"无效 JSON", "application/json", "password=Secret#1", []string{"17 字节"}, []string{"Secret#1"}},
+	}
+	for _, tt := range tests {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+		c.Request.Header.Set("Content-Type", tt.contentType)
+
+		summary := readAuditBody(c)
+		for _, s := range tt.want {
+			if !strings.Contains(summary, s) {
+				t.Errorf("%s: 摘要应包含 %q: %s", tt.name, s, summary)
+			}
+		}
+		for _, s := range tt.secrets {
+			if strings.Contains(summary, s) {
+				t.Errorf("%s: 摘要泄露 %q: %s", tt.name, s, summary)
+			}
+		}
+		// 请求体需还原，供后续处理读取
+		if rest, _ := io.ReadAll(c.Request.Body); string(rest) != tt.body {
+			t.Errorf("%s: 请求体未还原: %q", tt.name, rest)
+		}
+	}
+}
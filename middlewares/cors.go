@@ -30,9 +30,10 @@ func Cors() gin.HandlerFunc {
 		// 根据环境决定是否允许所有来源
 		AllowAllOrigins: allowAll,
 		AllowOrigins:     allowOrigins,
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length", "Authorization"},
+		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "HEAD", "PATCH"},
+		// Upload-* 为分片上传使用的请求 / 响应头
+		AllowHeaders:     []string{"Origin", "Content-Type", "Authorization", "Upload-Offset", "Upload-Checksum"},
+		ExposeHeaders:    []string{"Content-Length", "Authorization", "Location", "Upload-Offset", "Upload-Length"},
 		AllowCredentials: false,
 		MaxAge:           12 * time.Hour,
 	})
@@ -25,20 +25,54 @@ func RefreshJwtKey() {
 }
 
 type MyClaims struct {
-	Username string `json:"username"`           // 用户名
-	jwt.RegisteredClaims                          // 标准JWT声明（包含过期时间、签发者等）
+	UserID               uint   `json:"uid"`           // 用户ID
+	Username             string `json:"username"`      // 用户名
+	Role                 int    `json:"role"`          // 角色码（角色变更时会话会被撤销，因此可直接信任）
+	MustChangePassword   bool   `json:"mcp,omitempty"` // 必须先修改密码（修改密码后会话会被撤销）
+	jwt.RegisteredClaims        // 标准JWT声明（包含过期时间、签发者等）
 }
 
-// SetToken 生成JWT token
-// 参数: username - 用户名
+// 令牌默认有效期
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 7 * 24 * time.Hour
+)
+
+// AccessTokenTTL 访问令牌有效期
+func AccessTokenTTL() time.Duration {
+	if m := utils.ServerConfig.Auth.AccessTokenMinutes; m > 0 {
+		return time.Duration(m) * time.Minute
+	}
+	return defaultAccessTokenTTL
+}
+
+// RefreshTokenTTL 刷新令牌有效期
+func RefreshTokenTTL() time.Duration {
+	if d := utils.ServerConfig.Auth.RefreshTokenDays; d > 0 {
+		return time.Duration(d) * 24 * time.Hour
+	}
+	return defaultRefreshTokenTTL
+}
+
+// passwordChangePaths 必须修改密码期间仍允许访问的接口
+var passwordChangePaths = map[string]bool{
+	"/api/v1/user/password": true,
+	"/api/v1/logout":        true,
+}
+
+// SetToken 生成JWT访问令牌
+// 参数: user - 用户（使用 ID、用户名、角色和是否必须修改密码）, sessionID - 所属会话ID（写入 jti，撤销会话即可使令牌失效）
 // 返回: token字符串和状态码
-func SetToken(username string) (string, int) {
-	// 设置token过期时间为10小时后
-	expireTime := time.Now().Add(10 * time.Hour)
-	
+func SetToken(user model.User, sessionID string) (string, int) {
+	expireTime := time.Now().Add(AccessTokenTTL())
+
 	SetClaims := MyClaims{
-		username,
+		user.ID,
+		user.Username,
+		user.Role,
+		user.MustChangePassword,
 		jwt.RegisteredClaims{
+			ID:        sessionID,                      // 会话ID
 			ExpiresAt: jwt.NewNumericDate(expireTime), // 过期时间
 			Issuer:    "yanblog",                      // 签发者
 		},
@@ -73,10 +107,11 @@ func CheckToken(tokenString string) (*MyClaims, int) {
 	}
 }
 
-// AdminRequired 管理员权限中间件，仅允许超级管理员和管理员操作
-func AdminRequired() gin.HandlerFunc {
+// RequirePermission 权限中间件，要求当前用户的角色拥有全部指定权限
+// 必须放在 JwtToken 之后使用（角色从令牌声明中读取，不再查询数据库）
+func RequirePermission(perms ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		username, exists := c.Get("username")
+		role, exists := c.Get("role")
 		if !exists {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"status":  errmsg.ERROR_TOKEN_WRONG,
@@ -86,15 +121,16 @@ func AdminRequired() gin.HandlerFunc {
 			return
 		}
 
-		role := model.GetUserRole(username.(string))
-	if role > 2 {
-		c.JSON(http.StatusForbidden, gin.H{
-			"status":  errmsg.ERROR_USER_NO_RIGHT,
-			"message": "无权执行此操作，需要管理员权限",
-		})
-		c.Abort()
-		return
-	}
+		for _, perm := range perms {
+			if !model.HasPermission(role.(int), perm) {
+				c.JSON(http.StatusForbidden, gin.H{
+					"status":  errmsg.ERROR_USER_NO_RIGHT,
+					"message": "无权执行此操作，需要权限: " + perm,
+				})
+				c.Abort()
+				return
+			}
+		}
 
 		c.Next()
 	}
@@ -155,8 +191,33 @@ func JwtToken() gin.HandlerFunc {
 			return
 		}
 
-		// 6. 验证通过，将用户名存入上下文供后续处理使用
+		// 6. 检查会话是否已被撤销（退出登录、删除用户、修改角色等）
+		if key.ID == "" || !model.IsSessionActive(key.ID) {
+			code = errmsg.ERROR_TOKEN_REVOKED
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"status":  code,
+				"message": errmsg.GetErrMsg(code),
+			})
+			c.Abort()
+			return
+		}
+
+		// 7. 必须修改密码时只允许访问修改密码和退出登录接口
+		if key.MustChangePassword && !passwordChangePaths[c.FullPath()] {
+			code = errmsg.ERROR_PASSWORD_CHANGE
+			c.JSON(http.StatusForbidden, gin.H{
+				"status":  code,
+				"message": errmsg.GetErrMsg(code),
+			})
+			c.Abort()
+			return
+		}
+
+		// 8. 验证通过，将用户信息和会话ID存入上下文供后续处理使用
+		c.Set("user_id", key.UserID)
 		c.Set("username", key.Username)
+		c.Set("role", key.Role)
+		c.Set("session_id", key.ID)
 		c.Next()
 	}
 }
\ No newline at end of file
@@ -1,10 +1,14 @@
 package middlewares
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
+	"io"
 	"net/http"
 	"sync"
 	"time"
+	"yanblog/utils"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/driver/sqlite"
@@ -22,8 +26,13 @@ type apiRateLimit struct {
 var apiRateLimits sync.Map
 
 const (
-	defaultAPIRateLimit    = 100
-	defaultAPIRateInterval = time.Minute
+	defaultAPIRateLimit     = 100
+	defaultAPIRateInterval  = time.Minute
+	defaultCommentRateLimit = 5
+
+	// 两步登录的第二步：每个登录挑战最多提交的次数和计数周期（与登录挑战的有效期一致）
+	twoFactorMaxTries = 5
+	twoFactorWindow   = 5 * time.Minute
 )
 
 var (
@@ -165,13 +174,54 @@ func LoginRateLimit() gin.HandlerFunc {
 // APIRateLimit 通用 API 限流中间件（基于令牌桶算法）
 // 默认每分钟 100 次请求限制
 func APIRateLimit() gin.HandlerFunc {
+	return RateLimit("api", defaultAPIRateLimit, defaultAPIRateInterval, "请求过于频繁，请稍后再试")
+}
+
+// CommentRateLimit 发表评论限流中间件
+// 每个 IP 每分钟最多发表 comment.RateLimit 条评论（默认 5 条）
+func CommentRateLimit() gin.HandlerFunc {
+	maxTokens := utils.ServerConfig.Comment.RateLimit
+	if maxTokens <= 0 {
+		maxTokens = defaultCommentRateLimit
+	}
+	return RateLimit("comment", maxTokens, time.Minute, "评论过于频繁，请稍后再试")
+}
+
+// RateLimit 按 IP 限流的中间件（基于令牌桶算法）
+// 参数: scope - 限流作用域（不同作用域分别计数）, maxTokens - 每个周期允许的请求数,
+// interval - 令牌恢复周期, message - 超限时的提示信息
+func RateLimit(scope string, maxTokens int, interval time.Duration, message string) gin.HandlerFunc {
+	return rateLimitBy(scope, func(c *gin.Context) string { return c.ClientIP() }, maxTokens, interval, message)
+}
+
+// TwoFactorRateLimit 两步登录第二步的频率限制中间件
+// 按登录挑战令牌计数，不占用登录接口按 IP 计算的次数；次数用尽后需重新输入密码获取新的挑战
+func TwoFactorRateLimit() gin.HandlerFunc {
+	return rateLimitBy("2fa", challengeToken, twoFactorMaxTries, twoFactorWindow, "验证码尝试次数过多，请重新登录")
+}
+
+// challengeToken 从请求体中读取登录挑战令牌（读取后还原请求体），没有令牌时按 IP 计数
+func challengeToken(c *gin.Context) string {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, 4096))
+	c.Request.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), c.Request.Body))
+	var data struct {
+		MfaToken string `json:"mfa_token"`
+	}
+	if err != nil || json.Unmarshal(body, &data) != nil || data.MfaToken == "" {
+		return "ip:" + c.ClientIP()
+	}
+	return "token:" + data.MfaToken
+}
+
+// rateLimitBy 按 key 返回的键限流的中间件（基于令牌桶算法）
+func rateLimitBy(scope string, key func(c *gin.Context) string, maxTokens int, interval time.Duration, message string) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		ip := c.ClientIP()
+		key := scope + "|" + key(c)
 
-		item, _ := apiRateLimits.LoadOrStore(ip, &apiRateLimit{
-			tokens:     defaultAPIRateLimit,
-			maxTokens:  defaultAPIRateLimit,
-			rate:       defaultAPIRateInterval,
+		item, _ := apiRateLimits.LoadOrStore(key, &apiRateLimit{
+			tokens:     maxTokens,
+			maxTokens:  maxTokens,
+			rate:       interval,
 			lastAccess: time.Now(),
 		})
 
@@ -194,7 +244,7 @@ func APIRateLimit() gin.HandlerFunc {
 		} else {
 			c.JSON(http.StatusTooManyRequests, gin.H{
 				"status":  429,
-				"message": "请求过于频繁，请稍后再试",
+				"message": message,
 			})
 			c.Abort()
 		}
@@ -1,8 +1,13 @@
 package middlewares
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/gin-gonic/gin"
 )
 
 func TestInitRateLimiter(t *testing.T) {
@@ -50,4 +55,37 @@ func TestShutdown(t *testing.T) {
 	if shutdownCancel == nil {
 		t.Log("Shutdown cancel function cleared")
 	}
-}
\ No newline at end of file
+}
+// TestTwoFactorRateLimit 按登录挑战令牌计数，不同挑战互不影响，处理函数仍能读取请求体
+func TestTwoFactorRateLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("login/2fa", TwoFactorRateLimit(), func(c *gin.Context) {
+		var data struct {
+			MfaToken string `json:"mfa_token"`
+		}
+		if err := c.ShouldBindJSON(&data); err != nil || data.MfaToken == "" {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		c.Status(http.StatusOK)
+	})
+	post := func(token string) int {
+		w := httptest.NewRecorder()
+		body := strings.NewReader(`{"mfa_token":"` + token + `","code":"000000"}`)
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/login/2fa", body))
+		return w.Code
+	}
+
+	for i := 0; i < twoFactorMaxTries; i++ {
+		if code := post("challenge-a"); code != http.StatusOK {
+			t.Fatalf("第 %d 次: %d", i+1, code)
+		}
+	}
+	if code := post("challenge-a"); code != http.StatusTooManyRequests {
+		t.Errorf("超过次数: %d, want 429", code)
+	}
+	if code := post("challenge-b"); code != http.StatusOK {
+		t.Errorf("新的挑战: %d, want 200", code)
+	}
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"yanblog/utils"
 	"yanblog/utils/errmsg"
 )
 
@@ -27,3 +28,45 @@ func CheckArtTitleWithId(id int, title string) int {
 	}
 	return errmsg.SUCCESS
 }
+
+// 导入文章时匹配已有文章的方式
+const (
+	ArtMatchID    = "id"    // Front Matter 中的 id
+	ArtMatchSlug  = "slug"  // 别名（包括文章改名前的历史别名）
+	ArtMatchTitle = "title" // 标题
+)
+
+// FindArtForImport 查找导入的文章对应的已有文章，依次按 ID、别名和标题匹配
+// 参数: id - Front Matter 中的文章ID（0 表示未指定）, slug - 别名, title - 标题
+// 返回: 已有文章ID（0 表示没有匹配）和匹配方式
+func FindArtForImport(id uint, slug string, title string) (uint, string) {
+	var art Article
+	if id > 0 && db.Select("id").Where("id = ?", id).First(&art).Error == nil {
+		return art.ID, ArtMatchID
+	}
+	if s := utils.Slugify(slug); s != "" {
+		if isNumericSlug(s) {
+			s = SlugKindArticle + "-" + s
+		}
+		if db.Select("id").Where("slug = ?", s).First(&art).Error == nil {
+			return art.ID, ArtMatchSlug
+		}
+		if targetID, ok := findSlugHistory(SlugKindArticle, s); ok {
+			return targetID, ArtMatchSlug
+		}
+	}
+	if title != "" && db.Select("id").Where("title = ?", title).Order("id").First(&art).Error == nil {
+		return art.ID, ArtMatchTitle
+	}
+	return 0, ""
+}
+
+// IsUploadReferenced 判断上传文件是否被其他文章引用（已删除的文章在删除时已释放引用，不计入）
+// 参数: url - 站内地址，如 /uploads/article/a.png, excludeID - 排除的文章ID
+func IsUploadReferenced(url string, excludeID uint) bool {
+	var count int64
+	db.Model(&Article{}).
+		Where("id <> ? AND (content LIKE ? OR img = ? OR pdf_url = ?)", excludeID, "%"+url+"%", url, url).
+		Count(&count)
+	return count > 0
+}
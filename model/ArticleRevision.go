@@ -0,0 +1,114 @@
+package model
+
+import (
+	"time"
+	"yanblog/utils/errmsg"
+
+	"gorm.io/gorm"
+)
+
+// ArticleRevision 文章修订记录（每次保存文章时记录一份快照）
+type ArticleRevision struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	ArticleID uint      `gorm:"not null;index" json:"article_id"`
+	Title     string    `gorm:"type:varchar(100);not null" json:"title"`
+	Cid       int       `gorm:"type:int" json:"cid"`
+	Desc      string    `gorm:"type:varchar(200)" json:"desc"`
+	Content   string    `gorm:"type:longtext" json:"content,omitempty"`
+	Img       string    `gorm:"type:varchar(100)" json:"img"`
+	Type      int       `gorm:"type:int;default:1" json:"type"`
+	PdfUrl    string    `gorm:"type:varchar(200)" json:"pdf_url"`
+	Tags      string    `gorm:"type:varchar(200)" json:"tags"`
+	Username  string    `gorm:"type:varchar(20);index" json:"username"` // 产生该版本的用户（空表示历史数据或系统导入）
+	Note      string    `gorm:"type:varchar(100)" json:"note"`          // 备注，如"恢复自版本 #3"
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+}
+
+// SaveArtRevision 为文章当前内容保存一份修订快照
+// 参数: id - 文章ID, username - 操作用户, note - 备注
+// 返回: 状态码
+func SaveArtRevision(id int, username string, note string) int {
+	var art Article
+	if err := db.Where("id = ?", id).First(&art).Error; err != nil {
+		return errmsg.ERROR_ART_NOT_EXIST
+	}
+	return createArtRevision(art.ID, &art, username, note)
+}
+
+// AddArtRevision 将给定内容保存为文章的新修订版本，不修改文章当前内容（用于导入时保留为新版本，可对比后恢复）
+// 参数: id - 文章ID, data - 修订内容, username - 操作用户, note - 备注
+// 返回: 状态码
+func AddArtRevision(id int, data *Article, username string, note string) int {
+	var count int64
+	if db.Model(&Article{}).Where("id = ?", id).Count(&count); count == 0 {
+		return errmsg.ERROR_ART_NOT_EXIST
+	}
+	EnsureArtBaseRevision(id)
+	return createArtRevision(uint(id), data, username, note)
+}
+
+func createArtRevision(id uint, art *Article, username string, note string) int {
+	rev := ArticleRevision{
+		ArticleID: id,
+		Title:     art.Title,
+		Cid:       art.Cid,
+		Desc:      art.Desc,
+		Content:   art.Content,
+		Img:       art.Img,
+		Type:      art.Type,
+		PdfUrl:    art.PdfUrl,
+		Tags:      art.Tags,
+		Username:  username,
+		Note:      note,
+	}
+	if err := db.Create(&rev).Error; err != nil {
+		return errmsg.ERROR
+	}
+	return errmsg.SUCCESS
+}
+
+// EnsureArtBaseRevision 文章尚无修订记录时，先保存当前内容作为初始版本
+// 用于兼容引入修订功能之前创建的文章，保证第一次编辑前的内容不丢失
+func EnsureArtBaseRevision(id int) {
+	var count int64
+	db.Model(&ArticleRevision{}).Where("article_id = ?", id).Count(&count)
+	if count == 0 {
+		SaveArtRevision(id, "", "初始版本")
+	}
+}
+
+// GetArtRevisions 查询文章的修订列表（不含正文，按时间倒序）
+// 参数: id - 文章ID, pageSize - 每页数量, pageNum - 页码
+// 返回: 修订列表和总数
+func GetArtRevisions(id int, pageSize int, pageNum int) ([]ArticleRevision, int64) {
+	var revs []ArticleRevision
+	var total int64
+
+	query := db.Model(&ArticleRevision{}).Where("article_id = ?", id)
+	query.Count(&total)
+
+	query = query.Omit("content").Order("id DESC")
+
+	var err error
+	if pageSize == -1 || pageNum == -1 {
+		err = query.Find(&revs).Error
+	} else {
+		err = query.Limit(pageSize).Offset((pageNum - 1) * pageSize).Find(&revs).Error
+	}
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, 0
+	}
+	return revs, total
+}
+
+// GetArtRevision 查询单个修订（必须属于指定文章）
+// 参数: artID - 文章ID, revID - 修订ID
+// 返回: 修订信息和状态码
+func GetArtRevision(artID int, revID int) (ArticleRevision, int) {
+	var rev ArticleRevision
+	err := db.Where("id = ? AND article_id = ?", revID, artID).First(&rev).Error
+	if err != nil {
+		return rev, errmsg.ERROR_REVISION_NOT_EXIST
+	}
+	return rev, errmsg.SUCCESS
+}
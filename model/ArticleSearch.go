@@ -0,0 +1,455 @@
+package model
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"unicode/utf8"
+	"yanblog/utils"
+	"yanblog/utils/errmsg"
+
+	"gorm.io/gorm/clause"
+)
+
+// 全文检索说明：
+//   - SQLite 使用 FTS5 虚拟表 article_fts（trigram 分词，支持中文子串匹配），由触发器与 article 表保持同步；
+//     mattn/go-sqlite3 需使用 -tags sqlite_fts5 编译才包含 FTS5，未启用时自动退化为 LIKE 查询
+//   - MySQL 使用 ngram 解析器的 FULLTEXT 索引，由数据库自动维护
+//   - 高亮片段中的命中词使用 <mark> 包裹，其余内容均已做 HTML 转义
+
+const (
+	searchIndexName   = "ft_article_search"
+	highlightOpen     = "\x02" // 高亮起始占位符，转义后替换为 <mark>
+	highlightClose    = "\x03" // 高亮结束占位符，转义后替换为 </mark>
+	snippetRadius     = 60     // Go 侧生成片段时命中词前后保留的字符数
+	trigramMinRunes   = 3      // trigram 分词要求每个检索词至少 3 个字符
+	searchSnippetSize = 32     // FTS5 snippet() 返回的最大词元数
+)
+
+// searchIndexReady 全文索引是否可用（不可用时使用 LIKE 查询）
+var searchIndexReady bool
+
+// isSQLite 判断当前是否使用 SQLite 数据库
+func isSQLite() bool {
+	return strings.ToUpper(utils.ServerConfig.Database.Db) == "SQLITE"
+}
+
+// descColumn 返回按方言转义后的 desc 列名（desc 是 SQL 关键字）
+func descColumn() string {
+	if isSQLite() {
+		return `"desc"`
+	}
+	return "`desc`"
+}
+
+// initSearchIndex 初始化全文索引（在 InitDB 中调用）
+func initSearchIndex() {
+	if isSQLite() {
+		searchIndexReady = initSQLiteSearchIndex()
+	} else {
+		searchIndexReady = initMySQLSearchIndex()
+	}
+	if !searchIndexReady {
+		fmt.Println("⚠️  全文索引不可用，文章搜索将使用 LIKE 查询")
+	}
+}
+
+func initSQLiteSearchIndex() bool {
+	err := db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS article_fts USING fts5(title, summary, tags, content, tokenize='trigram')`).Error
+	if err != nil {
+		fmt.Println("⚠️  SQLite 未启用 FTS5（编译时需添加 -tags sqlite_fts5）:", err)
+		// 删除旧触发器，避免其引用不可用的 FTS5 表导致文章写入失败
+		for _, name := range []string{"article_fts_ai", "article_fts_ad", "article_fts_au"} {
+			db.Exec("DROP TRIGGER IF EXISTS " + name)
+		}
+		return false
+	}
+
+	triggers := []string{
+		`CREATE TRIGGER IF NOT EXISTS article_fts_ai AFTER INSERT ON article BEGIN
+			INSERT INTO article_fts(rowid, title, summary, tags, content) VALUES (new.id, new.title, new."desc", new.tags, new.content);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS article_fts_ad AFTER DELETE ON article BEGIN
+			DELETE FROM article_fts WHERE rowid = old.id;
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS article_fts_au AFTER UPDATE OF title, "desc", tags, content ON article BEGIN
+			DELETE FROM article_fts WHERE rowid = old.id;
+			INSERT INTO article_fts(rowid, title, summary, tags, content) VALUES (new.id, new.title, new."desc", new.tags, new.content);
+		END`,
+	}
+	for _, sql := range triggers {
+		if err := db.Exec(sql).Error; err != nil {
+			fmt.Println("创建全文索引触发器失败:", err)
+			return false
+		}
+	}
+
+	// 启动时重建一次索引，覆盖未启用 FTS5 期间产生的修改
+	if code := rebuildSQLiteSearchIndex(); code != errmsg.SUCCESS {
+		return false
+	}
+	return true
+}
+
+func initMySQLSearchIndex() bool {
+	var count int64
+	db.Raw(`SELECT COUNT(*) FROM information_schema.statistics
+		WHERE table_schema = DATABASE() AND table_name = 'article' AND index_name = ?`, searchIndexName).Scan(&count)
+	if count > 0 {
+		return true
+	}
+
+	err := db.Exec(fmt.Sprintf("ALTER TABLE article ADD FULLTEXT INDEX %s (title, `desc`, tags, content) WITH PARSER ngram", searchIndexName)).Error
+	if err != nil {
+		fmt.Println("创建 FULLTEXT 索引失败:", err)
+		return false
+	}
+	return true
+}
+
+func rebuildSQLiteSearchIndex() int {
+	tx := db.Begin()
+	if err := tx.Exec("DELETE FROM article_fts").Error; err != nil {
+		tx.Rollback()
+		return errmsg.ERROR
+	}
+	err := tx.Exec(`INSERT INTO article_fts(rowid, title, summary, tags, content)
+		SELECT id, title, "desc", tags, content FROM article`).Error
+	if err != nil {
+		tx.Rollback()
+		return errmsg.ERROR
+	}
+	if err := tx.Commit().Error; err != nil {
+		return errmsg.ERROR
+	}
+	return errmsg.SUCCESS
+}
+
+// RebuildSearchIndex 重建全文索引（MySQL 由数据库自动维护，无需重建）
+// 返回: 状态码
+func RebuildSearchIndex() int {
+	if !searchIndexReady {
+		return errmsg.ERROR_SEARCH_NO_INDEX
+	}
+	if isSQLite() {
+		return rebuildSQLiteSearchIndex()
+	}
+	return errmsg.SUCCESS
+}
+
+// searchHit 全文检索命中的文章ID及高亮信息
+type searchHit struct {
+	ID      uint    `gorm:"column:id"`
+	Score   float64 `gorm:"column:score"`
+	TitleHL string  `gorm:"column:title_hl"`
+	Snippet string  `gorm:"column:snippet"`
+}
+
+// searchArticleFullText 按相关度搜索已发布文章，并填充高亮标题和内容片段
+// 参数: keyword - 搜索关键词, cid - 分类ID, pageSize - 每页数量, pageNum - 页码
+// 返回: 文章列表、状态码和总数
+func searchArticleFullText(keyword string, cid int, pageSize int, pageNum int) ([]Article, int, int64) {
+	terms := splitSearchTerms(keyword)
+	if len(terms) == 0 {
+		return []Article{}, errmsg.SUCCESS, 0
+	}
+
+	var hits []searchHit
+	var total int64
+	var err error
+
+	switch {
+	case !searchIndexReady || (isSQLite() && hasShortTerm(terms)):
+		return searchArticleLike(terms, cid, pageSize, pageNum)
+	case isSQLite():
+		hits, total, err = searchSQLite(terms, cid, pageSize, pageNum)
+	default:
+		hits, total, err = searchMySQL(terms, cid, pageSize, pageNum)
+	}
+	if err != nil {
+		return nil, errmsg.ERROR, 0
+	}
+	if len(hits) == 0 {
+		return []Article{}, errmsg.SUCCESS, total
+	}
+
+	ids := make([]uint, len(hits))
+	for i, h := range hits {
+		ids[i] = h.ID
+	}
+	var arts []Article
+	if err := db.Preload("Category").Where("id IN ?", ids).Find(&arts).Error; err != nil {
+		return nil, errmsg.ERROR, 0
+	}
+	byID := make(map[uint]Article, len(arts))
+	for _, a := range arts {
+		byID[a.ID] = a
+	}
+
+	// 按相关度顺序组装结果
+	result := make([]Article, 0, len(hits))
+	for _, h := range hits {
+		art, ok := byID[h.ID]
+		if !ok {
+			continue
+		}
+		art.Score = h.Score
+		if h.TitleHL != "" {
+			art.TitleHighlight = renderHighlight(h.TitleHL)
+		} else {
+			art.TitleHighlight = highlightTerms(art.Title, terms)
+		}
+		if h.Snippet != "" {
+			art.Snippet = renderHighlight(h.Snippet)
+		} else {
+			art.Snippet = buildSnippet(searchableText(art), terms)
+		}
+		result = append(result, art)
+	}
+	return result, errmsg.SUCCESS, total
+}
+
+func searchSQLite(terms []string, cid int, pageSize int, pageNum int) ([]searchHit, int64, error) {
+	quoted := make([]string, len(terms))
+	for i, t := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(t, `"`, `""`) + `"`
+	}
+	match := strings.Join(quoted, " AND ")
+
+	where := `article_fts MATCH ? AND article.status = ? AND article.deleted_at IS NULL`
+	args := []interface{}{match, ArticleStatusPublished}
+	if cid > 0 {
+		where += " AND article.cid = ?"
+		args = append(args, cid)
+	}
+
+	var total int64
+	err := db.Raw(`SELECT COUNT(*) FROM article_fts JOIN article ON article.id = article_fts.rowid WHERE `+where, args...).Scan(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// bm25 越小越相关；标题权重最高，其次是摘要和标签
+	sql := fmt.Sprintf(`SELECT article_fts.rowid AS id,
+			-bm25(article_fts, 10.0, 4.0, 4.0, 1.0) AS score,
+			highlight(article_fts, 0, char(2), char(3)) AS title_hl,
+			snippet(article_fts, -1, char(2), char(3), '…', %d) AS snippet
+		FROM article_fts JOIN article ON article.id = article_fts.rowid
+		WHERE %s ORDER BY score DESC`, searchSnippetSize, where)
+	sql, args = appendLimit(sql, args, pageSize, pageNum)
+
+	var hits []searchHit
+	err = db.Raw(sql, args...).Scan(&hits).Error
+	return hits, total, err
+}
+
+func searchMySQL(terms []string, cid int, pageSize int, pageNum int) ([]searchHit, int64, error) {
+	// 布尔模式：每个词都必须出现（与 SQLite 的 AND 语义一致）
+	quoted := make([]string, len(terms))
+	for i, t := range terms {
+		quoted[i] = `+"` + strings.ReplaceAll(t, `"`, "") + `"`
+	}
+	against := strings.Join(quoted, " ")
+	matchExpr := "MATCH(title, `desc`, tags, content) AGAINST (? IN BOOLEAN MODE)"
+
+	where := matchExpr + " AND status = ? AND deleted_at IS NULL"
+	args := []interface{}{against, ArticleStatusPublished}
+	if cid > 0 {
+		where += " AND cid = ?"
+		args = append(args, cid)
+	}
+
+	var total int64
+	if err := db.Raw("SELECT COUNT(*) FROM article WHERE "+where, args...).Scan(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	sql := fmt.Sprintf("SELECT id, %s AS score FROM article WHERE %s ORDER BY score DESC", matchExpr, where)
+	sql, args = appendLimit(sql, append([]interface{}{against}, args...), pageSize, pageNum)
+
+	var hits []searchHit
+	err := db.Raw(sql, args...).Scan(&hits).Error
+	return hits, total, err
+}
+
+// searchArticleLike 全文索引不可用或检索词过短时的 LIKE 查询（覆盖标题、摘要、标签和正文）
+func searchArticleLike(terms []string, cid int, pageSize int, pageNum int) ([]Article, int, int64) {
+	var articleList []Article
+	var total int64
+
+	query := publishedScope(db.Preload("Category"))
+	titleMatch := make([]string, 0, len(terms))
+	titleArgs := make([]interface{}, 0, len(terms))
+	for _, t := range terms {
+		like := "%" + strings.ToLower(t) + "%"
+		query = query.Where(fmt.Sprintf("LOWER(title) LIKE ? OR LOWER(%s) LIKE ? OR LOWER(tags) LIKE ? OR LOWER(content) LIKE ?", descColumn()),
+			like, like, like, like)
+		titleMatch = append(titleMatch, "LOWER(title) LIKE ?")
+		titleArgs = append(titleArgs, like)
+	}
+	if cid > 0 {
+		query = query.Where("cid = ?", cid)
+	}
+
+	query.Model(&Article{}).Count(&total)
+
+	// 标题命中的排在前面，其余按发布时间倒序
+	order := fmt.Sprintf("CASE WHEN %s THEN 0 ELSE 1 END, created_at DESC", strings.Join(titleMatch, " AND "))
+	query = query.Clauses(clause.OrderBy{Expression: clause.Expr{SQL: order, Vars: titleArgs, WithoutParentheses: true}})
+
+	var err error
+	if pageSize == -1 || pageNum == -1 {
+		err = query.Find(&articleList).Error
+	} else {
+		err = query.Limit(pageSize).Offset((pageNum - 1) * pageSize).Find(&articleList).Error
+	}
+	if err != nil {
+		return nil, errmsg.ERROR, 0
+	}
+
+	for i := range articleList {
+		articleList[i].TitleHighlight = highlightTerms(articleList[i].Title, terms)
+		articleList[i].Snippet = buildSnippet(searchableText(articleList[i]), terms)
+	}
+	return articleList, errmsg.SUCCESS, total
+}
+
+// appendLimit 为原生 SQL 追加分页条件
+func appendLimit(sql string, args []interface{}, pageSize int, pageNum int) (string, []interface{}) {
+	if pageSize == -1 || pageNum == -1 {
+		return sql, args
+	}
+	return sql + " LIMIT ? OFFSET ?", append(args, pageSize, (pageNum-1)*pageSize)
+}
+
+// splitSearchTerms 按空白拆分检索词并去重
+func splitSearchTerms(keyword string) []string {
+	seen := make(map[string]bool)
+	var terms []string
+	for _, t := range strings.Fields(keyword) {
+		key := strings.ToLower(t)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		terms = append(terms, t)
+	}
+	return terms
+}
+
+func hasShortTerm(terms []string) bool {
+	for _, t := range terms {
+		if utf8.RuneCountInString(t) < trigramMinRunes {
+			return true
+		}
+	}
+	return false
+}
+
+// searchableText 生成片段时使用的文本：优先正文，正文为空时使用摘要
+func searchableText(art Article) string {
+	if strings.TrimSpace(art.Content) != "" {
+		return art.Content
+	}
+	return art.Desc
+}
+
+// renderHighlight 转义文本并将高亮占位符替换为 <mark> 标签
+func renderHighlight(s string) string {
+	s = html.EscapeString(s)
+	s = strings.ReplaceAll(s, highlightOpen, "<mark>")
+	return strings.ReplaceAll(s, highlightClose, "</mark>")
+}
+
+// highlightTerms 在文本中标记所有检索词（不区分大小写），返回转义后的 HTML
+func highlightTerms(text string, terms []string) string {
+	runes := []rune(text)
+	lower := []rune(strings.ToLower(text))
+	if len(lower) != len(runes) {
+		// 极少数字符大小写转换后长度变化，此时不做高亮
+		return html.EscapeString(text)
+	}
+
+	type span struct{ start, end int }
+	var spans []span
+	for _, t := range terms {
+		tr := []rune(strings.ToLower(t))
+		if len(tr) == 0 {
+			continue
+		}
+		for i := 0; i+len(tr) <= len(lower); i++ {
+			if string(lower[i:i+len(tr)]) == string(tr) {
+				spans = append(spans, span{i, i + len(tr)})
+				i += len(tr) - 1
+			}
+		}
+	}
+	if len(spans) == 0 {
+		return html.EscapeString(text)
+	}
+
+	// 合并重叠区间
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+	merged := []span{spans[0]}
+	for _, s := range spans[1:] {
+		last := &merged[len(merged)-1]
+		if s.start <= last.end {
+			if s.end > last.end {
+				last.end = s.end
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+
+	var b strings.Builder
+	pos := 0
+	for _, s := range merged {
+		b.WriteString(string(runes[pos:s.start]))
+		b.WriteString(highlightOpen)
+		b.WriteString(string(runes[s.start:s.end]))
+		b.WriteString(highlightClose)
+		pos = s.end
+	}
+	b.WriteString(string(runes[pos:]))
+	return renderHighlight(b.String())
+}
+
+// buildSnippet 截取首个命中词附近的文本作为片段并高亮
+func buildSnippet(text string, terms []string) string {
+	runes := []rune(text)
+	lower := strings.ToLower(text)
+
+	first := -1
+	for _, t := range terms {
+		if idx := strings.Index(lower, strings.ToLower(t)); idx >= 0 {
+			runeIdx := utf8.RuneCountInString(lower[:idx])
+			if first == -1 || runeIdx < first {
+				first = runeIdx
+			}
+		}
+	}
+	if first == -1 {
+		first = 0
+	}
+
+	start := first - snippetRadius
+	if start < 0 {
+		start = 0
+	}
+	end := first + snippetRadius*2
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	snippet := strings.Join(strings.Fields(string(runes[start:end])), " ")
+	result := highlightTerms(snippet, terms)
+	if start > 0 {
+		result = "…" + result
+	}
+	if end < len(runes) {
+		result += "…"
+	}
+	return result
+}
@@ -1,7 +1,9 @@
 package model
 
 import (
+	"fmt"
 	"strings"
+	"time"
 	"yanblog/utils"
 	"yanblog/utils/errmsg"
 
@@ -12,7 +14,8 @@ type Article struct {
 	Category Category `gorm:"foreignkey:Cid"`
 	gorm.Model
 	Title     string `gorm:"type:varchar(100);not null;index" json:"title"` // 添加索引，优化搜索性能
-	Cid       int    `gorm:"type:int;not null;index" json:"cid"`           // 添加索引，优化分类筛选
+	Slug      string `gorm:"type:varchar(100);uniqueIndex" json:"slug"`     // URL 别名，留空时根据标题自动生成
+	Cid       int    `gorm:"type:int;not null;index" json:"cid"`            // 添加索引，优化分类筛选
 	Desc      string `gorm:"type:varchar(200)" json:"desc"`
 	Content   string `gorm:"type:longtext" json:"content"`
 	Img       string `gorm:"type:varchar(100)" json:"img"`
@@ -22,6 +25,64 @@ type Article struct {
 	Views     int    `gorm:"type:int;default:0;index" json:"views"` // 添加索引，优化热门文章查询
 	Tags      string `gorm:"type:varchar(200);index" json:"tags"`   // 添加索引，优化标签搜索
 	TagModels []Tag  `gorm:"many2many:article_tags" json:"tag_models"`
+
+	Status    string     `gorm:"type:varchar(20);not null;default:published;index" json:"status"` // 文章状态: draft, published, scheduled, archived
+	PublishAt *time.Time `gorm:"index" json:"publish_at"`                                         // 发布时间（预约发布时为计划发布时间）
+
+	// 以下字段仅在搜索结果中返回，不存库
+	Score          float64 `gorm:"-" json:"score,omitempty"`           // 相关度
+	TitleHighlight string  `gorm:"-" json:"title_highlight,omitempty"` // 高亮后的标题（HTML）
+	Snippet        string  `gorm:"-" json:"snippet,omitempty"`         // 高亮后的内容片段（HTML）
+}
+
+// 文章状态
+const (
+	ArticleStatusDraft     = "draft"     // 草稿
+	ArticleStatusPublished = "published" // 已发布
+	ArticleStatusScheduled = "scheduled" // 预约发布
+	ArticleStatusArchived  = "archived"  // 已归档（不在前台展示）
+)
+
+// IsValidArtStatus 判断文章状态是否合法
+func IsValidArtStatus(status string) bool {
+	switch status {
+	case ArticleStatusDraft, ArticleStatusPublished, ArticleStatusScheduled, ArticleStatusArchived:
+		return true
+	}
+	return false
+}
+
+// normalizeArtStatus 规范化文章状态和发布时间
+// 未指定状态时默认为已发布；预约发布必须指定发布时间，且时间已过的直接视为已发布
+// 参数: status - 文章状态, publishAt - 发布时间, now - 当前时间
+// 返回: 规范化后的状态、发布时间和状态码
+func normalizeArtStatus(status string, publishAt *time.Time, now time.Time) (string, *time.Time, int) {
+	if status == "" {
+		status = ArticleStatusPublished
+	}
+	if !IsValidArtStatus(status) {
+		return status, publishAt, errmsg.ERROR_ART_STATUS_WRONG
+	}
+
+	switch status {
+	case ArticleStatusScheduled:
+		if publishAt == nil || publishAt.IsZero() {
+			return status, publishAt, errmsg.ERROR_ART_PUBLISH_TIME
+		}
+		if !publishAt.After(now) {
+			status = ArticleStatusPublished
+		}
+	case ArticleStatusPublished:
+		if publishAt == nil || publishAt.IsZero() {
+			publishAt = &now
+		}
+	}
+	return status, publishAt, errmsg.SUCCESS
+}
+
+// publishedScope 仅查询已发布的文章（所有前台查询必须使用）
+func publishedScope(query *gorm.DB) *gorm.DB {
+	return query.Where("status = ?", ArticleStatusPublished)
 }
 
 // parseTags 解析标签字符串为 Tag 模型切片（公共函数，消除重复代码）
@@ -52,6 +113,19 @@ func parseTags(tagsStr string) []Tag {
 // 参数: data - 文章信息
 // 返回: 状态码
 func CreateArt(data *Article) int {
+	status, publishAt, code := normalizeArtStatus(data.Status, data.PublishAt, time.Now())
+	if code != errmsg.SUCCESS {
+		return code
+	}
+	data.Status = status
+	data.PublishAt = publishAt
+
+	slug, ok := resolveSlug(SlugKindArticle, data.Slug, data.Title, "", "", 0)
+	if !ok {
+		return errmsg.ERROR_ART_SLUG_USED
+	}
+	data.Slug = slug
+
 	// 处理标签逻辑：使用公共的解析函数
 	data.TagModels = parseTags(data.Tags)
 
@@ -63,20 +137,19 @@ func CreateArt(data *Article) int {
 }
 
 // SearchArticle 搜索文章
+// 有关键词时走全文索引按相关度排序，并返回高亮片段；无关键词时按置顶和发布时间排序
 // 参数: keyword - 搜索关键词, cid - 分类ID, pageSize - 每页数量, pageNum - 页码
 // 返回: 文章列表、状态码和总数
 func SearchArticle(keyword string, cid int, pageSize int, pageNum int) ([]Article, int, int64) {
+	if keyword = strings.TrimSpace(keyword); keyword != "" {
+		return searchArticleFullText(keyword, cid, pageSize, pageNum)
+	}
+
 	var articleList []Article
 	var total int64
 
 	// 构建查询条件
-	query := db.Preload("Category")
-
-	// 如果有关键词，则添加标题和描述的模糊搜索
-	if keyword != "" {
-		searchTerm := "%" + strings.ToLower(keyword) + "%"
-		query = query.Where("LOWER(title) LIKE ? OR LOWER(desc) LIKE ? OR LOWER(tags) LIKE ?", searchTerm, searchTerm, searchTerm)
-	}
+	query := publishedScope(db.Preload("Category"))
 
 	// 如果有分类ID，则添加分类筛选
 	if cid > 0 {
@@ -112,7 +185,7 @@ func GetCateArt(id int, pageSize int, pageNum int) ([]Article, int, int64) {
 	var cateArtList []Article
 	var total int64
 
-	query := db.Preload("Category").Where("cid = ?", id).Order("top ASC, created_at DESC")
+	query := publishedScope(db.Preload("Category")).Where("cid = ?", id).Order("top ASC, created_at DESC")
 
 	// 先查询总数（性能优化：分离 Count 和 Find）
 	query.Model(&Article{}).Count(&total)
@@ -130,10 +203,41 @@ func GetCateArt(id int, pageSize int, pageNum int) ([]Article, int, int64) {
 	return cateArtList, errmsg.SUCCESS, total
 }
 
-// GetArtInfo 查询单个文章
+// GetArtInfo 查询单个文章（仅已发布）
 // 参数: id - 文章ID
 // 返回: 文章信息和状态码
 func GetArtInfo(id int) (Article, int) {
+	var art Article
+	err := publishedScope(db.Preload("Category")).Where("id = ?", id).First(&art).Error
+	if err != nil {
+		return art, errmsg.ERROR_ART_NOT_EXIST
+	}
+	return art, errmsg.SUCCESS
+}
+
+// GetArtInfoBySlug 通过 slug 查询单个文章（仅已发布）
+// slug 已变更时通过历史记录找到文章，由调用方跳转到当前 slug
+// 参数: slug - 文章 slug
+// 返回: 文章信息、是否为历史 slug 和状态码
+func GetArtInfoBySlug(slug string) (Article, bool, int) {
+	var art Article
+	err := publishedScope(db.Preload("Category")).Where("slug = ?", slug).First(&art).Error
+	if err == nil {
+		return art, false, errmsg.SUCCESS
+	}
+
+	id, ok := findSlugHistory(SlugKindArticle, slug)
+	if !ok {
+		return art, false, errmsg.ERROR_ART_NOT_EXIST
+	}
+	art, code := GetArtInfo(int(id))
+	return art, code == errmsg.SUCCESS, code
+}
+
+// GetArtInfoForAdmin 查询单个文章（后台使用，不限状态）
+// 参数: id - 文章ID
+// 返回: 文章信息和状态码
+func GetArtInfoForAdmin(id int) (Article, int) {
 	var art Article
 	err := db.Preload("Category").Where("id = ?", id).First(&art).Error
 	if err != nil {
@@ -155,7 +259,7 @@ func GetArt(pageSize int, pageNum int, excludeTop ...bool) ([]Article, int, int6
 	var articleList []Article
 	var total int64
 
-	query := db.Preload("Category").Order("top ASC, created_at DESC")
+	query := publishedScope(db.Preload("Category")).Order("top ASC, created_at DESC")
 
 	// 如果需要排除置顶文章
 	if len(excludeTop) > 0 && excludeTop[0] {
@@ -180,7 +284,7 @@ func GetArt(pageSize int, pageNum int, excludeTop ...bool) ([]Article, int, int6
 // GetHotArticles 查询热门文章
 func GetHotArticles(limit int) ([]Article, int) {
 	var articleList []Article
-	err := db.Preload("Category").Order("views DESC").Limit(limit).Find(&articleList).Error
+	err := publishedScope(db.Preload("Category")).Order("views DESC").Limit(limit).Find(&articleList).Error
 	if err != nil {
 		return nil, errmsg.ERROR
 	}
@@ -198,7 +302,7 @@ func GetRelatedArticles(id int, tags string) ([]Article, int) {
 	// 分割标签
 	tagList := strings.Split(tags, ",") // 假设前端传逗号分隔 e.g. "go,web"
 
-	tx := db.Preload("Category").Where("id != ?", id)
+	tx := publishedScope(db.Preload("Category")).Where("id != ?", id)
 
 	// 构建 OR 查询条件
 	var conditions []string
@@ -233,7 +337,7 @@ func GetTopArt(num int) ([]Article, int) {
 	var topArtList []Article
 	var err error
 
-	err = db.Preload("Category").Where("top > 0").Order("top ASC").Limit(num).Find(&topArtList).Error
+	err = publishedScope(db.Preload("Category")).Where("top > 0").Order("top ASC").Limit(num).Find(&topArtList).Error
 	if err != nil {
 		return nil, errmsg.ERROR
 	}
@@ -258,7 +362,7 @@ func GetArchive() ([]Archive, int) {
 		dateExpr = "DATE_FORMAT(created_at, '%Y-%m') as date"
 	}
 
-	err := db.Model(&Article{}).
+	err := publishedScope(db.Model(&Article{})).
 		Select(dateExpr + ", count(*) as count").
 		Group("date").
 		Order("date desc").
@@ -270,13 +374,23 @@ func GetArchive() ([]Archive, int) {
 	return archives, errmsg.SUCCESS
 }
 
-// EditArt 编辑文章
+// EditArt 编辑文章，释放修改后不再引用的上传文件
 // 参数: id - 文章ID, data - 更新的文章信息
 // 返回: 状态码
 func EditArt(id int, data *Article) int {
 	var art Article
+	var old Article
+	if err := db.Select("id", "title", "slug", "content", "img", "pdf_url").Where("id = ?", id).First(&old).Error; err != nil {
+		return errmsg.ERROR_ART_NOT_EXIST
+	}
+	slug, ok := resolveSlug(SlugKindArticle, data.Slug, data.Title, old.Title, old.Slug, old.ID)
+	if !ok {
+		return errmsg.ERROR_ART_SLUG_USED
+	}
+
 	var maps = make(map[string]interface{})
 	maps["title"] = data.Title
+	maps["slug"] = slug
 	maps["cid"] = data.Cid
 	maps["desc"] = data.Desc
 	maps["content"] = data.Content
@@ -298,25 +412,33 @@ func EditArt(id int, data *Article) int {
 	if err != nil {
 		return errmsg.ERROR
 	}
+	recordSlugChange(SlugKindArticle, old.ID, old.Slug, slug)
+	data.Slug = slug
 
 	// 更新关联
 	art.ID = uint(id)
 	db.Model(&art).Association("TagModels").Replace(newTags)
 
+	releaseArtUploads(old.ID, &old, data)
 	return errmsg.SUCCESS
 }
 
-// DeleteArt 删除文章
+// DeleteArt 删除文章，并释放文章引用的上传文件
 // 参数: id - 文章ID
 // 返回: 状态码
 func DeleteArt(id int) int {
 	var art Article
+	db.Select("id", "content", "img", "pdf_url").Where("id = ?", id).First(&art)
 	// 先清理文章-标签关联关系
 	db.Exec("DELETE FROM article_tags WHERE article_id = ?", id)
-	err = db.Where("id = ? ", id).Delete(&art).Error
+	deleteArtComments(id)
+	err = db.Where("id = ? ", id).Delete(&Article{}).Error
 	if err != nil {
 		return errmsg.ERROR
 	}
+	if art.ID != 0 {
+		releaseArtUploads(art.ID, &art, nil)
+	}
 	return errmsg.SUCCESS
 }
 
@@ -335,8 +457,32 @@ func BatchDeleteArts(ids []int) (deleted int, failed int) {
 // GetSitemapData 获取站点地图所需数据
 func GetSitemapData() ([]Article, int) {
 	var articles []Article
-	// 只查询 ID 和 UpdatedAt，减少数据量
-	err := db.Select("id", "updated_at").Find(&articles).Error
+	// 只查询 ID、Slug 和 UpdatedAt，减少数据量
+	err := publishedScope(db.Select("id", "slug", "updated_at")).Find(&articles).Error
+	if err != nil {
+		return nil, errmsg.ERROR
+	}
+	return articles, errmsg.SUCCESS
+}
+
+// GetFeedArticles 获取订阅源所需的最新已发布文章
+// 参数: cid - 分类ID（0 表示不限）, tag - 标签名（空表示不限）, limit - 最大数量
+// 返回: 文章列表（按发布时间倒序）和状态码
+func GetFeedArticles(cid int, tag string, limit int) ([]Article, int) {
+	var articles []Article
+
+	query := publishedScope(db.Preload("Category"))
+	if cid > 0 {
+		query = query.Where("cid = ?", cid)
+	}
+	if tag != "" {
+		query = query.Where("id IN (?)", db.Table("article_tags").
+			Select("article_tags.article_id").
+			Joins("JOIN tag ON tag.id = article_tags.tag_id").
+			Where("tag.name = ?", tag))
+	}
+
+	err := query.Order("COALESCE(publish_at, created_at) DESC").Limit(limit).Find(&articles).Error
 	if err != nil {
 		return nil, errmsg.ERROR
 	}
@@ -354,7 +500,7 @@ func GetRandomArticle() (Article, int) {
 		randFunc = "RANDOM()"
 	}
 
-	err := db.Preload("Category").Order(randFunc).Limit(1).First(&art).Error
+	err := publishedScope(db.Preload("Category")).Order(randFunc).Limit(1).First(&art).Error
 	if err != nil {
 		return art, errmsg.ERROR
 	}
@@ -366,13 +512,13 @@ func GetRandomArticle() (Article, int) {
 func GetAdjacentArticle(id int) (prev *Article, next *Article, code int) {
 	// 先获取当前文章的创建时间
 	var currentArt Article
-	if err := db.Select("id", "created_at").Where("id = ?", id).First(&currentArt).Error; err != nil {
+	if err := publishedScope(db.Select("id", "created_at")).Where("id = ?", id).First(&currentArt).Error; err != nil {
 		return nil, nil, errmsg.ERROR_ART_NOT_EXIST
 	}
 
 	// 查询上一篇（发布时间比当前文章更早的最新一篇）
 	var prevArt Article
-	err := db.Preload("Category").
+	err := publishedScope(db.Preload("Category")).
 		Where("created_at < ?", currentArt.CreatedAt).
 		Order("created_at DESC").
 		Limit(1).
@@ -383,7 +529,7 @@ func GetAdjacentArticle(id int) (prev *Article, next *Article, code int) {
 
 	// 查询下一篇（发布时间比当前文章更新的最早一篇）
 	var nextArt Article
-	err = db.Preload("Category").
+	err = publishedScope(db.Preload("Category")).
 		Where("created_at > ?", currentArt.CreatedAt).
 		Order("created_at ASC").
 		Limit(1).
@@ -394,3 +540,101 @@ func GetAdjacentArticle(id int) (prev *Article, next *Article, code int) {
 
 	return prev, next, errmsg.SUCCESS
 }
+
+// GetArtForAdmin 后台查询文章列表（不限状态）
+// 参数: status - 状态筛选（为空表示全部）, keyword - 标题关键词, pageSize - 每页数量, pageNum - 页码
+// 返回: 文章列表、状态码和总数
+func GetArtForAdmin(status string, keyword string, pageSize int, pageNum int) ([]Article, int, int64) {
+	var articleList []Article
+	var total int64
+
+	query := db.Preload("Category")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if keyword != "" {
+		query = query.Where("LOWER(title) LIKE ?", "%"+strings.ToLower(keyword)+"%")
+	}
+	query = query.Order("created_at DESC")
+
+	query.Model(&Article{}).Count(&total)
+
+	var err error
+	if pageSize == -1 || pageNum == -1 {
+		err = query.Find(&articleList).Error
+	} else {
+		err = query.Limit(pageSize).Offset((pageNum - 1) * pageSize).Find(&articleList).Error
+	}
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, errmsg.ERROR, 0
+	}
+	return articleList, errmsg.SUCCESS, total
+}
+
+// GetArtIDs 查询文章 ID 列表（批量导出用，不限状态时包含草稿等所有文章）
+// 参数: status - 文章状态（空表示不限）
+// 返回: 按创建时间倒序的文章 ID
+func GetArtIDs(status string) []int {
+	var ids []int
+	query := db.Model(&Article{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	query.Order("created_at DESC").Pluck("id", &ids)
+	return ids
+}
+
+// UpdateArtStatus 修改文章状态
+// 参数: id - 文章ID, status - 目标状态, publishAt - 发布时间（预约发布时必填）
+// 返回: 状态码
+func UpdateArtStatus(id int, status string, publishAt *time.Time) int {
+	var art Article
+	if err := db.Select("id", "publish_at").Where("id = ?", id).First(&art).Error; err != nil {
+		return errmsg.ERROR_ART_NOT_EXIST
+	}
+
+	// 重新发布时保留首次发布时间
+	if status == ArticleStatusPublished && publishAt == nil {
+		publishAt = art.PublishAt
+	}
+
+	status, publishAt, code := normalizeArtStatus(status, publishAt, time.Now())
+	if code != errmsg.SUCCESS {
+		return code
+	}
+
+	err := db.Model(&Article{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     status,
+		"publish_at": publishAt,
+	}).Error
+	if err != nil {
+		return errmsg.ERROR
+	}
+	return errmsg.SUCCESS
+}
+
+// PublishDueArticles 将到期的预约文章切换为已发布
+// 返回: 本次发布的文章数量
+func PublishDueArticles() int64 {
+	result := db.Model(&Article{}).
+		Where("status = ? AND publish_at <= ?", ArticleStatusScheduled, time.Now()).
+		Update("status", ArticleStatusPublished)
+	if result.Error != nil {
+		fmt.Println("预约文章发布失败:", result.Error)
+		return 0
+	}
+	return result.RowsAffected
+}
+
+// RunArticleScheduler 预约发布调度器，每分钟检查一次到期文章（在 main.go 中以 goroutine 启动）
+func RunArticleScheduler() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+
+	PublishDueArticles()
+	for range ticker.C {
+		if n := PublishDueArticles(); n > 0 {
+			fmt.Printf("已自动发布 %d 篇预约文章\n", n)
+		}
+	}
+}
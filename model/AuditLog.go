@@ -0,0 +1,120 @@
+package model
+
+import (
+	"strings"
+	"time"
+	"yanblog/utils/errmsg"
+
+	"gorm.io/gorm"
+)
+
+// AuditLog 后台操作审计日志（由 middlewares.Audit 为所有后台写操作自动记录）
+type AuditLog struct {
+	ID         uint      `gorm:"primarykey" json:"id"`
+	UserID     uint      `gorm:"index" json:"user_id"`
+	Username   string    `gorm:"type:varchar(20);index" json:"username"`
+	IP         string    `gorm:"type:varchar(45)" json:"ip"`
+	Method     string    `gorm:"type:varchar(10)" json:"method"`
+	Action     string    `gorm:"type:varchar(150);index" json:"action"`         // 路由，如 "DELETE /api/v1/article/:id"
+	Path       string    `gorm:"type:varchar(500)" json:"path"`                 // 实际请求路径（含查询参数）
+	Target     string    `gorm:"type:varchar(255)" json:"target"`               // 操作对象，如 "id=5"
+	Before     string    `gorm:"column:before_summary;type:text" json:"before"` // 修改前摘要
+	After      string    `gorm:"column:after_summary;type:text" json:"after"`   // 修改后摘要（默认为脱敏后的请求体）
+	HTTPStatus int       `json:"http_status"`
+	Status     int       `gorm:"index" json:"status"` // 业务状态码，200 表示成功
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
+
+// AuditQuery 审计日志查询条件
+type AuditQuery struct {
+	Username string    // 操作人（精确匹配）
+	Action   string    // 路由关键词（模糊匹配）
+	Keyword  string    // 在路径、对象、前后摘要中模糊搜索
+	Result   string    // success / fail，为空则不限
+	Start    time.Time // 起始时间（含）
+	End      time.Time // 结束时间（不含）
+}
+
+// CreateAuditLog 写入一条审计日志
+func CreateAuditLog(log *AuditLog) int {
+	if err := db.Create(log).Error; err != nil {
+		return errmsg.ERROR
+	}
+	return errmsg.SUCCESS
+}
+
+// applyAuditQuery 按查询条件构建审计日志查询
+func applyAuditQuery(query *gorm.DB, q AuditQuery) *gorm.DB {
+	if q.Username != "" {
+		query = query.Where("username = ?", q.Username)
+	}
+	if q.Action != "" {
+		query = query.Where("action LIKE ?", "%"+q.Action+"%")
+	}
+	if q.Keyword != "" {
+		kw := "%" + strings.ToLower(q.Keyword) + "%"
+		query = query.Where("(LOWER(path) LIKE ? OR LOWER(target) LIKE ? OR LOWER(before_summary) LIKE ? OR LOWER(after_summary) LIKE ?)", kw, kw, kw, kw)
+	}
+	switch q.Result {
+	case "success":
+		query = query.Where("status = ?", errmsg.SUCCESS)
+	case "fail":
+		query = query.Where("status <> ?", errmsg.SUCCESS)
+	}
+	if !q.Start.IsZero() {
+		query = query.Where("created_at >= ?", q.Start)
+	}
+	if !q.End.IsZero() {
+		query = query.Where("created_at < ?", q.End)
+	}
+	return query
+}
+
+// GetAuditLogs 分页查询审计日志（按时间倒序）
+// 参数: q - 查询条件, pageSize - 每页数量, pageNum - 页码
+// 返回: 日志列表和总数
+func GetAuditLogs(q AuditQuery, pageSize int, pageNum int) ([]AuditLog, int64) {
+	var logs []AuditLog
+	var total int64
+
+	query := applyAuditQuery(db.Model(&AuditLog{}), q)
+	query.Count(&total)
+
+	query = query.Order("id DESC")
+	var err error
+	if pageSize == -1 || pageNum == -1 {
+		err = query.Find(&logs).Error
+	} else {
+		err = query.Limit(pageSize).Offset((pageNum - 1) * pageSize).Find(&logs).Error
+	}
+	if err != nil {
+		return []AuditLog{}, 0
+	}
+	return logs, total
+}
+
+// EachAuditLog 按时间倒序分批遍历符合条件的审计日志（导出 CSV 用，避免一次性加载）
+// 参数: q - 查询条件, fn - 处理函数，返回 false 时停止遍历
+func EachAuditLog(q AuditQuery, fn func(AuditLog) bool) error {
+	const batchSize = 500
+	var lastID uint
+	for {
+		var logs []AuditLog
+		query := applyAuditQuery(db.Model(&AuditLog{}), q)
+		if lastID > 0 {
+			query = query.Where("id < ?", lastID)
+		}
+		if err := query.Order("id DESC").Limit(batchSize).Find(&logs).Error; err != nil {
+			return err
+		}
+		for _, log := range logs {
+			if !fn(log) {
+				return nil
+			}
+		}
+		if len(logs) < batchSize {
+			return nil
+		}
+		lastID = logs[len(logs)-1].ID
+	}
+}
@@ -0,0 +1,1038 @@
+package model
+
+import (
+	"archive/zip"
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"yanblog/utils"
+	"yanblog/utils/storage"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// 全站备份为一个 ZIP 归档，数据库以 JSON 导出，与数据库类型无关（SQLite 的备份可以恢复到 MySQL）：
+//
+//	manifest.json                   格式版本、各表记录数、归档内所有文件的大小和 SHA-256
+//	database/<表名>.json             数据表的全部记录（包括软删除的记录）
+//	files/uploads/...               上传文件（当前存储后端中的全部文件）
+//	files/about.md                  关于页面内容
+//	files/config/backend.yaml       后端配置
+//	files/config/frontend.yaml      前端配置
+//	files/data/upload_history.json  旧版本的 ZIP 导入历史（现保存在 upload_task 表中，恢复后自动导入）
+
+const (
+	BackupFormat  = "yanblog-backup"
+	BackupVersion = 1 // 归档格式版本，格式不兼容时递增
+
+	backupManifestName = "manifest.json"
+	backupDatabaseDir  = "database/"
+	backupFilesDir     = "files/"
+	// 恢复时的临时文件 / 目录前缀，备份时跳过
+	restoreTempPrefix = ".restore-"
+	// 恢复数据时每批写入的记录数
+	restoreBatchSize = 200
+)
+
+// ErrBackupInvalid 备份文件格式错误或校验失败
+var ErrBackupInvalid = errors.New("备份文件无效")
+
+// backupMu 备份和恢复互斥执行
+var backupMu sync.Mutex
+
+// AcquireBackupLock 获取备份 / 恢复锁，已有任务在执行时返回 false
+func AcquireBackupLock() bool {
+	return backupMu.TryLock()
+}
+
+// ReleaseBackupLock 释放备份 / 恢复锁
+func ReleaseBackupLock() {
+	backupMu.Unlock()
+}
+
+// BackupManifest 备份清单
+type BackupManifest struct {
+	Format    string           `json:"format"`
+	Version   int              `json:"version"`
+	CreatedAt time.Time        `json:"created_at"`
+	Database  string           `json:"database"` // 备份来源的数据库类型: sqlite / mysql
+	Tables    map[string]int64 `json:"tables"`   // 表名 -> 记录数
+	Files     []BackupFile     `json:"files"`    // 归档内除清单外的所有文件
+}
+
+// BackupFile 归档内的文件及其校验信息
+type BackupFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// backupTable 参与备份的数据表
+type backupTable struct {
+	model interface{} // 数据模型；为 nil 时表示多对多关联表
+	join  string      // 关联表名（只有整数外键列）
+}
+
+// backupTables 按恢复时的写入顺序排列，被外键引用的表在前
+var backupTables = []backupTable{
+	{model: &User{}},
+	{model: &Category{}},
+	{model: &Tag{}},
+	{model: &Article{}},
+	{join: "article_tags"},
+	{model: &ArticleRevision{}},
+	{model: &Comment{}},
+	{model: &UserSession{}},
+	{model: &UserTOTP{}},
+	{model: &UserRecoveryCode{}},
+	{model: &AuditLog{}},
+	{model: &SlugHistory{}},
+	{model: &UploadTask{}},
+	{model: &UploadBlob{}},
+	{model: &RecycleItem{}},
+}
+
+// parse 解析数据模型，关联表返回 nil
+func (t backupTable) parse(tx *gorm.DB) (*schema.Schema, error) {
+	if t.model == nil {
+		return nil, nil
+	}
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(t.model); err != nil {
+		return nil, err
+	}
+	return stmt.Schema, nil
+}
+
+// name 数据表名
+func (t backupTable) name(tx *gorm.DB) (string, error) {
+	s, err := t.parse(tx)
+	if err != nil || s == nil {
+		return t.join, err
+	}
+	return s.Table, nil
+}
+
+// backupSource 需要备份的文件或目录
+type backupSource struct {
+	name   string // 在归档 files/ 下的路径
+	local  string // 备份时读取的本地路径
+	target string // 恢复时写入的本地路径
+	dir    bool   // 上传文件：当前存储后端（storage.Current()）的全部内容，通过存储读写，local 和 target 不使用
+}
+
+func backupSources() []backupSource {
+	frontendConfig := utils.GetFrontEndConfigPath()
+	return []backupSource{
+		{name: "uploads", dir: true},
+		{name: "about.md", local: utils.AboutFilePath, target: utils.AboutFilePath},
+		// 备份当前生效的配置文件，恢复时与保存配置一样写入 config/backend/config.yaml
+		{name: "config/backend.yaml", local: utils.ConfigFilePath(), target: utils.GetConfigPath("config/backend/config.yaml")},
+		{name: "config/frontend.yaml", local: frontendConfig, target: frontendConfig},
+		{name: "data/upload_history.json", local: utils.UploadHistoryFile, target: utils.UploadHistoryFile},
+	}
+}
+
+// findBackupSource 查找归档内文件所属的备份来源，返回来源和在来源目录中的相对路径
+func findBackupSource(sources []backupSource, name string) (backupSource, string, bool) {
+	rel := strings.TrimPrefix(name, backupFilesDir)
+	for _, src := range sources {
+		if rel == src.name && !src.dir {
+			return src, "", true
+		}
+		if src.dir && strings.HasPrefix(rel, src.name+"/") {
+			return src, strings.TrimPrefix(rel, src.name+"/"), true
+		}
+	}
+	return backupSource{}, "", false
+}
+
+func databaseType() string {
+	if isSQLite() {
+		return "sqlite"
+	}
+	return "mysql"
+}
+
+// WriteBackup 将数据库和站点文件写入 ZIP 归档，调用方需先获取备份锁
+func WriteBackup(w io.Writer) (*BackupManifest, error) {
+	manifest := &BackupManifest{
+		Format:    BackupFormat,
+		Version:   BackupVersion,
+		CreatedAt: time.Now(),
+		Database:  databaseType(),
+		Tables:    make(map[string]int64),
+		Files:     []BackupFile{},
+	}
+	zw := zip.NewWriter(w)
+
+	// 所有数据表在同一个事务中导出，保证表之间的数据一致
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, t := range backupTables {
+			name, err := t.name(tx)
+			if err != nil {
+				return err
+			}
+			var rows int64
+			err = writeBackupEntry(zw, manifest, backupDatabaseDir+name+".json", manifest.CreatedAt, func(w io.Writer) error {
+				rows, err = dumpTable(tx, t, w)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("导出数据表 %s 失败: %w", name, err)
+			}
+			manifest.Tables[name] = rows
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, src := range backupSources() {
+		if err := addBackupSource(zw, manifest, src); err != nil {
+			return nil, fmt.Errorf("备份 %s 失败: %w", src.name, err)
+		}
+	}
+
+	// 清单最后写入，包含前面所有文件的校验值
+	mw, err := zw.CreateHeader(&zip.FileHeader{Name: backupManifestName, Method: zip.Deflate, Modified: manifest.CreatedAt})
+	if err != nil {
+		return nil, err
+	}
+	enc := json.NewEncoder(mw)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(manifest); err != nil {
+		return nil, err
+	}
+	return manifest, zw.Close()
+}
+
+// writeBackupEntry 写入归档内的一个文件，并将大小和校验值记录到清单
+func writeBackupEntry(zw *zip.Writer, manifest *BackupManifest, name string, modified time.Time, write func(w io.Writer) error) error {
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: modified})
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	counter := &countingWriter{}
+	if err := write(io.MultiWriter(w, h, counter)); err != nil {
+		return err
+	}
+	manifest.Files = append(manifest.Files, BackupFile{Path: name, Size: counter.n, SHA256: hex.EncodeToString(h.Sum(nil))})
+	return nil
+}
+
+type countingWriter struct{ n int64 }
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
+
+// addBackupSource 将一个文件或目录写入归档，不存在时跳过
+func addBackupSource(zw *zip.Writer, manifest *BackupManifest, src backupSource) error {
+	if src.dir {
+		return addBackupStorage(zw, manifest, src)
+	}
+	info, err := os.Stat(src.local)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(src.local)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return writeBackupEntry(zw, manifest, backupFilesDir+src.name, info.ModTime(), func(w io.Writer) error {
+		_, err := io.Copy(w, f)
+		return err
+	})
+}
+
+// addBackupStorage 将存储中的所有文件写入归档（S3、WebDAV 等远程存储同样适用）
+func addBackupStorage(zw *zip.Writer, manifest *BackupManifest, src backupSource) error {
+	store := storage.Current()
+	err := storage.Walk(store, "", func(info storage.FileInfo) error {
+		if strings.HasPrefix(info.Name, restoreTempPrefix) {
+			if info.IsDir {
+				return storage.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir {
+			return nil // 目录随文件一起恢复
+		}
+		r, err := store.Get(info.Path)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		return writeBackupEntry(zw, manifest, backupFilesDir+src.name+"/"+info.Path, info.ModTime, func(w io.Writer) error {
+			_, err := io.Copy(w, r)
+			return err
+		})
+	})
+	if storage.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// dumpTable 以 JSON 数组导出数据表的全部记录，返回记录数
+func dumpTable(tx *gorm.DB, t backupTable, w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	var count int64
+	writeRow := func(row map[string]interface{}) error {
+		data, err := json.Marshal(row)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			bw.WriteString(",")
+		}
+		bw.WriteString("\n")
+		bw.Write(data)
+		count++
+		return nil
+	}
+
+	bw.WriteString("[")
+	s, err := t.parse(tx)
+	if err != nil {
+		return 0, err
+	}
+	if s == nil {
+		var rows []map[string]interface{}
+		if err := tx.Table(t.join).Find(&rows).Error; err != nil {
+			return 0, err
+		}
+		for _, row := range rows {
+			if err := writeRow(row); err != nil {
+				return 0, err
+			}
+		}
+	} else {
+		// 按主键分批读取，避免一次性加载整张表
+		batch := reflect.New(reflect.SliceOf(s.ModelType))
+		err = tx.Unscoped().FindInBatches(batch.Interface(), 500, func(_ *gorm.DB, _ int) error {
+			rows := batch.Elem()
+			for i := 0; i < rows.Len(); i++ {
+				row := make(map[string]interface{}, len(s.DBNames))
+				for _, name := range s.DBNames {
+					row[name], _ = s.FieldsByDBName[name].ValueOf(context.Background(), rows.Index(i))
+				}
+				if err := writeRow(row); err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+		if err != nil {
+			return 0, err
+		}
+	}
+	bw.WriteString("\n]\n")
+	return count, bw.Flush()
+}
+
+// BackupArchive 已通过校验的备份归档
+type BackupArchive struct {
+	Manifest *BackupManifest
+	reader   *zip.ReadCloser
+	entries  map[string]*zip.File
+}
+
+// OpenBackup 打开并校验备份归档：格式版本、文件路径以及每个文件的大小和 SHA-256
+func OpenBackup(file string) (*BackupArchive, error) {
+	rc, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrBackupInvalid, err)
+	}
+	manifest, entries, err := verifyBackup(&rc.Reader)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	return &BackupArchive{Manifest: manifest, reader: rc, entries: entries}, nil
+}
+
+// Close 关闭归档文件
+func (a *BackupArchive) Close() error {
+	return a.reader.Close()
+}
+
+// backupTableFileRegex 数据表文件名
+var backupTableFileRegex = regexp.MustCompile(`^` + backupDatabaseDir + `[a-z0-9_]+\.json$`)
+
+// validBackupPath 判断归档内的文件路径是否合法：不能包含 .. 等路径穿越，且必须属于数据表或已知的备份来源
+func validBackupPath(sources []backupSource, name string) bool {
+	if name == "" || strings.Contains(name, "\\") || path.IsAbs(name) || path.Clean(name) != name {
+		return false
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." || strings.HasPrefix(part, restoreTempPrefix) {
+			return false
+		}
+	}
+	if strings.HasPrefix(name, backupDatabaseDir) {
+		return backupTableFileRegex.MatchString(name)
+	}
+	_, _, ok := findBackupSource(sources, name)
+	return ok
+}
+
+// verifyBackup 校验归档内容，返回清单和文件索引
+func verifyBackup(zr *zip.Reader) (*BackupManifest, map[string]*zip.File, error) {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", ErrBackupInvalid, fmt.Sprintf(format, args...))
+	}
+
+	entries := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		if strings.HasSuffix(f.Name, "/") {
+			continue // 目录项
+		}
+		if _, ok := entries[f.Name]; ok {
+			return nil, nil, invalid("文件重复: %s", f.Name)
+		}
+		entries[f.Name] = f
+	}
+
+	mf, ok := entries[backupManifestName]
+	if !ok {
+		return nil, nil, invalid("缺少 %s", backupManifestName)
+	}
+	rc, err := mf.Open()
+	if err != nil {
+		return nil, nil, invalid("%v", err)
+	}
+	var manifest BackupManifest
+	err = json.NewDecoder(io.LimitReader(rc, 64<<20)).Decode(&manifest)
+	rc.Close()
+	if err != nil {
+		return nil, nil, invalid("清单格式错误: %v", err)
+	}
+	if manifest.Format != BackupFormat {
+		return nil, nil, invalid("不是 YanBlog 备份文件")
+	}
+	if manifest.Version < 1 || manifest.Version > BackupVersion {
+		return nil, nil, invalid("不支持的备份版本 %d（当前支持 %d）", manifest.Version, BackupVersion)
+	}
+
+	sources := backupSources()
+	listed := make(map[string]bool, len(manifest.Files))
+	for _, file := range manifest.Files {
+		if !validBackupPath(sources, file.Path) {
+			return nil, nil, invalid("非法的文件路径: %s", file.Path)
+		}
+		if listed[file.Path] {
+			return nil, nil, invalid("清单中文件重复: %s", file.Path)
+		}
+		listed[file.Path] = true
+
+		f, ok := entries[file.Path]
+		if !ok {
+			return nil, nil, invalid("缺少文件: %s", file.Path)
+		}
+		if f.UncompressedSize64 != uint64(file.Size) {
+			return nil, nil, invalid("文件大小不一致: %s", file.Path)
+		}
+		sum, size, err := hashZipFile(f, file.Size)
+		if err != nil {
+			return nil, nil, invalid("读取 %s 失败: %v", file.Path, err)
+		}
+		if size != file.Size || sum != file.SHA256 {
+			return nil, nil, invalid("文件校验失败: %s", file.Path)
+		}
+	}
+	for name := range entries {
+		if name != backupManifestName && !listed[name] {
+			return nil, nil, invalid("清单中未记录的文件: %s", name)
+		}
+	}
+	for table := range manifest.Tables {
+		if !listed[backupDatabaseDir+table+".json"] {
+			return nil, nil, invalid("缺少数据表: %s", table)
+		}
+	}
+	return &manifest, entries, nil
+}
+
+// hashZipFile 计算归档内文件的 SHA-256，最多读取 limit+1 字节，防止实际内容超过声明的大小
+func hashZipFile(f *zip.File, limit int64) (string, int64, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", 0, err
+	}
+	defer rc.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, io.LimitReader(rc, limit+1))
+	if err != nil {
+		return "", n, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
+
+// BackupDiff 恢复前后的差异（恢复前预览）
+type BackupDiff struct {
+	CreatedAt time.Time         `json:"created_at"` // 备份时间
+	Database  string            `json:"database"`   // 备份来源的数据库类型
+	Tables    []BackupTableDiff `json:"tables"`
+	Added     []string          `json:"added"`   // 恢复后新增的文件
+	Removed   []string          `json:"removed"` // 恢复后删除的文件
+	Changed   []string          `json:"changed"` // 内容将被替换的文件
+	Unchanged int               `json:"unchanged"`
+}
+
+// BackupTableDiff 数据表当前记录数和备份中的记录数
+type BackupTableDiff struct {
+	Table   string `json:"table"`
+	Current int64  `json:"current"`
+	Backup  int64  `json:"backup"`
+}
+
+// Diff 对比备份与当前数据，不修改任何数据
+func (a *BackupArchive) Diff() (*BackupDiff, error) {
+	diff := &BackupDiff{
+		CreatedAt: a.Manifest.CreatedAt,
+		Database:  a.Manifest.Database,
+		Added:     []string{},
+		Removed:   []string{},
+		Changed:   []string{},
+	}
+	for _, t := range backupTables {
+		name, err := t.name(db)
+		if err != nil {
+			return nil, err
+		}
+		var count int64
+		if t.model == nil {
+			db.Table(t.join).Count(&count)
+		} else {
+			db.Unscoped().Model(t.model).Count(&count)
+		}
+		diff.Tables = append(diff.Tables, BackupTableDiff{Table: name, Current: count, Backup: a.Manifest.Tables[name]})
+	}
+	if err := a.diffFiles(diff); err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// diffFiles 对比备份中的文件与恢复目标位置的现有文件
+func (a *BackupArchive) diffFiles(diff *BackupDiff) error {
+	// 备份中的文件：来源路径 -> 清单记录
+	archived := make(map[string]BackupFile)
+	for _, file := range a.Manifest.Files {
+		if strings.HasPrefix(file.Path, backupFilesDir) {
+			archived[strings.TrimPrefix(file.Path, backupFilesDir)] = file
+		}
+	}
+	for _, src := range backupSources() {
+		local, err := localBackupFiles(src)
+		if err != nil {
+			return err
+		}
+		for name, p := range local {
+			file, ok := archived[name]
+			if !ok {
+				// 备份中没有的单个文件恢复时保持不变，只有目录会被整体替换（备份中的目录为空时同样替换为空目录）
+				if src.dir {
+					diff.Removed = append(diff.Removed, name)
+				}
+				continue
+			}
+			if same, err := sameFile(src, p, file); err != nil {
+				return err
+			} else if same {
+				diff.Unchanged++
+			} else {
+				diff.Changed = append(diff.Changed, name)
+			}
+		}
+		for name := range archived {
+			if _, ok := local[name]; !ok && (name == src.name || strings.HasPrefix(name, src.name+"/")) {
+				diff.Added = append(diff.Added, name)
+			}
+		}
+	}
+	sort.Strings(diff.Added)
+	sort.Strings(diff.Removed)
+	sort.Strings(diff.Changed)
+	return nil
+}
+
+// localBackupFiles 列出备份来源在恢复目标位置的现有文件：归档内路径 -> 本地路径（上传文件为存储中的路径）
+func localBackupFiles(src backupSource) (map[string]string, error) {
+	files := make(map[string]string)
+	if src.dir {
+		err := storage.Walk(storage.Current(), "", func(info storage.FileInfo) error {
+			if strings.HasPrefix(info.Name, restoreTempPrefix) {
+				if info.IsDir {
+					return storage.SkipDir
+				}
+				return nil
+			}
+			if !info.IsDir {
+				files[src.name+"/"+info.Path] = info.Path
+			}
+			return nil
+		})
+		if err != nil && !storage.IsNotExist(err) {
+			return nil, err
+		}
+		return files, nil
+	}
+
+	info, err := os.Stat(src.target)
+	if os.IsNotExist(err) {
+		return files, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode().IsRegular() {
+		files[src.name] = src.target
+	}
+	return files, nil
+}
+
+// sameFile 判断现有文件与备份中的文件内容是否相同
+func sameFile(src backupSource, name string, file BackupFile) (bool, error) {
+	var size int64
+	var open func() (io.ReadCloser, error)
+	if src.dir {
+		store := storage.Current()
+		info, err := store.Stat(name)
+		if err != nil {
+			return false, err
+		}
+		size, open = info.Size, func() (io.ReadCloser, error) { return store.Get(name) }
+	} else {
+		info, err := os.Stat(name)
+		if err != nil {
+			return false, err
+		}
+		size, open = info.Size(), func() (io.ReadCloser, error) { return os.Open(name) }
+	}
+	if size != file.Size {
+		return false, nil
+	}
+	f, err := open()
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return false, err
+	}
+	return hex.EncodeToString(h.Sum(nil)) == file.SHA256, nil
+}
+
+// Restore 用备份替换当前的数据库和站点文件，调用方需先获取备份锁
+// 文件先解压到目标位置旁的临时目录，数据库在事务中整体替换，事务提交前再逐个重命名替换文件；
+// 任一步骤失败时数据库回滚，已替换的文件恢复原状
+func (a *BackupArchive) Restore() error {
+	swaps, err := a.stageFiles()
+	defer swaps.cleanup()
+	if err != nil {
+		return fmt.Errorf("解压备份文件失败: %w", err)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := a.restoreDatabase(tx); err != nil {
+			return err
+		}
+		return swaps.apply()
+	})
+	if err != nil {
+		// 事务提交失败时文件可能已经替换
+		if rbErr := swaps.rollback(); rbErr != nil {
+			return fmt.Errorf("%v；回滚文件失败: %v", err, rbErr)
+		}
+		return err
+	}
+
+	RebuildSearchIndex()
+	migrateUploadHistory()
+	if err := utils.ReloadConfig(); err != nil {
+		return fmt.Errorf("数据已恢复，但重新加载配置失败: %w", err)
+	}
+	return nil
+}
+
+// restoreDatabase 清空数据表并写入备份中的记录
+func (a *BackupArchive) restoreDatabase(tx *gorm.DB) error {
+	// 按外键依赖的逆序清空
+	for i := len(backupTables) - 1; i >= 0; i-- {
+		t := backupTables[i]
+		var err error
+		if t.model == nil {
+			err = tx.Exec("DELETE FROM " + tx.Statement.Quote(t.join)).Error
+		} else {
+			err = tx.Session(&gorm.Session{AllowGlobalUpdate: true, SkipHooks: true}).Unscoped().Delete(t.model).Error
+		}
+		if err != nil {
+			return fmt.Errorf("清空数据表失败: %w", err)
+		}
+	}
+
+	for _, t := range backupTables {
+		name, err := t.name(tx)
+		if err != nil {
+			return err
+		}
+		f, ok := a.entries[backupDatabaseDir+name+".json"]
+		if !ok {
+			continue // 备份时还没有这张表
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = restoreTable(tx, t, rc)
+		rc.Close()
+		if err != nil {
+			return fmt.Errorf("恢复数据表 %s 失败: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// restoreTable 读取 dumpTable 导出的 JSON 数组并分批写入
+func restoreTable(tx *gorm.DB, t backupTable, r io.Reader) error {
+	s, err := t.parse(tx)
+	if err != nil {
+		return err
+	}
+	dec := json.NewDecoder(r)
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		return errors.New("数据格式错误")
+	}
+
+	var batch []map[string]interface{}
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		var err error
+		if s == nil {
+			err = tx.Table(t.join).Create(&batch).Error
+		} else {
+			// 使用 map 写入，保留零值字段，也不触发密码加密等钩子
+			err = tx.Session(&gorm.Session{SkipHooks: true}).Model(t.model).Create(&batch).Error
+		}
+		batch = nil
+		return err
+	}
+
+	for dec.More() {
+		var raw map[string]json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return err
+		}
+		row, err := decodeBackupRow(s, raw)
+		if err != nil {
+			return err
+		}
+		batch = append(batch, row)
+		if len(batch) >= restoreBatchSize {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
+
+// decodeBackupRow 按字段类型解码一条记录；s 为 nil 时为关联表，所有列都是整数
+// 备份中存在但当前版本已删除的列会被忽略
+func decodeBackupRow(s *schema.Schema, raw map[string]json.RawMessage) (map[string]interface{}, error) {
+	row := make(map[string]interface{}, len(raw))
+	for column, value := range raw {
+		if s == nil {
+			var v int64
+			if err := json.Unmarshal(value, &v); err != nil {
+				return nil, fmt.Errorf("字段 %s: %w", column, err)
+			}
+			row[column] = v
+			continue
+		}
+		field, ok := s.FieldsByDBName[column]
+		if !ok {
+			continue
+		}
+		v := reflect.New(field.FieldType)
+		if err := json.Unmarshal(value, v.Interface()); err != nil {
+			return nil, fmt.Errorf("字段 %s: %w", column, err)
+		}
+		row[column] = v.Elem().Interface()
+	}
+	return row, nil
+}
+
+// restoreSwap 一个备份来源的替换操作
+// 单个文件：新内容解压到同目录的临时文件，替换时重命名覆盖
+// 上传文件：新内容写入存储根目录下的临时子目录，替换时逐项移动（本地存储的根目录可能是 Docker 挂载点，不能整体重命名）
+type restoreSwap struct {
+	src      backupSource
+	store    storage.Storage // 上传文件所在的存储
+	staged   string          // 解压的新内容
+	old      string          // 移走的旧内容
+	existed  bool            // 单个文件：替换前目标文件是否存在
+	applied  bool            // 是否已完成替换
+	oldNames []string        // 上传文件：已移入 old 的旧子项
+	newNames []string        // 上传文件：已从 staged 移入的新子项
+}
+
+type restoreSwaps []*restoreSwap
+
+// stageFiles 将备份中的文件解压到各目标位置旁的临时路径
+func (a *BackupArchive) stageFiles() (restoreSwaps, error) {
+	var swaps restoreSwaps
+	suffix := fmt.Sprintf("%s%d", restoreTempPrefix, time.Now().UnixNano())
+	sources := backupSources()
+	bySource := make(map[string]*restoreSwap)
+	addSwap := func(src backupSource) *restoreSwap {
+		swap := &restoreSwap{src: src}
+		if src.dir {
+			swap.store = storage.Current()
+			swap.staged = suffix + "-new"
+			swap.old = suffix + "-old"
+		} else {
+			swap.staged = src.target + suffix + "-new"
+			swap.old = src.target + suffix + "-old"
+		}
+		bySource[src.name] = swap
+		swaps = append(swaps, swap)
+		return swap
+	}
+
+	// 上传文件总是整体替换：备份中没有上传文件时恢复为空，与 Diff 的预览一致
+	for _, src := range sources {
+		if !src.dir {
+			continue
+		}
+		swap := addSwap(src)
+		if err := swap.store.Mkdir(swap.staged); err != nil {
+			return swaps, err
+		}
+	}
+
+	for _, file := range a.Manifest.Files {
+		if !strings.HasPrefix(file.Path, backupFilesDir) {
+			continue
+		}
+		src, rel, ok := findBackupSource(sources, file.Path)
+		if !ok {
+			continue
+		}
+		swap := bySource[src.name]
+		if swap == nil {
+			swap = addSwap(src)
+		}
+
+		var err error
+		if src.dir {
+			err = extractBackupToStorage(a.entries[file.Path], swap.store, path.Join(swap.staged, rel))
+		} else {
+			err = extractBackupFile(a.entries[file.Path], swap.staged)
+		}
+		if err != nil {
+			return swaps, err
+		}
+	}
+	return swaps, nil
+}
+
+func extractBackupFile(f *zip.File, dest string) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0755); err != nil {
+		return err
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	out, err := os.OpenFile(dest, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, rc); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Chtimes(dest, f.Modified, f.Modified)
+}
+
+// extractBackupToStorage 将归档内的文件写入存储，本地存储保留修改时间
+func extractBackupToStorage(f *zip.File, store storage.Storage, name string) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err := store.Put(name, rc, int64(f.UncompressedSize64)); err != nil {
+		return err
+	}
+	if l, ok := store.(storage.Localer); ok {
+		return os.Chtimes(l.LocalPath(name), f.Modified, f.Modified)
+	}
+	return nil
+}
+
+// apply 依次替换所有文件，失败时撤销已完成的替换
+func (swaps restoreSwaps) apply() error {
+	for _, s := range swaps {
+		if err := s.apply(); err != nil {
+			if rbErr := swaps.rollback(); rbErr != nil {
+				return fmt.Errorf("替换文件失败: %v；回滚失败: %v", err, rbErr)
+			}
+			return fmt.Errorf("替换文件失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// listStorageDir 列出存储目录的子项，目录不存在时返回空
+func listStorageDir(store storage.Storage, dir string) ([]storage.FileInfo, error) {
+	items, err := store.List(dir)
+	if storage.IsNotExist(err) {
+		return nil, nil
+	}
+	return items, err
+}
+
+func (s *restoreSwap) apply() error {
+	if !s.src.dir {
+		if _, err := os.Stat(s.src.target); err == nil {
+			if err := os.Rename(s.src.target, s.old); err != nil {
+				return err
+			}
+			s.existed = true
+		}
+		if err := os.Rename(s.staged, s.src.target); err != nil {
+			return err
+		}
+		s.applied = true
+		return nil
+	}
+
+	if err := s.store.Mkdir(s.old); err != nil {
+		return err
+	}
+	children, err := listStorageDir(s.store, "")
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		if strings.HasPrefix(child.Name, restoreTempPrefix) {
+			continue
+		}
+		if err := s.store.Move(child.Name, path.Join(s.old, child.Name)); err != nil {
+			return err
+		}
+		s.oldNames = append(s.oldNames, child.Name)
+	}
+	children, err = listStorageDir(s.store, s.staged)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		if err := s.store.Move(path.Join(s.staged, child.Name), child.Name); err != nil {
+			return err
+		}
+		s.newNames = append(s.newNames, child.Name)
+	}
+	s.applied = true
+	return nil
+}
+
+// rollback 撤销已完成的替换，恢复原来的文件
+func (swaps restoreSwaps) rollback() error {
+	var errs []error
+	for i := len(swaps) - 1; i >= 0; i-- {
+		if err := swaps[i].rollback(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *restoreSwap) rollback() error {
+	if !s.src.dir {
+		if s.applied {
+			if err := os.Rename(s.src.target, s.staged); err != nil {
+				return err
+			}
+			s.applied = false
+		}
+		if s.existed {
+			if err := os.Rename(s.old, s.src.target); err != nil {
+				return err
+			}
+			s.existed = false
+		}
+		return nil
+	}
+
+	s.applied = false
+	for len(s.newNames) > 0 {
+		name := s.newNames[len(s.newNames)-1]
+		if err := s.store.Move(name, path.Join(s.staged, name)); err != nil {
+			return err
+		}
+		s.newNames = s.newNames[:len(s.newNames)-1]
+	}
+	for len(s.oldNames) > 0 {
+		name := s.oldNames[len(s.oldNames)-1]
+		if err := s.store.Move(path.Join(s.old, name), name); err != nil {
+			return err
+		}
+		s.oldNames = s.oldNames[:len(s.oldNames)-1]
+	}
+	return nil
+}
+
+// cleanup 删除临时文件：恢复成功时为旧内容，失败时为解压的新内容
+func (swaps restoreSwaps) cleanup() {
+	for _, s := range swaps {
+		// 回滚失败时旧内容仍在临时路径中，保留以便手动恢复
+		if s.src.dir && len(s.oldNames) > 0 && !s.applied {
+			continue
+		}
+		if !s.src.dir && s.existed && !s.applied {
+			continue
+		}
+		if s.src.dir {
+			s.store.Delete(s.staged)
+			s.store.Delete(s.old)
+			continue
+		}
+		os.RemoveAll(s.staged)
+		os.RemoveAll(s.old)
+	}
+}
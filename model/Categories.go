@@ -10,6 +10,7 @@ import (
 type Category struct {
 	gorm.Model
 	Name string `gorm:"type:varchar(20);not null;uniqueIndex" json:"name"` // 添加唯一索引
+	Slug string `gorm:"type:varchar(100);uniqueIndex" json:"slug"`         // URL 别名，留空时根据名称自动生成
 	Img  string `gorm:"type:varchar(255)" json:"img"`
 	Top  int    `gorm:"type:int;not null;default:0;index" json:"top"` // 添加索引，优化置顶查询
 	// 添加文章计数字段（使用gorm:"-"标记，表示不直接映射到数据库字段，保证数据一致性）
@@ -51,6 +52,12 @@ func CreateCate(data *Category) int {
 		data.Top = 0
 	}
 
+	slug, ok := resolveSlug(SlugKindCategory, data.Slug, data.Name, "", "", 0)
+	if !ok {
+		return errmsg.ERROR_CATE_SLUG_USED
+	}
+	data.Slug = slug
+
 	err := db.Create(&data).Error
 	if err != nil {
 		return errmsg.ERROR
@@ -120,7 +127,7 @@ func GetCate(pageSize int, pageNum int) ([]Category, int64) {
 	// 为每个分类获取文章数量
 	for i := range cate {
 		var count int64
-		db.Model(&Article{}).Where("cid = ?", cate[i].ID).Count(&count)
+		publishedScope(db.Model(&Article{})).Where("cid = ?", cate[i].ID).Count(&count)
 		cate[i].ArticleCount = int(count)
 	}
 
@@ -137,21 +144,50 @@ func GetCateInfo(id int) (Category, int) {
 		return cate, errmsg.ERROR_CATE_NOT_EXIST
 	}
 
-	// 获取该分类下的文章数量
+	// 获取该分类下的文章数量（仅统计已发布）
 	var count int64
-	db.Model(&Article{}).Where("cid = ?", id).Count(&count)
+	publishedScope(db.Model(&Article{})).Where("cid = ?", id).Count(&count)
 	cate.ArticleCount = int(count)
 
 	return cate, errmsg.SUCCESS
 }
 
+// GetCateInfoBySlug 通过 slug 获取分类信息（包含文章数量）
+// slug 已变更时通过历史记录找到分类，由调用方跳转到当前 slug
+// 参数: slug - 分类 slug
+// 返回: 分类信息、是否为历史 slug 和状态码
+func GetCateInfoBySlug(slug string) (Category, bool, int) {
+	var cate Category
+	if err := db.Select("id").Where("slug = ?", slug).First(&cate).Error; err == nil {
+		cate, code := GetCateInfo(int(cate.ID))
+		return cate, false, code
+	}
+
+	id, ok := findSlugHistory(SlugKindCategory, slug)
+	if !ok {
+		return cate, false, errmsg.ERROR_CATE_NOT_EXIST
+	}
+	cate, code := GetCateInfo(int(id))
+	return cate, code == errmsg.SUCCESS, code
+}
+
 // EditCate 编辑分类信息
 // 参数: id - 分类ID, data - 更新的分类信息
 // 返回: 状态码
 func EditCate(id int, data *Category) int {
 	var cate Category
+	var old Category
+	if err := db.Select("id", "name", "slug", "img").Where("id = ?", id).First(&old).Error; err != nil {
+		return errmsg.ERROR_CATE_NOT_EXIST
+	}
+	slug, ok := resolveSlug(SlugKindCategory, data.Slug, data.Name, old.Name, old.Slug, old.ID)
+	if !ok {
+		return errmsg.ERROR_CATE_SLUG_USED
+	}
+
 	var maps = make(map[string]interface{})
 	maps["name"] = data.Name
+	maps["slug"] = slug
 	maps["img"] = data.Img
 	maps["top"] = data.Top
 
@@ -159,10 +195,16 @@ func EditCate(id int, data *Category) int {
 	if err != nil {
 		return errmsg.ERROR
 	}
+	recordSlugChange(SlugKindCategory, old.ID, old.Slug, slug)
+	data.Slug = slug
+	// 更换封面后释放原封面
+	if old.Img != "" && old.Img != data.Img {
+		ReleaseUpload(old.Img, 0, old.ID)
+	}
 	return errmsg.SUCCESS
 }
 
-// DeleteCate 删除分类（检查是否有关联文章）
+// DeleteCate 删除分类（检查是否有关联文章），并释放分类封面
 func DeleteCate(id int) int {
 	// 检查该分类下是否还有文章
 	var count int64
@@ -172,10 +214,15 @@ func DeleteCate(id int) int {
 	}
 
 	var cate Category
-	err = db.Where("id = ? ", id).Delete(&cate).Error
+	db.Select("id", "img").Where("id = ?", id).First(&cate)
+	err = db.Where("id = ? ", id).Delete(&Category{}).Error
 	if err != nil {
 		return errmsg.ERROR
 	}
+	// 相同内容的上传只保存一份，仍被其他文章或分类使用时只减少引用计数
+	if cate.Img != "" {
+		ReleaseUpload(cate.Img, 0, cate.ID)
+	}
 	return errmsg.SUCCESS
 }
 
@@ -192,6 +239,7 @@ func GetOrCreateCategory(name string) int {
 	// 不存在则创建
 	newCate := Category{
 		Name: name,
+		Slug: uniqueSlug(SlugKindCategory, name, 0),
 	}
 	err = db.Create(&newCate).Error
 	if err != nil {
@@ -0,0 +1,99 @@
+package model
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+	"yanblog/utils/errmsg"
+)
+
+// ChunkedUploadDir 分片上传的临时文件目录，每个上传一个 <ID>.part 文件
+const ChunkedUploadDir = "./temp_upload"
+
+// chunkedUploadTTL 分片上传超过该时间没有新数据即视为放弃，由 RunChunkedUploadCleanup 清理
+const chunkedUploadTTL = 24 * time.Hour
+
+// ChunkedUpload 可断点续传的分片上传
+// 已接收的字节数以临时文件的大小为准，服务重启后客户端可查询偏移量后继续上传
+type ChunkedUpload struct {
+	ID        string    `gorm:"primarykey;type:varchar(64)" json:"id"`
+	FileName  string    `gorm:"type:varchar(255);not null" json:"file_name"`
+	Size      int64     `gorm:"not null" json:"size"`
+	Type      string    `gorm:"type:varchar(20)" json:"type"` // 上传类型，同 UpLoadFile；import 表示用于 ZIP 上传或博客导入
+	Key       string    `gorm:"type:varchar(100)" json:"key"`
+	ArticleID int       `json:"article_id"`                       // 上传文章图片时正在编辑的文章
+	Checksum  string    `gorm:"type:varchar(64)" json:"checksum"` // 整个文件的 SHA-256（十六进制），为空不校验
+	Username  string    `gorm:"type:varchar(20);index" json:"username"`
+	Completed bool      `json:"completed"` // 已接收全部数据并通过校验（只有 import 类型会保留到被使用）
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `gorm:"index" json:"updated_at"`
+}
+
+// ChunkedUploadPath 分片上传的临时文件路径
+func ChunkedUploadPath(id string) string {
+	return filepath.Join(ChunkedUploadDir, id+".part")
+}
+
+// CreateChunkedUpload 新建分片上传并创建空的临时文件
+func CreateChunkedUpload(upload *ChunkedUpload) int {
+	upload.ID = randomToken()[:32]
+	if err := os.MkdirAll(ChunkedUploadDir, 0755); err != nil {
+		return errmsg.ERROR
+	}
+	f, err := os.Create(ChunkedUploadPath(upload.ID))
+	if err != nil {
+		return errmsg.ERROR
+	}
+	f.Close()
+	if err := db.Create(upload).Error; err != nil {
+		os.Remove(ChunkedUploadPath(upload.ID))
+		return errmsg.ERROR
+	}
+	return errmsg.SUCCESS
+}
+
+// GetChunkedUpload 查询用户自己的分片上传
+func GetChunkedUpload(id string, username string) (ChunkedUpload, int) {
+	var upload ChunkedUpload
+	if err := db.Where("id = ? AND username = ?", id, username).First(&upload).Error; err != nil {
+		return upload, errmsg.ERROR_CHUNK_NOT_EXIST
+	}
+	return upload, errmsg.SUCCESS
+}
+
+// TouchChunkedUpload 收到新数据后更新时间，推迟过期清理
+func TouchChunkedUpload(id string) {
+	db.Model(&ChunkedUpload{}).Where("id = ?", id).Update("updated_at", time.Now())
+}
+
+// CompleteChunkedUpload 标记分片上传已完成
+func CompleteChunkedUpload(id string) int {
+	if err := db.Model(&ChunkedUpload{}).Where("id = ?", id).Update("completed", true).Error; err != nil {
+		return errmsg.ERROR
+	}
+	return errmsg.SUCCESS
+}
+
+// DeleteChunkedUpload 删除分片上传记录和临时文件（文件已被移走时只删除记录）
+func DeleteChunkedUpload(id string) {
+	db.Where("id = ?", id).Delete(&ChunkedUpload{})
+	os.Remove(ChunkedUploadPath(id))
+}
+
+// RunChunkedUploadCleanup 定期清理超过 chunkedUploadTTL 没有更新的分片上传
+func RunChunkedUploadCleanup() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		var ids []string
+		db.Model(&ChunkedUpload{}).Where("updated_at < ?", time.Now().Add(-chunkedUploadTTL)).Pluck("id", &ids)
+		for _, id := range ids {
+			DeleteChunkedUpload(id)
+		}
+		if len(ids) > 0 {
+			fmt.Printf("已清理 %d 个过期的分片上传\n", len(ids))
+		}
+	}
+}
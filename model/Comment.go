@@ -0,0 +1,264 @@
+package model
+
+import (
+	"strings"
+	"yanblog/utils"
+	"yanblog/utils/errmsg"
+
+	"gorm.io/gorm"
+)
+
+// Comment 文章评论（支持楼中楼回复）
+type Comment struct {
+	gorm.Model
+	ArticleID uint   `gorm:"not null;index" json:"article_id" validate:"required" label:"文章"`
+	ParentID  uint   `gorm:"not null;default:0;index" json:"parent_id"` // 回复的评论ID，0 表示顶层评论
+	RootID    uint   `gorm:"not null;default:0;index" json:"root_id"`   // 所属顶层评论ID，顶层评论为 0
+	Nickname  string `gorm:"type:varchar(50);not null" json:"nickname" validate:"required,max=50" label:"昵称"`
+	Email     string `gorm:"type:varchar(100)" json:"email,omitempty" validate:"omitempty,email,max=100" label:"邮箱"`
+	Website   string `gorm:"type:varchar(200)" json:"website" validate:"omitempty,url,max=200" label:"网站"`
+	Content   string `gorm:"type:text;not null" json:"content" validate:"required" label:"评论内容"`
+	Status    string `gorm:"type:varchar(20);not null;default:pending;index" json:"status"` // 评论状态: pending, approved, spam
+	IP        string `gorm:"type:varchar(45)" json:"ip,omitempty"`
+	UserAgent string `gorm:"type:varchar(255)" json:"user_agent,omitempty"`
+
+	ArticleTitle string    `gorm:"-" json:"article_title,omitempty"` // 后台列表展示用，不存库
+	Replies      []Comment `gorm:"-" json:"replies,omitempty"`       // 前台展示的回复，不存库
+}
+
+// 评论状态
+const (
+	CommentStatusPending  = "pending"  // 待审核
+	CommentStatusApproved = "approved" // 已通过
+	CommentStatusSpam     = "spam"     // 垃圾评论
+)
+
+// defaultCommentMaxLength 评论默认最大字数
+const defaultCommentMaxLength = 1000
+
+// IsValidCommentStatus 判断评论状态是否合法
+func IsValidCommentStatus(status string) bool {
+	switch status {
+	case CommentStatusPending, CommentStatusApproved, CommentStatusSpam:
+		return true
+	}
+	return false
+}
+
+// CommentMaxLength 评论最大字数（未配置时使用默认值）
+func CommentMaxLength() int {
+	if n := utils.ServerConfig.Comment.MaxLength; n > 0 {
+		return n
+	}
+	return defaultCommentMaxLength
+}
+
+// matchBlockWord 检查文本是否包含屏蔽词（不区分大小写）
+// 返回: 是否命中
+func matchBlockWord(words []string, texts ...string) bool {
+	for _, text := range texts {
+		lower := strings.ToLower(text)
+		for _, w := range words {
+			w = strings.ToLower(strings.TrimSpace(w))
+			if w != "" && strings.Contains(lower, w) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CreateComment 发表评论
+// 回复时校验父评论属于同一文章且已通过审核；命中屏蔽词的评论直接标记为垃圾评论
+// 参数: data - 评论信息
+// 返回: 状态码
+func CreateComment(data *Comment) int {
+	var art Article
+	if err := publishedScope(db.Select("id")).Where("id = ?", data.ArticleID).First(&art).Error; err != nil {
+		return errmsg.ERROR_COMMENT_CLOSED
+	}
+
+	data.RootID = 0
+	if data.ParentID > 0 {
+		var parent Comment
+		err := db.Where("id = ? AND article_id = ? AND status = ?", data.ParentID, data.ArticleID, CommentStatusApproved).
+			First(&parent).Error
+		if err != nil {
+			return errmsg.ERROR_COMMENT_PARENT_WRONG
+		}
+		data.RootID = parent.RootID
+		if data.RootID == 0 {
+			data.RootID = parent.ID
+		}
+	}
+
+	switch {
+	case matchBlockWord(utils.ServerConfig.Comment.BlockWords, data.Nickname, data.Website, data.Content):
+		data.Status = CommentStatusSpam
+	case utils.ServerConfig.Comment.AutoApprove:
+		data.Status = CommentStatusApproved
+	default:
+		data.Status = CommentStatusPending
+	}
+
+	if err := db.Create(data).Error; err != nil {
+		return errmsg.ERROR
+	}
+	return errmsg.SUCCESS
+}
+
+// GetArtComments 查询文章下已通过审核的评论（按顶层评论分页，回复挂在顶层评论下）
+// 参数: artID - 文章ID, pageSize - 每页数量, pageNum - 页码
+// 返回: 顶层评论列表和顶层评论总数
+func GetArtComments(artID int, pageSize int, pageNum int) ([]Comment, int64) {
+	var roots []Comment
+	var total int64
+
+	query := db.Model(&Comment{}).Where("article_id = ? AND parent_id = 0 AND status = ?", artID, CommentStatusApproved)
+	query.Count(&total)
+
+	query = query.Order("created_at DESC")
+	var err error
+	if pageSize == -1 || pageNum == -1 {
+		err = query.Find(&roots).Error
+	} else {
+		err = query.Limit(pageSize).Offset((pageNum - 1) * pageSize).Find(&roots).Error
+	}
+	if err != nil || len(roots) == 0 {
+		return []Comment{}, total
+	}
+
+	rootIDs := make([]uint, len(roots))
+	for i, r := range roots {
+		rootIDs[i] = r.ID
+	}
+	var replies []Comment
+	db.Where("root_id IN ? AND status = ?", rootIDs, CommentStatusApproved).Order("created_at ASC").Find(&replies)
+
+	// 回复按时间正序挂到所属顶层评论下；父评论未通过审核的回复不展示
+	visible := make(map[uint]bool, len(roots)+len(replies))
+	for _, r := range roots {
+		visible[r.ID] = true
+	}
+	byRoot := make(map[uint][]Comment)
+	for _, r := range replies {
+		if !visible[r.ParentID] {
+			continue
+		}
+		visible[r.ID] = true
+		byRoot[r.RootID] = append(byRoot[r.RootID], r)
+	}
+	for i := range roots {
+		roots[i].Replies = byRoot[roots[i].ID]
+	}
+
+	stripCommentPrivacy(roots)
+	return roots, total
+}
+
+// stripCommentPrivacy 清除前台不应看到的字段（邮箱、IP、UA）
+func stripCommentPrivacy(comments []Comment) {
+	for i := range comments {
+		comments[i].Email = ""
+		comments[i].IP = ""
+		comments[i].UserAgent = ""
+		stripCommentPrivacy(comments[i].Replies)
+	}
+}
+
+// GetCommentsForAdmin 后台查询评论列表（审核队列）
+// 参数: status - 状态筛选（为空则不限）, artID - 文章ID（0 表示不限）, pageSize - 每页数量, pageNum - 页码
+// 返回: 评论列表和总数
+func GetCommentsForAdmin(status string, artID int, pageSize int, pageNum int) ([]Comment, int64) {
+	var comments []Comment
+	var total int64
+
+	query := db.Model(&Comment{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if artID > 0 {
+		query = query.Where("article_id = ?", artID)
+	}
+	query.Count(&total)
+
+	query = query.Order("created_at DESC")
+	var err error
+	if pageSize == -1 || pageNum == -1 {
+		err = query.Find(&comments).Error
+	} else {
+		err = query.Limit(pageSize).Offset((pageNum - 1) * pageSize).Find(&comments).Error
+	}
+	if err != nil && err != gorm.ErrRecordNotFound {
+		return nil, 0
+	}
+
+	// 填充文章标题
+	artIDs := make([]uint, 0, len(comments))
+	for _, cm := range comments {
+		artIDs = append(artIDs, cm.ArticleID)
+	}
+	if len(artIDs) > 0 {
+		var arts []Article
+		db.Unscoped().Select("id", "title").Where("id IN ?", artIDs).Find(&arts)
+		titles := make(map[uint]string, len(arts))
+		for _, a := range arts {
+			titles[a.ID] = a.Title
+		}
+		for i := range comments {
+			comments[i].ArticleTitle = titles[comments[i].ArticleID]
+		}
+	}
+	return comments, total
+}
+
+// UpdateCommentStatus 修改评论状态（通过 / 驳回为垃圾评论 / 退回待审核）
+// 参数: ids - 评论ID列表, status - 目标状态
+// 返回: 实际更新的数量和状态码
+func UpdateCommentStatus(ids []int, status string) (int64, int) {
+	if !IsValidCommentStatus(status) {
+		return 0, errmsg.ERROR_COMMENT_STATUS_WRONG
+	}
+	result := db.Model(&Comment{}).Where("id IN ?", ids).Update("status", status)
+	if result.Error != nil {
+		return 0, errmsg.ERROR
+	}
+	return result.RowsAffected, errmsg.SUCCESS
+}
+
+// DeleteComments 删除评论及其下的全部回复
+// 参数: ids - 评论ID列表
+// 返回: 删除的数量和状态码
+func DeleteComments(ids []int) (int64, int) {
+	var deleted int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// 逐层收集回复，兼容多级回复
+		pending := make([]uint, 0, len(ids))
+		for _, id := range ids {
+			pending = append(pending, uint(id))
+		}
+		for len(pending) > 0 {
+			result := tx.Where("id IN ?", pending).Delete(&Comment{})
+			if result.Error != nil {
+				return result.Error
+			}
+			deleted += result.RowsAffected
+
+			var children []uint
+			if err := tx.Model(&Comment{}).Where("parent_id IN ?", pending).Pluck("id", &children).Error; err != nil {
+				return err
+			}
+			pending = children
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, errmsg.ERROR
+	}
+	return deleted, errmsg.SUCCESS
+}
+
+// deleteArtComments 删除文章下的全部评论（删除文章时调用）
+func deleteArtComments(artID int) {
+	db.Where("article_id = ?", artID).Delete(&Comment{})
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"crypto/rand"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -43,16 +44,22 @@ func InitDB() {
 	sqlDB.SetMaxOpenConns(100)
 	sqlDB.SetConnMaxLifetime(10 * time.Second)
 
-	db.AutoMigrate(&User{}, &Category{}, &Article{}, &Tag{})
+	db.AutoMigrate(&User{}, &Category{}, &Article{}, &Tag{}, &ArticleRevision{}, &Comment{}, &UserSession{}, &UserTOTP{}, &UserRecoveryCode{}, &AuditLog{}, &SlugHistory{}, &UploadTask{}, &ChunkedUpload{}, &UploadBlob{}, &RecycleItem{})
 	migrateTags()
+	migrateUploadHistory()
+	backfillSlugs()
+	initSearchIndex()
 
 	var count int64
 	db.Model(&User{}).Count(&count)
 	if count == 0 {
+		password := initialPassword()
 		admin := User{
 			Username: "admin",
-			Password: "123456",
+			Password: password,
 			Role:     1,
+			// 初始密码打印在日志中，首次登录后必须先修改
+			MustChangePassword: true,
 		}
 		if err := db.Create(&admin).Error; err != nil {
 			fmt.Println("创建默认超级管理员失败:", err)
@@ -62,14 +69,16 @@ func InitDB() {
 			fmt.Println("===========================================")
 			fmt.Println("已创建默认超级管理员账号:")
 			fmt.Println("  用户名: admin")
-			fmt.Println("  密码:   ****** (请查看下方)")
-			fmt.Printf("  明文密码: %s\n", "123456") // 仅用于开发环境，生产环境请修改
+			fmt.Printf("  密码:   %s\n", password) // 随机生成，只在首次创建时显示这一次
 			fmt.Println("")
-			fmt.Println("🔴 请立即登录后台并修改默认密码！")
+			fmt.Println("请立即记下该密码，之后不会再次显示。")
+			fmt.Println("🔴 首次登录后必须修改默认密码，修改前无法使用其他功能！")
 			fmt.Println("===========================================")
 		}
 	}
 
+	flagDefaultPassword()
+
 	// 首次运行时创建演示文章
 	var articleCount int64
 	db.Model(&Article{}).Count(&articleCount)
@@ -212,8 +221,7 @@ func migrateTags() {
 func createDemoArticle() {
 	title, cateName, tagNames, desc, content := loadDemoArticle()
 
-	cate := Category{Name: cateName}
-	db.FirstOrCreate(&cate, Category{Name: cateName})
+	cid := GetOrCreateCategory(cateName)
 
 	var tags []Tag
 	for _, name := range tagNames {
@@ -224,7 +232,8 @@ func createDemoArticle() {
 
 	demo := Article{
 		Title:     title,
-		Cid:       int(cate.ID),
+		Slug:      uniqueSlug(SlugKindArticle, title, 0),
+		Cid:       cid,
 		Desc:      desc,
 		Content:   content,
 		Tags:      strings.Join(tagNames, ","),
@@ -309,4 +318,29 @@ func loadDemoArticle() (title, category string, tags []string, desc, content str
 
 func getFallbackContent() string {
 	return "## 欢迎使用 YanBlog\n\n这是一篇自动生成的演示文章。\n\n如需自定义，请编辑 `web/frontend/public/static/demo-article.md` 后删除数据库重新启动。"
-}
\ No newline at end of file
+}
+
+// initialPassword 生成初始管理员的随机密码（12 位字母和数字，符合 6-20 位的长度要求）
+func initialPassword() string {
+	const chars = "abcdefghijkmnpqrstuvwxyzABCDEFGHJKLMNPQRSTUVWXYZ23456789" // 去掉易混淆的 0/O、1/l/I
+	b := make([]byte, 12)
+	rand.Read(b)
+	for i := range b {
+		b[i] = chars[int(b[i])%len(chars)]
+	}
+	return string(b)
+}
+
+// flagDefaultPassword 旧版本创建的 admin 账号如果仍在使用初始密码 123456，强制其修改密码
+func flagDefaultPassword() {
+	var admin User
+	err := db.Select("id", "password", "must_change_password").
+		Where("username = ? AND must_change_password = ?", "admin", false).First(&admin).Error
+	if err != nil {
+		return
+	}
+	if CheckPassword("123456", admin.Password) {
+		db.Model(&User{}).Where("id = ?", admin.ID).Update("must_change_password", true)
+		fmt.Println("🔴 admin 账号仍在使用初始密码，下次登录后必须修改密码")
+	}
+}
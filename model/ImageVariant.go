@@ -0,0 +1,409 @@
+package model
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"yanblog/utils"
+	"yanblog/utils/imaging"
+	"yanblog/utils/storage"
+)
+
+// ImageVariantDir 上传图片的缩略图和不同宽度图片在存储中的目录，按原图的路径存放：
+// .variants/<原图路径>/<尺寸>.<扩展名>，如 .variants/article/cover/1.jpg/w640.webp
+const ImageVariantDir = ".variants"
+
+// ImageSize 图片尺寸，Name 用于访问地址 /img/<Name>/<上传路径>
+type ImageSize struct {
+	Name  string `json:"name"`
+	Width int    `json:"width"`
+	Crop  bool   `json:"crop"` // 从中间裁剪为正方形缩略图
+}
+
+// ImageBackfillResult 为已有图片补生成各尺寸的结果
+type ImageBackfillResult struct {
+	Images    int      `json:"images"`    // 处理的图片数
+	Generated int      `json:"generated"` // 生成的尺寸数
+	Stripped  int      `json:"stripped"`  // 去除了元数据的图片数
+	Failed    int      `json:"failed"`
+	Errors    []string `json:"errors"` // 最多保留 maxBackfillErrors 条
+}
+
+const maxBackfillErrors = 50
+
+// imageSettings 图片处理配置（未配置的项使用默认值）
+type imageSettings struct {
+	stripExif bool
+	quality   int
+	thumbSize int
+	widths    []int
+	webp      bool
+}
+
+func getImageSettings() imageSettings {
+	cfg := utils.GetConfig().Image
+	s := imageSettings{
+		stripExif: cfg.StripExif,
+		quality:   cfg.Quality,
+		thumbSize: cfg.ThumbSize,
+		widths:    cfg.Widths,
+		webp:      cfg.WebP,
+	}
+	if s.quality < 1 || s.quality > 100 {
+		s.quality = 82
+	}
+	if s.thumbSize <= 0 {
+		s.thumbSize = 200
+	}
+	if len(s.widths) == 0 {
+		s.widths = []int{320, 640, 1280}
+	}
+	return s
+}
+
+// ImageSizes 当前配置的所有尺寸：缩略图 thumb 和各宽度 w<宽度>
+func ImageSizes() []ImageSize {
+	s := getImageSettings()
+	sizes := []ImageSize{{Name: "thumb", Width: s.thumbSize, Crop: true}}
+	for _, w := range s.widths {
+		if w > 0 {
+			sizes = append(sizes, ImageSize{Name: "w" + strconv.Itoa(w), Width: w})
+		}
+	}
+	return sizes
+}
+
+// FindImageSize 按名称查找尺寸，只允许配置中的尺寸，避免任意宽度请求生成大量文件
+func FindImageSize(name string) (ImageSize, bool) {
+	for _, size := range ImageSizes() {
+		if size.Name == name {
+			return size, true
+		}
+	}
+	return ImageSize{}, false
+}
+
+// imageVariantDir 原图（存储中的路径）的变体目录，原图位于隐藏目录时返回 false
+func imageVariantDir(name string) (string, bool) {
+	name, ok := storage.Clean(name)
+	if !ok || name == "" {
+		return "", false
+	}
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") {
+			return "", false
+		}
+	}
+	return ImageVariantDir + "/" + name, true
+}
+
+// keyLock 按键加锁，不同的键互不阻塞，键不再使用时自动释放
+type keyLock struct {
+	mu    sync.Mutex
+	locks map[string]*keyLockEntry
+}
+
+type keyLockEntry struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// Lock 锁定指定的键，返回解锁函数
+func (k *keyLock) Lock(key string) func() {
+	k.mu.Lock()
+	if k.locks == nil {
+		k.locks = make(map[string]*keyLockEntry)
+	}
+	l, ok := k.locks[key]
+	if !ok {
+		l = &keyLockEntry{}
+		k.locks[key] = l
+	}
+	l.refs++
+	k.mu.Unlock()
+
+	l.mu.Lock()
+	return func() {
+		l.mu.Unlock()
+		k.mu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(k.locks, key)
+		}
+		k.mu.Unlock()
+	}
+}
+
+var (
+	// imageLocks 按原图加锁，避免同一张图片被并发生成
+	imageLocks keyLock
+	// imageSem 限制同时处理的图片数，从读取原图开始占用，避免大量上传或请求时占满 CPU 和内存
+	imageSem = make(chan struct{}, 2)
+)
+
+func lockImage(name string) func() {
+	return imageLocks.Lock(name)
+}
+
+// acquireImage 等待空闲的图片处理位置，在持有原图的锁之后、读取原图之前调用
+// 返回: 释放函数
+func acquireImage() func() {
+	imageSem <- struct{}{}
+	return func() { <-imageSem }
+}
+
+// freshVariant 查找比原图新的变体文件，webp 为 true 时优先返回 WebP
+// 生成时 WebP 不比原格式小则不保存，此时返回原格式
+func freshVariant(dir string, size ImageSize, src storage.FileInfo, webp bool) (string, bool) {
+	exts := []string{".jpg", ".png"}
+	if webp {
+		exts = append([]string{".webp"}, exts...)
+	}
+	for _, ext := range exts {
+		p := dir + "/" + size.Name + ext
+		if info, err := storage.Current().Stat(p); err == nil && !info.ModTime.Before(src.ModTime) {
+			return p, true
+		}
+	}
+	return "", false
+}
+
+// needsVariant 宽度不小于原图时直接使用原图，不生成该尺寸
+func needsVariant(size ImageSize, width int) bool {
+	return size.Crop || size.Width < width
+}
+
+// ImageVariant 返回指定尺寸图片在存储中的路径，还没有生成或原图已更新时立即生成
+// 原图不超过该宽度时返回原图路径
+func ImageVariant(name string, size ImageSize, webp bool) (string, error) {
+	dir, ok := imageVariantDir(name)
+	if !ok || !imaging.IsSupported(path.Ext(name)) {
+		return name, nil
+	}
+	info, err := storage.Current().Stat(name)
+	if err != nil {
+		return "", err
+	}
+	if p, ok := freshVariant(dir, size, info, webp); ok {
+		return p, nil
+	}
+
+	unlock := lockImage(name)
+	defer unlock()
+	// 等待期间可能已由其他请求生成
+	if p, ok := freshVariant(dir, size, info, webp); ok {
+		return p, nil
+	}
+	release := acquireImage()
+	defer release()
+	data, err := storage.ReadFile(storage.Current(), name)
+	if err != nil {
+		return "", err
+	}
+	width, _, _, err := imaging.Bounds(data)
+	if err != nil {
+		return "", err
+	}
+	if !needsVariant(size, width) {
+		return name, nil
+	}
+	if err := writeImageVariants(dir, data, []ImageSize{size}); err != nil {
+		return "", err
+	}
+	if p, ok := freshVariant(dir, size, info, webp); ok {
+		return p, nil
+	}
+	return name, nil
+}
+
+// GenerateImageVariants 生成图片的所有尺寸，已有且比原图新的跳过，force 时全部重新生成
+// 返回: 生成的尺寸数
+func GenerateImageVariants(name string, force bool) (int, error) {
+	dir, ok := imageVariantDir(name)
+	if !ok || !imaging.IsSupported(path.Ext(name)) {
+		return 0, nil
+	}
+	unlock := lockImage(name)
+	defer unlock()
+
+	s := storage.Current()
+	info, err := s.Stat(name)
+	if err != nil {
+		return 0, err
+	}
+	release := acquireImage()
+	defer release()
+	data, err := storage.ReadFile(s, name)
+	if err != nil {
+		return 0, err
+	}
+	width, _, _, err := imaging.Bounds(data)
+	if err != nil {
+		return 0, err
+	}
+	var pending []ImageSize
+	for _, size := range ImageSizes() {
+		if !needsVariant(size, width) {
+			continue
+		}
+		if _, fresh := freshVariant(dir, size, info, false); fresh && !force {
+			continue
+		}
+		pending = append(pending, size)
+	}
+	if len(pending) == 0 {
+		return 0, nil
+	}
+	if err := writeImageVariants(dir, data, pending); err != nil {
+		return 0, err
+	}
+	return len(pending), nil
+}
+
+// writeImageVariants 解码原图并生成指定尺寸（调用方持有原图的锁和 acquireImage 的处理位置）
+// 每个尺寸保存为原格式（见 imaging.VariantFormat），开启 WebP 时另存一份比原格式小的 WebP
+func writeImageVariants(dir string, data []byte, sizes []ImageSize) error {
+	s := getImageSettings()
+	store := storage.Current()
+	img, format, err := imaging.Decode(data)
+	if err != nil {
+		return err
+	}
+	variantFormat := imaging.VariantFormat(format, img)
+	for _, size := range sizes {
+		var out image.Image
+		if size.Crop {
+			out = imaging.Thumbnail(img, size.Width)
+		} else {
+			out = imaging.Resize(img, size.Width)
+		}
+		// 原图格式变化（同名文件被替换）时清理旧格式的文件
+		for _, ext := range []string{".jpg", ".png", ".webp"} {
+			store.Delete(dir + "/" + size.Name + ext)
+		}
+
+		var buf bytes.Buffer
+		if err := imaging.Encode(&buf, out, variantFormat, s.quality); err != nil {
+			return err
+		}
+		if err := storage.WriteFile(store, dir+"/"+size.Name+imaging.Ext(variantFormat), buf.Bytes()); err != nil {
+			return err
+		}
+		if !s.webp {
+			continue
+		}
+		var webp bytes.Buffer
+		if err := imaging.Encode(&webp, out, imaging.FormatWebP, s.quality); err != nil {
+			return err
+		}
+		if webp.Len() < buf.Len() {
+			if err := storage.WriteFile(store, dir+"/"+size.Name+".webp", webp.Bytes()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// StripImageMetadata 去除图片中的 EXIF 等元数据并替换原文件
+// 返回: 是否有元数据被去除
+func StripImageMetadata(name string) (bool, error) {
+	if !imaging.IsSupported(path.Ext(name)) {
+		return false, nil
+	}
+	unlock := lockImage(name)
+	defer unlock()
+	release := acquireImage()
+	defer release()
+
+	data, err := storage.ReadFile(storage.Current(), name)
+	if err != nil {
+		return false, err
+	}
+	_, _, format, err := imaging.Bounds(data)
+	if err != nil {
+		return false, err
+	}
+	out, err := imaging.StripMetadata(data, format, getImageSettings().quality)
+	if err != nil {
+		return false, err
+	}
+	if bytes.Equal(out, data) {
+		return false, nil
+	}
+	return true, storage.WriteFile(storage.Current(), name, out)
+}
+
+// ProcessUploadedImage 处理刚上传的图片：按配置去除元数据，并在后台生成各尺寸
+// 后台生成与其他图片处理共用 imageSem，排队期间不读取原图
+// 生成失败不影响上传，访问时会再次尝试生成
+func ProcessUploadedImage(name string) {
+	if !imaging.IsSupported(path.Ext(name)) {
+		return
+	}
+	if getImageSettings().stripExif {
+		if _, err := StripImageMetadata(name); err != nil {
+			fmt.Printf("去除图片元数据失败 %s: %v\n", name, err)
+		}
+	}
+	go func() {
+		if _, err := GenerateImageVariants(name, false); err != nil {
+			fmt.Printf("生成图片尺寸失败 %s: %v\n", name, err)
+		}
+	}()
+}
+
+// RemoveImageVariants 删除文件或目录（含其中所有图片）已生成的各尺寸，在删除、移动、重命名原图后调用
+func RemoveImageVariants(name string) {
+	if dir, ok := imageVariantDir(name); ok {
+		storage.Current().Delete(dir)
+	}
+}
+
+// BackfillImageVariants 为存储中已有的图片补生成各尺寸（跳过隐藏目录，如 .variants 和 .recycle）
+// 参数: force - 重新生成已有的尺寸, stripExif - 同时去除元数据（会修改原图）, progress - 每处理一张图片后回调（可为 nil）
+func BackfillImageVariants(force bool, stripExif bool, progress func(name string)) ImageBackfillResult {
+	result := ImageBackfillResult{Errors: []string{}}
+	fail := func(name string, err error) {
+		result.Failed++
+		if len(result.Errors) < maxBackfillErrors {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", name, err))
+		}
+	}
+
+	storage.Walk(storage.Current(), "", func(info storage.FileInfo) error {
+		if strings.HasPrefix(info.Name, ".") {
+			if info.IsDir {
+				return storage.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir || !imaging.IsSupported(path.Ext(info.Name)) {
+			return nil
+		}
+		name := info.Path
+
+		result.Images++
+		if stripExif {
+			if stripped, err := StripImageMetadata(name); err != nil {
+				fail(name, err)
+				return nil
+			} else if stripped {
+				result.Stripped++
+			}
+		}
+		n, err := GenerateImageVariants(name, force)
+		if err != nil {
+			fail(name, err)
+		}
+		result.Generated += n
+		if progress != nil {
+			progress(name)
+		}
+		return nil
+	})
+	return result
+}
@@ -0,0 +1,107 @@
+package model
+
+// 用户角色
+const (
+	RoleSuperAdmin = 1 // 超级管理员
+	RoleAdmin      = 2 // 管理员
+	RoleUser       = 3 // 普通用户（不能登录后台）
+	RoleEditor     = 4 // 编辑（只能管理内容）
+)
+
+// 权限标识，路由分组通过 middlewares.RequirePermission 声明所需权限
+const (
+	PermUserRead        = "user:read"        // 查看用户
+	PermUserWrite       = "user:write"       // 新增 / 编辑 / 删除用户、管理会话
+	PermArticleWrite    = "article:write"    // 新增 / 编辑文章、修订、ZIP 导入
+	PermArticleDelete   = "article:delete"   // 删除文章
+	PermCategoryWrite   = "category:write"   // 管理分类
+	PermTagWrite        = "tag:write"        // 管理标签
+	PermCommentModerate = "comment:moderate" // 审核评论
+	PermFileUpload      = "file:upload"      // 上传图片等附件
+	PermFileRead        = "file:read"        // 浏览文件管理
+	PermFileWrite       = "file:write"       // 新建 / 重命名 / 移动 / 压缩等文件操作
+	PermFileDelete      = "file:delete"      // 删除文件、回收站
+	PermConfigRead      = "config:read"      // 查看配置、系统状态
+	PermConfigWrite     = "config:write"     // 修改配置、关于页
+	PermAuditRead       = "audit:read"       // 查看、导出审计日志
+	PermSystemBackup    = "system:backup"    // 全站备份与恢复（恢复会替换用户表，仅超级管理员拥有）
+)
+
+// allPermissions 管理员拥有的权限（超级管理员另外拥有 PermSystemBackup）
+var allPermissions = []string{
+	PermUserRead, PermUserWrite,
+	PermArticleWrite, PermArticleDelete, PermCategoryWrite, PermTagWrite, PermCommentModerate,
+	PermFileUpload, PermFileRead, PermFileWrite, PermFileDelete,
+	PermConfigRead, PermConfigWrite,
+	PermAuditRead,
+}
+
+// rolePermissions 角色拥有的权限
+var rolePermissions = map[int][]string{
+	RoleSuperAdmin: append(append([]string{}, allPermissions...), PermSystemBackup),
+	RoleAdmin:      allPermissions,
+	RoleEditor: {
+		PermArticleWrite, PermArticleDelete, PermCategoryWrite, PermTagWrite, PermCommentModerate,
+		PermFileUpload,
+	},
+}
+
+// roleRank 角色等级，数值越小权限越高（角色码本身不代表高低）
+var roleRank = map[int]int{
+	RoleSuperAdmin: 0,
+	RoleAdmin:      1,
+	RoleEditor:     2,
+	RoleUser:       3,
+}
+
+// IsValidRole 判断角色码是否合法
+func IsValidRole(role int) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// RolePermissions 返回角色拥有的权限列表
+func RolePermissions(role int) []string {
+	return rolePermissions[role]
+}
+
+// HasPermission 判断角色是否拥有指定权限
+func HasPermission(role int, perm string) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// CanLoginAdmin 判断角色能否登录后台（至少拥有一项权限）
+func CanLoginAdmin(role int) bool {
+	return len(rolePermissions[role]) > 0
+}
+
+// CanManageRole 判断 current 角色能否管理（创建 / 修改 / 删除）target 角色的用户
+// 超级管理员可以管理所有人，其他角色只能管理等级低于自己的用户
+func CanManageRole(current int, target int) bool {
+	if current == RoleSuperAdmin {
+		return true
+	}
+	cr, ok1 := roleRank[current]
+	tr, ok2 := roleRank[target]
+	return ok1 && ok2 && tr > cr
+}
+
+// visibleRoles 返回 current 角色在用户列表中可以看到的角色（自己及等级更低的角色）
+func visibleRoles(current int) []int {
+	cr, ok := roleRank[current]
+	if !ok {
+		return nil
+	}
+	var roles []int
+	for role, rank := range roleRank {
+		if rank >= cr {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
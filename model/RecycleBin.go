@@ -0,0 +1,297 @@
+package model
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+	"yanblog/utils"
+	"yanblog/utils/errmsg"
+	"yanblog/utils/storage"
+)
+
+// RecycleBinDir 回收站在存储中的目录
+const RecycleBinDir = ".recycle"
+
+// recycleTimeLayout 回收站中名称的时间前缀格式：<删除时间>_<原名称>
+const recycleTimeLayout = "20060102_150405"
+
+// 恢复时原位置已存在同名文件的处理方式
+const (
+	RestoreRename    = "rename"    // 恢复为 <原名称>_restored<扩展名>
+	RestoreOverwrite = "overwrite" // 原位置的文件先移入回收站，再恢复
+	RestoreSkip      = "skip"      // 不恢复，保留在回收站中
+)
+
+// RecycleItem 回收站中的项目（回收站目录下的一个文件或目录）
+type RecycleItem struct {
+	ID           uint       `gorm:"primarykey" json:"id"`
+	OriginalPath string     `gorm:"type:varchar(500);not null" json:"original_path"`
+	RecyclePath  string     `gorm:"type:varchar(500);not null;uniqueIndex" json:"recycle_path"`
+	Name         string     `gorm:"type:varchar(255);not null" json:"name"`
+	Size         int64      `json:"size"` // 目录为其中所有文件的大小之和
+	IsDir        bool       `json:"is_dir"`
+	Username     string     `gorm:"type:varchar(20);index" json:"username"` // 删除的用户（空表示系统或旧版本留下的项目）
+	RecycledAt   time.Time  `gorm:"index" json:"deleted_at"`
+	ExpiresAt    *time.Time `gorm:"-" json:"expires_at,omitempty"` // 按保留天数自动永久删除的时间
+}
+
+// recycleRetention 回收站项目的保留时长，0 表示不自动清理
+func recycleRetention() time.Duration {
+	days := utils.GetConfig().RecycleBin.RetentionDays
+	if days <= 0 {
+		return 0
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// parseRecycleName 解析回收站中的名称 <删除时间>_<原名称>，返回原名称和删除时间
+func parseRecycleName(name string) (string, time.Time, bool) {
+	if len(name) <= len(recycleTimeLayout)+1 || name[len(recycleTimeLayout)] != '_' {
+		return "", time.Time{}, false
+	}
+	t, err := time.ParseInLocation(recycleTimeLayout, name[:len(recycleTimeLayout)], time.Local)
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return name[len(recycleTimeLayout)+1:], t, true
+}
+
+// restoredName 原位置已存在同名文件时的恢复名称：<原名称>_restored<扩展名>，仍然存在时追加序号
+func restoredName(name string, exists func(string) bool) string {
+	ext := path.Ext(name)
+	if strings.HasPrefix(path.Base(name), ".") && ext == path.Base(name) {
+		ext = ""
+	}
+	base := strings.TrimSuffix(name, ext)
+	candidate := base + "_restored" + ext
+	for i := 2; exists(candidate); i++ {
+		candidate = fmt.Sprintf("%s_restored_%d%s", base, i, ext)
+	}
+	return candidate
+}
+
+// storageSize 文件的大小，或目录中所有文件的大小之和
+func storageSize(store storage.Storage, info storage.FileInfo) int64 {
+	if !info.IsDir {
+		return info.Size
+	}
+	var size int64
+	storage.Walk(store, info.Path, func(f storage.FileInfo) error {
+		if !f.IsDir {
+			size += f.Size
+		}
+		return nil
+	})
+	return size
+}
+
+// RecycleUpload 将存储中的文件或目录移动到回收站并记录原位置，同时删除已生成的图片尺寸
+// 回收站中的名称为 <删除时间>_<原名称>，同名时在扩展名前追加序号
+func RecycleUpload(name string, username string) (RecycleItem, error) {
+	store := storage.Current()
+	info, err := store.Stat(name)
+	if err != nil {
+		return RecycleItem{}, err
+	}
+	if err := store.Mkdir(RecycleBinDir); err != nil {
+		return RecycleItem{}, err
+	}
+
+	now := time.Now()
+	base := path.Base(name)
+	prefix := now.Format(recycleTimeLayout) + "_"
+	recyclePath := path.Join(RecycleBinDir, prefix+base)
+	ext := path.Ext(base)
+	for i := 1; storage.Exists(store, recyclePath); i++ {
+		recyclePath = path.Join(RecycleBinDir, fmt.Sprintf("%s%s_%d%s", prefix, strings.TrimSuffix(base, ext), i, ext))
+	}
+
+	item := RecycleItem{
+		OriginalPath: name,
+		RecyclePath:  recyclePath,
+		Name:         base,
+		Size:         storageSize(store, info),
+		IsDir:        info.IsDir,
+		Username:     username,
+		RecycledAt:   now,
+	}
+	if err := store.Move(name, recyclePath); err != nil {
+		return RecycleItem{}, err
+	}
+	RemoveImageVariants(name)
+	// 同名的旧记录（文件已被手动删除）不再有效
+	db.Where("recycle_path = ?", recyclePath).Delete(&RecycleItem{})
+	if err := db.Create(&item).Error; err != nil {
+		fmt.Printf("记录回收站项目失败 %s: %v\n", recyclePath, err)
+	}
+	return item, nil
+}
+
+// syncRecycleBin 使回收站记录与回收站目录一致：为旧版本留下或手动放入的项目补充记录（按名称推断原位置），删除文件已不存在的记录
+func syncRecycleBin() error {
+	store := storage.Current()
+	entries, err := store.List(RecycleBinDir)
+	if err != nil && !storage.IsNotExist(err) {
+		return err
+	}
+
+	var items []RecycleItem
+	if err := db.Find(&items).Error; err != nil {
+		return err
+	}
+	recorded := make(map[string]bool, len(items))
+	for _, item := range items {
+		recorded[item.RecyclePath] = true
+	}
+
+	present := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		present[e.Path] = true
+		if recorded[e.Path] {
+			continue
+		}
+		item := RecycleItem{
+			OriginalPath: e.Name,
+			RecyclePath:  e.Path,
+			Name:         e.Name,
+			Size:         storageSize(store, e),
+			IsDir:        e.IsDir,
+			RecycledAt:   e.ModTime,
+		}
+		if original, t, ok := parseRecycleName(e.Name); ok {
+			item.OriginalPath, item.Name, item.RecycledAt = original, original, t
+		}
+		if item.RecycledAt.IsZero() {
+			item.RecycledAt = time.Now()
+		}
+		db.Create(&item)
+	}
+	for _, item := range items {
+		if !present[item.RecyclePath] {
+			db.Delete(&item)
+		}
+	}
+	return nil
+}
+
+// ListRecycleItems 获取回收站中的项目，按删除时间倒序
+func ListRecycleItems() ([]RecycleItem, error) {
+	if err := syncRecycleBin(); err != nil {
+		return nil, err
+	}
+	items := []RecycleItem{}
+	if err := db.Order("recycled_at DESC, id DESC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	if retention := recycleRetention(); retention > 0 {
+		for i := range items {
+			expires := items[i].RecycledAt.Add(retention)
+			items[i].ExpiresAt = &expires
+		}
+	}
+	return items, nil
+}
+
+// GetRecycleItem 根据 ID 或回收站路径查询项目
+func GetRecycleItem(id uint, recyclePath string) (RecycleItem, int) {
+	var item RecycleItem
+	query := db.Where("id = ?", id)
+	if id == 0 {
+		syncRecycleBin()
+		query = db.Where("recycle_path = ?", recyclePath)
+	}
+	if err := query.First(&item).Error; err != nil {
+		return item, errmsg.ERROR_RECYCLE_NOT_EXIST
+	}
+	return item, errmsg.SUCCESS
+}
+
+// RestoreRecycleItem 将回收站中的项目恢复到原位置
+// 参数: conflict - 原位置已存在同名文件时的处理方式（RestoreRename / RestoreOverwrite / RestoreSkip）, username - 操作用户（覆盖时原位置的文件以该用户名义移入回收站）
+// 返回: 恢复后的路径和状态码
+func RestoreRecycleItem(item RecycleItem, conflict string, username string) (string, int) {
+	store := storage.Current()
+	if !storage.Exists(store, item.RecyclePath) {
+		db.Delete(&item)
+		return "", errmsg.ERROR_RECYCLE_NOT_EXIST
+	}
+
+	target := item.OriginalPath
+	if storage.Exists(store, target) {
+		switch conflict {
+		case RestoreSkip:
+			return target, errmsg.ERROR_RESTORE_CONFLICT
+		case RestoreOverwrite:
+			if _, err := RecycleUpload(target, username); err != nil {
+				return target, errmsg.ERROR
+			}
+		default:
+			target = restoredName(target, func(name string) bool {
+				return storage.Exists(store, name)
+			})
+		}
+	}
+
+	if err := store.Move(item.RecyclePath, target); err != nil {
+		return target, errmsg.ERROR
+	}
+	db.Delete(&item)
+	return target, errmsg.SUCCESS
+}
+
+// DeleteRecycleItem 永久删除回收站中的项目
+func DeleteRecycleItem(item RecycleItem) int {
+	if err := storage.Current().Delete(item.RecyclePath); err != nil {
+		return errmsg.ERROR
+	}
+	db.Delete(&item)
+	return errmsg.SUCCESS
+}
+
+// EmptyRecycleBin 清空回收站，返回被永久删除的项目
+func EmptyRecycleBin() ([]RecycleItem, error) {
+	items, err := ListRecycleItems()
+	if err != nil {
+		return nil, err
+	}
+	if err := storage.Current().Delete(RecycleBinDir); err != nil {
+		return nil, err
+	}
+	db.Where("1 = 1").Delete(&RecycleItem{})
+	return items, nil
+}
+
+// PurgeExpiredRecycleItems 永久删除超过保留天数的回收站项目，返回删除的数量
+func PurgeExpiredRecycleItems() int {
+	retention := recycleRetention()
+	if retention <= 0 {
+		return 0
+	}
+	if err := syncRecycleBin(); err != nil {
+		fmt.Println("读取回收站失败:", err)
+		return 0
+	}
+	var items []RecycleItem
+	db.Where("recycled_at < ?", time.Now().Add(-retention)).Find(&items)
+	purged := 0
+	for _, item := range items {
+		if DeleteRecycleItem(item) == errmsg.SUCCESS {
+			purged++
+		}
+	}
+	return purged
+}
+
+// RunRecycleBinCleanup 启动时及之后每小时永久删除超过保留天数的回收站项目
+func RunRecycleBinCleanup() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		if n := PurgeExpiredRecycleItems(); n > 0 {
+			fmt.Printf("回收站已自动清理 %d 个过期项目\n", n)
+		}
+		<-ticker.C
+	}
+}
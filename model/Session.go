@@ -0,0 +1,164 @@
+package model
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+	"yanblog/utils/errmsg"
+)
+
+// UserSession 登录会话
+// 访问令牌的 jti 即会话ID，会话被撤销后该会话签发的所有访问令牌立即失效；
+// 刷新令牌只保存哈希，每次刷新都会轮换
+type UserSession struct {
+	ID              uint       `gorm:"primarykey" json:"id"`
+	SessionID       string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"session_id"`
+	UserID          uint       `gorm:"not null;index" json:"user_id"`
+	Username        string     `gorm:"type:varchar(20);not null" json:"username"`
+	RefreshHash     string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	PrevRefreshHash string     `gorm:"type:varchar(64);index" json:"-"` // 上一个刷新令牌，用于发现令牌被盗用后的重放
+	IP              string     `gorm:"type:varchar(45)" json:"ip"`
+	UserAgent       string     `gorm:"type:varchar(255)" json:"user_agent"`
+	ExpiresAt       time.Time  `gorm:"not null;index" json:"expires_at"` // 刷新令牌过期时间
+	LastUsedAt      time.Time  `json:"last_used_at"`
+	RevokedAt       *time.Time `gorm:"index" json:"revoked_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+}
+
+// randomToken 生成随机令牌（hex 编码）
+func randomToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateSession 用户登录成功后创建会话
+// 参数: username - 用户名, ip - 客户端IP, userAgent - 客户端UA, ttl - 刷新令牌有效期
+// 返回: 会话、刷新令牌明文和状态码
+func CreateSession(username string, ip string, userAgent string, ttl time.Duration) (UserSession, string, int) {
+	var user User
+	if err := db.Select("id", "username").Where("username = ?", username).First(&user).Error; err != nil {
+		return UserSession{}, "", errmsg.ERROR_USER_NOT_EXIST
+	}
+
+	if len(userAgent) > 255 {
+		userAgent = userAgent[:255]
+	}
+	refreshToken := randomToken()
+	now := time.Now()
+	session := UserSession{
+		SessionID:   randomToken(),
+		UserID:      user.ID,
+		Username:    user.Username,
+		RefreshHash: hashRefreshToken(refreshToken),
+		IP:          ip,
+		UserAgent:   userAgent,
+		ExpiresAt:   now.Add(ttl),
+		LastUsedAt:  now,
+	}
+	if err := db.Create(&session).Error; err != nil {
+		return UserSession{}, "", errmsg.ERROR
+	}
+	return session, refreshToken, errmsg.SUCCESS
+}
+
+// RotateSession 使用刷新令牌换取新的刷新令牌（旧令牌立即作废）
+// 已轮换掉的旧令牌再次出现说明令牌可能被盗用，此时撤销整个会话
+// 参数: refreshToken - 刷新令牌明文, ttl - 新刷新令牌有效期
+// 返回: 会话、新刷新令牌明文和状态码
+func RotateSession(refreshToken string, ttl time.Duration) (UserSession, string, int) {
+	var session UserSession
+	hash := hashRefreshToken(refreshToken)
+	now := time.Now()
+
+	if err := db.Where("refresh_hash = ?", hash).First(&session).Error; err != nil {
+		// 重放已轮换的令牌：撤销该会话
+		db.Model(&UserSession{}).Where("prev_refresh_hash = ? AND revoked_at IS NULL", hash).Update("revoked_at", now)
+		return UserSession{}, "", errmsg.ERROR_REFRESH_WRONG
+	}
+	if session.RevokedAt != nil || now.After(session.ExpiresAt) {
+		return UserSession{}, "", errmsg.ERROR_REFRESH_WRONG
+	}
+	// 用户已被删除
+	var count int64
+	db.Model(&User{}).Where("id = ?", session.UserID).Count(&count)
+	if count == 0 {
+		RevokeSession(session.SessionID)
+		return UserSession{}, "", errmsg.ERROR_REFRESH_WRONG
+	}
+
+	newToken := randomToken()
+	result := db.Model(&UserSession{}).
+		Where("id = ? AND refresh_hash = ?", session.ID, hash). // 并发刷新时只有一个请求能成功
+		Updates(map[string]interface{}{
+			"refresh_hash":      hashRefreshToken(newToken),
+			"prev_refresh_hash": hash,
+			"expires_at":        now.Add(ttl),
+			"last_used_at":      now,
+		})
+	if result.Error != nil {
+		return UserSession{}, "", errmsg.ERROR
+	}
+	if result.RowsAffected == 0 {
+		return UserSession{}, "", errmsg.ERROR_REFRESH_WRONG
+	}
+	return session, newToken, errmsg.SUCCESS
+}
+
+// IsSessionActive 判断会话是否有效（未撤销且刷新令牌未过期）
+func IsSessionActive(sessionID string) bool {
+	var count int64
+	db.Model(&UserSession{}).
+		Where("session_id = ? AND revoked_at IS NULL AND expires_at > ?", sessionID, time.Now()).
+		Count(&count)
+	return count > 0
+}
+
+// RevokeSession 撤销单个会话（退出登录）
+// 返回: 状态码
+func RevokeSession(sessionID string) int {
+	err := db.Model(&UserSession{}).
+		Where("session_id = ? AND revoked_at IS NULL", sessionID).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return errmsg.ERROR
+	}
+	return errmsg.SUCCESS
+}
+
+// RevokeUserSessions 撤销用户的全部会话（删除用户、修改角色或密码时调用）
+// 参数: userID - 用户ID
+// 返回: 撤销的会话数
+func RevokeUserSessions(userID int) int64 {
+	result := db.Model(&UserSession{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected
+}
+
+// GetUserSessions 查询用户当前有效的会话
+// 参数: userID - 用户ID
+// 返回: 会话列表（按最近使用时间倒序）
+func GetUserSessions(userID int) []UserSession {
+	var sessions []UserSession
+	db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").Find(&sessions)
+	return sessions
+}
+
+// RunSessionCleanup 定期清理已过期或已撤销超过 7 天的会话记录
+func RunSessionCleanup() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		cutoff := time.Now().Add(-7 * 24 * time.Hour)
+		db.Where("expires_at < ? OR revoked_at < ?", cutoff, cutoff).Delete(&UserSession{})
+	}
+}
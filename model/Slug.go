@@ -0,0 +1,135 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"yanblog/utils"
+
+	"gorm.io/gorm/clause"
+)
+
+// slug 所属对象类型
+const (
+	SlugKindArticle  = "article"
+	SlugKindCategory = "category"
+)
+
+// SlugHistory 记录文章 / 分类曾经使用过的 slug，旧链接访问时 301 跳转到当前 slug
+type SlugHistory struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Kind      string    `gorm:"type:varchar(20);not null;uniqueIndex:idx_slug_history_kind_slug" json:"kind"`
+	Slug      string    `gorm:"type:varchar(100);not null;uniqueIndex:idx_slug_history_kind_slug" json:"slug"`
+	TargetID  uint      `gorm:"not null;index" json:"target_id"` // 文章或分类 ID
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// slugModel 返回 slug 所属的数据模型
+func slugModel(kind string) interface{} {
+	if kind == SlugKindCategory {
+		return &Category{}
+	}
+	return &Article{}
+}
+
+// slugTaken 判断 slug 是否已被其他对象占用（包括回收站中的文章和其他对象的历史 slug）
+// 参数: kind - 对象类型, slug - 待检查的 slug, excludeID - 当前对象 ID（新建时为 0）
+func slugTaken(kind string, slug string, excludeID uint) bool {
+	var count int64
+	db.Unscoped().Model(slugModel(kind)).Where("slug = ? AND id <> ?", slug, excludeID).Count(&count)
+	if count > 0 {
+		return true
+	}
+	db.Model(&SlugHistory{}).Where("kind = ? AND slug = ? AND target_id <> ?", kind, slug, excludeID).Count(&count)
+	return count > 0
+}
+
+// uniqueSlug 根据标题生成不冲突的 slug，冲突时追加 -2、-3 等后缀
+// 纯数字的 slug 会与 ID 链接混淆，自动加上类型前缀
+// 参数: kind - 对象类型, title - 标题或名称, excludeID - 当前对象 ID（新建时为 0）
+func uniqueSlug(kind string, title string, excludeID uint) string {
+	base := utils.Slugify(title)
+	if base == "" {
+		base = kind
+	} else if isNumericSlug(base) {
+		base = kind + "-" + base
+	}
+
+	slug := base
+	for i := 2; slugTaken(kind, slug, excludeID); i++ {
+		suffix := fmt.Sprintf("-%d", i)
+		if len(base)+len(suffix) > utils.SlugMaxLength {
+			base = strings.TrimRight(base[:utils.SlugMaxLength-len(suffix)], "-")
+		}
+		slug = base + suffix
+	}
+	return slug
+}
+
+// resolveSlug 规范化并确定对象保存时使用的 slug
+// 手动指定的 slug 被占用时返回 false；未指定时沿用旧 slug，标题变更或旧 slug 为空时重新生成
+// 参数: kind - 对象类型, input - 手动指定的 slug, title - 标题, oldTitle / oldSlug - 修改前的标题和 slug, id - 对象 ID（新建时为 0）
+// 返回: 最终 slug 和是否可用
+func resolveSlug(kind string, input string, title string, oldTitle string, oldSlug string, id uint) (string, bool) {
+	if slug := utils.Slugify(input); slug != "" {
+		if isNumericSlug(slug) {
+			slug = kind + "-" + slug
+		}
+		if slug != oldSlug && slugTaken(kind, slug, id) {
+			return slug, false
+		}
+		return slug, true
+	}
+	if oldSlug != "" && title == oldTitle {
+		return oldSlug, true
+	}
+	return uniqueSlug(kind, title, id), true
+}
+
+// recordSlugChange 记录 slug 变更：旧 slug 写入历史并指向当前对象
+// 如果对象改回了自己以前用过的 slug，删除对应的历史记录
+func recordSlugChange(kind string, id uint, oldSlug string, newSlug string) {
+	if oldSlug == "" || oldSlug == newSlug {
+		return
+	}
+	db.Where("kind = ? AND slug = ? AND target_id = ?", kind, newSlug, id).Delete(&SlugHistory{})
+	db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "kind"}, {Name: "slug"}},
+		DoUpdates: clause.AssignmentColumns([]string{"target_id", "created_at"}),
+	}).Create(&SlugHistory{Kind: kind, Slug: oldSlug, TargetID: id})
+}
+
+// findSlugHistory 通过历史 slug 查找对象 ID
+func findSlugHistory(kind string, slug string) (uint, bool) {
+	var history SlugHistory
+	if err := db.Where("kind = ? AND slug = ?", kind, slug).First(&history).Error; err != nil {
+		return 0, false
+	}
+	return history.TargetID, true
+}
+
+// backfillSlugs 为升级前创建、尚未设置 slug 的文章和分类生成 slug
+func backfillSlugs() {
+	var articles []Article
+	// 优先为未删除的文章分配不带后缀的 slug
+	db.Unscoped().Select("id", "title").Where("slug IS NULL OR slug = ''").Order("deleted_at IS NOT NULL, id").Find(&articles)
+	for _, art := range articles {
+		slug := uniqueSlug(SlugKindArticle, art.Title, art.ID)
+		db.Unscoped().Model(&Article{}).Where("id = ?", art.ID).UpdateColumn("slug", slug)
+	}
+
+	var cates []Category
+	db.Unscoped().Select("id", "name").Where("slug IS NULL OR slug = ''").Find(&cates)
+	for _, cate := range cates {
+		slug := uniqueSlug(SlugKindCategory, cate.Name, cate.ID)
+		db.Unscoped().Model(&Category{}).Where("id = ?", cate.ID).UpdateColumn("slug", slug)
+	}
+
+	if len(articles) > 0 || len(cates) > 0 {
+		fmt.Printf("已为 %d 篇文章、%d 个分类生成 slug\n", len(articles), len(cates))
+	}
+}
+
+func isNumericSlug(s string) bool {
+	return strings.Trim(s, "0123456789") == ""
+}
@@ -0,0 +1,321 @@
+package model
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+	"yanblog/utils"
+	"yanblog/utils/errmsg"
+
+	"gorm.io/gorm"
+)
+
+// UserTOTP 用户的两步验证（TOTP）设置
+// 生成密钥后需用一次验证码确认才会启用，未确认的密钥可以重新生成
+type UserTOTP struct {
+	ID        uint       `gorm:"primarykey" json:"id"`
+	UserID    uint       `gorm:"not null;uniqueIndex" json:"user_id"`
+	Secret    string     `gorm:"type:varchar(64);not null" json:"-"`
+	Enabled   bool       `gorm:"not null;default:false" json:"enabled"`
+	LastStep  int64      `gorm:"not null;default:0" json:"-"` // 最近一次验证通过的时间步，同一验证码不能重复使用
+	EnabledAt *time.Time `json:"enabled_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// UserRecoveryCode 两步验证恢复码（只保存哈希，每个只能使用一次）
+type UserRecoveryCode struct {
+	ID        uint       `gorm:"primarykey"`
+	UserID    uint       `gorm:"not null;index"`
+	CodeHash  string     `gorm:"type:varchar(64);not null;index"`
+	UsedAt    *time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
+
+const (
+	recoveryCodeCount = 10              // 每次生成的恢复码数量
+	totpSkew          = 1               // 允许前后各 1 个时间步（30 秒）的时钟偏差
+	loginChallengeTTL = 5 * time.Minute // 两步登录中第二步的有效期
+	maxChallengeTries = 5               // 每个登录挑战最多尝试次数
+)
+
+// TwoFactorStatus 两步验证状态
+type TwoFactorStatus struct {
+	Enabled       bool       `json:"enabled"`
+	EnabledAt     *time.Time `json:"enabled_at"`
+	RecoveryCodes int64      `json:"recovery_codes"` // 剩余可用恢复码数量
+}
+
+// GetTwoFactorStatus 查询用户的两步验证状态
+func GetTwoFactorStatus(userID uint) TwoFactorStatus {
+	var status TwoFactorStatus
+	var totp UserTOTP
+	if err := db.Where("user_id = ? AND enabled = ?", userID, true).First(&totp).Error; err != nil {
+		return status
+	}
+	status.Enabled = true
+	status.EnabledAt = totp.EnabledAt
+	db.Model(&UserRecoveryCode{}).Where("user_id = ? AND used_at IS NULL", userID).Count(&status.RecoveryCodes)
+	return status
+}
+
+// IsTwoFactorEnabled 判断用户是否已启用两步验证
+// 参数: username - 用户名
+// 返回: 用户ID和是否已启用
+func IsTwoFactorEnabled(username string) (uint, bool) {
+	var user User
+	if err := db.Select("id").Where("username = ?", username).First(&user).Error; err != nil {
+		return 0, false
+	}
+	var count int64
+	db.Model(&UserTOTP{}).Where("user_id = ? AND enabled = ?", user.ID, true).Count(&count)
+	return user.ID, count > 0
+}
+
+// SetupTwoFactor 为用户生成新的 TOTP 密钥（尚未启用）
+// 已启用两步验证时需先关闭才能重新设置
+// 返回: 密钥和状态码
+func SetupTwoFactor(userID uint) (string, int) {
+	var totp UserTOTP
+	err := db.Where("user_id = ?", userID).First(&totp).Error
+	if err == nil && totp.Enabled {
+		return "", errmsg.ERROR_TOTP_ENABLED
+	}
+
+	secret, genErr := utils.GenerateTOTPSecret()
+	if genErr != nil {
+		return "", errmsg.ERROR
+	}
+	if err != nil {
+		totp = UserTOTP{UserID: userID}
+	}
+	totp.Secret = secret
+	totp.LastStep = 0
+	if err := db.Save(&totp).Error; err != nil {
+		return "", errmsg.ERROR
+	}
+	return secret, errmsg.SUCCESS
+}
+
+// EnableTwoFactor 用验证码确认密钥并启用两步验证，同时生成恢复码
+// 返回: 恢复码明文（只在此时返回一次）和状态码
+func EnableTwoFactor(userID uint, code string) ([]string, int) {
+	var totp UserTOTP
+	if err := db.Where("user_id = ?", userID).First(&totp).Error; err != nil {
+		return nil, errmsg.ERROR_TOTP_NOT_SETUP
+	}
+	if totp.Enabled {
+		return nil, errmsg.ERROR_TOTP_ENABLED
+	}
+	step, ok := utils.ValidateTOTP(totp.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, errmsg.ERROR_TOTP_WRONG
+	}
+
+	var codes []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&totp).Updates(map[string]interface{}{
+			"enabled":    true,
+			"enabled_at": now,
+			"last_step":  step,
+		}).Error; err != nil {
+			return err
+		}
+		var err error
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, errmsg.ERROR
+	}
+	return codes, errmsg.SUCCESS
+}
+
+// DisableTwoFactor 关闭两步验证，删除密钥和全部恢复码
+func DisableTwoFactor(userID uint) int {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&UserTOTP{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&UserRecoveryCode{}).Error
+	})
+	if err != nil {
+		return errmsg.ERROR
+	}
+	return errmsg.SUCCESS
+}
+
+// RegenerateRecoveryCodes 重新生成恢复码（旧恢复码全部作废）
+// 返回: 新恢复码明文和状态码
+func RegenerateRecoveryCodes(userID uint) ([]string, int) {
+	if !GetTwoFactorStatus(userID).Enabled {
+		return nil, errmsg.ERROR_TOTP_NOT_SETUP
+	}
+	var codes []string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = replaceRecoveryCodes(tx, userID)
+		return err
+	})
+	if err != nil {
+		return nil, errmsg.ERROR
+	}
+	return codes, errmsg.SUCCESS
+}
+
+// VerifySecondFactor 校验第二因素：6 位数字按 TOTP 验证码处理，否则按恢复码处理
+// 验证码和恢复码都只能使用一次
+// 返回: 状态码
+func VerifySecondFactor(userID uint, code string) int {
+	code = strings.TrimSpace(code)
+	if len(code) == utils.TOTPDigits && strings.Trim(code, "0123456789") == "" {
+		return verifyTOTPCode(userID, code)
+	}
+	return useRecoveryCode(userID, code)
+}
+
+// verifyTOTPCode 校验 TOTP 验证码，并拒绝重复使用已验证过的时间步
+func verifyTOTPCode(userID uint, code string) int {
+	var totp UserTOTP
+	if err := db.Where("user_id = ? AND enabled = ?", userID, true).First(&totp).Error; err != nil {
+		return errmsg.ERROR_TOTP_NOT_SETUP
+	}
+	step, ok := utils.ValidateTOTP(totp.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		return errmsg.ERROR_TOTP_WRONG
+	}
+	// 条件更新保证并发请求中同一时间步只有一个能通过
+	result := db.Model(&UserTOTP{}).Where("id = ? AND last_step < ?", totp.ID, step).Update("last_step", step)
+	if result.Error != nil {
+		return errmsg.ERROR
+	}
+	if result.RowsAffected == 0 {
+		return errmsg.ERROR_TOTP_WRONG
+	}
+	return errmsg.SUCCESS
+}
+
+// useRecoveryCode 使用一个恢复码
+func useRecoveryCode(userID uint, code string) int {
+	hash := hashRecoveryCode(code)
+	if hash == "" {
+		return errmsg.ERROR_TOTP_WRONG
+	}
+	result := db.Model(&UserRecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, hash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return errmsg.ERROR
+	}
+	if result.RowsAffected == 0 {
+		return errmsg.ERROR_TOTP_WRONG
+	}
+	return errmsg.SUCCESS
+}
+
+// replaceRecoveryCodes 删除旧恢复码并生成一组新的
+func replaceRecoveryCodes(tx *gorm.DB, userID uint) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&UserRecoveryCode{}).Error; err != nil {
+		return nil, err
+	}
+	codes := make([]string, recoveryCodeCount)
+	records := make([]UserRecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := hex.EncodeToString(b)
+		codes[i] = raw[:5] + "-" + raw[5:]
+		records[i] = UserRecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(codes[i])}
+	}
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// normalizeRecoveryCode 统一恢复码格式（忽略大小写、空格和连字符）
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+func hashRecoveryCode(code string) string {
+	code = normalizeRecoveryCode(code)
+	if code == "" {
+		return ""
+	}
+	return hashRefreshToken(code)
+}
+
+// loginChallenge 密码验证通过、等待第二因素的登录
+type loginChallenge struct {
+	username  string
+	userID    uint
+	expiresAt time.Time
+	tries     int
+}
+
+var (
+	loginChallenges   = make(map[string]*loginChallenge)
+	loginChallengesMu sync.Mutex
+)
+
+// CreateLoginChallenge 密码验证通过后创建登录挑战，客户端凭返回的令牌提交第二因素
+func CreateLoginChallenge(username string, userID uint) string {
+	loginChallengesMu.Lock()
+	defer loginChallengesMu.Unlock()
+
+	now := time.Now()
+	for token, ch := range loginChallenges {
+		if now.After(ch.expiresAt) {
+			delete(loginChallenges, token)
+		}
+	}
+	token := randomToken()
+	loginChallenges[token] = &loginChallenge{
+		username:  username,
+		userID:    userID,
+		expiresAt: now.Add(loginChallengeTTL),
+	}
+	return token
+}
+
+// CompleteLoginChallenge 提交第二因素完成登录
+// 验证通过或尝试次数用尽后挑战立即作废
+// 返回: 用户名和状态码
+func CompleteLoginChallenge(token string, code string) (string, int) {
+	loginChallengesMu.Lock()
+	ch, ok := loginChallenges[token]
+	if ok && time.Now().After(ch.expiresAt) {
+		delete(loginChallenges, token)
+		ok = false
+	}
+	if !ok {
+		loginChallengesMu.Unlock()
+		return "", errmsg.ERROR_TOTP_CHALLENGE
+	}
+	ch.tries++
+	if ch.tries >= maxChallengeTries {
+		delete(loginChallenges, token)
+	}
+	loginChallengesMu.Unlock()
+
+	if code := VerifySecondFactor(ch.userID, code); code != errmsg.SUCCESS {
+		return "", code
+	}
+
+	loginChallengesMu.Lock()
+	delete(loginChallenges, token)
+	loginChallengesMu.Unlock()
+	return ch.username, errmsg.SUCCESS
+}
+
+// LoginChallengeTTL 登录挑战有效期
+func LoginChallengeTTL() time.Duration {
+	return loginChallengeTTL
+}
@@ -2,21 +2,18 @@ package model
 
 import (
 	"fmt"
-	"io"
 	"mime/multipart"
-	"os"
-	"path/filepath"
+	"path"
 	"time"
 	"yanblog/utils/errmsg"
 )
 
-// UpLoadFile 上传文件到本地
+// UpLoadFile 上传文件到存储（本地 uploads 目录或配置的远程存储）
 // 参数: file - 要上传的文件, fileHeader - 文件头信息, uploadType - 上传类型(article/category), key - 关键标识(文章标题/分类名)
 // 返回: 文件访问URL和状态码
 func UpLoadFile(file multipart.File, fileHeader *multipart.FileHeader, uploadType string, key string) (string, int) {
-	// 1. 确定存储目录
-	baseDir := "./uploads"
-	targetDir := baseDir
+	// 1. 确定存储目录（相对存储根目录）
+	var targetDir string
 
 	// 简单的文件名清理函数，防止路径遍历和非法字符
 	// cleanKey := filepath.Clean(key) // 废弃: 不再依赖用户输入的 key 作为目录名，避免改名带来的路径失效问题
@@ -24,56 +21,40 @@ func UpLoadFile(file multipart.File, fileHeader *multipart.FileHeader, uploadTyp
 	switch uploadType {
 	case "avatar":
 		// 用户头像
-		targetDir = filepath.Join(baseDir, "avatar")
+		targetDir = "avatar"
 	case "category":
 		// 分类封面
-		targetDir = filepath.Join(baseDir, "category")
+		targetDir = "category"
 	case "article", "markdown":
 		// 文章内容图片 (Markdown中插入的)
 		// 使用 年/月 格式，避免单文件夹文件过多
 		now := time.Now()
-		targetDir = filepath.Join(baseDir, "article", "content", fmt.Sprintf("%d%02d", now.Year(), now.Month()))
+		targetDir = path.Join("article", "content", fmt.Sprintf("%d%02d", now.Year(), now.Month()))
 	case "cover":
 		// 文章封面
-		targetDir = filepath.Join(baseDir, "article", "cover")
+		targetDir = path.Join("article", "cover")
 	case "pdf":
 		// PDF文件
-		targetDir = filepath.Join(baseDir, "article", "pdf")
+		targetDir = path.Join("article", "pdf")
 	case "system":
 		// 系统配置图片 (Logo, 背景等)
-		targetDir = filepath.Join(baseDir, "system")
+		targetDir = "system"
 	default:
 		// 其他/通用
-		targetDir = filepath.Join(baseDir, "common")
+		targetDir = "common"
 	}
 
-	// 确保存储目录存在
-	if _, err := os.Stat(targetDir); os.IsNotExist(err) {
-		_ = os.MkdirAll(targetDir, 0755)
-	}
-
-	// 2. 生成文件名
-	ext := filepath.Ext(fileHeader.Filename)
-	fileName := fmt.Sprintf("%d%s", time.Now().UnixNano(), ext)
-	filePath := filepath.Join(targetDir, fileName)
-
-	// 3. 创建目标文件
-	out, err := os.Create(filePath)
+	// 2. 按内容哈希写入存储（自动创建目录），相同内容的文件只保存一份
+	name, created, err := StoreUpload(file, fileHeader.Size, targetDir, path.Ext(fileHeader.Filename))
 	if err != nil {
 		return "", errmsg.ERROR
 	}
-	defer out.Close()
 
-	// 4. 写入文件内容
-	_, err = io.Copy(out, file)
-	if err != nil {
-		return "", errmsg.ERROR
+	// 3. 新保存的图片按配置去除元数据，并生成缩略图和不同宽度的图片
+	if created {
+		ProcessUploadedImage(name)
 	}
 
-	// 5. 返回访问URL
-	// 需要将文件路径转换为URL路径 (将反斜杠转换为斜杠)
-	relPath, _ := filepath.Rel(".", filePath)
-	url := "/" + filepath.ToSlash(relPath)
-
-	return url, errmsg.SUCCESS
+	// 4. 返回访问URL
+	return "/uploads/" + name, errmsg.SUCCESS
 }
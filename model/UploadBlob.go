@@ -0,0 +1,447 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"yanblog/utils"
+	"yanblog/utils/storage"
+
+	"gorm.io/gorm"
+)
+
+// UploadBlob 按内容寻址的上传文件
+// 内容（SHA-256）相同的上传只在存储中保存一份，RefCount 记录这份文件被上传（或迁移时被合并）的次数
+// 哈希按上传时的原始内容计算（去除元数据之前），迁移合并时多个哈希可能指向同一个文件，此时文件的引用数为这些记录之和
+type UploadBlob struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	Hash      string    `gorm:"type:varchar(64);not null;uniqueIndex" json:"hash"` // 内容的 SHA-256（十六进制）
+	Name      string    `gorm:"type:varchar(255);not null;index" json:"name"`      // 文件在存储中的路径
+	Size      int64     `json:"size"`
+	RefCount  int       `gorm:"not null" json:"ref_count"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// uploadBlobHashLen 文件名中使用的哈希长度（128 位），避免地址超出文章封面字段的长度
+const uploadBlobHashLen = 32
+
+var (
+	// blobLocks 按内容哈希加锁，避免相同内容被并发保存成多份
+	blobLocks keyLock
+	// blobMu 保护引用计数的增减，避免文件刚被复用就因引用归零被删除
+	blobMu sync.Mutex
+)
+
+// uploadBlobName 根据内容哈希生成文件在存储中的路径
+func uploadBlobName(dir string, hash string, ext string) string {
+	return path.Join(dir, hash[:uploadBlobHashLen]+strings.ToLower(ext))
+}
+
+// StoreUpload 按内容保存上传文件：存储中已有相同内容时直接复用并增加引用计数，否则保存到 dir 目录下以哈希命名的文件
+// 参数: r - 文件内容, size - 文件大小（-1 表示未知）, dir - 存储目录, ext - 扩展名（含点）
+// 返回: 文件在存储中的路径，以及是否新保存了文件（复用已有文件时为 false）
+func StoreUpload(r io.ReadSeeker, size int64, dir string, ext string) (string, bool, error) {
+	h := sha256.New()
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return "", false, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return "", false, err
+	}
+	if size < 0 {
+		size = n
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+
+	unlock := blobLocks.Lock(hash)
+	defer unlock()
+
+	store := storage.Current()
+	var blob UploadBlob
+	blobMu.Lock()
+	found := db.Where("hash = ?", hash).First(&blob).Error == nil
+	if found {
+		if info, err := store.Stat(blob.Name); err == nil && !info.IsDir {
+			db.Model(&blob).UpdateColumn("ref_count", gorm.Expr("ref_count + 1"))
+			blobMu.Unlock()
+			return blob.Name, false, nil
+		}
+	}
+	blobMu.Unlock()
+
+	if found {
+		// 文件已在文件管理中被删除或移动：按原路径重新保存，之前引用该地址的内容也随之恢复
+		if err := store.Put(blob.Name, r, size); err != nil {
+			return "", false, err
+		}
+		db.Model(&blob).Updates(map[string]interface{}{"size": size, "ref_count": 1})
+		return blob.Name, true, nil
+	}
+
+	name := uploadBlobName(dir, hash, ext)
+	if err := store.Put(name, r, size); err != nil {
+		return "", false, err
+	}
+	if err := db.Create(&UploadBlob{Hash: hash, Name: name, Size: size, RefCount: 1}).Error; err != nil {
+		// 登记失败不影响本次上传，只是之后相同内容的上传无法复用该文件
+		fmt.Printf("登记上传文件失败 %s: %v\n", name, err)
+	}
+	return name, true, nil
+}
+
+// ReleaseUpload 释放文章或分类不再使用的上传文件：引用计数减一，计数归零且没有其他文章或分类引用时删除文件及其各尺寸
+// 参数: url - 站内上传地址, articleID / categoryID - 释放该文件的文章或分类（检查引用时排除，0 表示不排除）
+// 返回: 文件是否已被删除
+func ReleaseUpload(url string, articleID uint, categoryID uint) bool {
+	name, ok := utils.UploadURLToName(url)
+	if !ok {
+		return false
+	}
+
+	blobMu.Lock()
+	defer blobMu.Unlock()
+
+	var blobs []UploadBlob
+	db.Where("name = ?", name).Order("ref_count DESC").Find(&blobs)
+	refs := 0
+	for _, blob := range blobs {
+		refs += blob.RefCount
+	}
+	if refs > 1 {
+		db.Model(&blobs[0]).UpdateColumn("ref_count", gorm.Expr("ref_count - 1"))
+		return false
+	}
+	if isUploadInUse(url, articleID, categoryID) {
+		return false
+	}
+
+	store := storage.Current()
+	info, err := store.Stat(name)
+	if err != nil || info.IsDir || store.Delete(name) != nil {
+		return false
+	}
+	RemoveImageVariants(name)
+	db.Where("name = ?", name).Delete(&UploadBlob{})
+	return true
+}
+
+// releaseArtUploads 释放文章 old 中引用而 updated 中不再引用的上传文件，updated 为 nil 表示文章已删除，释放全部引用
+func releaseArtUploads(id uint, old *Article, updated *Article) {
+	current := make(map[string]bool)
+	if updated != nil {
+		for _, ref := range utils.UploadRefs(updated.Content, updated.Img, updated.PdfUrl) {
+			current[ref] = true
+		}
+	}
+	for _, ref := range utils.UploadRefs(old.Content, old.Img, old.PdfUrl) {
+		if !current[ref] {
+			ReleaseUpload(ref, id, 0)
+		}
+	}
+}
+
+// ForgetUploadBlobs 删除文件的内容哈希登记，在文件被移出上传目录（如移入回收站）后调用
+func ForgetUploadBlobs(name string) {
+	blobMu.Lock()
+	defer blobMu.Unlock()
+	db.Where("name = ?", name).Delete(&UploadBlob{})
+}
+
+// isUploadInUse 判断上传文件是否仍被其他文章或分类引用
+func isUploadInUse(url string, articleID uint, categoryID uint) bool {
+	if IsUploadReferenced(url, articleID) {
+		return true
+	}
+	var count int64
+	db.Model(&Category{}).Where("id <> ? AND img = ?", categoryID, url).Count(&count)
+	return count > 0
+}
+
+// DedupItem 去重统计中的一个文件
+type DedupItem struct {
+	Name     string `json:"name"`
+	Size     int64  `json:"size"`
+	RefCount int    `json:"ref_count"`
+	Saved    int64  `json:"saved"` // 去重为该文件节省的存储空间
+}
+
+// DedupStats 上传文件去重统计
+type DedupStats struct {
+	Files        int         `json:"files"`         // 按内容登记的文件数
+	Uploads      int         `json:"uploads"`       // 这些文件被上传的总次数
+	StoredBytes  int64       `json:"stored_bytes"`  // 实际占用的存储空间
+	LogicalBytes int64       `json:"logical_bytes"` // 不去重时需要的存储空间
+	SavedBytes   int64       `json:"saved_bytes"`   // 去重节省的存储空间
+	Top          []DedupItem `json:"top"`           // 节省空间最多的文件
+}
+
+// GetDedupStats 统计去重节省的存储空间，limit 为返回节省空间最多的文件数
+func GetDedupStats(limit int) (DedupStats, error) {
+	stats := DedupStats{Top: []DedupItem{}}
+	var items []DedupItem
+	err := db.Model(&UploadBlob{}).
+		Select("name, MAX(size) AS size, SUM(ref_count) AS ref_count").
+		Group("name").
+		Scan(&items).Error
+	if err != nil {
+		return stats, err
+	}
+	for i := range items {
+		item := &items[i]
+		if item.RefCount > 1 {
+			item.Saved = int64(item.RefCount-1) * item.Size
+		}
+		stats.Files++
+		stats.Uploads += item.RefCount
+		stats.StoredBytes += item.Size
+		stats.SavedBytes += item.Saved
+	}
+	stats.LogicalBytes = stats.StoredBytes + stats.SavedBytes
+
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].Saved > items[j].Saved
+	})
+	for _, item := range items {
+		if item.Saved == 0 || len(stats.Top) >= limit {
+			break
+		}
+		stats.Top = append(stats.Top, item)
+	}
+	return stats, nil
+}
+
+// DedupGroup 迁移时内容相同的一组文件
+type DedupGroup struct {
+	Hash    string   `json:"hash"`
+	Size    int64    `json:"size"`
+	Keep    string   `json:"keep"`    // 保留的文件
+	Removed []string `json:"removed"` // 合并到 Keep 后删除的文件
+}
+
+// DedupResult 去重迁移结果
+type DedupResult struct {
+	DryRun     bool         `json:"dry_run"`
+	Files      int          `json:"files"`      // 扫描的文件数
+	Duplicates int          `json:"duplicates"` // 重复的文件数
+	SavedBytes int64        `json:"saved_bytes"`
+	References int64        `json:"references"` // 改为指向保留文件的引用数（文章、历史版本、分类、关于页和前台配置）
+	Groups     []DedupGroup `json:"groups"`
+	Errors     []string     `json:"errors"`
+}
+
+// dedupKeep 选出一组相同内容的文件中保留的文件：优先保留已登记的文件，否则保留路径最短（相同时按字典序最前）的文件
+func dedupKeep(names []string, recorded map[string]bool) string {
+	keep := ""
+	for _, name := range names {
+		if keep != "" && recorded[keep] != recorded[name] {
+			if recorded[name] {
+				keep = name
+			}
+			continue
+		}
+		if keep == "" || len(name) < len(keep) || (len(name) == len(keep) && name < keep) {
+			keep = name
+		}
+	}
+	return keep
+}
+
+// DedupUploads 合并存储中已有的重复文件（跳过隐藏目录，如 .variants 和 .recycle）
+// 每组内容相同的文件只保留一份，引用其他副本的地址改为指向保留的文件后删除副本，并为所有文件登记内容哈希
+// dryRun 为 true 时只统计，不做任何修改
+func DedupUploads(dryRun bool, progress func(name string)) DedupResult {
+	result := DedupResult{DryRun: dryRun, Groups: []DedupGroup{}, Errors: []string{}}
+	store := storage.Current()
+
+	hashes := make(map[string][]string)
+	sizes := make(map[string]int64)
+	var order []string
+	err := storage.Walk(store, "", func(info storage.FileInfo) error {
+		if strings.HasPrefix(path.Base(info.Path), ".") {
+			if info.IsDir {
+				return storage.SkipDir
+			}
+			return nil
+		}
+		if info.IsDir {
+			return nil
+		}
+		hash, err := hashStorageFile(store, info.Path)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", info.Path, err))
+			return nil
+		}
+		if _, ok := hashes[hash]; !ok {
+			order = append(order, hash)
+		}
+		hashes[hash] = append(hashes[hash], info.Path)
+		sizes[hash] = info.Size
+		result.Files++
+		if progress != nil {
+			progress(info.Path)
+		}
+		return nil
+	})
+	if err != nil {
+		result.Errors = append(result.Errors, err.Error())
+		return result
+	}
+
+	blobMu.Lock()
+	defer blobMu.Unlock()
+
+	var blobs []UploadBlob
+	db.Find(&blobs)
+	recorded := make(map[string]bool, len(blobs))
+	for _, blob := range blobs {
+		recorded[blob.Name] = true
+	}
+
+	existing := make(map[string]bool)
+	for _, hash := range order {
+		names := hashes[hash]
+		keep := dedupKeep(names, recorded)
+		existing[keep] = true
+		// 没有登记的文件各算一次上传，合并后计入保留文件的引用数
+		extra := 0
+		for _, name := range names {
+			if !recorded[name] {
+				extra++
+			}
+		}
+
+		if len(names) > 1 {
+			group := DedupGroup{Hash: hash, Size: sizes[hash], Keep: keep, Removed: []string{}}
+			for _, name := range names {
+				if name == keep {
+					continue
+				}
+				n, err := rewriteUploadRefs("/uploads/"+name, "/uploads/"+keep, dryRun)
+				result.References += n
+				if err != nil {
+					result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", name, err))
+					existing[name] = true
+					continue
+				}
+				if !dryRun {
+					if err := store.Delete(name); err != nil {
+						result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", name, err))
+						existing[name] = true
+						continue
+					}
+					RemoveImageVariants(name)
+					db.Model(&UploadBlob{}).Where("name = ?", name).Update("name", keep)
+				}
+				group.Removed = append(group.Removed, name)
+				result.Duplicates++
+				result.SavedBytes += sizes[hash]
+			}
+			result.Groups = append(result.Groups, group)
+		}
+
+		if dryRun {
+			continue
+		}
+		var blob UploadBlob
+		if db.Where("hash = ?", hash).First(&blob).Error == nil {
+			db.Model(&blob).Updates(map[string]interface{}{"name": keep, "ref_count": gorm.Expr("ref_count + ?", extra)})
+		} else if err := db.Create(&UploadBlob{Hash: hash, Name: keep, Size: sizes[hash], RefCount: extra}).Error; err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", keep, err))
+		}
+	}
+
+	// 清理文件已不存在的登记
+	if !dryRun {
+		for _, blob := range blobs {
+			if !existing[blob.Name] {
+				db.Where("id = ? AND name = ?", blob.ID, blob.Name).Delete(&UploadBlob{})
+			}
+		}
+	}
+
+	sort.Slice(result.Groups, func(i, j int) bool {
+		return result.Groups[i].Keep < result.Groups[j].Keep
+	})
+	return result
+}
+
+// hashStorageFile 计算存储中文件内容的 SHA-256
+func hashStorageFile(store storage.Storage, name string) (string, error) {
+	r, err := store.Get(name)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// rewriteUploadRefs 将文章（包括已删除的文章）、历史版本、分类、关于页和前台配置中的上传地址 from 替换为 to
+// dryRun 为 true 时只统计引用数，不做修改
+func rewriteUploadRefs(from string, to string, dryRun bool) (int64, error) {
+	var total int64
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range []interface{}{&Article{}, &ArticleRevision{}} {
+			for _, column := range []string{"img", "pdf_url"} {
+				n, err := replaceColumn(tx.Unscoped().Model(table).Where(column+" = ?", from), column, to, dryRun)
+				if err != nil {
+					return err
+				}
+				total += n
+			}
+			n, err := replaceColumn(tx.Unscoped().Model(table).Where("content LIKE ?", "%"+from+"%"), "content", gorm.Expr("REPLACE(content, ?, ?)", from, to), dryRun)
+			if err != nil {
+				return err
+			}
+			total += n
+		}
+		n, err := replaceColumn(tx.Unscoped().Model(&Category{}).Where("img = ?", from), "img", to, dryRun)
+		total += n
+		return err
+	})
+	if err != nil {
+		return total, err
+	}
+
+	for _, file := range []string{utils.AboutFilePath, utils.GetFrontEndConfigPath()} {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		n := strings.Count(string(data), from)
+		if n == 0 {
+			continue
+		}
+		total += int64(n)
+		if dryRun {
+			continue
+		}
+		if err := os.WriteFile(file, []byte(strings.ReplaceAll(string(data), from, to)), 0644); err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// replaceColumn 更新查询到的记录的指定字段（不修改更新时间），dryRun 为 true 时只返回记录数
+func replaceColumn(query *gorm.DB, column string, value interface{}, dryRun bool) (int64, error) {
+	if dryRun {
+		var count int64
+		err := query.Count(&count).Error
+		return count, err
+	}
+	res := query.UpdateColumn(column, value)
+	return res.RowsAffected, res.Error
+}
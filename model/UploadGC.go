@@ -0,0 +1,230 @@
+package model
+
+import (
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+	"yanblog/utils"
+	"yanblog/utils/storage"
+)
+
+// uploadScanIgnoreDirs 扫描时跳过的顶层目录（临时文件，不会被内容引用）；以 . 开头的目录（.variants、.recycle）总是跳过
+var uploadScanIgnoreDirs = map[string]bool{
+	"temp_upload": true,
+	"temp_zip":    true,
+	"tmp":         true,
+}
+
+// UploadRefSource 引用上传文件的位置
+type UploadRefSource struct {
+	Type  string `json:"type"`            // article / revision / category / about / config
+	ID    uint   `json:"id,omitempty"`    // 文章、历史版本或分类的 ID
+	Title string `json:"title,omitempty"` // 文章标题或分类名称
+	Field string `json:"field,omitempty"` // content / img / pdf_url
+}
+
+// OrphanUpload 没有被任何内容引用的上传文件
+type OrphanUpload struct {
+	Name    string    `json:"name"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+}
+
+// BrokenUploadRef 指向不存在文件的引用
+type BrokenUploadRef struct {
+	URL     string            `json:"url"`
+	Sources []UploadRefSource `json:"sources"`
+}
+
+// UploadScanResult 上传文件引用扫描结果
+type UploadScanResult struct {
+	Files       int               `json:"files"`        // 扫描的文件数
+	Referenced  int               `json:"referenced"`   // 被引用的文件数
+	Recent      int               `json:"recent"`       // 未被引用但上传时间太近而跳过的文件数（可能正在编辑的文章还未保存）
+	Orphans     []OrphanUpload    `json:"orphans"`      // 未被引用的文件
+	OrphanBytes int64             `json:"orphan_bytes"` // 未被引用的文件总大小
+	Broken      []BrokenUploadRef `json:"broken"`       // 指向不存在文件的引用
+}
+
+// uploadRefCollector 按存储路径汇总引用
+type uploadRefCollector struct {
+	urls    map[string]string // 存储路径 -> 引用中出现的地址
+	sources map[string][]UploadRefSource
+}
+
+func (c *uploadRefCollector) add(source UploadRefSource, texts ...string) {
+	refs := append(utils.UploadRefs(texts...), utils.ImageVariantRefs(texts...)...)
+	for _, ref := range refs {
+		name, ok := uploadRefName(ref)
+		if !ok {
+			continue
+		}
+		if _, ok := c.urls[name]; !ok {
+			c.urls[name] = ref
+		}
+		c.sources[name] = append(c.sources[name], source)
+	}
+}
+
+// uploadRefName 将引用中的上传地址转为存储路径，地址中经过 URL 编码的字符（如空格、中文）会先解码
+func uploadRefName(ref string) (string, bool) {
+	if unescaped, err := url.PathUnescape(ref); err == nil {
+		ref = unescaped
+	}
+	return utils.UploadURLToName(ref)
+}
+
+// collectUploadRefs 收集文章（包括已删除的文章）、历史版本、分类、关于页和前台配置中引用的上传文件
+func collectUploadRefs() (*uploadRefCollector, error) {
+	c := &uploadRefCollector{urls: make(map[string]string), sources: make(map[string][]UploadRefSource)}
+
+	var articles []Article
+	if err := db.Unscoped().Select("id, title, content, img, pdf_url").Find(&articles).Error; err != nil {
+		return nil, err
+	}
+	for _, a := range articles {
+		c.add(UploadRefSource{Type: "article", ID: a.ID, Title: a.Title, Field: "content"}, a.Content)
+		c.add(UploadRefSource{Type: "article", ID: a.ID, Title: a.Title, Field: "img"}, a.Img)
+		c.add(UploadRefSource{Type: "article", ID: a.ID, Title: a.Title, Field: "pdf_url"}, a.PdfUrl)
+	}
+
+	var revisions []ArticleRevision
+	if err := db.Select("id, title, content, img, pdf_url").Find(&revisions).Error; err != nil {
+		return nil, err
+	}
+	for _, r := range revisions {
+		c.add(UploadRefSource{Type: "revision", ID: r.ID, Title: r.Title, Field: "content"}, r.Content)
+		c.add(UploadRefSource{Type: "revision", ID: r.ID, Title: r.Title, Field: "img"}, r.Img)
+		c.add(UploadRefSource{Type: "revision", ID: r.ID, Title: r.Title, Field: "pdf_url"}, r.PdfUrl)
+	}
+
+	var categories []Category
+	if err := db.Unscoped().Select("id, name, img").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	for _, cate := range categories {
+		c.add(UploadRefSource{Type: "category", ID: cate.ID, Title: cate.Name, Field: "img"}, cate.Img)
+	}
+
+	if data, err := os.ReadFile(utils.AboutFilePath); err == nil {
+		c.add(UploadRefSource{Type: "about"}, string(data))
+	}
+	if data, err := os.ReadFile(utils.GetFrontEndConfigPath()); err == nil {
+		c.add(UploadRefSource{Type: "config"}, string(data))
+	}
+	return c, nil
+}
+
+// isUploadRefCovered 判断存储路径是否被引用：引用文件本身，或引用了文件所在的目录
+func isUploadRefCovered(name string, refs map[string]string) bool {
+	for p := name; p != "." && p != ""; p = path.Dir(p) {
+		if _, ok := refs[p]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ScanUploads 对照数据库和上传目录，找出没有被引用的文件和指向不存在文件的引用
+// 跳过隐藏目录（.variants、.recycle）和临时目录；修改时间在 minAge 以内的文件不算作未引用（可能刚上传、文章还未保存）
+func ScanUploads(minAge time.Duration) (UploadScanResult, error) {
+	result := UploadScanResult{Orphans: []OrphanUpload{}, Broken: []BrokenUploadRef{}}
+	refs, err := collectUploadRefs()
+	if err != nil {
+		return result, err
+	}
+
+	store := storage.Current()
+	existing := make(map[string]bool)
+	cutoff := time.Now().Add(-minAge)
+	err = storage.Walk(store, "", func(info storage.FileInfo) error {
+		base := path.Base(info.Path)
+		if strings.HasPrefix(base, ".") || (info.IsDir && path.Dir(info.Path) == "." && uploadScanIgnoreDirs[base]) {
+			if info.IsDir {
+				return storage.SkipDir
+			}
+			return nil
+		}
+		existing[info.Path] = true
+		if info.IsDir {
+			return nil
+		}
+		result.Files++
+		if isUploadRefCovered(info.Path, refs.urls) {
+			result.Referenced++
+			return nil
+		}
+		if !info.ModTime.IsZero() && info.ModTime.After(cutoff) {
+			result.Recent++
+			return nil
+		}
+		result.Orphans = append(result.Orphans, OrphanUpload{Name: info.Path, Size: info.Size, ModTime: info.ModTime})
+		result.OrphanBytes += info.Size
+		return nil
+	})
+	if err != nil {
+		return result, err
+	}
+
+	for name, ref := range refs.urls {
+		if existing[name] {
+			continue
+		}
+		// 跳过的目录中的文件单独检查
+		if _, err := store.Stat(name); err == nil {
+			continue
+		}
+		result.Broken = append(result.Broken, BrokenUploadRef{URL: ref, Sources: refs.sources[name]})
+	}
+	sort.Slice(result.Broken, func(i, j int) bool {
+		return result.Broken[i].URL < result.Broken[j].URL
+	})
+	return result, nil
+}
+
+// RecycledUpload 移入回收站的未引用文件
+type RecycledUpload struct {
+	Name        string `json:"name"`
+	RecyclePath string `json:"recycle_path,omitempty"`
+	Size        int64  `json:"size"`
+}
+
+// RecycleOrphanUploads 重新扫描后将未被引用的文件移入回收站，并删除它们的内容哈希登记
+// 参数: minAge - 同 ScanUploads, only - 非空时只处理其中列出的文件（已不再是未引用的文件会被跳过）, dryRun - 只返回将移动的文件, username - 操作用户
+// 返回: 已（或将要）移入回收站的文件和失败信息
+func RecycleOrphanUploads(minAge time.Duration, only []string, dryRun bool, username string) ([]RecycledUpload, []string, error) {
+	scan, err := ScanUploads(minAge)
+	if err != nil {
+		return nil, nil, err
+	}
+	var selected map[string]bool
+	if len(only) > 0 {
+		selected = make(map[string]bool, len(only))
+		for _, name := range only {
+			selected[name] = true
+		}
+	}
+
+	moved := []RecycledUpload{}
+	errs := []string{}
+	for _, orphan := range scan.Orphans {
+		if selected != nil && !selected[orphan.Name] {
+			continue
+		}
+		item := RecycledUpload{Name: orphan.Name, Size: orphan.Size}
+		if !dryRun {
+			recycled, err := RecycleUpload(orphan.Name, username)
+			if err != nil {
+				errs = append(errs, orphan.Name+": "+err.Error())
+				continue
+			}
+			item.RecyclePath = recycled.RecyclePath
+			ForgetUploadBlobs(orphan.Name)
+		}
+		moved = append(moved, item)
+	}
+	return moved, errs, nil
+}
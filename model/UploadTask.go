@@ -0,0 +1,204 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+	"yanblog/utils"
+	"yanblog/utils/errmsg"
+
+	"gorm.io/gorm"
+)
+
+// 上传任务状态
+const (
+	UploadStatusProcessing = "processing"
+	UploadStatusRetrying   = "retrying"
+	UploadStatusCompleted  = "completed"
+	UploadStatusFailed     = "failed"
+	UploadStatusCancelled  = "cancelled"
+)
+
+// UploadTask 文章 ZIP 上传和博客导入任务（进度和逐篇结果随处理过程保存，服务重启后可继续处理或重试失败的文章）
+// 错误、文件列表和导入报告以 JSON 文本保存，由调用方编解码
+type UploadTask struct {
+	ID             string     `gorm:"primarykey;type:varchar(64)" json:"id"`
+	FileName       string     `gorm:"type:varchar(255)" json:"file_name"`
+	FileSize       int64      `json:"file_size"`
+	Format         string     `gorm:"type:varchar(20);index" json:"format"` // 博客导入的类型，ZIP 上传为空
+	Mode           string     `gorm:"type:varchar(20)" json:"mode"`         // 导入模式（skip、overwrite、version）
+	Status         string     `gorm:"type:varchar(20);index" json:"status"`
+	TotalFiles     int        `json:"total_files"`
+	Processed      int        `json:"processed"`
+	Success        int        `json:"success"`
+	Failed         int        `json:"failed"`
+	RetryCount     int        `json:"retry_count"`
+	MaxRetries     int        `json:"max_retries"`
+	Operator       string     `gorm:"type:varchar(20);index" json:"operator"` // 发起上传的用户
+	SourceFile     string     `gorm:"type:varchar(255)" json:"-"`             // 保存的原始文件，用于继续处理和重试
+	Errors         string     `gorm:"type:longtext" json:"-"`
+	ProcessedFiles string     `gorm:"type:longtext" json:"-"`
+	FailedFiles    string     `gorm:"type:longtext" json:"-"`
+	Report         string     `gorm:"type:longtext" json:"-"`
+	StartTime      time.Time  `gorm:"index" json:"start_time"`
+	EndTime        *time.Time `json:"end_time"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// UploadTaskQuery 上传历史查询条件
+type UploadTaskQuery struct {
+	Status  string    // 任务状态，为空则不限
+	Format  string    // 博客类型；zip 表示 ZIP 上传，为空则不限
+	Keyword string    // 文件名关键词（模糊匹配）
+	Start   time.Time // 起始时间（含）
+	End     time.Time // 结束时间（不含）
+}
+
+// IsUploadTaskRunning 任务是否仍在处理中
+func IsUploadTaskRunning(status string) bool {
+	return status == UploadStatusProcessing || status == UploadStatusRetrying
+}
+
+// SaveUploadTask 保存任务的当前状态（不存在时新建）
+func SaveUploadTask(task *UploadTask) int {
+	if err := db.Save(task).Error; err != nil {
+		return errmsg.ERROR
+	}
+	return errmsg.SUCCESS
+}
+
+// GetUploadTask 查询单个任务
+func GetUploadTask(id string) (UploadTask, int) {
+	var task UploadTask
+	if err := db.Where("id = ?", id).First(&task).Error; err != nil {
+		return task, errmsg.ERROR_UPLOAD_TASK_NOT_EXIST
+	}
+	return task, errmsg.SUCCESS
+}
+
+// GetRunningUploadTasks 查询处理中的任务（服务启动时用于恢复被中断的任务）
+func GetRunningUploadTasks() []UploadTask {
+	var tasks []UploadTask
+	db.Where("status IN ?", []string{UploadStatusProcessing, UploadStatusRetrying}).Order("start_time").Find(&tasks)
+	return tasks
+}
+
+// applyUploadTaskQuery 按查询条件构建上传历史查询
+func applyUploadTaskQuery(query *gorm.DB, q UploadTaskQuery) *gorm.DB {
+	if q.Status != "" {
+		query = query.Where("status = ?", q.Status)
+	}
+	switch q.Format {
+	case "":
+	case "zip":
+		query = query.Where("format = ?", "")
+	default:
+		query = query.Where("format = ?", q.Format)
+	}
+	if q.Keyword != "" {
+		query = query.Where("LOWER(file_name) LIKE ?", "%"+strings.ToLower(q.Keyword)+"%")
+	}
+	if !q.Start.IsZero() {
+		query = query.Where("start_time >= ?", q.Start)
+	}
+	if !q.End.IsZero() {
+		query = query.Where("start_time < ?", q.End)
+	}
+	return query
+}
+
+// GetUploadTasks 分页查询上传历史（按开始时间倒序）
+// 参数: q - 查询条件, pageSize - 每页数量, pageNum - 页码
+// 返回: 任务列表和总数
+func GetUploadTasks(q UploadTaskQuery, pageSize int, pageNum int) ([]UploadTask, int64) {
+	var tasks []UploadTask
+	var total int64
+
+	query := applyUploadTaskQuery(db.Model(&UploadTask{}), q)
+	query.Count(&total)
+
+	query = query.Order("start_time DESC")
+	var err error
+	if pageSize == -1 || pageNum == -1 {
+		err = query.Find(&tasks).Error
+	} else {
+		err = query.Limit(pageSize).Offset((pageNum - 1) * pageSize).Find(&tasks).Error
+	}
+	if err != nil {
+		return []UploadTask{}, 0
+	}
+	return tasks, total
+}
+
+// ClearUploadTasks 删除已结束的任务记录，处理中的任务保留
+// 返回: 需要清理的原始文件和删除的记录数
+func ClearUploadTasks() ([]string, int64) {
+	var files []string
+	db.Model(&UploadTask{}).Where("status NOT IN ? AND source_file <> ?", []string{UploadStatusProcessing, UploadStatusRetrying}, "").Pluck("source_file", &files)
+	result := db.Where("status NOT IN ?", []string{UploadStatusProcessing, UploadStatusRetrying}).Delete(&UploadTask{})
+	return files, result.RowsAffected
+}
+
+// legacyUploadHistory 旧版本保存在 JSON 文件中的上传历史
+type legacyUploadHistory struct {
+	TaskID     string    `json:"task_id"`
+	FileName   string    `json:"file_name"`
+	TotalFiles int       `json:"total_files"`
+	Success    int       `json:"success"`
+	Failed     int       `json:"failed"`
+	Status     string    `json:"status"`
+	Duration   string    `json:"duration"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// migrateUploadHistory 将旧版本的上传历史文件导入任务表，导入后删除文件
+// 同一任务在文件中可能有多条记录（重试后再次保存），以最新的一条为准
+func migrateUploadHistory() {
+	data, err := os.ReadFile(utils.UploadHistoryFile)
+	if err != nil {
+		return
+	}
+	var history []legacyUploadHistory
+	if err := json.Unmarshal(data, &history); err != nil {
+		fmt.Println("解析上传历史文件失败:", err)
+		return
+	}
+
+	imported := make(map[string]bool)
+	for _, h := range history {
+		if h.TaskID == "" || imported[h.TaskID] {
+			continue
+		}
+		imported[h.TaskID] = true
+		task := UploadTask{
+			ID:         h.TaskID,
+			FileName:   h.FileName,
+			Status:     h.Status,
+			TotalFiles: h.TotalFiles,
+			Processed:  h.TotalFiles,
+			Success:    h.Success,
+			Failed:     h.Failed,
+			StartTime:  h.CreatedAt,
+		}
+		if d, err := time.ParseDuration(h.Duration); err == nil {
+			end := h.CreatedAt.Add(d)
+			task.EndTime = &end
+		}
+		// 旧版本的任务已随进程结束
+		if IsUploadTaskRunning(task.Status) {
+			task.Status = UploadStatusFailed
+		}
+		var count int64
+		if db.Model(&UploadTask{}).Where("id = ?", task.ID).Count(&count); count > 0 {
+			continue
+		}
+		if err := db.Create(&task).Error; err != nil {
+			fmt.Println("导入上传历史失败:", err)
+			return
+		}
+	}
+	os.Remove(utils.UploadHistoryFile)
+	fmt.Printf("已将 %d 条上传历史导入数据库\n", len(imported))
+}
@@ -13,24 +13,25 @@ type User struct {
 	gorm.Model
 	Username string `gorm:"type:varchar(20);not null;uniqueIndex" json:"username" validate:"required,min=4,max=12" label:"用户名"` // 用户名（4-12位），唯一索引
 	Password string `gorm:"type:varchar(100);not null" json:"password"` // 密码（6-20位）
-	Role     int    `gorm:"type:int;DEFAULT:2;index" json:"role" validate:"required,gte=1" label:"角色码"`                    // 角色码（1:超级管理员, 2:管理员, 3:普通用户），添加索引
+	Role     int    `gorm:"type:int;DEFAULT:2;index" json:"role" validate:"required,gte=1,lte=4" label:"角色码"`              // 角色码（1:超级管理员, 2:管理员, 3:普通用户, 4:编辑），添加索引
+
+	MustChangePassword bool `gorm:"not null;default:false" json:"must_change_password"` // 必须先修改密码（如初始管理员账号），设置期间令牌只能用于修改密码
 }
 
 // applyRoleFilter 根据用户角色应用权限过滤条件（公共函数，消除重复代码）
 // 参数: query - GORM 查询对象, currentRole - 当前用户角色
 // 返回: 应用过滤后的查询对象
 func applyRoleFilter(query *gorm.DB, currentRole int) *gorm.DB {
-	switch currentRole {
-	case 2:
-		// 管理员(2)只能看到管理员(2)和普通用户(3)
-		return query.Where("role >= ?", 2)
-	case 3:
-		// 普通用户(3)只能看到普通用户(3)
-		return query.Where("role = ?", 3)
-	default:
-		// 超级管理员(1)可以看到所有用户
+	// 超级管理员可以看到所有用户
+	if currentRole == RoleSuperAdmin {
 		return query
 	}
+	// 其他角色只能看到自己及等级更低的角色（如管理员看不到超级管理员）
+	roles := visibleRoles(currentRole)
+	if len(roles) == 0 {
+		return query.Where("1 = 0")
+	}
+	return query.Where("role IN ?", roles)
 }
 
 // CheckUser 检查用户名是否已存在
@@ -151,6 +152,8 @@ func GetUsers(pageSize int, pageNum int, currentRole int) ([]User, int64) {
 // 返回: 状态码
 func EditUser(id int, data *User) int {
 	var user User
+	var old User
+	db.Select("username", "role").Where("id = ?", id).First(&old)
 
 	var maps = make(map[string]interface{})
 	maps["username"] = data.Username
@@ -171,6 +174,11 @@ func EditUser(id int, data *User) int {
 	if err != nil {
 		return errmsg.ERROR
 	}
+
+	// 用户名、角色或密码变更后，已签发的令牌全部失效
+	if old.Username != data.Username || old.Role != data.Role || data.Password != "" {
+		RevokeUserSessions(id)
+	}
 	return errmsg.SUCCESS
 }
 
@@ -183,6 +191,8 @@ func DeleteUser(id int) int {
 	if err != nil {
 		return errmsg.ERROR
 	}
+	RevokeUserSessions(id)
+	DisableTwoFactor(uint(id))
 	return errmsg.SUCCESS
 }
 
@@ -237,14 +247,57 @@ func CheckLogin(username string, password string) int {
 		return errmsg.ERROR_PASSWORD_WRONG
 	}
 
-	// 检查用户权限
-	if user.Role != 1 && user.Role != 2 {
+	// 检查用户权限（没有任何后台权限的角色不能登录）
+	if !CanLoginAdmin(user.Role) {
 		return errmsg.ERROR_USER_NO_RIGHT
 	}
 
 	return errmsg.SUCCESS
 }
 
+// GetLoginUser 获取签发令牌所需的用户信息
+// 参数: username - 用户名
+// 返回: 用户（不含密码）和状态码
+func GetLoginUser(username string) (User, int) {
+	var user User
+	err := db.Select("id", "username", "role", "must_change_password").Where("username = ?", username).First(&user).Error
+	if err != nil {
+		return User{}, errmsg.ERROR_USER_NOT_EXIST
+	}
+	return user, errmsg.SUCCESS
+}
+
+// ChangePassword 用户修改自己的密码
+// 修改成功后清除“必须修改密码”标记，并撤销该用户的全部会话
+// 参数: id - 用户ID, oldPassword - 原密码, newPassword - 新密码（调用方负责密码策略检查）
+// 返回: 状态码
+func ChangePassword(id uint, oldPassword string, newPassword string) int {
+	var user User
+	if err := db.Select("id", "password").Where("id = ?", id).First(&user).Error; err != nil {
+		return errmsg.ERROR_USER_NOT_EXIST
+	}
+	if !CheckPassword(oldPassword, user.Password) {
+		return errmsg.ERROR_PASSWORD_WRONG
+	}
+	if CheckPassword(newPassword, user.Password) {
+		return errmsg.ERROR_PASSWORD_REUSED
+	}
+
+	encrypted, err := EncryptPassword(newPassword)
+	if err != nil {
+		return errmsg.ERROR
+	}
+	err = db.Model(&User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"password":             encrypted,
+		"must_change_password": false,
+	}).Error
+	if err != nil {
+		return errmsg.ERROR
+	}
+	RevokeUserSessions(int(id))
+	return errmsg.SUCCESS
+}
+
 // GetUserRole 获取用户角色
 func GetUserRole(username string) int {
 	var user User
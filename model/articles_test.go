@@ -0,0 +1,61 @@
+package model
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"yanblog/utils/errmsg"
+)
+
+func TestNormalizeArtStatus(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.Local)
+	past := now.Add(-time.Hour)
+	future := now.Add(time.Hour)
+
+	status, publishAt, code := normalizeArtStatus("", nil, now)
+	if code != errmsg.SUCCESS || status != ArticleStatusPublished {
+		t.Fatalf("empty status should default to published, got %s (%d)", status, code)
+	}
+	if publishAt == nil || !publishAt.Equal(now) {
+		t.Fatal("published article should get publish time")
+	}
+
+	if _, _, code = normalizeArtStatus("unknown", nil, now); code != errmsg.ERROR_ART_STATUS_WRONG {
+		t.Fatalf("invalid status should be rejected, got %d", code)
+	}
+
+	if _, _, code = normalizeArtStatus(ArticleStatusScheduled, nil, now); code != errmsg.ERROR_ART_PUBLISH_TIME {
+		t.Fatalf("scheduled article without publish time should be rejected, got %d", code)
+	}
+
+	status, _, code = normalizeArtStatus(ArticleStatusScheduled, &future, now)
+	if code != errmsg.SUCCESS || status != ArticleStatusScheduled {
+		t.Fatalf("future schedule should stay scheduled, got %s (%d)", status, code)
+	}
+
+	status, _, code = normalizeArtStatus(ArticleStatusScheduled, &past, now)
+	if code != errmsg.SUCCESS || status != ArticleStatusPublished {
+		t.Fatalf("past schedule should be published, got %s (%d)", status, code)
+	}
+
+	status, publishAt, _ = normalizeArtStatus(ArticleStatusDraft, nil, now)
+	if status != ArticleStatusDraft || publishAt != nil {
+		t.Fatal("draft should keep empty publish time")
+	}
+}
+
+func TestHighlightTerms(t *testing.T) {
+	got := highlightTerms("Go <语言> 全文检索 go", []string{"go", "全文"})
+	want := "<mark>Go</mark> &lt;语言&gt; <mark>全文</mark>检索 <mark>go</mark>"
+	if got != want {
+		t.Fatalf("highlightTerms = %q, want %q", got, want)
+	}
+
+	snippet := buildSnippet(strings.Repeat("前", 100)+"关键词"+strings.Repeat("后", 200), []string{"关键词"})
+	if !strings.HasPrefix(snippet, "…") || !strings.HasSuffix(snippet, "…") {
+		t.Fatalf("snippet should be trimmed on both sides: %q", snippet)
+	}
+	if !strings.Contains(snippet, "<mark>关键词</mark>") {
+		t.Fatalf("snippet should highlight keyword: %q", snippet)
+	}
+}
@@ -0,0 +1,361 @@
+package model
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+	"yanblog/utils/storage"
+
+	"golang.org/x/net/webdav"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// buildBackup 生成测试用的归档，清单根据 files 自动生成，edit 用于在写入前篡改清单
+func buildBackup(t *testing.T, files map[string]string, edit func(m *BackupManifest)) *zip.Reader {
+	t.Helper()
+	m := &BackupManifest{Format: BackupFormat, Version: BackupVersion, CreatedAt: time.Now(), Tables: map[string]int64{}}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+		sum := sha256.Sum256([]byte(content))
+		m.Files = append(m.Files, BackupFile{Path: name, Size: int64(len(content)), SHA256: hex.EncodeToString(sum[:])})
+		if strings.HasPrefix(name, backupDatabaseDir) {
+			m.Tables[strings.TrimSuffix(strings.TrimPrefix(name, backupDatabaseDir), ".json")] = 1
+		}
+	}
+	if edit != nil {
+		edit(m)
+	}
+	w, _ := zw.Create(backupManifestName)
+	json.NewEncoder(w).Encode(m)
+	zw.Close()
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return zr
+}
+
+func TestVerifyBackup(t *testing.T) {
+	files := map[string]string{
+		"database/user.json":        `[{"id":1}]`,
+		"files/about.md":            "# about",
+		"files/uploads/a/b.png":     "png",
+		"files/config/backend.yaml": "server: {}",
+	}
+	manifest, entries, err := verifyBackup(buildBackup(t, files, nil))
+	if err != nil {
+		t.Fatalf("valid backup rejected: %v", err)
+	}
+	if len(manifest.Files) != len(files) || len(entries) != len(files)+1 {
+		t.Fatalf("unexpected manifest: %+v", manifest)
+	}
+
+	cases := map[string]func(m *BackupManifest){
+		"wrong format":   func(m *BackupManifest) { m.Format = "other" },
+		"newer version":  func(m *BackupManifest) { m.Version = BackupVersion + 1 },
+		"bad checksum":   func(m *BackupManifest) { m.Files[0].SHA256 = strings.Repeat("0", 64) },
+		"bad size":       func(m *BackupManifest) { m.Files[0].Size++ },
+		"unlisted file":  func(m *BackupManifest) { m.Files = m.Files[1:] },
+		"missing file":   func(m *BackupManifest) { m.Files = append(m.Files, BackupFile{Path: "files/uploads/x.png"}) },
+		"missing table":  func(m *BackupManifest) { m.Tables["article"] = 3 },
+		"path traversal": func(m *BackupManifest) { m.Files = append(m.Files, BackupFile{Path: "files/uploads/../../etc/passwd"}) },
+	}
+	for name, edit := range cases {
+		if _, _, err := verifyBackup(buildBackup(t, files, edit)); !errors.Is(err, ErrBackupInvalid) {
+			t.Errorf("%s: expected ErrBackupInvalid, got %v", name, err)
+		}
+	}
+}
+
+func TestValidBackupPath(t *testing.T) {
+	sources := backupSources()
+	valid := []string{
+		"database/article_tags.json",
+		"files/uploads/article/a.png",
+		"files/about.md",
+		"files/config/frontend.yaml",
+		"files/data/upload_history.json",
+	}
+	for _, p := range valid {
+		if !validBackupPath(sources, p) {
+			t.Errorf("%q should be valid", p)
+		}
+	}
+	invalid := []string{
+		"",
+		"/etc/passwd",
+		"files/uploads/../config/backend.yaml",
+		"files/uploads/./a.png",
+		`files\uploads\a.png`,
+		"files/uploads",
+		"files/other.txt",
+		"files/about.md/x",
+		"files/uploads/.restore-1-old/a.png",
+		"database/../user.json",
+		"database/User.json",
+		"manifest.json",
+	}
+	for _, p := range invalid {
+		if validBackupPath(sources, p) {
+			t.Errorf("%q should be invalid", p)
+		}
+	}
+}
+
+// openTestBackup 校验 buildBackup 生成的归档并返回可用于恢复的 BackupArchive
+func openTestBackup(t *testing.T, files map[string]string) *BackupArchive {
+	t.Helper()
+	manifest, entries, err := verifyBackup(buildBackup(t, files, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &BackupArchive{Manifest: manifest, entries: entries}
+}
+
+// TestRestoreEmptyUploads 备份时上传目录为空，恢复后上传目录也应为空，与 Diff 的预览一致
+func TestRestoreEmptyUploads(t *testing.T) {
+	t.Chdir(t.TempDir())
+	for _, name := range []string{"uploads/a.png", "uploads/sub/b.png"} {
+		os.MkdirAll(filepath.Dir(name), 0755)
+		os.WriteFile(name, []byte(name), 0644)
+	}
+	a := openTestBackup(t, map[string]string{"database/user.json": `[]`})
+
+	diff := &BackupDiff{}
+	if err := a.diffFiles(diff); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(diff.Removed, ","); got != "uploads/a.png,uploads/sub/b.png" {
+		t.Errorf("Removed = %v", diff.Removed)
+	}
+
+	swaps, err := a.stageFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := swaps.apply(); err != nil {
+		t.Fatal(err)
+	}
+	swaps.cleanup()
+	entries, err := os.ReadDir("uploads")
+	if err != nil || len(entries) != 0 {
+		t.Errorf("恢复后 uploads 应为空: %v %v", entries, err)
+	}
+}
+
+// storageFiles 存储中的所有文件：路径 -> 内容
+func storageFiles(t *testing.T, store storage.Storage) map[string]string {
+	t.Helper()
+	files := make(map[string]string)
+	err := storage.Walk(store, "", func(info storage.FileInfo) error {
+		if !info.IsDir {
+			data, err := storage.ReadFile(store, info.Path)
+			if err != nil {
+				return err
+			}
+			files[info.Path] = string(data)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// TestBackupRemoteStorage 使用 WebDAV 等远程存储时，上传文件通过存储备份和恢复
+func TestBackupRemoteStorage(t *testing.T) {
+	t.Chdir(t.TempDir())
+	server := httptest.NewServer(&webdav.Handler{Prefix: "/dav", FileSystem: webdav.NewMemFS(), LockSystem: webdav.NewMemLS()})
+	t.Cleanup(server.Close)
+	store, err := storage.NewWebDAV(storage.WebDAVOptions{URL: server.URL + "/dav/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := storage.SetCurrent(store)
+	t.Cleanup(func() { storage.SetCurrent(old) })
+
+	backedUp := map[string]string{
+		"article/a.png":                  "a",
+		"keep.txt":                       "keep",
+		".recycle/20260102_150405_x.png": "x",
+	}
+	for name, content := range backedUp {
+		if err := storage.WriteFile(store, name, []byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 备份上传文件
+	var uploads backupSource
+	for _, src := range backupSources() {
+		if src.dir {
+			uploads = src
+		}
+	}
+	manifest := &BackupManifest{Format: BackupFormat, Version: BackupVersion, CreatedAt: time.Now(), Tables: map[string]int64{}}
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if err := addBackupSource(zw, manifest, uploads); err != nil {
+		t.Fatal(err)
+	}
+	w, _ := zw.Create(backupManifestName)
+	json.NewEncoder(w).Encode(manifest)
+	zw.Close()
+	if len(manifest.Files) != len(backedUp) {
+		t.Fatalf("备份的文件 = %+v, want %d 个", manifest.Files, len(backedUp))
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, entries, err := verifyBackup(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := &BackupArchive{Manifest: m, entries: entries}
+
+	// 备份后修改存储中的文件
+	storage.WriteFile(store, "article/a.png", []byte("changed"))
+	storage.WriteFile(store, "extra.png", []byte("extra"))
+	modified := storageFiles(t, store)
+
+	diff := &BackupDiff{}
+	if err := a.diffFiles(diff); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(diff.Changed, ",") != "uploads/article/a.png" || strings.Join(diff.Removed, ",") != "uploads/extra.png" || diff.Unchanged != 2 {
+		t.Errorf("diff = %+v", diff)
+	}
+
+	// 回滚后恢复为修改后的状态
+	swaps, err := a.stageFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := swaps.apply(); err != nil {
+		t.Fatal(err)
+	}
+	if err := swaps.rollback(); err != nil {
+		t.Fatal(err)
+	}
+	swaps.cleanup()
+	if got := storageFiles(t, store); !reflect.DeepEqual(got, modified) {
+		t.Errorf("回滚后 = %v, want %v", got, modified)
+	}
+
+	// 恢复后与备份时一致，不留下临时目录
+	swaps, err = a.stageFiles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := swaps.apply(); err != nil {
+		t.Fatal(err)
+	}
+	swaps.cleanup()
+	if got := storageFiles(t, store); !reflect.DeepEqual(got, backedUp) {
+		t.Errorf("恢复后 = %v, want %v", got, backedUp)
+	}
+	if _, err := os.Stat("uploads"); !os.IsNotExist(err) {
+		t.Errorf("不应写入本地 uploads 目录: %v", err)
+	}
+}
+
+// openTestDB 使用临时的 SQLite 数据库替换 db，测试结束后还原
+func openTestDB(t *testing.T) {
+	t.Helper()
+	testDB, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{
+		Logger:                 logger.Default.LogMode(logger.Silent),
+		SkipDefaultTransaction: true,
+		NamingStrategy:         schema.NamingStrategy{SingularTable: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range backupTables {
+		if table.model != nil {
+			if err := testDB.AutoMigrate(table.model); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	old := db
+	db = testDB
+	t.Cleanup(func() {
+		db = old
+		if sqlDB, err := testDB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+}
+
+// TestBackupDatabase 备份包含所有数据表（包括上传文件去重和回收站索引），恢复后与备份时的记录一致
+func TestBackupDatabase(t *testing.T) {
+	t.Chdir(t.TempDir())
+	openTestDB(t)
+	blob := UploadBlob{Hash: strings.Repeat("a", 64), Name: "blob/aa/a.png", Size: 3, RefCount: 2}
+	if err := db.Create(&blob).Error; err != nil {
+		t.Fatal(err)
+	}
+	item := RecycleItem{OriginalPath: "article/a.png", RecyclePath: ".recycle/20260102_150405_a.png", Name: "a.png", Size: 3, Username: "admin", RecycledAt: time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)}
+	if err := db.Create(&item).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Create("backup.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	manifest, err := WriteBackup(f)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Tables["upload_blob"] != 1 || manifest.Tables["recycle_item"] != 1 {
+		t.Errorf("备份的数据表 = %v", manifest.Tables)
+	}
+	a, err := OpenBackup("backup.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer a.Close()
+
+	// 备份后修改记录
+	db.Model(&blob).Update("ref_count", 5)
+	db.Create(&UploadBlob{Hash: strings.Repeat("b", 64), Name: "blob/bb/b.png", Size: 1, RefCount: 1})
+	db.Delete(&item)
+
+	if err := db.Transaction(a.restoreDatabase); err != nil {
+		t.Fatal(err)
+	}
+	var blobs []UploadBlob
+	db.Find(&blobs)
+	if len(blobs) != 1 || blobs[0].Hash != blob.Hash || blobs[0].Name != blob.Name || blobs[0].RefCount != 2 {
+		t.Errorf("恢复后 upload_blob = %+v", blobs)
+	}
+	var items []RecycleItem
+	db.Find(&items)
+	if len(items) != 1 || items[0].RecyclePath != item.RecyclePath || items[0].OriginalPath != item.OriginalPath || !items[0].RecycledAt.Equal(item.RecycledAt) {
+		t.Errorf("恢复后 recycle_item = %+v", items)
+	}
+}
@@ -0,0 +1,17 @@
+package model
+
+import "testing"
+
+func TestMatchBlockWord(t *testing.T) {
+	words := []string{" 广告 ", "", "Casino"}
+
+	if !matchBlockWord(words, "正常昵称", "点击领取广告红包") {
+		t.Fatal("should match block word in content")
+	}
+	if !matchBlockWord(words, "online CASINO") {
+		t.Fatal("block word match should be case insensitive")
+	}
+	if matchBlockWord(words, "写得很好", "") {
+		t.Fatal("empty block word should not match everything")
+	}
+}
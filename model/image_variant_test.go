@@ -0,0 +1,51 @@
+package model
+
+import (
+	"testing"
+)
+
+func TestImageVariantDir(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"article/cover/1.jpg", ".variants/article/cover/1.jpg", true},
+		{"/avatar//a.png", ".variants/avatar/a.png", true},
+		{".variants/avatar/a.png/thumb.png", "", false},
+		{".recycle/20260101_a.png", "", false},
+		{"", "", false},
+		{"../data/a.png", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := imageVariantDir(tt.name)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("imageVariantDir(%q) = %q, %v; want %q, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestFindImageSize(t *testing.T) {
+	if size, ok := FindImageSize("thumb"); !ok || !size.Crop || size.Width <= 0 {
+		t.Errorf("thumb = %+v, %v", size, ok)
+	}
+	if size, ok := FindImageSize("w640"); !ok || size.Width != 640 || size.Crop {
+		t.Errorf("w640 = %+v, %v", size, ok)
+	}
+	// 只允许配置中的尺寸
+	if _, ok := FindImageSize("w641"); ok {
+		t.Error("w641 不应存在")
+	}
+}
+
+func TestNeedsVariant(t *testing.T) {
+	if needsVariant(ImageSize{Name: "w640", Width: 640}, 640) {
+		t.Error("原图不超过该宽度时不应生成")
+	}
+	if !needsVariant(ImageSize{Name: "w640", Width: 640}, 1000) {
+		t.Error("原图更宽时应生成")
+	}
+	if !needsVariant(ImageSize{Name: "thumb", Width: 200, Crop: true}, 100) {
+		t.Error("缩略图总是生成")
+	}
+}
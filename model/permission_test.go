@@ -0,0 +1,22 @@
+package model
+
+import "testing"
+
+func TestRolePermissions(t *testing.T) {
+	if !HasPermission(RoleEditor, PermArticleWrite) {
+		t.Fatal("editor should be able to write articles")
+	}
+	if HasPermission(RoleEditor, PermFileDelete) || HasPermission(RoleEditor, PermConfigWrite) {
+		t.Fatal("editor should not touch files or config")
+	}
+	if CanLoginAdmin(RoleUser) || !CanLoginAdmin(RoleEditor) {
+		t.Fatal("unexpected admin login right")
+	}
+
+	if !CanManageRole(RoleAdmin, RoleEditor) || CanManageRole(RoleAdmin, RoleAdmin) {
+		t.Fatal("admin should only manage lower roles")
+	}
+	if !CanManageRole(RoleEditor, RoleUser) || CanManageRole(RoleEditor, RoleAdmin) {
+		t.Fatal("unexpected editor manage right")
+	}
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRecycleName(t *testing.T) {
+	tests := []struct {
+		name     string
+		original string
+		ok       bool
+	}{
+		// 时间本身包含 _，不能按第一个 _ 拆分
+		{"20260102_150405_a_b.png", "a_b.png", true},
+		{"20260102_150405_dir", "dir", true},
+		{"20260102_150405_", "", false},
+		{"2026010_150405_a.png", "", false},
+		{"a.png", "", false},
+	}
+	for _, tt := range tests {
+		original, ts, ok := parseRecycleName(tt.name)
+		if ok != tt.ok || original != tt.original {
+			t.Errorf("parseRecycleName(%q) = %q, %v; want %q, %v", tt.name, original, ok, tt.original, tt.ok)
+		}
+		if ok {
+			want := time.Date(2026, 1, 2, 15, 4, 5, 0, time.Local)
+			if !ts.Equal(want) {
+				t.Errorf("parseRecycleName(%q) time = %v, want %v", tt.name, ts, want)
+			}
+		}
+	}
+}
+
+func TestRestoredName(t *testing.T) {
+	existing := map[string]bool{
+		"a/b_restored.png":   true,
+		"a/b_restored_2.png": true,
+	}
+	exists := func(name string) bool { return existing[name] }
+
+	tests := map[string]string{
+		"a/b.png":   "a/b_restored_3.png",
+		"c.tar.gz":  "c.tar_restored.gz",
+		"dir":       "dir_restored",
+		"a/.hidden": "a/.hidden_restored",
+	}
+	for name, want := range tests {
+		if got := restoredName(name, exists); got != want {
+			t.Errorf("restoredName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
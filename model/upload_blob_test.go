@@ -0,0 +1,141 @@
+package model
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"yanblog/utils/errmsg"
+	"yanblog/utils/storage"
+)
+
+func TestUploadBlobName(t *testing.T) {
+	hash := "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+	if got := uploadBlobName("article/cover", hash, ".JPG"); got != "article/cover/9f86d081884c7d659a2feaa0c55ad015.jpg" {
+		t.Errorf("uploadBlobName = %q", got)
+	}
+	if got := uploadBlobName("common", hash, ""); got != "common/9f86d081884c7d659a2feaa0c55ad015" {
+		t.Errorf("uploadBlobName 无扩展名 = %q", got)
+	}
+	// 地址需放得下文章封面字段（varchar(100)）
+	if url := "/uploads/" + uploadBlobName("article/content/202601", hash, ".jpeg"); len(url) > 100 {
+		t.Errorf("地址过长: %d", len(url))
+	}
+}
+
+func TestDedupKeep(t *testing.T) {
+	tests := []struct {
+		names    []string
+		recorded map[string]bool
+		want     string
+	}{
+		{[]string{"common/222.png", "avatar/1.png", "article/cover/3.png"}, nil, "avatar/1.png"},
+		{[]string{"common/b.png", "common/a.png"}, nil, "common/a.png"},
+		// 优先保留已登记的文件，避免登记的地址失效
+		{[]string{"avatar/1.png", "article/cover/3.png"}, map[string]bool{"article/cover/3.png": true}, "article/cover/3.png"},
+		{[]string{"x/long-name.png", "a/1.png", "b/2.png"}, map[string]bool{"x/long-name.png": true, "b/2.png": true}, "b/2.png"},
+	}
+	for _, tt := range tests {
+		if got := dedupKeep(tt.names, tt.recorded); got != tt.want {
+			t.Errorf("dedupKeep(%v) = %q, want %q", tt.names, got, tt.want)
+		}
+	}
+}
+
+func TestKeyLock(t *testing.T) {
+	var locks keyLock
+	unlock := locks.Lock("a")
+
+	// 不同的键互不阻塞
+	done := make(chan struct{})
+	go func() {
+		locks.Lock("b")()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("不同的键被阻塞")
+	}
+
+	// 相同的键需等待解锁
+	var mu sync.Mutex
+	acquired := false
+	done = make(chan struct{})
+	go func() {
+		locks.Lock("a")()
+		mu.Lock()
+		acquired = true
+		mu.Unlock()
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	mu.Lock()
+	if acquired {
+		t.Error("相同的键未被阻塞")
+	}
+	mu.Unlock()
+	unlock()
+	<-done
+
+	if len(locks.locks) != 0 {
+		t.Errorf("解锁后仍保留 %d 个锁", len(locks.locks))
+	}
+}
+
+// TestReleaseArtUploads 删除或编辑文章时释放不再引用的上传文件，引用计数归零后删除文件
+func TestReleaseArtUploads(t *testing.T) {
+	t.Chdir(t.TempDir())
+	openTestDB(t)
+
+	store := func(content string) string {
+		name, _, err := StoreUpload(strings.NewReader(content), -1, "article/cover", ".png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+	shared := store("shared")
+	store("shared")
+	own := store("own")
+	refCount := func(name string) int {
+		var blob UploadBlob
+		if db.Where("name = ?", name).First(&blob).Error != nil {
+			return 0
+		}
+		return blob.RefCount
+	}
+	exists := func(name string) bool {
+		return storage.Exists(storage.Current(), name)
+	}
+	if n := refCount(shared); n != 2 {
+		t.Fatalf("上传两次后引用计数 = %d, want 2", n)
+	}
+
+	a := Article{Title: "a", Content: "![](/uploads/" + shared + ")", Img: "/uploads/" + own}
+	b := Article{Title: "b", Img: "/uploads/" + shared}
+	for _, art := range []*Article{&a, &b} {
+		if code := CreateArt(art); code != errmsg.SUCCESS {
+			t.Fatalf("CreateArt(%s) = %d", art.Title, code)
+		}
+	}
+
+	if code := DeleteArt(int(a.ID)); code != errmsg.SUCCESS {
+		t.Fatalf("DeleteArt = %d", code)
+	}
+	if n := refCount(shared); n != 1 || !exists(shared) {
+		t.Errorf("删除文章后共用文件引用计数 = %d, 存在 = %v, want 1, true", n, exists(shared))
+	}
+	if n := refCount(own); n != 0 || exists(own) {
+		t.Errorf("删除文章后独占文件引用计数 = %d, 存在 = %v, want 0, false", n, exists(own))
+	}
+
+	// 编辑后不再引用：最后一个引用释放后删除文件
+	b.Img = ""
+	if code := EditArt(int(b.ID), &b); code != errmsg.SUCCESS {
+		t.Fatalf("EditArt = %d", code)
+	}
+	if n := refCount(shared); n != 0 || exists(shared) {
+		t.Errorf("编辑文章后共用文件引用计数 = %d, 存在 = %v, want 0, false", n, exists(shared))
+	}
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUploadRefCollector(t *testing.T) {
+	c := &uploadRefCollector{urls: make(map[string]string), sources: make(map[string][]UploadRefSource)}
+	content := "![a](/uploads/article/a.png) <img src=\"/img/w640/article/b.jpg\">\n" +
+		"![空格](/uploads/article/my%20photo.png) ![bad](/uploads/../config/config.yaml)"
+	c.add(UploadRefSource{Type: "article", ID: 1, Field: "content"}, content)
+	c.add(UploadRefSource{Type: "category", ID: 2, Field: "img"}, "/uploads/article/a.png")
+
+	want := map[string]string{
+		"article/a.png":        "/uploads/article/a.png",
+		"article/my photo.png": "/uploads/article/my%20photo.png",
+		"article/b.jpg":        "/uploads/article/b.jpg",
+	}
+	if !reflect.DeepEqual(c.urls, want) {
+		t.Errorf("urls = %v, want %v", c.urls, want)
+	}
+	if got := len(c.sources["article/a.png"]); got != 2 {
+		t.Errorf("article/a.png 应有 2 处引用, got %d", got)
+	}
+}
+
+func TestIsUploadRefCovered(t *testing.T) {
+	refs := map[string]string{
+		"article/a.png":   "/uploads/article/a.png",
+		"articles/标题":     "/uploads/articles/标题",
+		"common/sub/x.md": "/uploads/common/sub/x.md",
+	}
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"article/a.png", true},
+		{"article/b.png", false},
+		{"articles/标题/1.png", true}, // 引用了所在目录
+		{"common/sub", false},
+		{"a.png", false},
+	}
+	for _, tt := range tests {
+		if got := isUploadRefCovered(tt.name, refs); got != tt.want {
+			t.Errorf("isUploadRefCovered(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
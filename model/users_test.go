@@ -73,4 +73,4 @@ func TestBeforeSave_NotHashed(t *testing.T) {
 	if !CheckPassword(password, user.Password) {
 		t.Fatal("CheckPassword failed after BeforeSave")
 	}
-}
\ No newline at end of file
+}
@@ -9,7 +9,9 @@ import (
 	"time"
 	v1 "yanblog/api/v1"
 	middleware "yanblog/middlewares"
+	"yanblog/model"
 	"yanblog/utils"
+	"yanblog/utils/storage"
 
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-gonic/gin"
@@ -25,93 +27,174 @@ func InitRouter() {
 	// 使用中间件
 	r.Use(middleware.Logger())
 	r.Use(gin.Recovery())
-	r.Use(gzip.Gzip(gzip.DefaultCompression)) // 开启 gzip 压缩
+	r.Use(gzip.Gzip(gzip.DefaultCompression, // 开启 gzip 压缩
+		gzip.WithExcludedPaths([]string{"/api/v1/system/backup"}))) // 备份归档本身已压缩，且下载时需要解除写超时
 	r.Use(middleware.Cors())
 
-	// 确保必要的目录存在
-	os.MkdirAll("./uploads", 0755)
-
-	// 静态文件服务
-	r.Static("/uploads", "./uploads")
+	// 静态文件服务（上传文件使用远程存储时由后端从存储读取）
+	if storage.Current().Type() == storage.TypeLocal {
+		os.MkdirAll(storage.LocalRoot, 0755)
+		r.Static("/uploads", storage.LocalRoot)
+	} else {
+		r.GET("/uploads/*filepath", v1.ServeUpload)
+		r.HEAD("/uploads/*filepath", v1.ServeUpload)
+	}
 	r.Static("/assets", "./web/frontend/public/assets")
 	r.Static("/static", "./web/frontend/public/static")
 	r.Static("/iconfont", "./web/frontend/public/iconfont")
 	r.StaticFile("/favicon.ico", "./web/frontend/public/favicon.ico")
+	r.GET("/img/:size/*filepath", v1.GetImageVariant) // 上传图片的缩略图和不同宽度
 	
 	r.StaticFile("/config.yaml", utils.GetFrontEndConfigPath())
 
-	// 用户路由分组
+	// 用户路由分组（仅需登录，具体权限由接口自行判断）
 	auth := r.Group("api/v1")
 	auth.Use(middleware.JwtToken())
+	auth.Use(middleware.Audit()) // 记录所有写操作
 	auth.Use(middleware.APIRateLimit())
 
-	// 管理员权限分组（仅超级管理员和管理员可操作）
-	admin := r.Group("api/v1")
-	admin.Use(middleware.JwtToken())
-	admin.Use(middleware.AdminRequired())
-	admin.Use(middleware.APIRateLimit())
+	// 按权限声明的后台路由分组（角色拥有的权限见 model/Permission.go）
+	requires := func(perms ...string) *gin.RouterGroup {
+		group := r.Group("api/v1")
+		group.Use(middleware.JwtToken())
+		group.Use(middleware.Audit()) // 记录所有写操作（包括因权限不足被拒绝的）
+		group.Use(middleware.RequirePermission(perms...))
+		group.Use(middleware.APIRateLimit())
+		return group
+	}
+	userRead := requires(model.PermUserRead)
+	userWrite := requires(model.PermUserWrite)
+	articleWrite := requires(model.PermArticleWrite)
+	articleDelete := requires(model.PermArticleDelete)
+	categoryWrite := requires(model.PermCategoryWrite)
+	tagWrite := requires(model.PermTagWrite)
+	commentModerate := requires(model.PermCommentModerate)
+	fileUpload := requires(model.PermFileUpload)
+	fileRead := requires(model.PermFileRead)
+	fileWrite := requires(model.PermFileWrite)
+	fileDelete := requires(model.PermFileDelete)
+	configRead := requires(model.PermConfigRead)
+	configWrite := requires(model.PermConfigWrite)
+	auditRead := requires(model.PermAuditRead)
+	systemBackup := requires(model.PermSystemBackup)
 
 	{
 		// 用户模块的路由接口
-		admin.POST("user/add", v1.AddUser) // 添加用户（需管理员权限）
-		admin.PUT("user/:id", v1.EditUser)
-		admin.DELETE("user/:id", v1.DeleteUser)
-		auth.GET("users", v1.GetUsers)           // 查询用户列表（需认证）
-		auth.GET("users/search", v1.SearchUsers) // 搜索用户（需认证）
+		userWrite.POST("user/add", v1.AddUser) // 添加用户
+		auth.PUT("user/:id", v1.EditUser)      // 编辑用户（可修改自己，修改他人由接口按角色判断）
+		userWrite.DELETE("user/:id", v1.DeleteUser)
+		auth.GET("user/:id/sessions", v1.GetUserSessions)             // 查询用户登录会话
+		auth.POST("user/:id/sessions/revoke", v1.RevokeUserSessions) // 撤销用户全部会话（强制下线）
+		auth.POST("logout", v1.Logout)                                // 退出登录
+		auth.PUT("user/password", middleware.LoginRateLimit(), v1.ChangePassword) // 修改自己的密码（与登录共用频率限制）
+		auth.GET("permissions", v1.GetMyPermissions)                  // 当前用户的权限列表
+		// 两步验证（TOTP）
+		auth.GET("user/2fa", v1.GetTwoFactorStatus)                       // 当前用户的两步验证状态
+		auth.POST("user/2fa/setup", v1.SetupTwoFactor)                    // 生成密钥和二维码
+		auth.POST("user/2fa/enable", v1.EnableTwoFactor)                  // 确认验证码并启用
+		auth.POST("user/2fa/disable", v1.DisableTwoFactor)                // 关闭（需密码和验证码）
+		auth.POST("user/2fa/recovery-codes", v1.RegenerateRecoveryCodes) // 重新生成恢复码
+		userWrite.DELETE("user/:id/2fa", v1.ResetUserTwoFactor)          // 为丢失验证器的用户重置
+		userRead.GET("users", v1.GetUsers)                            // 查询用户列表
+		userRead.GET("users/search", v1.SearchUsers)                  // 搜索用户
 		// 分类模块的路由接口
-		admin.POST("category/add", v1.AddCategory)
-		admin.PUT("category/:id", v1.EditCate)
-		admin.DELETE("category/:id", v1.DeleteCate)
+		categoryWrite.POST("category/add", v1.AddCategory)
+		categoryWrite.PUT("category/:id", v1.EditCate)
+		categoryWrite.DELETE("category/:id", v1.DeleteCate)
 		// 文章模块的路由接口
-		admin.POST("article/add", v1.AddArticle)
-		admin.POST("article/zip", v1.UploadArticleZipV2)                // ZIP上传（V2）
-		admin.GET("article/upload/:id", v1.GetUploadProgressV2)       // 获取上传进度
-		admin.DELETE("article/upload/:id", v1.CancelUploadV2)        // 取消上传任务
-		admin.GET("article/upload/:id/ws", v1.WebSocketProgress)     // WebSocket进度
-		admin.POST("article/upload/:id/retry", v1.RetryFailedUpload) // 重试失败文件
-		admin.GET("article/upload/history", v1.GetUploadHistory)     // 上传历史
-		admin.PUT("article/:id", v1.EditArt)
-
-		admin.DELETE("article/:id", v1.DeleteArt)
-		admin.POST("article/batch-delete", v1.BatchDeleteArt)
+		articleWrite.POST("article/add", v1.AddArticle)
+		articleWrite.POST("article/zip", v1.UploadArticleZipV2)                // ZIP上传（V2）
+		articleWrite.POST("article/import", v1.ImportBlog)                    // 导入 Hexo / Hugo / Jekyll / WordPress
+		articleWrite.GET("article/upload/:id", v1.GetUploadProgressV2)       // 获取上传进度
+		articleWrite.DELETE("article/upload/:id", v1.CancelUploadV2)        // 取消上传任务
+		articleWrite.GET("article/upload/:id/ws", v1.WebSocketProgress)     // WebSocket进度
+		articleWrite.POST("article/upload/:id/retry", v1.RetryFailedUpload) // 重试失败文件
+		articleWrite.GET("article/upload/history", v1.GetUploadHistory)     // 上传历史
+		articleWrite.DELETE("article/upload/history", v1.ClearUploadHistory) // 清空上传历史
+		articleWrite.GET("article/admin", v1.GetAdminArt)                   // 后台文章列表（含草稿）
+		articleWrite.GET("article/admin/:id", v1.GetAdminArtInfo)           // 后台文章详情（含草稿）
+		articleWrite.GET("article/export", v1.ExportArticles)               // 批量导出 Markdown ZIP
+		articleWrite.GET("article/:id/export", v1.ExportArticle)            // 导出单篇文章 Markdown ZIP
+		articleWrite.PUT("article/:id", v1.EditArt)
+		articleWrite.PUT("article/:id/status", v1.UpdateArtStatus)                     // 修改文章状态
+		articleWrite.GET("article/:id/revisions", v1.GetArtRevisions)                   // 修订历史
+		articleWrite.GET("article/:id/revisions/diff", v1.DiffArtRevisions)             // 对比两个修订
+		articleWrite.GET("article/:id/revisions/:rid", v1.GetArtRevision)               // 修订详情
+		articleWrite.POST("article/:id/revisions/:rid/restore", v1.RestoreArtRevision) // 恢复到指定修订
+		articleWrite.POST("article/search/reindex", v1.RebuildSearchIndex)             // 重建全文索引
+
+		articleDelete.DELETE("article/:id", v1.DeleteArt)
+		articleDelete.POST("article/batch-delete", v1.BatchDeleteArt)
+		// 评论审核
+		commentModerate.GET("comment/admin", v1.GetAdminComments)       // 评论列表（审核队列）
+		commentModerate.PUT("comment/:id/approve", v1.ApproveComment)   // 通过
+		commentModerate.PUT("comment/:id/reject", v1.RejectComment)     // 驳回（标记为垃圾评论）
+		commentModerate.DELETE("comment/:id", v1.DeleteComment)         // 删除（连同回复）
+		commentModerate.POST("comment/batch", v1.BatchModerateComments) // 批量审核
 		// 标签模块
-		admin.POST("tags/add", v1.AddTag)
-		admin.PUT("tags/:id", v1.EditTag)
-		admin.DELETE("tags/:id", v1.DeleteTag)
+		tagWrite.POST("tags/add", v1.AddTag)
+		tagWrite.PUT("tags/:id", v1.EditTag)
+		tagWrite.DELETE("tags/:id", v1.DeleteTag)
 		// 上传文件
-		admin.POST("upload", v1.UpLoad)
+		fileUpload.POST("upload", v1.UpLoad)
+		// 分片上传（断点续传）
+		fileUpload.POST("upload/chunked", v1.CreateChunkedUpload)
+		fileUpload.HEAD("upload/chunked/:id", v1.GetChunkedUploadOffset)
+		fileUpload.PATCH("upload/chunked/:id", v1.UploadChunk)
+		fileUpload.POST("upload/chunked/:id/complete", v1.CompleteChunkedUpload)
+		fileUpload.DELETE("upload/chunked/:id", v1.AbortChunkedUpload)
 		// 文件管理
-		auth.GET("files", v1.GetFileList)
-		admin.DELETE("files", v1.DeleteFile)
-		admin.POST("files/folder", v1.CreateDir)     // 创建目录
-		admin.PUT("files", v1.RenameFile)            // 重命名
-		admin.POST("files/move", v1.MoveFile)        // 移动文件/目录
-		admin.POST("files/copy", v1.CopyFile)        // 复制文件
-		admin.POST("files/batch-delete", v1.BatchDeleteFiles) // 批量删除
-		admin.POST("files/batch-upload", v1.BatchUploadFiles) // 批量上传
-		auth.GET("files/stats", v1.GetStorageStats) // 获取存储统计
+		fileRead.GET("files", v1.GetFileList)
+		fileDelete.DELETE("files", v1.DeleteFile)
+		fileWrite.POST("files/folder", v1.CreateDir)             // 创建目录
+		fileWrite.PUT("files", v1.RenameFile)                    // 重命名
+		fileWrite.POST("files/move", v1.MoveFile)                // 移动文件/目录
+		fileWrite.POST("files/copy", v1.CopyFile)                // 复制文件
+		fileDelete.POST("files/batch-delete", v1.BatchDeleteFiles) // 批量删除
+		fileWrite.POST("files/batch-upload", v1.BatchUploadFiles) // 批量上传
+		fileRead.GET("files/stats", v1.GetStorageStats)           // 获取存储统计
 		// V2 增强文件管理
-		auth.GET("files/v2/stats", v1.GetFileStats)              // 详细统计
-		auth.GET("files/v2/search", v1.SearchFiles)              // 搜索文件
-		admin.POST("files/v2/compress", v1.CompressFiles)        // 压缩
-		admin.POST("files/v2/extract", v1.ExtractZip)            // 解压
-		admin.POST("files/v2/recycle", v1.MoveToRecycleBin)      // 删除到回收站
-		auth.GET("files/v2/recycle", v1.GetRecycleBin)           // 回收站列表
-		admin.POST("files/v2/recycle/restore", v1.RestoreFromRecycleBin) // 恢复
-		admin.DELETE("files/v2/recycle", v1.EmptyRecycleBin)     // 清空回收站
-		auth.GET("files/v2/preview", v1.GetFilePreview)          // 文件预览
-		admin.PUT("files/v2/metadata", v1.SaveFileMetadata)      // 保存元数据
-		auth.GET("files/v2/metadata", v1.GetFileMetadata)        // 获取元数据
-		// 前端配置管理（更新需要管理员权限）
-		admin.PUT("frontend/config", v1.UpdateFrontEndConfig)
-		// 后端配置管理
-		admin.GET("backend/config", v1.GetBackendConfig)
-		admin.PUT("backend/config", v1.UpdateBackendConfig)
-		admin.POST("config/reload", v1.ReloadConfig)
-		admin.GET("config/all", v1.GetAllConfig)
-		admin.GET("system/status", v1.GetSystemStatus) // 获取系统状态信息（需管理员权限）
+		fileRead.GET("files/v2/stats", v1.GetFileStats)                      // 详细统计
+		fileRead.GET("files/v2/search", v1.SearchFiles)                      // 搜索文件
+		fileWrite.POST("files/v2/compress", v1.CompressFiles)                // 压缩
+		fileWrite.POST("files/v2/extract", v1.ExtractZip)                    // 解压
+		fileDelete.POST("files/v2/recycle", v1.MoveToRecycleBin)             // 删除到回收站
+		fileRead.GET("files/v2/recycle", v1.GetRecycleBin)                   // 回收站列表
+		fileWrite.POST("files/v2/recycle/restore", v1.RestoreFromRecycleBin) // 恢复
+		fileDelete.DELETE("files/v2/recycle", v1.EmptyRecycleBin)            // 清空回收站
+		fileDelete.DELETE("files/v2/recycle/:id", v1.DeleteRecycleItem)      // 永久删除回收站中的项目
+		fileRead.GET("files/v2/preview", v1.GetFilePreview)                  // 文件预览
+		fileWrite.PUT("files/v2/metadata", v1.SaveFileMetadata)              // 保存元数据
+		fileRead.GET("files/v2/metadata", v1.GetFileMetadata)                // 获取元数据
+		fileWrite.POST("files/images/backfill", v1.StartImageBackfill)       // 为已有图片补生成各尺寸
+		fileRead.GET("files/images/backfill", v1.GetImageBackfill)           // 补生成进度
+		fileRead.GET("files/dedup", v1.GetDedupStats)                        // 去重节省的空间
+		fileWrite.POST("files/dedup/migrate", v1.StartUploadDedup)           // 合并已有的重复文件
+		fileRead.GET("files/dedup/migrate", v1.GetUploadDedup)               // 合并进度
+		fileRead.GET("files/gc", v1.ScanUploads)                             // 未引用的文件和失效的引用
+		fileDelete.POST("files/gc/recycle", v1.RecycleOrphanUploads)         // 未引用的文件移入回收站
+		fileRead.GET("files/jobs", v1.GetFileJobs)                           // 后台任务列表
+		fileRead.GET("files/jobs/:id", v1.GetFileJob)                        // 任务进度和结果
+		fileRead.GET("files/jobs/:id/ws", v1.FileJobProgress)                // WebSocket 任务进度
+		fileWrite.DELETE("files/jobs/:id", v1.CancelFileJob)                 // 取消任务
+		// 前端配置管理
+		configWrite.PUT("frontend/config", v1.UpdateFrontEndConfig)
+		// 后端配置管理（包含密钥等敏感信息，读取也需要写权限）
+		configWrite.GET("backend/config", v1.GetBackendConfig)
+		configWrite.PUT("backend/config", v1.UpdateBackendConfig)
+		configWrite.POST("config/reload", v1.ReloadConfig)
+		configWrite.GET("config/all", v1.GetAllConfig)
+		configRead.GET("system/status", v1.GetSystemStatus) // 获取系统状态信息
 		// 关于页面内容管理
-		admin.PUT("about", v1.UpdateAboutContent)
+		configWrite.PUT("about", v1.UpdateAboutContent)
+		// 审计日志
+		auditRead.GET("audit", v1.GetAuditLogs)
+		auditRead.GET("audit/export", v1.ExportAuditLogs) // 导出 CSV
+		// 全站备份与恢复
+		systemBackup.POST("system/backup", v1.CreateBackup)                        // 下载备份（POST 以便记录审计日志）
+		systemBackup.POST("system/backup/restore", v1.UploadRestoreBackup)         // 上传备份并预览差异
+		systemBackup.POST("system/backup/restore/:id", v1.ApplyRestoreBackup)      // 确认恢复
+		systemBackup.DELETE("system/backup/restore/:id", v1.DiscardRestoreBackup) // 放弃恢复
 	}
 
 	// 公共路由分组
@@ -122,6 +205,7 @@ func InitRouter() {
 		router.GET("category", v1.GetCate)
 		router.GET("category/search", v1.SearchCate)    // 搜索分类
 		router.GET("category/info/:id", v1.GetCateInfo) // 获取分类信息
+		router.GET("category/slug/:slug", v1.GetCateInfoBySlug) // 通过 slug 获取分类信息（旧 slug 301 跳转）
 		router.GET("article", v1.GetArt)
 		router.GET("article/search", v1.SearchArt)          // 搜索文章
 		router.GET("article/top", v1.GetTopArt)             // 获取置顶文章
@@ -132,14 +216,30 @@ func InitRouter() {
 		router.GET("article/archive", v1.GetArchive)        // 归档
 		router.GET("article/list/:id", v1.GetCateArt)
 		router.GET("article/info/:id", v1.GetArtInfo)
+		router.GET("article/slug/:slug", v1.GetArtInfoBySlug) // 通过 slug 获取文章（旧 slug 301 跳转）
+		router.GET("comment/list/:id", v1.GetArtComments)                            // 获取文章评论
+		router.POST("comment/add", middleware.CommentRateLimit(), v1.AddComment) // 发表评论（限流）
 		router.GET("tags", v1.GetTags)                  // 获取标签列表
 		router.GET("weather", v1.GetWeather)            // 获取天气信息
 		router.GET("health", v1.HealthCheck)            // 健康检查（公开接口）
 		router.GET("frontend/config", v1.GetFrontEndConfig) // 获取前端配置（公开接口）
 		router.POST("login", middleware.LoginRateLimit(), v1.Login)
+		router.POST("login/2fa", middleware.TwoFactorRateLimit(), v1.LoginTwoFactor) // 两步登录：提交验证码或恢复码（按登录挑战限制次数）
+		router.POST("token/refresh", middleware.RateLimit("refresh", 30, time.Minute, "请求过于频繁，请稍后再试"), v1.RefreshToken) // 刷新访问令牌
 		router.GET("sitemap.xml", v1.GetSitemap) // 站点地图
 	}
 
+	// 订阅源（挂在站点根路径，便于阅读器自动发现）
+	r.GET("feed.xml", v1.GetRSSFeed)
+	r.GET("atom.xml", v1.GetAtomFeed)
+	r.GET("feed.json", v1.GetJSONFeed)
+	r.GET("feed/category/:id/feed.xml", v1.GetRSSFeed) // 分类订阅
+	r.GET("feed/category/:id/atom.xml", v1.GetAtomFeed)
+	r.GET("feed/category/:id/feed.json", v1.GetJSONFeed)
+	r.GET("feed/tag/:name/feed.xml", v1.GetRSSFeed) // 标签订阅
+	r.GET("feed/tag/:name/atom.xml", v1.GetAtomFeed)
+	r.GET("feed/tag/:name/feed.json", v1.GetJSONFeed)
+
 	srv := &http.Server{
 		Addr:           utils.ServerConfig.Server.HttpPort,
 		Handler:        r,
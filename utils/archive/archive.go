@@ -0,0 +1,403 @@
+// Package archive 文件管理中压缩包的创建和读取（zip、tar、tar.gz、tar.bz2），读取时限制条目数、解压后的总大小和压缩比，防止压缩炸弹
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// 压缩包格式
+const (
+	FormatZip    = "zip"
+	FormatTar    = "tar"
+	FormatTarGz  = "tar.gz"
+	FormatTarBz2 = "tar.bz2" // 只支持解压
+)
+
+// formatExts 各格式的扩展名，较长的扩展名在前
+var formatExts = []struct {
+	ext    string
+	format string
+}{
+	{".tar.gz", FormatTarGz},
+	{".tar.bz2", FormatTarBz2},
+	{".tgz", FormatTarGz},
+	{".tbz2", FormatTarBz2},
+	{".tbz", FormatTarBz2},
+	{".tar", FormatTar},
+	{".zip", FormatZip},
+}
+
+// Detect 根据文件名判断压缩包格式，不支持的格式返回空字符串
+func Detect(name string) string {
+	lower := strings.ToLower(name)
+	for _, f := range formatExts {
+		if strings.HasSuffix(lower, f.ext) && len(lower) > len(f.ext) {
+			return f.format
+		}
+	}
+	return ""
+}
+
+// otherExts 常见但不支持的压缩格式的扩展名
+var otherExts = []string{".rar", ".7z", ".gz", ".bz2", ".xz", ".zst", ".lz", ".lzma", ".z", ".cab", ".iso"}
+
+// IsOther 是否是不支持的压缩格式（如 .rar、.7z、.tar.zst）
+func IsOther(name string) bool {
+	if Detect(name) != "" {
+		return false
+	}
+	lower := strings.ToLower(name)
+	for _, ext := range otherExts {
+		if strings.HasSuffix(lower, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanCreate 是否支持创建该格式的压缩包
+func CanCreate(format string) bool {
+	switch format {
+	case FormatZip, FormatTar, FormatTarGz:
+		return true
+	}
+	return false
+}
+
+// Ext 格式的扩展名
+func Ext(format string) string {
+	if format == "" {
+		return ""
+	}
+	return "." + format
+}
+
+// Limits 解压的限制，为 0 的项不限制
+type Limits struct {
+	MaxEntries int   // 最多的条目数（含目录）
+	MaxSize    int64 // 解压后的总大小
+	MaxRatio   int64 // 解压后的总大小与压缩包大小之比
+}
+
+// DefaultLimits 文件管理解压时的默认限制
+var DefaultLimits = Limits{
+	MaxEntries: 10000,
+	MaxSize:    1 << 30,
+	MaxRatio:   100,
+}
+
+// ratioFloor 解压后的总大小在此以内时不检查压缩比，避免误判内容重复的小压缩包
+const ratioFloor = 16 << 20
+
+var (
+	// ErrTooManyEntries 条目数超过限制
+	ErrTooManyEntries = errors.New("压缩包中的文件过多")
+	// ErrTooLarge 解压后的总大小超过限制
+	ErrTooLarge = errors.New("压缩包解压后过大")
+	// ErrRatio 压缩比超过限制
+	ErrRatio = errors.New("压缩包的压缩比异常")
+	// ErrUnsupported 不支持的格式
+	ErrUnsupported = errors.New("不支持的压缩格式")
+)
+
+// LimitError 超过解压限制时返回的错误，可用 errors.Is 判断具体原因
+type LimitError struct {
+	Err   error
+	Limit int64
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%v（上限 %d）", e.Err, e.Limit)
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+// IsLimit 错误是否是超过解压限制
+func IsLimit(err error) bool {
+	var e *LimitError
+	return errors.As(err, &e)
+}
+
+// Entry 压缩包中的条目
+type Entry struct {
+	Name    string // 条目名称（未经清理，调用方需自行防止路径遍历）
+	IsDir   bool
+	Size    int64 // 头部记录的大小，读取时以实际数据为准
+	ModTime time.Time
+}
+
+// Reader 按顺序读取压缩包中的条目，只返回普通文件和目录（符号链接、硬链接等被跳过）
+type Reader struct {
+	limits  Limits
+	size    int64 // 压缩包大小，用于检查压缩比
+	entries int
+	total   int64 // 已读取的解压后数据大小
+	err     error // 读取时超过的限制
+
+	zr    *zip.ReadCloser
+	zi    int
+	cur   io.Closer // 正在读取的 ZIP 条目
+	tr    *tar.Reader
+	close func() error
+}
+
+// OpenFile 打开本地压缩包文件
+func OpenFile(name string, format string, limits Limits) (*Reader, error) {
+	if format == FormatZip {
+		zr, err := zip.OpenReader(name)
+		if err != nil {
+			return nil, err
+		}
+		r := &Reader{limits: limits, zr: zr, close: zr.Close}
+		if info, err := os.Stat(name); err == nil {
+			r.size = info.Size()
+		}
+		if err := r.checkZipHeaders(); err != nil {
+			zr.Close()
+			return nil, err
+		}
+		return r, nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	r, err := NewTarReader(f, info.Size(), format, limits)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	closeTar := r.close
+	r.close = func() error {
+		closeTar()
+		return f.Close()
+	}
+	return r, nil
+}
+
+// NewTarReader 从流中读取 tar 格式（tar、tar.gz、tar.bz2）的压缩包，size 为压缩包大小
+func NewTarReader(src io.Reader, size int64, format string, limits Limits) (*Reader, error) {
+	r := &Reader{limits: limits, size: size, close: func() error { return nil }}
+	switch format {
+	case FormatTar:
+	case FormatTarGz:
+		gz, err := gzip.NewReader(src)
+		if err != nil {
+			return nil, err
+		}
+		src = gz
+		r.close = gz.Close
+	case FormatTarBz2:
+		src = bzip2.NewReader(src)
+	default:
+		return nil, ErrUnsupported
+	}
+	r.tr = tar.NewReader(src)
+	return r, nil
+}
+
+// checkZipHeaders 按 ZIP 头部记录的条目数和大小预先检查，避免解压到一半才发现超过限制
+func (r *Reader) checkZipHeaders() error {
+	if r.limits.MaxEntries > 0 && len(r.zr.File) > r.limits.MaxEntries {
+		return &LimitError{Err: ErrTooManyEntries, Limit: int64(r.limits.MaxEntries)}
+	}
+	var total uint64
+	for _, f := range r.zr.File {
+		total += f.UncompressedSize64
+		if err := r.checkSize(total); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSize 检查解压后的总大小和压缩比
+func (r *Reader) checkSize(total uint64) error {
+	if r.limits.MaxSize > 0 && total > uint64(r.limits.MaxSize) {
+		return &LimitError{Err: ErrTooLarge, Limit: r.limits.MaxSize}
+	}
+	if r.limits.MaxRatio > 0 && r.size > 0 && total > ratioFloor && total/uint64(r.size) > uint64(r.limits.MaxRatio) {
+		return &LimitError{Err: ErrRatio, Limit: r.limits.MaxRatio}
+	}
+	return nil
+}
+
+// Next 返回下一个条目及其数据，没有更多条目时返回 io.EOF
+// 数据只在下一次调用 Next 前有效，读取时实际数据超过限制会返回 LimitError
+func (r *Reader) Next() (*Entry, io.Reader, error) {
+	for {
+		entry, data, err := r.next()
+		if err != nil {
+			return nil, nil, err
+		}
+		if entry == nil {
+			continue
+		}
+		r.entries++
+		if r.limits.MaxEntries > 0 && r.entries > r.limits.MaxEntries {
+			return nil, nil, &LimitError{Err: ErrTooManyEntries, Limit: int64(r.limits.MaxEntries)}
+		}
+		if entry.IsDir {
+			return entry, strings.NewReader(""), nil
+		}
+		return entry, &countingReader{r: data, reader: r}, nil
+	}
+}
+
+// next 读取下一个条目，需要跳过的条目返回 nil
+func (r *Reader) next() (*Entry, io.Reader, error) {
+	if r.zr != nil {
+		if r.cur != nil {
+			r.cur.Close()
+			r.cur = nil
+		}
+		if r.zi >= len(r.zr.File) {
+			return nil, nil, io.EOF
+		}
+		f := r.zr.File[r.zi]
+		r.zi++
+		mode := f.Mode()
+		if !mode.IsDir() && !mode.IsRegular() {
+			return nil, nil, nil
+		}
+		entry := &Entry{Name: f.Name, IsDir: mode.IsDir(), Size: int64(f.UncompressedSize64), ModTime: f.Modified}
+		if entry.IsDir {
+			return entry, nil, nil
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, nil, err
+		}
+		r.cur = rc
+		return entry, rc, nil
+	}
+
+	h, err := r.tr.Next()
+	if err != nil {
+		return nil, nil, err
+	}
+	switch h.Typeflag {
+	case tar.TypeDir:
+		return &Entry{Name: h.Name, IsDir: true, ModTime: h.ModTime}, nil, nil
+	case tar.TypeReg:
+		return &Entry{Name: h.Name, Size: h.Size, ModTime: h.ModTime}, r.tr, nil
+	}
+	return nil, nil, nil
+}
+
+// Len ZIP 中的条目数，tar 格式无法预先得知时为 0
+func (r *Reader) Len() int {
+	if r.zr != nil {
+		return len(r.zr.File)
+	}
+	return 0
+}
+
+// Exceeded 读取条目数据时超过的限制（存储写入失败时可据此区分原因），未超过时为 nil
+func (r *Reader) Exceeded() error {
+	return r.err
+}
+
+// Close 关闭压缩包
+func (r *Reader) Close() error {
+	if r.cur != nil {
+		r.cur.Close()
+	}
+	return r.close()
+}
+
+// countingReader 统计实际读取的解压后数据，超过限制时返回错误（不信任头部记录的大小）
+type countingReader struct {
+	r      io.Reader
+	reader *Reader
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.reader.total += int64(n)
+	if limitErr := c.reader.checkSize(uint64(c.reader.total)); limitErr != nil {
+		c.reader.err = limitErr
+		return n, limitErr
+	}
+	return n, err
+}
+
+// Writer 创建压缩包
+type Writer struct {
+	zw *zip.Writer
+	tw *tar.Writer
+	gz *gzip.Writer
+}
+
+// NewWriter 创建写入 w 的压缩包，format 须为 CanCreate 支持的格式
+func NewWriter(w io.Writer, format string) (*Writer, error) {
+	switch format {
+	case FormatZip:
+		return &Writer{zw: zip.NewWriter(w)}, nil
+	case FormatTar:
+		return &Writer{tw: tar.NewWriter(w)}, nil
+	case FormatTarGz:
+		gz := gzip.NewWriter(w)
+		return &Writer{tw: tar.NewWriter(gz), gz: gz}, nil
+	}
+	return nil, ErrUnsupported
+}
+
+// Add 添加文件，tar 格式需要准确的 size
+func (w *Writer) Add(name string, size int64, modTime time.Time, r io.Reader) error {
+	if w.zw != nil {
+		writer, err := w.zw.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: modTime,
+		})
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(writer, r)
+		return err
+	}
+	err := w.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Size:     size,
+		Mode:     0644,
+		ModTime:  modTime,
+		Format:   tar.FormatPAX,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w.tw, r)
+	return err
+}
+
+// Close 写入压缩包的结尾
+func (w *Writer) Close() error {
+	if w.zw != nil {
+		return w.zw.Close()
+	}
+	if err := w.tw.Close(); err != nil {
+		return err
+	}
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetect(t *testing.T) {
+	tests := map[string]string{
+		"a.zip":        FormatZip,
+		"a.ZIP":        FormatZip,
+		"a.tar":        FormatTar,
+		"a.tar.gz":     FormatTarGz,
+		"a.tgz":        FormatTarGz,
+		"a.tar.bz2":    FormatTarBz2,
+		"a.tbz2":       FormatTarBz2,
+		"dir/a.b.zip":  FormatZip,
+		"a.gz":         "",
+		"a.rar":        "",
+		"a.7z":         "",
+		"a.tar.zst":    "",
+		".zip":         "",
+		"archive":      "",
+		"a.tar.gz.txt": "",
+	}
+	for name, want := range tests {
+		if got := Detect(name); got != want {
+			t.Errorf("Detect(%q) = %q, want %q", name, got, want)
+		}
+	}
+	for _, name := range []string{"a.rar", "a.7z", "a.tar.zst", "a.gz", "a.XZ"} {
+		if !IsOther(name) {
+			t.Errorf("IsOther(%q) = false", name)
+		}
+	}
+	for _, name := range []string{"a.zip", "a.tar.gz", "a.txt", "archive"} {
+		if IsOther(name) {
+			t.Errorf("IsOther(%q) = true", name)
+		}
+	}
+	if CanCreate(FormatTarBz2) || !CanCreate(FormatTarGz) {
+		t.Error("CanCreate: tar.bz2 只支持解压，tar.gz 支持创建")
+	}
+}
+
+// writeArchive 创建包含给定文件的压缩包，返回本地路径
+func writeArchive(t *testing.T, format string, files map[string]string) string {
+	t.Helper()
+	name := filepath.Join(t.TempDir(), "test"+Ext(format))
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w, err := NewWriter(f, format)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n, content := range files {
+		if err := w.Add(n, int64(len(content)), time.Now(), strings.NewReader(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+// readAll 读取压缩包中的所有文件
+func readAll(name string, format string, limits Limits) (map[string]string, error) {
+	r, err := OpenFile(name, format, limits)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	files := make(map[string]string)
+	for {
+		entry, data, err := r.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return files, err
+		}
+		if entry.IsDir {
+			continue
+		}
+		b, err := io.ReadAll(data)
+		if err != nil {
+			return files, err
+		}
+		files[entry.Name] = string(b)
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	files := map[string]string{
+		"dir/a.txt":  "hello",
+		"dir/b/c.md": "# 标题",
+		"empty":      "",
+	}
+	for _, format := range []string{FormatZip, FormatTar, FormatTarGz} {
+		name := writeArchive(t, format, files)
+		got, err := readAll(name, format, DefaultLimits)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if len(got) != len(files) {
+			t.Fatalf("%s: got %v, want %v", format, got, files)
+		}
+		for n, content := range files {
+			if got[n] != content {
+				t.Errorf("%s: %s = %q, want %q", format, n, got[n], content)
+			}
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	big := strings.Repeat("a", 32<<20) // 32MB 的重复内容，压缩后远小于 1/100
+	tests := []struct {
+		name   string
+		files  map[string]string
+		limits Limits
+		want   error
+	}{
+		{"条目数", map[string]string{"a": "1", "b": "2", "c": "3"}, Limits{MaxEntries: 2}, ErrTooManyEntries},
+		{"总大小", map[string]string{"a": "12345", "b": "67890"}, Limits{MaxSize: 8}, ErrTooLarge},
+		{"压缩比", map[string]string{"bomb": big}, Limits{MaxRatio: 100}, ErrRatio},
+		{"未超过", map[string]string{"a": "12345"}, DefaultLimits, nil},
+	}
+	for _, format := range []string{FormatZip, FormatTarGz} {
+		for _, tt := range tests {
+			name := writeArchive(t, format, tt.files)
+			_, err := readAll(name, format, tt.limits)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("%s %s: err = %v, want %v", format, tt.name, err, tt.want)
+			}
+			if tt.want != nil && !IsLimit(err) {
+				t.Errorf("%s %s: IsLimit(%v) = false", format, tt.name, err)
+			}
+		}
+	}
+}
+
+// TestCountingReader 头部记录的大小可能与实际数据不符，按实际读取的数据检查限制
+func TestCountingReader(t *testing.T) {
+	r := &Reader{limits: Limits{MaxSize: 500}}
+	cr := &countingReader{r: strings.NewReader(strings.Repeat("x", 1000)), reader: r}
+	if _, err := io.ReadAll(cr); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("读取 = %v, want ErrTooLarge", err)
+	}
+}
+
+func TestTarSkipsLinks(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Typeflag: tar.TypeSymlink, Name: "link", Linkname: "/etc/passwd"})
+	tw.WriteHeader(&tar.Header{Typeflag: tar.TypeLink, Name: "hard", Linkname: "a.txt"})
+	tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: "dir/"})
+	tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "dir/a.txt", Size: 2, Mode: 0644})
+	tw.Write([]byte("ok"))
+	tw.Close()
+
+	r, err := NewTarReader(&buf, int64(buf.Len()), FormatTar, DefaultLimits)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for {
+		entry, _, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, entry.Name)
+	}
+	if strings.Join(names, ",") != "dir/,dir/a.txt" {
+		t.Errorf("entries = %v, want [dir/ dir/a.txt]", names)
+	}
+}
@@ -0,0 +1,127 @@
+package utils
+
+import "strings"
+
+// 行级差异操作类型
+const (
+	DiffEqual  = "equal"
+	DiffInsert = "insert"
+	DiffDelete = "delete"
+)
+
+// DiffLine 行级差异中的一行
+type DiffLine struct {
+	Op      string `json:"op"`       // equal, insert, delete
+	Text    string `json:"text"`     // 行内容
+	OldLine int    `json:"old_line"` // 旧文本中的行号（从 1 开始，插入行为 0）
+	NewLine int    `json:"new_line"` // 新文本中的行号（从 1 开始，删除行为 0）
+}
+
+// DiffLines 使用 Myers 算法计算两段文本的行级差异
+// 参数: oldText - 旧文本, newText - 新文本
+// 返回: 按顺序排列的差异行
+func DiffLines(oldText string, newText string) []DiffLine {
+	a := splitLines(oldText)
+	b := splitLines(newText)
+	n, m := len(a), len(b)
+	max := n + m
+
+	result := make([]DiffLine, 0, max)
+	if max == 0 {
+		return result
+	}
+
+	// v[k+offset] 记录对角线 k 上能到达的最远 x；trace 保存每一步的 v 用于回溯
+	offset := max
+	v := make([]int, 2*max+2)
+	var trace [][]int
+
+	for d := 0; d <= max; d++ {
+		snapshot := make([]int, len(v))
+		copy(snapshot, v)
+		trace = append(trace, snapshot)
+
+		done := false
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[k-1+offset] < v[k+1+offset]) {
+				x = v[k+1+offset] // 向下：插入
+			} else {
+				x = v[k-1+offset] + 1 // 向右：删除
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[k+offset] = x
+			if x >= n && y >= m {
+				done = true
+				break
+			}
+		}
+		if done {
+			break
+		}
+	}
+
+	// 从终点回溯出编辑路径（倒序）
+	x, y := n, m
+	reversed := make([]DiffLine, 0, max)
+	for d := len(trace) - 1; d >= 0 && (x > 0 || y > 0); d-- {
+		vd := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && vd[k-1+offset] < vd[k+1+offset]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := vd[prevK+offset]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, DiffLine{Op: DiffEqual, Text: a[x-1], OldLine: x, NewLine: y})
+			x--
+			y--
+		}
+		if d == 0 {
+			break
+		}
+		if x == prevX {
+			reversed = append(reversed, DiffLine{Op: DiffInsert, Text: b[y-1], NewLine: y})
+		} else {
+			reversed = append(reversed, DiffLine{Op: DiffDelete, Text: a[x-1], OldLine: x})
+		}
+		x, y = prevX, prevY
+	}
+
+	for i := len(reversed) - 1; i >= 0; i-- {
+		result = append(result, reversed[i])
+	}
+	return result
+}
+
+// DiffStats 统计差异中新增和删除的行数
+func DiffStats(lines []DiffLine) (added int, removed int) {
+	for _, l := range lines {
+		switch l.Op {
+		case DiffInsert:
+			added++
+		case DiffDelete:
+			removed++
+		}
+	}
+	return
+}
+
+// splitLines 按行拆分文本（统一换行符，空文本返回空切片）
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.TrimSuffix(s, "\n")
+	return strings.Split(s, "\n")
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+// applyDiff 根据差异结果重建新旧文本，用于校验差异的正确性
+func applyDiff(lines []DiffLine) (oldText string, newText string) {
+	var oldLines, newLines []string
+	for _, l := range lines {
+		switch l.Op {
+		case DiffEqual:
+			oldLines = append(oldLines, l.Text)
+			newLines = append(newLines, l.Text)
+		case DiffDelete:
+			oldLines = append(oldLines, l.Text)
+		case DiffInsert:
+			newLines = append(newLines, l.Text)
+		}
+	}
+	return strings.Join(oldLines, "\n"), strings.Join(newLines, "\n")
+}
+
+func TestDiffLines(t *testing.T) {
+	cases := []struct {
+		old, new       string
+		added, removed int
+	}{
+		{"", "", 0, 0},
+		{"", "a\nb", 2, 0},
+		{"a\nb", "", 0, 2},
+		{"a\nb\nc", "a\nb\nc", 0, 0},
+		{"a\nb\nc", "a\nx\nc", 1, 1},
+		{"a\nb\nc\na\nb\nb\na", "c\nb\na\nb\na\nc", 2, 3},
+		{"# 标题\n\n第一段\n第二段\n", "# 新标题\n\n第一段\n补充\n第二段\n", 2, 1},
+	}
+
+	for _, tc := range cases {
+		lines := DiffLines(tc.old, tc.new)
+		gotOld, gotNew := applyDiff(lines)
+		if gotOld != strings.TrimSuffix(tc.old, "\n") || gotNew != strings.TrimSuffix(tc.new, "\n") {
+			t.Fatalf("diff of %q -> %q does not reproduce inputs", tc.old, tc.new)
+		}
+		added, removed := DiffStats(lines)
+		if added != tc.added || removed != tc.removed {
+			t.Errorf("diff of %q -> %q: got +%d -%d, want +%d -%d", tc.old, tc.new, added, removed, tc.added, tc.removed)
+		}
+	}
+}
+
+func TestDiffLinesNumbers(t *testing.T) {
+	lines := DiffLines("a\nb\nc", "a\nc\nd")
+	for _, l := range lines {
+		switch l.Op {
+		case DiffEqual:
+			if l.OldLine == 0 || l.NewLine == 0 {
+				t.Errorf("equal line %q should have both line numbers", l.Text)
+			}
+		case DiffDelete:
+			if l.OldLine == 0 || l.NewLine != 0 {
+				t.Errorf("deleted line %q has wrong line numbers", l.Text)
+			}
+		case DiffInsert:
+			if l.NewLine == 0 || l.OldLine != 0 {
+				t.Errorf("inserted line %q has wrong line numbers", l.Text)
+			}
+		}
+	}
+}
@@ -14,22 +14,58 @@ const (
 	ERROR_TOKEN_TYPE_WRONG   = 1007
 	ERROR_USER_NO_RIGHT      = 1008
 	ERROR_USER_WITH_WRONG_ID = 1009
+	ERROR_TOKEN_REVOKED      = 1010
+	ERROR_REFRESH_WRONG      = 1011
+	ERROR_TOTP_REQUIRED      = 1012
+	ERROR_TOTP_WRONG         = 1013
+	ERROR_TOTP_CHALLENGE     = 1014
+	ERROR_TOTP_NOT_SETUP     = 1015
+	ERROR_TOTP_ENABLED       = 1016
+	ERROR_PASSWORD_WEAK      = 1017
+	ERROR_PASSWORD_CHANGE    = 1018
+	ERROR_PASSWORD_REUSED    = 1019
 	// 文章模块的错误
-	ERROR_ART_NOT_EXIST  = 2001
-	ERROR_ART_TITLE_USED = 2002
+	ERROR_ART_NOT_EXIST    = 2001
+	ERROR_ART_TITLE_USED   = 2002
+	ERROR_ART_STATUS_WRONG = 2003
+	ERROR_ART_PUBLISH_TIME = 2004
+	ERROR_ART_SLUG_USED    = 2005
+	// 文章修订的错误
+	ERROR_REVISION_NOT_EXIST = 2101
+	// 文章搜索的错误
+	ERROR_SEARCH_NO_INDEX = 2201
 	// 分类模块的错误
 	ERROR_CATENAME_USED     = 3001
 	ERROR_CATE_NOT_EXIST    = 3002
 	ERROR_CATE_HAS_ARTICLES = 3003
+	ERROR_CATE_SLUG_USED    = 3004
 
 	// 标签模块的错误
 	ERROR_TAG_EXIST     = 4001
 	ERROR_TAG_NOT_EXIST = 4002
 	
 	// 上传模块的错误
-	ERROR_UPLOAD_BUSY    = 5001
-	ERROR_FILE_TOO_LARGE = 5002
-	ERROR_ZIP_CORRUPTED  = 5003
+	ERROR_UPLOAD_BUSY           = 5001
+	ERROR_FILE_TOO_LARGE        = 5002
+	ERROR_ZIP_CORRUPTED         = 5003
+	ERROR_UPLOAD_TASK_NOT_EXIST = 5004
+	ERROR_CHUNK_NOT_EXIST       = 5005
+	ERROR_CHUNK_OFFSET          = 5006
+	ERROR_CHECKSUM_MISMATCH     = 5007
+	ERROR_UPLOAD_INCOMPLETE     = 5008
+	// 评论模块的错误
+	ERROR_COMMENT_NOT_EXIST    = 6001
+	ERROR_COMMENT_CLOSED       = 6002
+	ERROR_COMMENT_TOO_LONG     = 6003
+	ERROR_COMMENT_PARENT_WRONG = 6004
+	ERROR_COMMENT_STATUS_WRONG = 6005
+	// 备份模块的错误
+	ERROR_BACKUP_BUSY      = 7001
+	ERROR_BACKUP_INVALID   = 7002
+	ERROR_BACKUP_NOT_EXIST = 7003
+	// 文件管理模块的错误
+	ERROR_RECYCLE_NOT_EXIST = 8001
+	ERROR_RESTORE_CONFLICT  = 8002
 )
 
 var codeMsg = map[int]string{
@@ -44,19 +80,53 @@ var codeMsg = map[int]string{
 	ERROR_TOKEN_TYPE_WRONG:   "TOKEN格式错误,请重新登陆",
 	ERROR_USER_NO_RIGHT:      "该用户无权限",
 	ERROR_USER_WITH_WRONG_ID: "用户与ID不匹配",
+	ERROR_TOKEN_REVOKED:      "登录已失效,请重新登陆",
+	ERROR_REFRESH_WRONG:      "刷新令牌无效或已过期,请重新登陆",
+	ERROR_TOTP_REQUIRED:      "请输入两步验证码",
+	ERROR_TOTP_WRONG:         "验证码错误或已使用",
+	ERROR_TOTP_CHALLENGE:     "两步验证已超时,请重新登录",
+	ERROR_TOTP_NOT_SETUP:     "尚未设置两步验证",
+	ERROR_TOTP_ENABLED:       "两步验证已开启",
+	ERROR_PASSWORD_WEAK:      "密码不符合安全策略",
+	ERROR_PASSWORD_CHANGE:    "请先修改初始密码",
+	ERROR_PASSWORD_REUSED:    "新密码不能与原密码相同",
 	ERROR_ART_NOT_EXIST:      "文章不存在",
 	ERROR_ART_TITLE_USED:     "文章标题已存在",
+	ERROR_ART_STATUS_WRONG:   "文章状态不合法",
+	ERROR_ART_PUBLISH_TIME:   "预约发布时间无效",
+	ERROR_ART_SLUG_USED:      "文章别名已被占用",
+	ERROR_REVISION_NOT_EXIST: "文章修订版本不存在",
+	ERROR_SEARCH_NO_INDEX:    "全文索引不可用",
 
 	ERROR_CATENAME_USED:     "该分类已存在",
 	ERROR_CATE_NOT_EXIST:    "该分类不存在",
 	ERROR_CATE_HAS_ARTICLES: "该分类下还有文章，无法删除",
+	ERROR_CATE_SLUG_USED:    "分类别名已被占用",
 
 	ERROR_TAG_EXIST:     "标签已存在",
 	ERROR_TAG_NOT_EXIST: "标签不存在",
 	
-	ERROR_UPLOAD_BUSY:    "上传任务繁忙，请稍后再试",
-	ERROR_FILE_TOO_LARGE: "文件过大，超过限制",
-	ERROR_ZIP_CORRUPTED:  "ZIP文件损坏或格式错误",
+	ERROR_UPLOAD_BUSY:           "上传任务繁忙，请稍后再试",
+	ERROR_FILE_TOO_LARGE:        "文件过大，超过限制",
+	ERROR_ZIP_CORRUPTED:         "ZIP文件损坏或格式错误",
+	ERROR_UPLOAD_TASK_NOT_EXIST: "上传任务不存在",
+	ERROR_CHUNK_NOT_EXIST:       "上传不存在或已过期",
+	ERROR_CHUNK_OFFSET:          "上传偏移量不匹配",
+	ERROR_CHECKSUM_MISMATCH:     "文件校验失败",
+	ERROR_UPLOAD_INCOMPLETE:     "文件尚未上传完成",
+
+	ERROR_COMMENT_NOT_EXIST:    "评论不存在",
+	ERROR_COMMENT_CLOSED:       "该文章暂不允许评论",
+	ERROR_COMMENT_TOO_LONG:     "评论内容过长",
+	ERROR_COMMENT_PARENT_WRONG: "回复的评论不存在",
+	ERROR_COMMENT_STATUS_WRONG: "评论状态不合法",
+
+	ERROR_BACKUP_BUSY:      "备份或恢复任务正在进行，请稍后再试",
+	ERROR_BACKUP_INVALID:   "备份文件无效或已损坏",
+	ERROR_BACKUP_NOT_EXIST: "待恢复的备份不存在或已过期",
+
+	ERROR_RECYCLE_NOT_EXIST: "回收站中不存在该项目",
+	ERROR_RESTORE_CONFLICT:  "原位置已存在同名文件",
 }
 
 // 获取codeMsg
@@ -0,0 +1,335 @@
+// Package imaging 上传图片的处理：解码、按 EXIF 方向旋转、去除元数据、缩放和编码（纯 Go 实现）
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"image"
+	"image/draw"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+	_ "golang.org/x/image/bmp"
+	xdraw "golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// 图片格式（与 image.Decode 返回的格式名一致）
+const (
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+	FormatWebP = "webp"
+	FormatBMP  = "bmp"
+)
+
+// MaxPixels 允许处理的最大像素数，超过时拒绝解码，防止超大尺寸图片耗尽内存
+const MaxPixels = 50_000_000
+
+// ErrTooLarge 图片尺寸超过 MaxPixels
+var ErrTooLarge = errors.New("图片尺寸过大")
+
+// supportedExts 可以生成缩略图和不同尺寸的图片扩展名（GIF 可能是动图，SVG、ICO 不处理）
+var supportedExts = map[string]bool{
+	".jpg": true, ".jpeg": true, ".png": true, ".webp": true, ".bmp": true,
+}
+
+// IsSupported 扩展名对应的图片能否处理
+func IsSupported(ext string) bool {
+	return supportedExts[strings.ToLower(ext)]
+}
+
+// Ext 格式对应的文件扩展名
+func Ext(format string) string {
+	if format == FormatJPEG {
+		return ".jpg"
+	}
+	return "." + format
+}
+
+// Bounds 读取图片按正常方向显示时的宽高和格式（只解析文件头，不解码像素）
+func Bounds(data []byte) (width int, height int, format string, err error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, "", err
+	}
+	if format == FormatJPEG && Orientation(data) >= 5 {
+		return cfg.Height, cfg.Width, format, nil
+	}
+	return cfg.Width, cfg.Height, format, nil
+}
+
+// Decode 解码图片，JPEG 按 EXIF 方向旋转为正常显示的方向
+func Decode(data []byte) (image.Image, string, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	if cfg.Width*cfg.Height > MaxPixels {
+		return nil, format, ErrTooLarge
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, format, err
+	}
+	if format == FormatJPEG {
+		img = ApplyOrientation(img, Orientation(data))
+	}
+	return img, format, nil
+}
+
+// Encode 按格式编码图片，quality 只对 JPEG 有效
+func Encode(w io.Writer, img image.Image, format string, quality int) error {
+	switch format {
+	case FormatJPEG:
+		return jpeg.Encode(w, img, &jpeg.Options{Quality: quality})
+	case FormatPNG:
+		enc := png.Encoder{CompressionLevel: png.BestCompression}
+		return enc.Encode(w, img)
+	case FormatWebP:
+		// 无损 WebP（纯 Go 编码器不支持有损压缩）
+		return nativewebp.Encode(w, img, nil)
+	}
+	return errors.New("不支持的图片格式: " + format)
+}
+
+// VariantFormat 生成缩放图片使用的格式：JPEG 保持 JPEG，不透明的 WebP、BMP 转为 JPEG，其余使用 PNG
+func VariantFormat(format string, img image.Image) string {
+	if format == FormatJPEG {
+		return FormatJPEG
+	}
+	if (format == FormatWebP || format == FormatBMP) && isOpaque(img) {
+		return FormatJPEG
+	}
+	return FormatPNG
+}
+
+func isOpaque(img image.Image) bool {
+	if o, ok := img.(interface{ Opaque() bool }); ok {
+		return o.Opaque()
+	}
+	return false
+}
+
+// Resize 按宽度等比缩放，宽度不小于原图时返回原图
+func Resize(img image.Image, width int) image.Image {
+	b := img.Bounds()
+	if width <= 0 || width >= b.Dx() {
+		return img
+	}
+	height := (b.Dy()*width + b.Dx()/2) / b.Dx()
+	if height < 1 {
+		height = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Src, nil)
+	return dst
+}
+
+// Thumbnail 从中间裁剪为正方形后缩放到 size × size（原图较小时不放大）
+func Thumbnail(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	side := min(b.Dx(), b.Dy())
+	crop := image.Rect(0, 0, side, side).Add(b.Min).Add(image.Pt((b.Dx()-side)/2, (b.Dy()-side)/2))
+	if size > side {
+		size = side
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, crop, draw.Src, nil)
+	return dst
+}
+
+// Orientation 读取 JPEG 中 EXIF 记录的方向（1-8），没有或无法解析时返回 1
+func Orientation(data []byte) int {
+	exif := jpegExif(data)
+	if len(exif) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder
+	switch string(exif[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+	ifd := int(order.Uint32(exif[4:8]))
+	if ifd < 8 || ifd+2 > len(exif) {
+		return 1
+	}
+	count := int(order.Uint16(exif[ifd:]))
+	for i := 0; i < count; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(exif) {
+			break
+		}
+		// 0x0112 Orientation，类型 SHORT
+		if order.Uint16(exif[entry:]) == 0x0112 && order.Uint16(exif[entry+2:]) == 3 {
+			if o := int(order.Uint16(exif[entry+8:])); o >= 1 && o <= 8 {
+				return o
+			}
+			return 1
+		}
+	}
+	return 1
+}
+
+// jpegExif 返回 JPEG 中 EXIF 段的 TIFF 数据（不含 "Exif\0\0" 头）
+func jpegExif(data []byte) []byte {
+	var exif []byte
+	walkJPEGSegments(data, func(marker byte, seg []byte) bool {
+		if marker == 0xE1 && bytes.HasPrefix(seg[4:], []byte("Exif\x00\x00")) {
+			exif = seg[10:]
+			return false
+		}
+		return true
+	})
+	return exif
+}
+
+// walkJPEGSegments 遍历 JPEG 在图像数据（SOS）之前的标记段，seg 包含标记和长度字段
+// fn 返回 false 时停止遍历；返回图像数据开始的位置，格式错误时返回 -1
+func walkJPEGSegments(data []byte, fn func(marker byte, seg []byte) bool) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return -1
+	}
+	pos := 2
+	for pos+4 <= len(data) {
+		if data[pos] != 0xFF {
+			return -1
+		}
+		marker := data[pos+1]
+		if marker == 0xFF { // 填充字节
+			pos++
+			continue
+		}
+		if marker == 0xDA {
+			return pos
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return -1
+		}
+		if !fn(marker, data[pos:end]) {
+			return pos
+		}
+		pos = end
+	}
+	return -1
+}
+
+// ApplyOrientation 按 EXIF 方向旋转或翻转图片，使其以正常方向显示
+func ApplyOrientation(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	b := img.Bounds()
+	src := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(src, src.Bounds(), img, b.Min, draw.Src)
+
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // 水平翻转
+				dx, dy = w-1-x, y
+			case 3: // 旋转 180°
+				dx, dy = w-1-x, h-1-y
+			case 4: // 垂直翻转
+				dx, dy = x, h-1-y
+			case 5: // 沿左上-右下对角线翻转
+				dx, dy = y, x
+			case 6: // 顺时针旋转 90°
+				dx, dy = h-1-y, x
+			case 7: // 沿右上-左下对角线翻转
+				dx, dy = h-1-y, w-1-x
+			case 8: // 逆时针旋转 90°
+				dx, dy = y, w-1-x
+			}
+			copy(dst.Pix[dst.PixOffset(dx, dy):dst.PixOffset(dx, dy)+4], src.Pix[src.PixOffset(x, y):src.PixOffset(x, y)+4])
+		}
+	}
+	return dst
+}
+
+// StripMetadata 去除 JPEG、PNG 中的 EXIF（含 GPS 位置、相机信息）、XMP 和文本等元数据，保留 ICC 颜色配置
+// JPEG 的 EXIF 方向不是 1 时去除后方向会丢失，因此按方向旋转后重新编码
+// 其他格式原样返回
+func StripMetadata(data []byte, format string, quality int) ([]byte, error) {
+	switch format {
+	case FormatJPEG:
+		if Orientation(data) > 1 {
+			img, _, err := Decode(data)
+			if err != nil {
+				return nil, err
+			}
+			var buf bytes.Buffer
+			if err := Encode(&buf, img, FormatJPEG, quality); err != nil {
+				return nil, err
+			}
+			return buf.Bytes(), nil
+		}
+		return stripJPEG(data)
+	case FormatPNG:
+		return stripPNG(data)
+	}
+	return data, nil
+}
+
+// stripJPEG 去除 APP1（EXIF、XMP）、APP13（Photoshop IPTC）和注释段，图像数据原样保留
+func stripJPEG(data []byte) ([]byte, error) {
+	out := make([]byte, 0, len(data))
+	out = append(out, 0xFF, 0xD8)
+	start := walkJPEGSegments(data, func(marker byte, seg []byte) bool {
+		if marker != 0xE1 && marker != 0xED && marker != 0xFE {
+			out = append(out, seg...)
+		}
+		return true
+	})
+	if start < 0 {
+		return nil, errors.New("JPEG 格式错误")
+	}
+	return append(out, data[start:]...), nil
+}
+
+// pngMetadataChunks 需要去除的 PNG 数据块
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true, "tEXt": true, "zTXt": true, "iTXt": true, "tIME": true,
+}
+
+// stripPNG 去除 EXIF、文本和时间数据块
+func stripPNG(data []byte) ([]byte, error) {
+	const signature = "\x89PNG\r\n\x1a\n"
+	if !bytes.HasPrefix(data, []byte(signature)) {
+		return nil, errors.New("PNG 格式错误")
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, signature...)
+	pos := len(signature)
+	for pos < len(data) {
+		if pos+8 > len(data) {
+			return nil, errors.New("PNG 格式错误")
+		}
+		end := pos + 12 + int(binary.BigEndian.Uint32(data[pos:]))
+		if end > len(data) || end < pos {
+			return nil, errors.New("PNG 格式错误")
+		}
+		if !pngMetadataChunks[string(data[pos+4:pos+8])] {
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+	}
+	return out, nil
+}
@@ -0,0 +1,197 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// withExif 在 JPEG 的 SOI 之后插入只包含方向的 EXIF 段
+func withExif(t *testing.T, data []byte, orientation uint16) []byte {
+	t.Helper()
+	tiff := []byte("II*\x00\x08\x00\x00\x00")
+	entry := make([]byte, 2+12+4)
+	binary.LittleEndian.PutUint16(entry[0:], 1)
+	binary.LittleEndian.PutUint16(entry[2:], 0x0112)
+	binary.LittleEndian.PutUint16(entry[4:], 3)
+	binary.LittleEndian.PutUint32(entry[6:], 1)
+	binary.LittleEndian.PutUint16(entry[10:], orientation)
+	payload := append([]byte("Exif\x00\x00"), append(tiff, entry...)...)
+
+	seg := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(seg[2:], uint16(len(payload)+2))
+	seg = append(seg, payload...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, seg...)
+	return append(out, data[2:]...)
+}
+
+func encodeJPEG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.RGBA{uint8(x * 10), uint8(y * 10), 100, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestOrientation(t *testing.T) {
+	data := encodeJPEG(t, 8, 4)
+	if got := Orientation(data); got != 1 {
+		t.Errorf("Orientation(无 EXIF) = %d, want 1", got)
+	}
+	if got := Orientation(withExif(t, data, 6)); got != 6 {
+		t.Errorf("Orientation = %d, want 6", got)
+	}
+	if got := Orientation([]byte("not a jpeg")); got != 1 {
+		t.Errorf("Orientation(非 JPEG) = %d, want 1", got)
+	}
+}
+
+func TestDecodeAppliesOrientation(t *testing.T) {
+	data := withExif(t, encodeJPEG(t, 8, 4), 6)
+	img, format, err := Decode(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if format != FormatJPEG {
+		t.Errorf("format = %q", format)
+	}
+	if b := img.Bounds(); b.Dx() != 4 || b.Dy() != 8 {
+		t.Errorf("旋转后尺寸 = %dx%d, want 4x8", b.Dx(), b.Dy())
+	}
+	w, h, _, err := Bounds(data)
+	if err != nil || w != 4 || h != 8 {
+		t.Errorf("Bounds = %dx%d (%v), want 4x8", w, h, err)
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	// 2x1：左红右蓝
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	red := color.NRGBA{255, 0, 0, 255}
+	blue := color.NRGBA{0, 0, 255, 255}
+	src.Set(0, 0, red)
+	src.Set(1, 0, blue)
+
+	tests := []struct {
+		orientation int
+		w, h        int
+		first       color.NRGBA // 左上角像素
+	}{
+		{1, 2, 1, red},
+		{2, 2, 1, blue},
+		{3, 2, 1, blue},
+		{6, 1, 2, red},
+		{8, 1, 2, blue},
+	}
+	for _, tt := range tests {
+		out := ApplyOrientation(src, tt.orientation)
+		b := out.Bounds()
+		if b.Dx() != tt.w || b.Dy() != tt.h {
+			t.Errorf("orientation %d: 尺寸 %dx%d, want %dx%d", tt.orientation, b.Dx(), b.Dy(), tt.w, tt.h)
+			continue
+		}
+		if got := color.NRGBAModel.Convert(out.At(b.Min.X, b.Min.Y)); got != tt.first {
+			t.Errorf("orientation %d: 左上角 = %v, want %v", tt.orientation, got, tt.first)
+		}
+	}
+}
+
+func TestStripJPEG(t *testing.T) {
+	plain := encodeJPEG(t, 8, 4)
+	// 方向为 1 时只去除 EXIF 段，图像数据不变
+	data := withExif(t, plain, 1)
+	out, err := StripMetadata(data, FormatJPEG, 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, plain) {
+		t.Errorf("去除 EXIF 后与原始 JPEG 不同（%d / %d 字节）", len(out), len(plain))
+	}
+
+	// 方向不为 1 时按方向旋转后重新编码
+	out, err = StripMetadata(withExif(t, plain, 6), FormatJPEG, 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(out, []byte("Exif\x00\x00")) {
+		t.Error("重新编码后仍包含 EXIF")
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(out))
+	if err != nil || cfg.Width != 4 || cfg.Height != 8 {
+		t.Errorf("重新编码后尺寸 %dx%d (%v), want 4x8", cfg.Width, cfg.Height, err)
+	}
+}
+
+func TestStripPNG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	plain := buf.Bytes()
+
+	// 在 IHDR 之后插入 tEXt 块（CRC 不参与去除判断）
+	text := []byte("Comment\x00secret")
+	chunk := make([]byte, 8, 12+len(text))
+	binary.BigEndian.PutUint32(chunk, uint32(len(text)))
+	copy(chunk[4:], "tEXt")
+	chunk = append(chunk, text...)
+	chunk = append(chunk, 0, 0, 0, 0)
+	ihdrEnd := 8 + 12 + 13
+	data := append(append(append([]byte{}, plain[:ihdrEnd]...), chunk...), plain[ihdrEnd:]...)
+
+	out, err := StripMetadata(data, FormatPNG, 80)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, plain) {
+		t.Error("去除 tEXt 后与原始 PNG 不同")
+	}
+	if _, err := StripMetadata([]byte("broken"), FormatPNG, 80); err == nil {
+		t.Error("格式错误的 PNG 应返回错误")
+	}
+}
+
+func TestResizeAndThumbnail(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 400, 300))
+
+	if b := Resize(img, 200).Bounds(); b.Dx() != 200 || b.Dy() != 150 {
+		t.Errorf("Resize(200) = %dx%d, want 200x150", b.Dx(), b.Dy())
+	}
+	if out := Resize(img, 800); out != image.Image(img) {
+		t.Error("宽度不小于原图时应返回原图")
+	}
+	if b := Thumbnail(img, 100).Bounds(); b.Dx() != 100 || b.Dy() != 100 {
+		t.Errorf("Thumbnail(100) = %dx%d, want 100x100", b.Dx(), b.Dy())
+	}
+	if b := Thumbnail(img, 500).Bounds(); b.Dx() != 300 || b.Dy() != 300 {
+		t.Errorf("Thumbnail(500) = %dx%d, 原图较小时不应放大", b.Dx(), b.Dy())
+	}
+}
+
+func TestEncodeWebP(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	var buf bytes.Buffer
+	if err := Encode(&buf, img, FormatWebP, 80); err != nil {
+		t.Fatal(err)
+	}
+	decoded, format, err := Decode(buf.Bytes())
+	if err != nil || format != FormatWebP {
+		t.Fatalf("解码 WebP 失败: %v (%s)", err, format)
+	}
+	if b := decoded.Bounds(); b.Dx() != 16 || b.Dy() != 16 {
+		t.Errorf("WebP 尺寸 %dx%d", b.Dx(), b.Dy())
+	}
+}
@@ -0,0 +1,554 @@
+// Package importer 解析其他博客系统的文章：Hexo / Hugo / Jekyll 源码目录和 WordPress WXR 导出文件
+// 只负责识别目录结构、解析 Front Matter 和改写正文中的文件引用，文章入库和文件上传由调用方完成
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// 支持的博客类型
+const (
+	FormatHexo      = "hexo"
+	FormatHugo      = "hugo"
+	FormatJekyll    = "jekyll"
+	FormatWordPress = "wordpress"
+)
+
+// 文章状态，与 model 中的文章状态取值一致
+const (
+	StatusPublished = "published"
+	StatusDraft     = "draft"
+	StatusScheduled = "scheduled"
+)
+
+// IsValidFormat 判断博客类型是否受支持
+func IsValidFormat(format string) bool {
+	switch format {
+	case FormatHexo, FormatHugo, FormatJekyll, FormatWordPress:
+		return true
+	}
+	return false
+}
+
+// Post 解析出的文章
+type Post struct {
+	Source    string    // 来源：源码目录中的文件路径或 WXR 中的条目
+	Title     string    // 标题
+	Slug      string    // 原 URL 别名，未指定时取文件名
+	Date      time.Time // 发布时间，零值表示未指定
+	Category  string    // 分类（多个分类或多级分类时取第一个）
+	Tags      []string  // 标签
+	Desc      string    // 摘要
+	Cover     string    // 封面图引用
+	Status    string    // 文章状态
+	Content   string    // 正文（WordPress 为 HTML）
+	AssetDirs []string  // 解析正文中相对路径引用的目录（相对于站点根目录），按优先级排列
+}
+
+// SourceFile 源码目录中的文章文件
+type SourceFile struct {
+	Path  string // 相对于站点根目录的路径，使用 / 分隔
+	Draft bool   // 位于草稿目录（_drafts）
+}
+
+// skipDirs 识别和解压源码目录时跳过的目录（依赖、版本库和生成的站点）
+var skipDirs = []string{"node_modules", ".git", "public", "resources", "_site", ".deploy_git"}
+
+// Skip 判断源码目录中的文件是否可以跳过
+func Skip(p string) bool {
+	for _, part := range strings.Split(p, "/") {
+		for _, dir := range skipDirs {
+			if part == dir {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// hugoConfigFiles Hugo 站点根目录下的配置文件
+var hugoConfigFiles = []string{"hugo.toml", "hugo.yaml", "hugo.yml", "hugo.json", "config.toml", "config.yaml", "config.yml", "config.json"}
+
+// Detect 根据文件列表识别博客类型和站点根目录
+// 参数: paths - 归档内的文件路径（使用 / 分隔）
+// 返回: 博客类型、站点根目录（"" 表示归档根目录，否则以 / 结尾）和是否识别成功
+func Detect(paths []string) (string, string, bool) {
+	files := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		files[p] = true
+	}
+
+	type candidate struct{ format, root string }
+	var found []candidate
+	for _, p := range paths {
+		if Skip(p) || !isMarkdown(p) {
+			continue
+		}
+		if root, ok := cutDir(p, "source/_posts/"); ok {
+			found = append(found, candidate{FormatHexo, root})
+		} else if root, ok := cutDir(p, "source/_drafts/"); ok {
+			found = append(found, candidate{FormatHexo, root})
+		} else if root, ok := cutDir(p, "_posts/"); ok {
+			found = append(found, candidate{FormatJekyll, root})
+		} else if root, ok := cutDir(p, "content/"); ok && hasHugoConfig(files, root) {
+			found = append(found, candidate{FormatHugo, root})
+		}
+	}
+	if len(found) == 0 {
+		return "", "", false
+	}
+	// 多个候选时取层级最浅的站点（如主题自带的示例站点位于更深的目录）
+	sort.SliceStable(found, func(i, j int) bool {
+		return strings.Count(found[i].root, "/") < strings.Count(found[j].root, "/")
+	})
+	return found[0].format, found[0].root, true
+}
+
+// cutDir 如果路径中包含目录 dir，返回 dir 之前的部分
+func cutDir(p string, dir string) (string, bool) {
+	if strings.HasPrefix(p, dir) {
+		return "", true
+	}
+	if i := strings.Index(p, "/"+dir); i >= 0 {
+		return p[:i+1], true
+	}
+	return "", false
+}
+
+func hasHugoConfig(files map[string]bool, root string) bool {
+	for _, name := range hugoConfigFiles {
+		if files[root+name] {
+			return true
+		}
+	}
+	for p := range files {
+		if strings.HasPrefix(p, root+"config/_default/") {
+			return true
+		}
+	}
+	return false
+}
+
+func isMarkdown(p string) bool {
+	ext := strings.ToLower(path.Ext(p))
+	return ext == ".md" || ext == ".markdown"
+}
+
+// postDirs 各博客类型存放文章的目录，第二个返回值为草稿目录
+func postDirs(format string) ([]string, []string) {
+	switch format {
+	case FormatHexo:
+		return []string{"source/_posts/"}, []string{"source/_drafts/"}
+	case FormatJekyll:
+		return []string{"_posts/"}, []string{"_drafts/"}
+	case FormatHugo:
+		return []string{"content/"}, nil
+	}
+	return nil, nil
+}
+
+// PostFiles 列出站点中的文章文件
+// 参数: format - 博客类型, root - Detect 返回的站点根目录, paths - 归档内的文件路径
+func PostFiles(format string, root string, paths []string) []SourceFile {
+	dirs, drafts := postDirs(format)
+	var files []SourceFile
+	for _, p := range paths {
+		if !strings.HasPrefix(p, root) || !isMarkdown(p) {
+			continue
+		}
+		rel := strings.TrimPrefix(p, root)
+		if Skip(rel) {
+			continue
+		}
+		// Hugo 的 _index.md 是栏目列表页，不是文章
+		if format == FormatHugo && strings.HasPrefix(path.Base(rel), "_index.") {
+			continue
+		}
+		for _, dir := range dirs {
+			if strings.HasPrefix(rel, dir) {
+				files = append(files, SourceFile{Path: rel})
+			}
+		}
+		for _, dir := range drafts {
+			if strings.HasPrefix(rel, dir) {
+				files = append(files, SourceFile{Path: rel, Draft: true})
+			}
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files
+}
+
+// StaticDirs 站点绝对路径（如 /images/a.png）对应的源码目录（相对于站点根目录），按优先级排列
+func StaticDirs(format string) []string {
+	switch format {
+	case FormatHexo:
+		return []string{"source/"}
+	case FormatHugo:
+		return []string{"static/", "assets/"}
+	case FormatJekyll:
+		return []string{""}
+	case FormatWordPress:
+		// WXR 一起打包的 wp-content/uploads 目录
+		return []string{""}
+	}
+	return nil
+}
+
+// jekyllDateName Jekyll 文章文件名中的日期前缀，如 2020-01-02-hello.md
+var jekyllDateName = regexp.MustCompile(`^(\d{4}-\d{1,2}-\d{1,2})-(.+)$`)
+
+// ParsePost 解析源码目录中的 Markdown 文章（支持 YAML、TOML 和 JSON 格式的 Front Matter）
+func ParsePost(format string, file SourceFile, data []byte) (*Post, error) {
+	fm, body, err := splitFrontMatter(data)
+	if err != nil {
+		return nil, err
+	}
+
+	dir := path.Dir(file.Path)
+	name := strings.TrimSuffix(path.Base(file.Path), path.Ext(file.Path))
+	post := &Post{
+		Source:    file.Path,
+		Content:   body,
+		AssetDirs: []string{dir},
+		Status:    StatusPublished,
+	}
+
+	// 文件名作为默认的标题和别名
+	var nameDate time.Time
+	if format == FormatJekyll {
+		if m := jekyllDateName.FindStringSubmatch(name); m != nil {
+			nameDate, _ = parseTime(m[1])
+			name = m[2]
+		}
+	}
+	if format == FormatHugo && name == "index" {
+		name = path.Base(dir) // Page Bundle: content/posts/hello/index.md
+	}
+	if format == FormatHexo {
+		// post_asset_folder 开启时，文章资源放在与文件同名的目录中
+		post.AssetDirs = append(post.AssetDirs, path.Join(dir, name))
+	}
+
+	post.Title = firstString(fm, "title")
+	if post.Title == "" {
+		post.Title = name
+	}
+	post.Slug = firstString(fm, "slug")
+	if post.Slug == "" {
+		post.Slug = name
+	}
+	post.Date = firstTime(fm, "date", "publishdate", "pubdate")
+	if post.Date.IsZero() {
+		post.Date = nameDate
+	}
+	if cates := stringList(fm["categories"], false); len(cates) > 0 {
+		post.Category = cates[0]
+	} else {
+		post.Category = firstString(fm, "category")
+	}
+	post.Tags = stringList(fm["tags"], format == FormatJekyll)
+	post.Desc = firstString(fm, "description", "desc", "summary", "excerpt")
+	post.Cover = coverOf(fm)
+
+	if file.Draft || isTrue(fm["draft"]) || isFalse(fm["published"]) {
+		post.Status = StatusDraft
+	}
+
+	switch format {
+	case FormatHexo:
+		post.Content = convertHexoTags(post.Content)
+	case FormatJekyll:
+		post.Content = convertJekyllURLs(post.Content)
+		post.Cover = convertJekyllURLs(post.Cover)
+	}
+	return post, nil
+}
+
+// splitFrontMatter 拆分 Front Matter 和正文，Front Matter 的键统一转为小写
+func splitFrontMatter(data []byte) (map[string]interface{}, string, error) {
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	fm := make(map[string]interface{})
+
+	var delim string
+	switch {
+	case strings.HasPrefix(text, "---\n"):
+		delim = "---"
+	case strings.HasPrefix(text, "+++\n"):
+		delim = "+++"
+	case strings.HasPrefix(text, "{"):
+		// JSON Front Matter：开头的 JSON 对象，之后为正文
+		dec := json.NewDecoder(strings.NewReader(text))
+		if err := dec.Decode(&fm); err != nil {
+			return nil, "", fmt.Errorf("Front Matter 格式错误: %w", err)
+		}
+		return lowerKeys(fm), strings.TrimLeft(text[dec.InputOffset():], "\n"), nil
+	default:
+		return fm, text, nil
+	}
+
+	rest := text[len(delim)+1:]
+	end := -1
+	if strings.HasPrefix(rest, delim+"\n") || rest == delim {
+		end = 0
+	} else if i := strings.Index(rest, "\n"+delim+"\n"); i >= 0 {
+		end = i + 1
+	} else if strings.HasSuffix(rest, "\n"+delim) {
+		end = len(rest) - len(delim)
+	}
+	if end < 0 {
+		return fm, text, nil // 没有结束标记，按正文处理
+	}
+	head := rest[:end]
+	body := strings.TrimPrefix(rest[end+len(delim):], "\n")
+
+	var err error
+	if delim == "+++" {
+		err = toml.Unmarshal([]byte(head), &fm)
+	} else {
+		err = yaml.Unmarshal([]byte(head), &fm)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("Front Matter 格式错误: %w", err)
+	}
+	return lowerKeys(fm), strings.TrimLeft(body, "\n"), nil
+}
+
+func lowerKeys(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[strings.ToLower(k)] = v
+	}
+	return out
+}
+
+// firstString 返回第一个非空的字符串字段
+func firstString(fm map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if s := strings.TrimSpace(toString(fm[key])); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+// firstTime 返回第一个能解析为时间的字段
+func firstTime(fm map[string]interface{}, keys ...string) time.Time {
+	for _, key := range keys {
+		switch v := fm[key].(type) {
+		case nil:
+			continue
+		case time.Time:
+			return v
+		default:
+			if t, err := parseTime(toString(v)); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case []interface{}, map[string]interface{}:
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// stringList 将字符串或（嵌套）数组转为字符串列表
+// 字符串按逗号分隔；splitSpace 为 true 时（Jekyll）没有逗号的字符串按空格分隔
+func stringList(v interface{}, splitSpace bool) []string {
+	var list []string
+	switch v := v.(type) {
+	case string:
+		sep := func(r rune) bool { return r == ',' || r == '，' }
+		if splitSpace && !strings.ContainsAny(v, ",，") {
+			sep = func(r rune) bool { return r == ' ' || r == '\t' }
+		}
+		for _, s := range strings.FieldsFunc(v, sep) {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+	case []interface{}:
+		// Hexo 的多级分类写作嵌套数组，如 [[生活, 日记], 技术]
+		for _, item := range v {
+			list = append(list, stringList(item, false)...)
+		}
+	case nil:
+	default:
+		if s := toString(v); s != "" {
+			list = append(list, s)
+		}
+	}
+	return list
+}
+
+// coverOf 读取封面图：不同主题使用的字段不同，PaperMod 等主题写作 cover: {image: ...}
+func coverOf(fm map[string]interface{}) string {
+	if cover, ok := fm["cover"].(map[string]interface{}); ok {
+		return strings.TrimSpace(toString(cover["image"]))
+	}
+	if s := firstString(fm, "cover", "featured_image", "featuredimage", "feature_image", "image", "thumbnail", "banner"); s != "" {
+		return s
+	}
+	if images := stringList(fm["images"], false); len(images) > 0 {
+		return images[0]
+	}
+	return ""
+}
+
+func isTrue(v interface{}) bool {
+	switch v := v.(type) {
+	case bool:
+		return v
+	case string:
+		return strings.EqualFold(v, "true") || v == "yes"
+	}
+	return false
+}
+
+func isFalse(v interface{}) bool {
+	switch v := v.(type) {
+	case bool:
+		return !v
+	case string:
+		return strings.EqualFold(v, "false") || v == "no"
+	}
+	return false
+}
+
+// timeFormats 常见的日期格式，没有时区的按服务器本地时间解析
+var timeFormats = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05 -07:00",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+	"2006-1-2 15:04:05",
+	"2006-1-2",
+	"2006/01/02 15:04:05",
+	"2006/01/02 15:04",
+	"2006/01/02",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// parseTime 解析日期字符串
+func parseTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, f := range timeFormats {
+		if t, err := time.ParseInLocation(f, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New("无法解析日期: " + s)
+}
+
+// hexoTagRegex Hexo 文章资源标签: {% asset_img a.png 标题 %}、{% asset_link a.pdf 标题 %}、{% asset_path a.png %}
+var hexoTagRegex = regexp.MustCompile(`\{%\s*(asset_img|asset_link|asset_path)\s+(\S+)\s*(.*?)\s*%\}`)
+
+// convertHexoTags 将 Hexo 资源标签转为 Markdown 图片和链接
+func convertHexoTags(content string) string {
+	return hexoTagRegex.ReplaceAllStringFunc(content, func(m string) string {
+		sub := hexoTagRegex.FindStringSubmatch(m)
+		name, title := sub[2], strings.Trim(sub[3], `"'`)
+		switch sub[1] {
+		case "asset_img":
+			return "![" + title + "](" + name + ")"
+		case "asset_link":
+			if title == "" {
+				title = name
+			}
+			return "[" + title + "](" + name + ")"
+		}
+		return name
+	})
+}
+
+// jekyllURLRegex Jekyll 模板中的站点地址: {{ site.baseurl }}、{{ "/a.png" | relative_url }}
+var (
+	jekyllSiteURLRegex = regexp.MustCompile(`\{\{\s*site\.(url|baseurl)\s*\}\}`)
+	jekyllFilterRegex  = regexp.MustCompile(`\{\{\s*["']([^"']+)["']\s*\|\s*(relative_url|absolute_url)\s*\}\}`)
+)
+
+// convertJekyllURLs 去掉 Jekyll 模板中的站点地址，保留站内绝对路径
+func convertJekyllURLs(content string) string {
+	content = jekyllFilterRegex.ReplaceAllString(content, "$1")
+	return jekyllSiteURLRegex.ReplaceAllString(content, "")
+}
+
+// refRegex 正文中的文件引用：Markdown 图片和链接（可带标题）、HTML 的 src / href 属性（含 Hugo figure 短代码）
+var refRegex = regexp.MustCompile(`(!?\[[^\]]*\]\()(<[^>]*>|[^)\s]+)([^)]*\))|(\b(?:src|href)\s*=\s*)("[^"]*"|'[^']*')`)
+
+// RewriteRefs 改写正文中引用的地址：replace 返回新地址和是否替换，未替换的保持原样
+func RewriteRefs(content string, replace func(ref string) (string, bool)) string {
+	return refRegex.ReplaceAllStringFunc(content, func(m string) string {
+		sub := refRegex.FindStringSubmatch(m)
+		if sub[1] != "" {
+			ref := strings.TrimSuffix(strings.TrimPrefix(sub[2], "<"), ">")
+			if newRef, ok := replace(ref); ok {
+				return sub[1] + newRef + sub[3]
+			}
+			return m
+		}
+		quote := sub[5][:1]
+		if newRef, ok := replace(sub[5][1 : len(sub[5])-1]); ok {
+			return sub[4] + quote + newRef + quote
+		}
+		return m
+	})
+}
+
+// LocalRef 将正文中的引用转为源码目录中的路径
+// 参数: ref - 引用地址, wpUploads - 是否识别 WordPress 上传目录的完整地址
+// 返回: 清理后的路径、是否为站点绝对路径（以 / 开头）和是否为本地文件引用
+func LocalRef(ref string, wpUploads bool) (string, bool, bool) {
+	ref = strings.TrimSpace(ref)
+	if i := strings.IndexAny(ref, "?#"); i >= 0 {
+		ref = ref[:i]
+	}
+	if ref == "" {
+		return "", false, false
+	}
+	if wpUploads {
+		// https://example.com/wp-content/uploads/2020/01/a.png -> /wp-content/uploads/2020/01/a.png
+		if i := strings.Index(ref, "/wp-content/uploads/"); i >= 0 {
+			ref = ref[i:]
+		}
+	}
+	if strings.HasPrefix(ref, "//") || strings.Contains(ref, ":") || strings.HasPrefix(strings.ToLower(ref), "mailto") {
+		return "", false, false // 外链
+	}
+	if unescaped, err := url.PathUnescape(ref); err == nil {
+		ref = unescaped
+	}
+	absolute := strings.HasPrefix(ref, "/")
+	clean := path.Clean("/" + ref)[1:]
+	if clean == "" || isMarkdown(clean) {
+		return "", false, false
+	}
+	return clean, absolute, true
+}
@@ -0,0 +1,230 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDetect(t *testing.T) {
+	cases := []struct {
+		name   string
+		paths  []string
+		format string
+		root   string
+		ok     bool
+	}{
+		{"hexo", []string{"_config.yml", "source/_posts/a.md", "node_modules/x/source/_posts/b.md"}, FormatHexo, "", true},
+		{"hexo in folder", []string{"blog/source/_drafts/a.md", "blog/themes/t/source/_posts/demo.md"}, FormatHexo, "blog/", true},
+		{"jekyll", []string{"site/_config.yml", "site/_posts/2020-01-02-a.markdown"}, FormatJekyll, "site/", true},
+		{"hugo", []string{"hugo.toml", "content/posts/a.md"}, FormatHugo, "", true},
+		{"hugo config dir", []string{"config/_default/hugo.yaml", "content/a/index.md"}, FormatHugo, "", true},
+		{"content without hugo config", []string{"content/a.md"}, "", "", false},
+		{"single markdown", []string{"a.md"}, "", "", false},
+	}
+	for _, tc := range cases {
+		format, root, ok := Detect(tc.paths)
+		if format != tc.format || root != tc.root || ok != tc.ok {
+			t.Errorf("%s: got (%q, %q, %v), want (%q, %q, %v)", tc.name, format, root, ok, tc.format, tc.root, tc.ok)
+		}
+	}
+}
+
+func TestPostFiles(t *testing.T) {
+	paths := []string{
+		"content/_index.md",
+		"content/posts/_index.md",
+		"content/posts/a.md",
+		"content/posts/b/index.md",
+		"content/posts/b/cover.png",
+		"static/img/x.png",
+	}
+	got := PostFiles(FormatHugo, "", paths)
+	want := []SourceFile{{Path: "content/posts/a.md"}, {Path: "content/posts/b/index.md"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("hugo: got %+v", got)
+	}
+
+	got = PostFiles(FormatJekyll, "site/", []string{"site/_posts/2020-01-01-a.md", "site/_drafts/b.markdown", "site/about.md"})
+	want = []SourceFile{{Path: "_drafts/b.markdown", Draft: true}, {Path: "_posts/2020-01-01-a.md"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("jekyll: got %+v", got)
+	}
+}
+
+func TestParsePost(t *testing.T) {
+	hexo := "---\ntitle: 你好\ndate: 2021-03-04 05:06:07\ncategories:\n  - [生活, 日记]\n  - 技术\ntags: [Go, 博客]\ndescription: 摘要\n---\n\n正文 {% asset_img a.png 示意图 %}\n"
+	post, err := ParsePost(FormatHexo, SourceFile{Path: "source/_posts/hello.md"}, []byte(hexo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Title != "你好" || post.Slug != "hello" || post.Category != "生活" || post.Desc != "摘要" || post.Status != StatusPublished {
+		t.Errorf("hexo: unexpected post %+v", post)
+	}
+	if !reflect.DeepEqual(post.Tags, []string{"Go", "博客"}) {
+		t.Errorf("hexo: tags = %v", post.Tags)
+	}
+	if !post.Date.Equal(time.Date(2021, 3, 4, 5, 6, 7, 0, time.Local)) {
+		t.Errorf("hexo: date = %v", post.Date)
+	}
+	if post.Content != "正文 ![示意图](a.png)\n" {
+		t.Errorf("hexo: content = %q", post.Content)
+	}
+	if !reflect.DeepEqual(post.AssetDirs, []string{"source/_posts", "source/_posts/hello"}) {
+		t.Errorf("hexo: asset dirs = %v", post.AssetDirs)
+	}
+
+	hugo := "+++\ntitle = \"Hugo\"\ndate = 2022-01-02T03:04:05+08:00\ndraft = true\nslug = \"my-post\"\ncategories = [\"笔记\"]\ntags = [\"a\", \"b\"]\nfeatured_image = \"cover.jpg\"\n+++\nbody\n"
+	post, err = ParsePost(FormatHugo, SourceFile{Path: "content/posts/x/index.md"}, []byte(hugo))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Title != "Hugo" || post.Slug != "my-post" || post.Status != StatusDraft || post.Category != "笔记" || post.Cover != "cover.jpg" || post.Content != "body\n" {
+		t.Errorf("hugo: unexpected post %+v", post)
+	}
+	if post.Date.Unix() != time.Date(2022, 1, 1, 19, 4, 5, 0, time.UTC).Unix() {
+		t.Errorf("hugo: date = %v", post.Date)
+	}
+
+	jekyll := "---\nlayout: post\ntags: go web\ncategory: 开发\npublished: false\nimage: \"{{ site.baseurl }}/assets/c.png\"\n---\n![x]({{ \"/assets/a.png\" | relative_url }})\n"
+	post, err = ParsePost(FormatJekyll, SourceFile{Path: "_posts/2019-12-31-new-year.md"}, []byte(jekyll))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Title != "new-year" || post.Slug != "new-year" || post.Category != "开发" || post.Status != StatusDraft || post.Cover != "/assets/c.png" {
+		t.Errorf("jekyll: unexpected post %+v", post)
+	}
+	if !reflect.DeepEqual(post.Tags, []string{"go", "web"}) || !post.Date.Equal(time.Date(2019, 12, 31, 0, 0, 0, 0, time.Local)) {
+		t.Errorf("jekyll: tags = %v, date = %v", post.Tags, post.Date)
+	}
+	if post.Content != "![x](/assets/a.png)\n" {
+		t.Errorf("jekyll: content = %q", post.Content)
+	}
+
+	post, err = ParsePost(FormatHugo, SourceFile{Path: "content/b.md"}, []byte("{\n  \"Title\": \"JSON\",\n  \"cover\": {\"image\": \"/c.png\"}\n}\n\ntext"))
+	if err != nil || post.Title != "JSON" || post.Cover != "/c.png" || post.Content != "text" {
+		t.Errorf("json front matter: %+v, %v", post, err)
+	}
+
+	if _, err := ParsePost(FormatHexo, SourceFile{Path: "a.md"}, []byte("---\ntitle: [\n---\n")); err == nil {
+		t.Error("invalid front matter should fail")
+	}
+}
+
+func TestRewriteRefs(t *testing.T) {
+	content := `![a](img/a.png "t") [doc](<my file.pdf>) [ext](https://x.com/a.png) <img src="/b.png"> <a href='c.zip'>`
+	got := RewriteRefs(content, func(ref string) (string, bool) {
+		if strings.HasPrefix(ref, "https://") {
+			return "", false
+		}
+		return "/uploads/" + strings.TrimPrefix(ref, "/"), true
+	})
+	want := `![a](/uploads/img/a.png "t") [doc](/uploads/my file.pdf) [ext](https://x.com/a.png) <img src="/uploads/b.png"> <a href='/uploads/c.zip'>`
+	if got != want {
+		t.Errorf("got  %s\nwant %s", got, want)
+	}
+}
+
+func TestLocalRef(t *testing.T) {
+	cases := []struct {
+		ref       string
+		wp        bool
+		path      string
+		absolute  bool
+		localFile bool
+	}{
+		{"img/a.png", false, "img/a.png", false, true},
+		{"./img/a.png?v=1#x", false, "img/a.png", false, true},
+		{"/images/%E5%9B%BE.png", false, "images/图.png", true, true},
+		{"../../../etc/passwd", false, "etc/passwd", false, true},
+		{"https://example.com/wp-content/uploads/2020/01/a.png", true, "wp-content/uploads/2020/01/a.png", true, true},
+		{"https://example.com/a.png", true, "", false, false},
+		{"//cdn.com/a.png", false, "", false, false},
+		{"mailto:a@b.c", false, "", false, false},
+		{"other-post.md", false, "", false, false},
+		{"#anchor", false, "", false, false},
+	}
+	for _, tc := range cases {
+		p, absolute, ok := LocalRef(tc.ref, tc.wp)
+		if p != tc.path || absolute != tc.absolute || ok != tc.localFile {
+			t.Errorf("%q: got (%q, %v, %v)", tc.ref, p, absolute, ok)
+		}
+	}
+}
+
+func TestParseWXR(t *testing.T) {
+	wxr := `<?xml version="1.0" encoding="UTF-8" ?>
+<rss version="2.0" xmlns:excerpt="http://wordpress.org/export/1.2/excerpt/" xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:wp="http://wordpress.org/export/1.2/">
+<channel>
+	<item>
+		<title>封面</title>
+		<wp:post_id>10</wp:post_id>
+		<wp:post_type>attachment</wp:post_type>
+		<wp:attachment_url>https://blog.example.com/wp-content/uploads/2020/01/cover.jpg</wp:attachment_url>
+	</item>
+	<item>
+		<title>Hello &amp; World&nbsp;</title>
+		<content:encoded><![CDATA[<p>正文</p>]]></content:encoded>
+		<excerpt:encoded><![CDATA[摘要]]></excerpt:encoded>
+		<wp:post_id>1</wp:post_id>
+		<wp:post_date>2020-01-02 10:00:00</wp:post_date>
+		<wp:post_date_gmt>2020-01-02 02:00:00</wp:post_date_gmt>
+		<wp:post_name>%e4%bd%a0%e5%a5%bd</wp:post_name>
+		<wp:status>publish</wp:status>
+		<wp:post_type>post</wp:post_type>
+		<category domain="category" nicename="uncategorized"><![CDATA[Uncategorized]]></category>
+		<category domain="category" nicename="tech"><![CDATA[技术]]></category>
+		<category domain="post_tag" nicename="go"><![CDATA[Go]]></category>
+		<wp:postmeta><wp:meta_key>_thumbnail_id</wp:meta_key><wp:meta_value>10</wp:meta_value></wp:postmeta>
+	</item>
+	<item>
+		<title>草稿</title>
+		<wp:post_id>2</wp:post_id>
+		<wp:post_date>2021-05-06 07:08:09</wp:post_date>
+		<wp:post_date_gmt>0000-00-00 00:00:00</wp:post_date_gmt>
+		<wp:status>draft</wp:status>
+		<wp:post_type>post</wp:post_type>
+	</item>
+	<item>
+		<title>已删除</title>
+		<wp:status>trash</wp:status>
+		<wp:post_type>post</wp:post_type>
+	</item>
+	<item>
+		<title>关于</title>
+		<wp:status>publish</wp:status>
+		<wp:post_type>page</wp:post_type>
+	</item>
+</channel>
+</rss>`
+	if !IsWXR([]byte(wxr)) || IsWXR([]byte("<rss></rss>")) {
+		t.Fatal("IsWXR mismatch")
+	}
+	posts, skipped, err := ParseWXR(strings.NewReader(wxr))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(posts) != 2 || skipped != 1 {
+		t.Fatalf("got %d posts, %d skipped", len(posts), skipped)
+	}
+
+	p := posts[0]
+	if p.Title != "Hello & World " && p.Title != "Hello & World" {
+		t.Errorf("title = %q", p.Title)
+	}
+	if p.Slug != "你好" || p.Content != "<p>正文</p>" || p.Desc != "摘要" || p.Category != "技术" || p.Status != StatusPublished {
+		t.Errorf("unexpected post %+v", p)
+	}
+	if !reflect.DeepEqual(p.Tags, []string{"Go"}) || p.Cover != "https://blog.example.com/wp-content/uploads/2020/01/cover.jpg" {
+		t.Errorf("tags = %v, cover = %q", p.Tags, p.Cover)
+	}
+	if !p.Date.Equal(time.Date(2020, 1, 2, 2, 0, 0, 0, time.UTC)) {
+		t.Errorf("date = %v", p.Date)
+	}
+
+	draft := posts[1]
+	if draft.Status != StatusDraft || !draft.Date.Equal(time.Date(2021, 5, 6, 7, 8, 9, 0, time.Local)) {
+		t.Errorf("draft = %+v", draft)
+	}
+}
@@ -0,0 +1,162 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// WordPress 导出文件（WXR）是带有 wp / content / excerpt 命名空间扩展的 RSS
+// wp 命名空间的地址随导出版本变化（export/1.1/、export/1.2/），因此按本地名称匹配
+
+// contentNamespace 正文所在的 content:encoded 元素的命名空间，摘要 excerpt:encoded 的本地名称相同
+const contentNamespace = "http://purl.org/rss/1.0/modules/content/"
+
+type wxrRSS struct {
+	Channel struct {
+		Items []wxrItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+type wxrItem struct {
+	Title         string        `xml:"title"`
+	Link          string        `xml:"link"`
+	PubDate       string        `xml:"pubDate"`
+	Encoded       []wxrEncoded  `xml:"encoded"`
+	PostID        string        `xml:"post_id"`
+	PostDate      string        `xml:"post_date"`
+	PostDateGMT   string        `xml:"post_date_gmt"`
+	PostName      string        `xml:"post_name"`
+	Status        string        `xml:"status"`
+	PostType      string        `xml:"post_type"`
+	AttachmentURL string        `xml:"attachment_url"`
+	Categories    []wxrCategory `xml:"category"`
+	Meta          []wxrMeta     `xml:"postmeta"`
+}
+
+type wxrEncoded struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+type wxrCategory struct {
+	Domain string `xml:"domain,attr"`
+	Name   string `xml:",chardata"`
+}
+
+type wxrMeta struct {
+	Key   string `xml:"meta_key"`
+	Value string `xml:"meta_value"`
+}
+
+// IsWXR 判断文件是否为 WordPress 导出文件
+func IsWXR(data []byte) bool {
+	head := data
+	if len(head) > 4096 {
+		head = head[:4096]
+	}
+	return bytes.Contains(head, []byte("wordpress.org/export/"))
+}
+
+// ParseWXR 解析 WordPress 导出文件中的文章（不含页面、附件和菜单等其他类型）
+// 已删除（trash）和自动草稿（auto-draft）不导入
+// 返回: 文章列表和跳过的条目数
+func ParseWXR(r io.Reader) ([]*Post, int, error) {
+	dec := xml.NewDecoder(r)
+	// 导出内容经常包含 HTML 实体和不规范的标签
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+
+	var rss wxrRSS
+	if err := dec.Decode(&rss); err != nil {
+		return nil, 0, fmt.Errorf("WXR 文件格式错误: %w", err)
+	}
+
+	// 附件 ID -> 地址，用于解析特色图片（_thumbnail_id）
+	attachments := make(map[string]string)
+	for _, item := range rss.Channel.Items {
+		if item.PostType == "attachment" && item.AttachmentURL != "" {
+			attachments[strings.TrimSpace(item.PostID)] = strings.TrimSpace(item.AttachmentURL)
+		}
+	}
+
+	var posts []*Post
+	skipped := 0
+	for _, item := range rss.Channel.Items {
+		if item.PostType != "post" {
+			continue
+		}
+		post := &Post{
+			Source: "WordPress #" + strings.TrimSpace(item.PostID),
+			Title:  strings.TrimSpace(item.Title),
+		}
+		switch strings.TrimSpace(item.Status) {
+		case "publish":
+			post.Status = StatusPublished
+		case "future":
+			post.Status = StatusScheduled
+		case "draft", "pending", "private":
+			post.Status = StatusDraft
+		default:
+			skipped++
+			continue
+		}
+
+		for _, enc := range item.Encoded {
+			if enc.XMLName.Space == contentNamespace {
+				post.Content = strings.TrimSpace(enc.Value)
+			} else if strings.Contains(enc.XMLName.Space, "/excerpt/") {
+				post.Desc = strings.TrimSpace(enc.Value)
+			}
+		}
+		if slug, err := url.PathUnescape(strings.TrimSpace(item.PostName)); err == nil {
+			post.Slug = slug // 中文别名在导出文件中是 URL 编码的
+		}
+		post.Date = wxrDate(item)
+
+		for _, cate := range item.Categories {
+			name := strings.TrimSpace(cate.Name)
+			if name == "" {
+				continue
+			}
+			switch cate.Domain {
+			case "category":
+				// 未分类（Uncategorized）是 WordPress 的默认分类，不导入
+				if post.Category == "" && name != "Uncategorized" && name != "未分类" {
+					post.Category = name
+				}
+			case "post_tag":
+				post.Tags = append(post.Tags, name)
+			}
+		}
+		for _, meta := range item.Meta {
+			if strings.TrimSpace(meta.Key) == "_thumbnail_id" {
+				post.Cover = attachments[strings.TrimSpace(meta.Value)]
+			}
+		}
+		if post.Title == "" {
+			post.Title = post.Slug
+		}
+		posts = append(posts, post)
+	}
+	return posts, skipped, nil
+}
+
+// wxrDate 文章发布时间：优先使用 UTC 时间，草稿的 post_date_gmt 为 0000-00-00 00:00:00
+func wxrDate(item wxrItem) time.Time {
+	if t, err := time.Parse("2006-01-02 15:04:05", strings.TrimSpace(item.PostDateGMT)); err == nil {
+		return t.Local()
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", strings.TrimSpace(item.PostDate), time.Local); err == nil {
+		return t
+	}
+	if t, err := time.Parse(time.RFC1123Z, strings.TrimSpace(item.PubDate)); err == nil {
+		return t.Local()
+	}
+	return time.Time{}
+}
@@ -0,0 +1,41 @@
+package utils
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+// markdown 渲染器（GFM：表格、删除线、任务列表、自动链接）
+// 文章由管理员编写，与前台一致保留正文中的原始 HTML
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithRendererOptions(html.WithUnsafe()),
+)
+
+// RenderMarkdown 将 Markdown 渲染为 HTML
+// 参数: src - Markdown 文本
+// 返回: HTML 文本，渲染失败时返回空字符串
+func RenderMarkdown(src string) string {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(src), &buf); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// AbsolutizeLinks 将 HTML 中以 / 开头的站内链接和图片地址转换为绝对地址
+// 订阅阅读器不在站点域名下打开内容，相对地址会失效
+func AbsolutizeLinks(html string, baseUrl string) string {
+	baseUrl = strings.TrimRight(baseUrl, "/")
+	replacer := strings.NewReplacer(
+		`src="//`, `src="//`,
+		`href="//`, `href="//`,
+		`src="/`, `src="`+baseUrl+`/`,
+		`href="/`, `href="`+baseUrl+`/`,
+	)
+	return replacer.Replace(html)
+}
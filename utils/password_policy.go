@@ -0,0 +1,137 @@
+package utils
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 密码策略默认值
+const (
+	defaultPasswordMinLength  = 8
+	defaultPasswordMinClasses = 2
+	passwordMaxBytes          = 72 // bcrypt 只使用前 72 字节
+)
+
+//go:embed passwords/common.txt
+var commonPasswordList string
+
+var (
+	commonPasswords     map[string]bool
+	commonPasswordsOnce sync.Once
+
+	blockList     map[string]bool
+	blockListPath string
+	blockListMu   sync.Mutex
+)
+
+// CheckPasswordPolicy 按配置的密码策略检查新密码
+// 参数: password - 明文密码, username - 用户名（密码不能包含用户名）
+// 返回: 不符合策略时的提示信息，符合时返回空字符串
+func CheckPasswordPolicy(password string, username string) string {
+	policy := ServerConfig.Password
+	minLength := policy.MinLength
+	if minLength <= 0 {
+		minLength = defaultPasswordMinLength
+	}
+	minClasses := policy.MinClasses
+	if minClasses <= 0 {
+		minClasses = defaultPasswordMinClasses
+	}
+
+	if utf8.RuneCountInString(password) < minLength {
+		return fmt.Sprintf("密码长度不能少于 %d 位", minLength)
+	}
+	if len(password) > passwordMaxBytes {
+		return fmt.Sprintf("密码长度不能超过 %d 字节", passwordMaxBytes)
+	}
+	if n := passwordClasses(password); n < minClasses {
+		return fmt.Sprintf("密码需至少包含小写字母、大写字母、数字、符号中的 %d 类", minClasses)
+	}
+
+	lower := strings.ToLower(password)
+	if username != "" && strings.Contains(lower, strings.ToLower(username)) {
+		return "密码不能包含用户名"
+	}
+	if !policy.AllowCommon && isCommonPassword(lower) {
+		return "密码过于常见，请更换"
+	}
+	if isBlockedPassword(policy.BlockListFile, lower) {
+		return "该密码出现在泄露密码列表中，请更换"
+	}
+	return ""
+}
+
+// passwordClasses 统计密码包含的字符类别数（小写、大写、数字、符号）
+func passwordClasses(password string) int {
+	var lower, upper, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		default:
+			symbol = true
+		}
+	}
+	n := 0
+	for _, ok := range []bool{lower, upper, digit, symbol} {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
+
+// isCommonPassword 判断是否为内置列表中的常见弱密码
+func isCommonPassword(lower string) bool {
+	commonPasswordsOnce.Do(func() {
+		commonPasswords = parsePasswordList(strings.NewReader(commonPasswordList))
+	})
+	return commonPasswords[lower]
+}
+
+// isBlockedPassword 判断是否在配置的额外弱密码列表中（文件路径变化时重新加载）
+func isBlockedPassword(path string, lower string) bool {
+	if path == "" {
+		return false
+	}
+	blockListMu.Lock()
+	defer blockListMu.Unlock()
+
+	if path != blockListPath {
+		blockList = nil
+		blockListPath = path
+		f, err := os.Open(path)
+		if err != nil {
+			fmt.Printf("读取弱密码列表失败: %v\n", err)
+			return false
+		}
+		blockList = parsePasswordList(f)
+		f.Close()
+	}
+	return blockList[lower]
+}
+
+// parsePasswordList 解析密码列表（每行一个，# 开头为注释，不区分大小写）
+func parsePasswordList(r io.Reader) map[string]bool {
+	list := make(map[string]bool)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list[strings.ToLower(line)] = true
+	}
+	return list
+}
@@ -0,0 +1,23 @@
+package utils
+
+import "testing"
+
+func TestCheckPasswordPolicy(t *testing.T) {
+	cases := []struct {
+		password string
+		ok       bool
+	}{
+		{"Ab1", false},            // 太短
+		{"abcdefghij", false},     // 只有一类字符
+		{"Password1", false},      // 常见弱密码（不区分大小写）
+		{"myadmin2024", false},    // 包含用户名
+		{"correct-horse-7", true}, // 小写 + 符号 + 数字
+		{"Tr0ub4dor&3", true},     // 四类字符
+	}
+	for _, c := range cases {
+		msg := CheckPasswordPolicy(c.password, "admin")
+		if (msg == "") != c.ok {
+			t.Errorf("CheckPasswordPolicy(%q) = %q, want ok=%v", c.password, msg, c.ok)
+		}
+	}
+}
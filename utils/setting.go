@@ -29,10 +29,68 @@ type Config struct {
 
 	JwtKey string `yaml:"JwtKey" json:"jwtKey"`
 
+	Auth struct {
+		AccessTokenMinutes int    `yaml:"AccessTokenMinutes" json:"accessTokenMinutes"`
+		RefreshTokenDays   int    `yaml:"RefreshTokenDays" json:"refreshTokenDays"`
+		TOTPIssuer         string `yaml:"TOTPIssuer" json:"totpIssuer"`
+	} `yaml:"auth" json:"auth"`
+
+	Password struct {
+		MinLength     int    `yaml:"MinLength" json:"minLength"`
+		MinClasses    int    `yaml:"MinClasses" json:"minClasses"`
+		AllowCommon   bool   `yaml:"AllowCommon" json:"allowCommon"`
+		BlockListFile string `yaml:"BlockListFile" json:"blockListFile"`
+	} `yaml:"password" json:"password"`
+
 	Weather struct {
 		DefaultCity string `yaml:"DefaultCity" json:"defaultCity"`
 	} `yaml:"weather" json:"weather"`
 
+	Feed struct {
+		Title string `yaml:"Title" json:"title"`
+		Mode  string `yaml:"Mode" json:"mode"`
+		Limit int    `yaml:"Limit" json:"limit"`
+	} `yaml:"feed" json:"feed"`
+
+	Comment struct {
+		AutoApprove bool     `yaml:"AutoApprove" json:"autoApprove"`
+		RateLimit   int      `yaml:"RateLimit" json:"rateLimit"`
+		MaxLength   int      `yaml:"MaxLength" json:"maxLength"`
+		BlockWords  []string `yaml:"BlockWords" json:"blockWords"`
+	} `yaml:"comment" json:"comment"`
+
+	Image struct {
+		StripExif bool  `yaml:"StripExif" json:"stripExif"`
+		Quality   int   `yaml:"Quality" json:"quality"`
+		ThumbSize int   `yaml:"ThumbSize" json:"thumbSize"`
+		Widths    []int `yaml:"Widths" json:"widths"`
+		WebP      bool  `yaml:"WebP" json:"webp"`
+	} `yaml:"image" json:"image"`
+
+	Storage struct {
+		Type string `yaml:"Type" json:"type"`
+		S3   struct {
+			Endpoint  string `yaml:"Endpoint" json:"endpoint"`
+			Region    string `yaml:"Region" json:"region"`
+			Bucket    string `yaml:"Bucket" json:"bucket"`
+			AccessKey string `yaml:"AccessKey" json:"accessKey"`
+			SecretKey string `yaml:"SecretKey" json:"secretKey"`
+			Prefix    string `yaml:"Prefix" json:"prefix"`
+			PathStyle bool   `yaml:"PathStyle" json:"pathStyle"`
+			PublicURL string `yaml:"PublicURL" json:"publicUrl"`
+		} `yaml:"S3" json:"s3"`
+		WebDAV struct {
+			URL       string `yaml:"URL" json:"url"`
+			Username  string `yaml:"Username" json:"username"`
+			Password  string `yaml:"Password" json:"password"`
+			PublicURL string `yaml:"PublicURL" json:"publicUrl"`
+		} `yaml:"WebDAV" json:"webdav"`
+	} `yaml:"storage" json:"storage"`
+
+	RecycleBin struct {
+		RetentionDays int `yaml:"RetentionDays" json:"retentionDays"`
+	} `yaml:"recycleBin" json:"recycleBin"`
+
 	FrontEndConfigPath string `yaml:"FrontEndConfigPath" json:"frontEndConfigPath"`
 
 	Cities []struct {
@@ -41,27 +99,39 @@ type Config struct {
 	} `yaml:"cities" json:"cities"`
 }
 
+// 站点数据文件路径（除数据库和配置文件外，全站备份需要包含的文件）
+const (
+	AboutFilePath     = "./web/frontend/public/static/about.md" // 关于页面内容
+	UploadHistoryFile = "./data/upload_history.json"            // 旧版本的 ZIP 导入历史，启动时导入数据库
+)
+
 var ServerConfig = Config{}
 var configMutex sync.RWMutex
 
 func init() {
-	configPath := getConfigPath("config/backend/config.yaml")
+	configPath := ConfigFilePath()
+	if configPath != getConfigPath("config/backend/config.yaml") {
+		log.Printf("未找到 config/backend/config.yaml，使用 %s", configPath)
+	}
 	file, err := os.ReadFile(configPath)
 	if err != nil {
-		log.Printf("未找到 config/backend/config.yaml，尝试旧路径...")
+		log.Printf("读取配置文件失败，使用默认值。错误信息：%s", err)
+		return
+	}
+	LoadConfig(file)
+}
+
+// ConfigFilePath 当前生效的后端配置文件路径
+// 依次查找 config/backend/config.yaml（可用 YANBLOG_CONFIG_PATH 覆盖）、旧路径 config/config.yaml 和模板文件
+func ConfigFilePath() string {
+	configPath := getConfigPath("config/backend/config.yaml")
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
 		configPath = "config/config.yaml"
-		file, err = os.ReadFile(configPath)
-		if err != nil {
-			log.Printf("未找到 config/config.yaml，使用模板 config/config_template.yaml")
+		if _, err := os.Stat(configPath); os.IsNotExist(err) {
 			configPath = "config/config_template.yaml"
-			file, err = os.ReadFile(configPath)
-			if err != nil {
-				log.Printf("读取配置文件失败，使用默认值。错误信息：%s", err)
-				return
-			}
 		}
 	}
-	LoadConfig(file)
+	return configPath
 }
 
 func GetConfigPath(defaultPath string) string {
@@ -131,14 +201,7 @@ func SaveConfig() error {
 }
 
 func ReloadConfig() error {
-	configPath := getConfigPath("config/backend/config.yaml")
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		configPath = "config/config.yaml"
-		if _, err := os.Stat(configPath); os.IsNotExist(err) {
-			configPath = "config/config_template.yaml"
-		}
-	}
-	file, err := os.ReadFile(configPath)
+	file, err := os.ReadFile(ConfigFilePath())
 	if err != nil {
 		return err
 	}
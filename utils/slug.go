@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/mozillazg/go-pinyin"
+)
+
+// SlugMaxLength slug 最大长度（按字节，slug 只包含 ASCII 字符）
+const SlugMaxLength = 80
+
+var slugPinyinArgs = func() pinyin.Args {
+	a := pinyin.NewArgs()
+	a.Style = pinyin.Normal // 不带声调
+	a.Fallback = func(r rune, a pinyin.Args) []string { return nil }
+	return a
+}()
+
+// Slugify 根据标题生成 URL 友好的 slug
+// 英文字母转小写、数字保留，汉字转为不带声调的拼音，其余字符视为分隔符
+// 例如 "Go 语言入门" => "go-yu-yan-ru-men"
+func Slugify(title string) string {
+	var b strings.Builder
+	sep := false // 是否需要在下一个词前插入分隔符
+	write := func(s string) {
+		if sep && b.Len() > 0 {
+			b.WriteByte('-')
+		}
+		b.WriteString(s)
+		sep = false
+	}
+
+	for _, r := range title {
+		switch {
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			write(string(unicode.ToLower(r)))
+		case unicode.Is(unicode.Han, r):
+			py := pinyin.SinglePinyin(r, slugPinyinArgs)
+			if len(py) > 0 && py[0] != "" {
+				sep = true
+				write(py[0])
+			}
+			sep = true
+		default:
+			sep = true
+		}
+	}
+
+	slug := b.String()
+	if len(slug) > SlugMaxLength {
+		slug = slug[:SlugMaxLength]
+		// 避免截断在单词中间
+		if i := strings.LastIndexByte(slug, '-'); i > SlugMaxLength/2 {
+			slug = slug[:i]
+		}
+	}
+	return strings.Trim(slug, "-")
+}
@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSlugify(t *testing.T) {
+	cases := map[string]string{
+		"Hello, World!":     "hello-world",
+		"Go 语言入门":           "go-yu-yan-ru-men",
+		"  多个   空格--和符号 ":   "duo-ge-kong-ge-he-fu-hao",
+		"Vue3实战":            "vue3-shi-zhan",
+		"!!!":               "",
+		"Déjà vu":           "d-j-vu",
+		"2024年度总结 (Part 1)": "2024-nian-du-zong-jie-part-1",
+	}
+	for in, want := range cases {
+		if got := Slugify(in); got != want {
+			t.Errorf("Slugify(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSlugifyLength(t *testing.T) {
+	slug := Slugify(strings.Repeat("中文标题", 30))
+	if len(slug) > SlugMaxLength {
+		t.Fatalf("slug too long: %d", len(slug))
+	}
+	if strings.HasSuffix(slug, "-") || strings.HasPrefix(slug, "-") {
+		t.Fatalf("slug should not start or end with '-': %q", slug)
+	}
+}
@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Local 本地磁盘存储
+type Local struct {
+	root string
+}
+
+// NewLocal 创建以 root 为根目录的本地存储
+func NewLocal(root string) *Local {
+	return &Local{root: root}
+}
+
+func (l *Local) Type() string {
+	return TypeLocal
+}
+
+// LocalPath 文件在本地磁盘上的路径
+func (l *Local) LocalPath(name string) string {
+	return filepath.Join(l.root, filepath.FromSlash(name))
+}
+
+// Put 先写入同目录下的临时文件再重命名，读取方不会看到写了一半的文件
+func (l *Local) Put(name string, r io.Reader, size int64) error {
+	p := l.LocalPath(name)
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(p), ".put-*")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		// CreateTemp 创建的文件权限为 0600，与其他上传文件保持一致
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), p)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+func (l *Local) Get(name string) (io.ReadCloser, error) {
+	f, err := os.Open(l.LocalPath(name))
+	if err != nil {
+		return nil, err
+	}
+	if info, err := f.Stat(); err == nil && info.IsDir() {
+		f.Close()
+		return nil, notExist("get", name)
+	}
+	return f, nil
+}
+
+func (l *Local) Stat(name string) (FileInfo, error) {
+	info, err := os.Stat(l.LocalPath(name))
+	if err != nil {
+		return FileInfo{}, err
+	}
+	return localInfo(name, info), nil
+}
+
+func localInfo(name string, info os.FileInfo) FileInfo {
+	fi := FileInfo{
+		Name:    info.Name(),
+		Path:    name,
+		ModTime: info.ModTime(),
+		IsDir:   info.IsDir(),
+	}
+	if !info.IsDir() {
+		fi.Size = info.Size()
+	}
+	return fi
+}
+
+func (l *Local) List(dir string) ([]FileInfo, error) {
+	entries, err := os.ReadDir(l.LocalPath(dir))
+	if err != nil {
+		return nil, err
+	}
+	items := make([]FileInfo, 0, len(entries))
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		items = append(items, localInfo(joinPath(dir, e.Name()), info))
+	}
+	return items, nil
+}
+
+func (l *Local) Mkdir(dir string) error {
+	return os.MkdirAll(l.LocalPath(dir), 0755)
+}
+
+func (l *Local) Move(src string, dst string) error {
+	if src == "" {
+		return ErrRoot
+	}
+	target := l.LocalPath(dst)
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+	return os.Rename(l.LocalPath(src), target)
+}
+
+func (l *Local) Copy(src string, dst string) error {
+	in, err := l.Get(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	return l.Put(dst, in, -1)
+}
+
+func (l *Local) Delete(name string) error {
+	if name == "" {
+		return ErrRoot
+	}
+	return os.RemoveAll(l.LocalPath(name))
+}
@@ -0,0 +1,485 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// S3Options S3 兼容对象存储的连接参数
+type S3Options struct {
+	Endpoint  string // 服务地址，如 https://s3.amazonaws.com、http://127.0.0.1:9000
+	Region    string // 区域，为空时使用 us-east-1
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	Prefix    string // 对象键前缀，多个站点共用一个存储桶时使用
+	PathStyle bool   // 使用 <Endpoint>/<Bucket>/<Key> 形式的地址（MinIO 等自建服务通常需要开启）
+}
+
+// S3 S3 兼容对象存储，使用 AWS Signature V4 签名
+// 对象存储没有真正的目录：以 / 结尾的前缀视为目录，Mkdir 创建一个以 / 结尾的空对象作为目录标记
+type S3 struct {
+	opts     S3Options
+	endpoint *url.URL
+	prefix   string
+	client   *http.Client
+	now      func() time.Time
+}
+
+// unsignedPayload 上传时不计算内容的 SHA-256，可以直接流式上传大文件
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// emptyPayloadHash 空请求体的 SHA-256
+const emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// NewS3 创建 S3 兼容对象存储
+func NewS3(opts S3Options) (*S3, error) {
+	if opts.Endpoint == "" || opts.Bucket == "" {
+		return nil, errors.New("S3 存储需要配置 Endpoint 和 Bucket")
+	}
+	endpoint, err := url.Parse(strings.TrimSuffix(opts.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("S3 Endpoint 格式错误: %s", opts.Endpoint)
+	}
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	prefix := strings.Trim(opts.Prefix, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &S3{
+		opts:     opts,
+		endpoint: endpoint,
+		prefix:   prefix,
+		client:   &http.Client{Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, ResponseHeaderTimeout: 60 * time.Second}},
+		now:      time.Now,
+	}, nil
+}
+
+func (s *S3) Type() string {
+	return TypeS3
+}
+
+// key 存储路径对应的对象键
+func (s *S3) key(name string) string {
+	return s.prefix + name
+}
+
+// dirKey 目录对应的对象键前缀（以 / 结尾，根目录为 Prefix 本身）
+func (s *S3) dirKey(dir string) string {
+	if dir == "" {
+		return s.prefix
+	}
+	return s.prefix + dir + "/"
+}
+
+// objectURL 对象的请求地址（key 为空时为存储桶地址）
+func (s *S3) objectURL(key string, query url.Values) *url.URL {
+	u := *s.endpoint
+	p := "/" + key
+	if s.opts.PathStyle {
+		p = strings.TrimSuffix(s.endpoint.Path, "/") + "/" + s.opts.Bucket + p
+	} else {
+		u.Host = s.opts.Bucket + "." + u.Host
+	}
+	u.Path, u.RawPath = p, s3Escape(p)
+	if query != nil {
+		u.RawQuery = s3Query(query)
+	}
+	return &u
+}
+
+// s3Escape 按 S3 签名规则编码对象键：除字母、数字和 -_.~/ 外全部编码
+func s3Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || strings.IndexByte("-_.~/", c) >= 0 {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// s3Query 按 S3 签名规则编码查询参数（按键排序，空格编码为 %20）
+func s3Query(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		for _, v := range query[k] {
+			parts = append(parts, s3Escape(k)+"="+strings.ReplaceAll(s3Escape(v), "/", "%2F"))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// sign 为请求添加 AWS Signature V4 签名
+func (s *S3) sign(req *http.Request, payloadHash string) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	headers := map[string]string{"host": host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") || lower == "range" || lower == "content-type" || lower == "content-md5" {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + s.opts.Region + "/s3/aws4_request"
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.opts.SecretKey), date)
+	key = hmacSHA256(key, s.opts.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.opts.AccessKey, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3Error 对象存储返回的错误
+type s3Error struct {
+	Status  int
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+func (e *s3Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("对象存储请求失败: HTTP %d", e.Status)
+	}
+	return fmt.Sprintf("对象存储请求失败: %s %s", e.Code, e.Message)
+}
+
+// do 发送签名后的请求，非 2xx 响应转为错误（404 转为不存在错误）
+func (s *S3) do(method string, key string, query url.Values, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(method, s.objectURL(key, query).String(), body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	payloadHash := emptyPayloadHash
+	if body != nil {
+		payloadHash = unsignedPayload
+		if size >= 0 {
+			req.ContentLength = size
+		}
+	}
+	s.sign(req, payloadHash)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, notExist(strings.ToLower(method), strings.TrimPrefix(key, s.prefix))
+	}
+	e := &s3Error{Status: resp.StatusCode}
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	xml.Unmarshal(data, e)
+	return nil, e
+}
+
+// Put 未知大小时先读入内存（S3 的 PUT 需要 Content-Length）
+func (s *S3) Put(name string, r io.Reader, size int64) error {
+	if size < 0 {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return WriteFile(s, name, data)
+	}
+	if size == 0 {
+		r = http.NoBody
+	}
+	resp, err := s.do(http.MethodPut, s.key(name), nil, r, size, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (s *S3) Get(name string) (io.ReadCloser, error) {
+	resp, err := s.do(http.MethodGet, s.key(name), nil, nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (s *S3) Stat(name string) (FileInfo, error) {
+	if name == "" {
+		return FileInfo{IsDir: true}, nil
+	}
+	resp, err := s.do(http.MethodHead, s.key(name), nil, nil, 0, nil)
+	if err == nil {
+		resp.Body.Close()
+		info := FileInfo{Name: path.Base(name), Path: name, Size: resp.ContentLength}
+		info.ModTime, _ = http.ParseTime(resp.Header.Get("Last-Modified"))
+		return info, nil
+	}
+	if !IsNotExist(err) {
+		return FileInfo{}, err
+	}
+	// 没有同名对象时检查是否为目录（存在以该路径为前缀的对象）
+	result, err := s.list(s.dirKey(name), "", "", 1)
+	if err != nil {
+		return FileInfo{}, err
+	}
+	if len(result.Contents) == 0 && len(result.CommonPrefixes) == 0 {
+		return FileInfo{}, notExist("stat", name)
+	}
+	return FileInfo{Name: path.Base(name), Path: name, IsDir: true}, nil
+}
+
+// listResult ListObjectsV2 的响应
+type listResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+}
+
+// list 列出一页对象，delimiter 为 / 时只列出直接子项
+func (s *S3) list(prefix string, delimiter string, token string, maxKeys int) (*listResult, error) {
+	query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+	if delimiter != "" {
+		query.Set("delimiter", delimiter)
+	}
+	if token != "" {
+		query.Set("continuation-token", token)
+	}
+	if maxKeys > 0 {
+		query.Set("max-keys", strconv.Itoa(maxKeys))
+	}
+	resp, err := s.do(http.MethodGet, "", query, nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var result listResult
+	if err := xml.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("解析对象列表失败: %w", err)
+	}
+	return &result, nil
+}
+
+// listAll 列出前缀下的所有对象键（含子目录中的对象和目录标记）
+func (s *S3) listAll(prefix string) ([]string, error) {
+	var keys []string
+	token := ""
+	for {
+		result, err := s.list(prefix, "", token, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range result.Contents {
+			keys = append(keys, obj.Key)
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return keys, nil
+		}
+		token = result.NextContinuationToken
+	}
+}
+
+func (s *S3) List(dir string) ([]FileInfo, error) {
+	prefix := s.dirKey(dir)
+	items := make([]FileInfo, 0)
+	token := ""
+	for {
+		result, err := s.list(prefix, "/", token, 0)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range result.CommonPrefixes {
+			name := strings.TrimSuffix(strings.TrimPrefix(p.Prefix, prefix), "/")
+			items = append(items, FileInfo{Name: name, Path: joinPath(dir, name), IsDir: true})
+		}
+		for _, obj := range result.Contents {
+			name := strings.TrimPrefix(obj.Key, prefix)
+			if name == "" { // 目录标记
+				continue
+			}
+			items = append(items, FileInfo{Name: name, Path: joinPath(dir, name), Size: obj.Size, ModTime: obj.LastModified})
+		}
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	if len(items) == 0 && dir != "" {
+		if _, err := s.Stat(dir); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// Mkdir 创建目录标记对象（对象存储的上级目录无需创建）
+func (s *S3) Mkdir(dir string) error {
+	if dir == "" {
+		return nil
+	}
+	resp, err := s.do(http.MethodPut, s.dirKey(dir), nil, http.NoBody, 0, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// copyObject 服务端复制对象
+func (s *S3) copyObject(srcKey string, dstKey string) error {
+	header := http.Header{"X-Amz-Copy-Source": {"/" + s3Escape(s.opts.Bucket+"/"+srcKey)}}
+	resp, err := s.do(http.MethodPut, dstKey, nil, nil, 0, header)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// 复制失败时也可能返回 200，错误信息在响应体中
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if strings.Contains(string(data), "<Error>") {
+		e := &s3Error{Status: resp.StatusCode}
+		xml.Unmarshal(data, e)
+		return e
+	}
+	return nil
+}
+
+func (s *S3) deleteObject(key string) error {
+	resp, err := s.do(http.MethodDelete, key, nil, nil, 0, nil)
+	if err != nil && !IsNotExist(err) {
+		return err
+	}
+	if resp != nil {
+		resp.Body.Close()
+	}
+	return nil
+}
+
+func (s *S3) Copy(src string, dst string) error {
+	if _, err := s.Stat(src); err != nil {
+		return err
+	}
+	return s.copyObject(s.key(src), s.key(dst))
+}
+
+// Move 对象存储不支持重命名，逐个复制后删除（目录移动时包含其中所有对象）
+func (s *S3) Move(src string, dst string) error {
+	if src == "" {
+		return ErrRoot
+	}
+	info, err := s.Stat(src)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir {
+		if err := s.copyObject(s.key(src), s.key(dst)); err != nil {
+			return err
+		}
+		return s.deleteObject(s.key(src))
+	}
+	srcPrefix, dstPrefix := s.dirKey(src), s.dirKey(dst)
+	keys, err := s.listAll(srcPrefix)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := s.copyObject(key, dstPrefix+strings.TrimPrefix(key, srcPrefix)); err != nil {
+			return err
+		}
+	}
+	for _, key := range keys {
+		if err := s.deleteObject(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Delete 删除对象，以及以该路径为前缀的所有对象（目录）
+func (s *S3) Delete(name string) error {
+	if name == "" {
+		return ErrRoot
+	}
+	if err := s.deleteObject(s.key(name)); err != nil {
+		return err
+	}
+	keys, err := s.listAll(s.dirKey(name))
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		if err := s.deleteObject(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+// Package storage 上传文件的存储后端：本地磁盘、S3 兼容对象存储（AWS S3、MinIO、OSS、COS 等）和 WebDAV
+// 文件以相对存储根目录的路径（/ 分隔，如 article/cover/1.jpg）访问，空字符串表示根目录
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"yanblog/utils"
+)
+
+// 存储类型
+const (
+	TypeLocal  = "local"
+	TypeS3     = "s3"
+	TypeWebDAV = "webdav"
+)
+
+// LocalRoot 本地存储的根目录，也是 /uploads 静态文件目录
+const LocalRoot = "./uploads"
+
+// FileInfo 文件或目录信息
+type FileInfo struct {
+	Name    string    // 文件名
+	Path    string    // 相对存储根目录的路径
+	Size    int64     // 文件大小，目录为 0
+	ModTime time.Time // 修改时间，对象存储中的目录为零值
+	IsDir   bool
+}
+
+// Storage 存储后端
+// 路径均为 Clean 处理后的相对路径；文件或目录不存在时返回的错误满足 errors.Is(err, fs.ErrNotExist)
+type Storage interface {
+	// Type 存储类型（local、s3、webdav）
+	Type() string
+	// Put 写入文件，已存在时覆盖，自动创建上级目录；size 未知时传 -1
+	Put(name string, r io.Reader, size int64) error
+	// Get 读取文件内容，调用方负责关闭
+	Get(name string) (io.ReadCloser, error)
+	// Stat 查询文件或目录信息
+	Stat(name string) (FileInfo, error)
+	// List 列出目录下的直接子项（不含子目录中的内容）
+	List(dir string) ([]FileInfo, error)
+	// Mkdir 创建目录（含上级目录），已存在时不报错
+	Mkdir(dir string) error
+	// Move 移动或重命名文件、目录，目标已存在时的行为由各后端决定，调用方应先检查
+	Move(src string, dst string) error
+	// Copy 复制文件
+	Copy(src string, dst string) error
+	// Delete 删除文件或目录（含其中所有内容），不存在时不报错
+	Delete(name string) error
+}
+
+// ErrRoot 不能删除或移动存储根目录
+var ErrRoot = errors.New("不能删除或移动根目录")
+
+// Localer 文件保存在本地磁盘的存储，可以直接取得文件路径（用于静态文件服务和需要随机读取的场景）
+type Localer interface {
+	LocalPath(name string) string
+}
+
+// Clean 规范化用户传入的路径，返回存储中的相对路径
+// 路径跳出根目录（如 ../config）时返回 false；根目录返回空字符串
+func Clean(name string) (string, bool) {
+	name = strings.ReplaceAll(name, "\\", "/")
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return "", false
+		}
+	}
+	cleaned := strings.TrimPrefix(path.Clean("/"+name), "/")
+	return cleaned, true
+}
+
+// notExist 包装不存在错误
+func notExist(op string, name string) error {
+	return &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+}
+
+// IsNotExist 错误是否表示文件或目录不存在
+func IsNotExist(err error) bool {
+	return errors.Is(err, fs.ErrNotExist)
+}
+
+// Exists 文件或目录是否存在
+func Exists(s Storage, name string) bool {
+	_, err := s.Stat(name)
+	return err == nil
+}
+
+// ReadFile 读取整个文件
+func ReadFile(s Storage, name string) ([]byte, error) {
+	r, err := s.Get(name)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// WriteFile 写入整个文件
+func WriteFile(s Storage, name string, data []byte) error {
+	return s.Put(name, bytes.NewReader(data), int64(len(data)))
+}
+
+// SkipDir 在 Walk 的回调中返回时跳过该目录
+var SkipDir = fs.SkipDir
+
+// Walk 递归遍历目录（按名称排序，先目录本身后其中内容），回调返回 SkipDir 时跳过该目录
+// 遍历的根目录本身不会传给回调
+func Walk(s Storage, dir string, fn func(info FileInfo) error) error {
+	items, err := s.List(dir)
+	if err != nil {
+		return err
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Name < items[j].Name })
+	for _, item := range items {
+		err := fn(item)
+		if item.IsDir {
+			if err == SkipDir {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := Walk(s, item.Path, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil && err != SkipDir {
+			return err
+		}
+	}
+	return nil
+}
+
+// joinPath 拼接相对路径，dir 为空表示根目录
+func joinPath(dir string, name string) string {
+	if dir == "" {
+		return name
+	}
+	return dir + "/" + name
+}
+
+var (
+	currentMu sync.RWMutex
+	current   Storage = NewLocal(LocalRoot)
+	publicURL string
+)
+
+// Current 当前使用的存储（服务启动时由 Init 按配置创建，默认为本地存储）
+func Current() Storage {
+	currentMu.RLock()
+	defer currentMu.RUnlock()
+	return current
+}
+
+// SetCurrent 替换当前使用的存储，返回原来的存储（用于测试）
+func SetCurrent(s Storage) Storage {
+	currentMu.Lock()
+	defer currentMu.Unlock()
+	old := current
+	current = s
+	return old
+}
+
+// Init 按配置创建存储
+func Init() error {
+	cfg := utils.GetConfig().Storage
+	var s Storage
+	var public string
+	switch strings.ToLower(cfg.Type) {
+	case "", TypeLocal:
+		s = NewLocal(LocalRoot)
+	case TypeS3:
+		s3, err := NewS3(S3Options{
+			Endpoint:  cfg.S3.Endpoint,
+			Region:    cfg.S3.Region,
+			Bucket:    cfg.S3.Bucket,
+			AccessKey: cfg.S3.AccessKey,
+			SecretKey: cfg.S3.SecretKey,
+			Prefix:    cfg.S3.Prefix,
+			PathStyle: cfg.S3.PathStyle,
+		})
+		if err != nil {
+			return err
+		}
+		s, public = s3, cfg.S3.PublicURL
+	case TypeWebDAV:
+		dav, err := NewWebDAV(WebDAVOptions{
+			URL:      cfg.WebDAV.URL,
+			Username: cfg.WebDAV.Username,
+			Password: cfg.WebDAV.Password,
+		})
+		if err != nil {
+			return err
+		}
+		s, public = dav, cfg.WebDAV.PublicURL
+	default:
+		return fmt.Errorf("不支持的存储类型: %s", cfg.Type)
+	}
+
+	currentMu.Lock()
+	current = s
+	publicURL = strings.TrimSuffix(public, "/")
+	currentMu.Unlock()
+	return nil
+}
+
+// PublicURL 文件的外部访问地址（配置了存储的公开访问地址时），未配置时返回空字符串，由后端代理访问
+func PublicURL(name string) string {
+	currentMu.RLock()
+	defer currentMu.RUnlock()
+	if publicURL == "" {
+		return ""
+	}
+	return publicURL + "/" + name
+}
@@ -0,0 +1,285 @@
+package storage
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+// WebDAVOptions WebDAV 服务的连接参数
+type WebDAVOptions struct {
+	URL      string // 存储根目录的地址，如 https://dav.example.com/remote.php/dav/files/user/blog
+	Username string
+	Password string
+}
+
+// WebDAV WebDAV 存储
+type WebDAV struct {
+	opts   WebDAVOptions
+	base   *url.URL
+	client *http.Client
+}
+
+// NewWebDAV 创建 WebDAV 存储
+func NewWebDAV(opts WebDAVOptions) (*WebDAV, error) {
+	if opts.URL == "" {
+		return nil, errors.New("WebDAV 存储需要配置 URL")
+	}
+	base, err := url.Parse(opts.URL)
+	if err != nil || base.Host == "" {
+		return nil, fmt.Errorf("WebDAV URL 格式错误: %s", opts.URL)
+	}
+	base.Path = strings.TrimSuffix(base.Path, "/")
+	base.RawPath = ""
+	return &WebDAV{
+		opts:   opts,
+		base:   base,
+		client: &http.Client{Transport: &http.Transport{Proxy: http.ProxyFromEnvironment, ResponseHeaderTimeout: 60 * time.Second}},
+	}, nil
+}
+
+func (d *WebDAV) Type() string {
+	return TypeWebDAV
+}
+
+// url 存储路径对应的地址，目录以 / 结尾
+func (d *WebDAV) url(name string, dir bool) string {
+	u := *d.base
+	u.Path += "/" + name
+	if dir && name != "" {
+		u.Path += "/"
+	}
+	return u.String()
+}
+
+// do 发送请求，非 2xx 响应转为错误（404 转为不存在错误）
+func (d *WebDAV) do(method string, name string, dir bool, body io.Reader, size int64, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(method, d.url(name, dir), body)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil && size >= 0 {
+		req.ContentLength = size
+	}
+	if d.opts.Username != "" {
+		req.SetBasicAuth(d.opts.Username, d.opts.Password)
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, notExist(strings.ToLower(method), name)
+	}
+	return nil, fmt.Errorf("WebDAV %s %s 失败: HTTP %d", method, name, resp.StatusCode)
+}
+
+func (d *WebDAV) Put(name string, r io.Reader, size int64) error {
+	if err := d.Mkdir(path.Dir(name)); err != nil {
+		return err
+	}
+	if size == 0 {
+		r = http.NoBody
+	}
+	resp, err := d.do(http.MethodPut, name, false, r, size, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (d *WebDAV) Get(name string) (io.ReadCloser, error) {
+	info, err := d.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir {
+		return nil, notExist("get", name)
+	}
+	resp, err := d.do(http.MethodGet, name, false, nil, 0, nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// multistatus PROPFIND 的响应
+type multistatus struct {
+	Responses []struct {
+		Href     string `xml:"href"`
+		Propstat []struct {
+			Prop struct {
+				ContentLength int64  `xml:"getcontentlength"`
+				LastModified  string `xml:"getlastmodified"`
+				ResourceType  struct {
+					Collection *struct{} `xml:"collection"`
+				} `xml:"resourcetype"`
+			} `xml:"prop"`
+			Status string `xml:"status"`
+		} `xml:"propstat"`
+	} `xml:"response"`
+}
+
+const propfindBody = `<?xml version="1.0" encoding="utf-8"?>
+<D:propfind xmlns:D="DAV:"><D:prop><D:resourcetype/><D:getcontentlength/><D:getlastmodified/></D:prop></D:propfind>`
+
+// propfind 查询文件或目录（depth 为 1 时同时返回直接子项）
+func (d *WebDAV) propfind(name string, depth string) ([]FileInfo, error) {
+	header := http.Header{"Depth": {depth}, "Content-Type": {"application/xml; charset=utf-8"}}
+	resp, err := d.do("PROPFIND", name, depth != "0", strings.NewReader(propfindBody), int64(len(propfindBody)), header)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var ms multistatus
+	if err := xml.NewDecoder(resp.Body).Decode(&ms); err != nil {
+		return nil, fmt.Errorf("解析 WebDAV 响应失败: %w", err)
+	}
+
+	items := make([]FileInfo, 0, len(ms.Responses))
+	for _, r := range ms.Responses {
+		href, err := url.Parse(r.Href)
+		if err != nil {
+			continue
+		}
+		rel, ok := strings.CutPrefix(strings.TrimSuffix(href.Path, "/"), d.base.Path)
+		if !ok {
+			continue
+		}
+		info := FileInfo{Path: strings.Trim(rel, "/")}
+		info.Name = path.Base(info.Path)
+		for _, ps := range r.Propstat {
+			if !strings.Contains(ps.Status, " 200 ") {
+				continue
+			}
+			if ps.Prop.ResourceType.Collection != nil {
+				info.IsDir = true
+			} else if ps.Prop.ContentLength > 0 {
+				info.Size = ps.Prop.ContentLength
+			}
+			if t, err := http.ParseTime(ps.Prop.LastModified); err == nil {
+				info.ModTime = t
+			}
+		}
+		items = append(items, info)
+	}
+	return items, nil
+}
+
+func (d *WebDAV) Stat(name string) (FileInfo, error) {
+	items, err := d.propfind(name, "0")
+	if err != nil {
+		return FileInfo{}, err
+	}
+	for _, item := range items {
+		if item.Path == name {
+			return item, nil
+		}
+	}
+	return FileInfo{}, notExist("stat", name)
+}
+
+func (d *WebDAV) List(dir string) ([]FileInfo, error) {
+	items, err := d.propfind(dir, "1")
+	if err != nil {
+		return nil, err
+	}
+	children := make([]FileInfo, 0, len(items))
+	for _, item := range items {
+		if item.Path == dir {
+			if !item.IsDir {
+				return nil, fmt.Errorf("%s 不是目录", dir)
+			}
+			continue
+		}
+		children = append(children, item)
+	}
+	return children, nil
+}
+
+// Mkdir 逐级创建目录（MKCOL 不会自动创建上级目录）
+func (d *WebDAV) Mkdir(dir string) error {
+	if dir == "" || dir == "." {
+		return nil
+	}
+	if info, err := d.Stat(dir); err == nil {
+		if !info.IsDir {
+			return fmt.Errorf("%s 已存在且不是目录", dir)
+		}
+		return nil
+	}
+	if err := d.Mkdir(path.Dir(dir)); err != nil {
+		return err
+	}
+	resp, err := d.do("MKCOL", dir, true, nil, 0, nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// transfer 服务端移动或复制
+func (d *WebDAV) transfer(method string, src string, dst string) error {
+	info, err := d.Stat(src)
+	if err != nil {
+		return err
+	}
+	if err := d.Mkdir(path.Dir(dst)); err != nil {
+		return err
+	}
+	header := http.Header{"Destination": {d.url(dst, info.IsDir)}, "Overwrite": {"T"}}
+	resp, err := d.do(method, src, info.IsDir, nil, 0, header)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (d *WebDAV) Move(src string, dst string) error {
+	if src == "" {
+		return ErrRoot
+	}
+	return d.transfer("MOVE", src, dst)
+}
+
+func (d *WebDAV) Copy(src string, dst string) error {
+	return d.transfer("COPY", src, dst)
+}
+
+func (d *WebDAV) Delete(name string) error {
+	if name == "" {
+		return ErrRoot
+	}
+	info, err := d.Stat(name)
+	if IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp, err := d.do(http.MethodDelete, name, info.IsDir, nil, 0, nil)
+	if IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// uploadURLRegex 匹配文本中引用的站内上传文件地址（Markdown 链接、HTML 属性或单独的地址），第 1 组为地址
+var uploadURLRegex = regexp.MustCompile(`(?:^|[\s("'=])(/uploads/[^\s)"'<>?#]+)`)
+
+// UploadRefs 提取文本中引用的站内上传文件地址（去重，按出现顺序）
+func UploadRefs(texts ...string) []string {
+	seen := make(map[string]bool)
+	var refs []string
+	for _, text := range texts {
+		for _, m := range uploadURLRegex.FindAllStringSubmatch(text, -1) {
+			if !seen[m[1]] {
+				seen[m[1]] = true
+				refs = append(refs, m[1])
+			}
+		}
+	}
+	return refs
+}
+
+// imageVariantURLRegex 匹配文本中引用的图片尺寸地址（/img/<尺寸>/<上传路径>），第 1 组为上传路径
+var imageVariantURLRegex = regexp.MustCompile(`(?:^|[\s("'=])/img/[A-Za-z0-9_-]+/([^\s)"'<>?#]+)`)
+
+// ImageVariantRefs 提取文本中引用的图片尺寸地址，返回对应原图的上传地址（去重，按出现顺序）
+func ImageVariantRefs(texts ...string) []string {
+	seen := make(map[string]bool)
+	var refs []string
+	for _, text := range texts {
+		for _, m := range imageVariantURLRegex.FindAllStringSubmatch(text, -1) {
+			ref := "/uploads/" + m[1]
+			if !seen[ref] {
+				seen[ref] = true
+				refs = append(refs, ref)
+			}
+		}
+	}
+	return refs
+}
+
+// UploadURLToName 将站内上传地址转为文件在存储中的路径（如 /uploads/article/a.png -> article/a.png），地址不在上传目录内时返回 false
+func UploadURLToName(url string) (string, bool) {
+	clean := path.Clean(url)
+	if !strings.HasPrefix(clean, "/uploads/") || clean != url {
+		return "", false
+	}
+	return strings.TrimPrefix(clean, "/uploads/"), true
+}
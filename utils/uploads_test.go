@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUploadRefs(t *testing.T) {
+	content := "![a](/uploads/a.png \"t\") [pdf](/uploads/doc/b.pdf)\n" +
+		`<img src="/uploads/c.jpg?x=1"> ![again](/uploads/a.png) https://other.com/uploads/d.png /uploadsx/e.png`
+	got := UploadRefs(content, "/uploads/cover.png", "")
+	want := []string{"/uploads/a.png", "/uploads/doc/b.pdf", "/uploads/c.jpg", "/uploads/cover.png"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestImageVariantRefs(t *testing.T) {
+	content := `<img src="/img/w640/article/a.jpg" srcset="/img/w320/article/a.jpg 320w, /img/thumb/b.png?format=webp 1x">` +
+		"\n![c](/uploads/c.png) /img/w640/ /imgx/w640/d.png"
+	got := ImageVariantRefs(content)
+	want := []string{"/uploads/article/a.jpg", "/uploads/b.png"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestUploadURLToName(t *testing.T) {
+	if p, ok := UploadURLToName("/uploads/article/a.png"); !ok || p != "article/a.png" {
+		t.Errorf("valid url: got %q, %v", p, ok)
+	}
+	for _, url := range []string{"/uploads/../config/config.yaml", "/uploads/./a.png", "/static/a.png", "uploads/a.png", "/uploads/"} {
+		if _, ok := UploadURLToName(url); ok {
+			t.Errorf("%q should be rejected", url)
+		}
+	}
+}
@@ -6,8 +6,10 @@ import MainLayout from '@/layouts/MainLayout.vue'
 
 // 页面组件
 const Login = () => import('@/views/Login.vue')
+const ChangePassword = () => import('@/views/ChangePassword.vue')
 const Dashboard = () => import('@/views/dashboard/Dashboard.vue')
 const UserList = () => import('@/views/user/UserList.vue')
+const Security = () => import('@/views/user/Security.vue')
 const CategoryList = () => import('@/views/category/CategoryList.vue')
 const TagList = () => import('@/views/tag/TagList.vue')
 const ArticleList = () => import('@/views/article/ArticleList.vue')
@@ -17,6 +19,8 @@ const ConfigEditor = () => import('@/views/system/ConfigEditor.vue')
 const BackendConfig = () => import('@/views/system/BackendConfig.vue')
 const AboutEditor = () => import('@/views/system/AboutEditor.vue')
 const SystemStatus = () => import('@/views/system/SystemStatus.vue')
+const AuditLog = () => import('@/views/system/AuditLog.vue')
+const BackupRestore = () => import('@/views/system/BackupRestore.vue')
 
 // 定义路由
 const routes: RouteRecordRaw[] = [
@@ -32,6 +36,14 @@ const routes: RouteRecordRaw[] = [
       title: '登录'
     }
   },
+  {
+    path: '/change-password',
+    name: 'ChangePassword',
+    component: ChangePassword,
+    meta: {
+      title: '修改密码'
+    }
+  },
   {
     path: '/',
     component: MainLayout,
@@ -54,6 +66,14 @@ const routes: RouteRecordRaw[] = [
           icon: 'User'
         }
       },
+      {
+        path: '/user/security',
+        name: 'Security',
+        component: Security,
+        meta: {
+          title: '账号安全'
+        }
+      },
       {
         path: '/category',
         name: 'CategoryList',
@@ -150,6 +170,24 @@ const routes: RouteRecordRaw[] = [
           title: '关于页管理',
           activeMenu: '/system'
         }
+      },
+      {
+        path: '/system/audit',
+        name: 'AuditLog',
+        component: AuditLog,
+        meta: {
+          title: '审计日志',
+          activeMenu: '/system'
+        }
+      },
+      {
+        path: '/system/backup',
+        name: 'BackupRestore',
+        component: BackupRestore,
+        meta: {
+          title: '备份与恢复',
+          activeMenu: '/system'
+        }
       }
     ]
   },
@@ -166,6 +204,15 @@ const router = createRouter({
   routes
 })
 
+// 当前用户是否必须先修改密码（登录时由后端返回）
+const mustChangePassword = () => {
+  try {
+    return JSON.parse(localStorage.getItem('user') || '{}').must_change_password === true
+  } catch (e) {
+    return false
+  }
+}
+
 // 全局前置守卫
 router.beforeEach((to, from, next) => {
   // 设置页面标题
@@ -181,6 +228,9 @@ router.beforeEach((to, from, next) => {
   } else if (to.path === '/login' && token) {
     // 已登录且去登录页，则重定向到首页
     next('/')
+  } else if (token && to.path !== '/change-password' && mustChangePassword()) {
+    // 初始密码未修改前只能访问修改密码页面
+    next('/change-password')
   } else {
     // 其他情况正常放行
     next()
@@ -25,30 +25,157 @@ apiClient.interceptors.request.use(
   }
 )
 
+// 清除登录状态并跳转到登录页
+const redirectToLogin = () => {
+  localStorage.removeItem('token')
+  localStorage.removeItem('refresh_token')
+  localStorage.removeItem('user')
+  // 使用相对路径跳转，确保在 admin base 路径下正确导航
+  const base = import.meta.env.BASE_URL || '/admin/'
+  window.location.href = base + 'login'
+}
+
+// 正在进行的刷新请求（并发的 401 共用同一次刷新）
+let refreshing: Promise<string> | null = null
+
+// 使用刷新令牌换取新的访问令牌，成功后返回新的访问令牌
+export const refreshAccessToken = (): Promise<string> => {
+  if (!refreshing) {
+    const refreshToken = localStorage.getItem('refresh_token')
+    refreshing = (refreshToken
+      ? axios.post('/api/v1/token/refresh', { refresh_token: refreshToken }).then((res) => {
+          if (res.data?.status !== 200) throw new Error(res.data?.message || '刷新令牌失败')
+          localStorage.setItem('token', res.data.token)
+          localStorage.setItem('refresh_token', res.data.refresh_token)
+          return res.data.token as string
+        })
+      : Promise.reject(new Error('没有刷新令牌'))
+    ).finally(() => {
+      refreshing = null
+    })
+  }
+  return refreshing
+}
+
 // 响应拦截器
 apiClient.interceptors.response.use(
   (response: AxiosResponse) => {
     return response
   },
-  (error) => {
+  async (error) => {
+    const config = error.config as (InternalAxiosRequestConfig & { _retried?: boolean }) | undefined
     if (error.response?.status === 401) {
+      // 访问令牌过期：尝试用刷新令牌换取新令牌后重试一次
+      if (config && !config._retried && !config.url?.includes('/v1/login')) {
+        config._retried = true
+        try {
+          const token = await refreshAccessToken()
+          config.headers.Authorization = `Bearer ${token}`
+          return apiClient(config)
+        } catch {
+          // 刷新失败，走下面的重新登录流程
+        }
+      }
       // token过期或无效，清除本地存储并跳转到登录页
-      localStorage.removeItem('token')
-      localStorage.removeItem('user')
-      // 使用相对路径跳转，确保在 admin base 路径下正确导航
-      const base = import.meta.env.BASE_URL || '/admin/'
-      window.location.href = base + 'login'
+      redirectToLogin()
+    }
+    // 必须先修改初始密码
+    if (error.response?.status === 403 && error.response.data?.status === 1018) {
+      window.location.href = '/change-password'
     }
     return Promise.reject(error)
   }
 )
 
+// 超过该大小的文件使用分片上传（可断点续传，不受单次请求大小和超时的限制）
+export const CHUNKED_UPLOAD_THRESHOLD = 20 << 20
+
+const toBase64 = (buf: ArrayBuffer) => btoa(String.fromCharCode(...new Uint8Array(buf)))
+
+// 分片上传：新建上传后逐片发送，网络中断时查询服务端已接收的字节数后继续，最后请求合并
+// options.type 同普通上传（article、cover、pdf 等），import 表示用于 ZIP 上传和博客导入
+// 返回合并接口的数据：普通文件为 { url }，import 为 { upload_id }
+export const uploadChunked = async (
+  file: File,
+  options: { type: string; key?: string; id?: number },
+  onProgress?: (percent: number) => void
+) => {
+  const created = await apiClient.post('/v1/upload/chunked', { filename: file.name, size: file.size, ...options })
+  if (created.data.status !== 200) throw new Error(created.data.message)
+  const { id, chunk_size: chunkSize } = created.data.data
+
+  let offset = 0
+  let failures = 0
+  while (offset < file.size) {
+    const chunk = file.slice(offset, offset + chunkSize)
+    const headers: Record<string, string> = {
+      'Content-Type': 'application/offset+octet-stream',
+      'Upload-Offset': String(offset)
+    }
+    // crypto.subtle 只在 HTTPS 或 localhost 下可用，不可用时不校验分片
+    if (window.crypto?.subtle) {
+      const digest = await window.crypto.subtle.digest('SHA-256', await chunk.arrayBuffer())
+      headers['Upload-Checksum'] = `sha256 ${toBase64(digest)}`
+    }
+    try {
+      const res = await apiClient.patch(`/v1/upload/chunked/${id}`, chunk, { headers, timeout: 0 })
+      offset = res.data.data.offset
+      failures = 0
+    } catch (err) {
+      // 连续失败 3 次放弃，否则稍后从服务端已接收的位置继续
+      if (++failures >= 3) throw err
+      await new Promise((resolve) => setTimeout(resolve, 1000 * failures))
+      const head = await apiClient.head(`/v1/upload/chunked/${id}`)
+      offset = Number(head.headers['upload-offset'])
+    }
+    onProgress?.(Math.round((offset / file.size) * 100))
+  }
+
+  const done = await apiClient.post(`/v1/upload/chunked/${id}/complete`, null, { timeout: 0 })
+  if (done.data.status !== 200) throw new Error(done.data.message)
+  return done.data.data
+}
+
 // 用户相关API
 export const userApi = {
   // 登录
   login: (data: { username: string; password: string }) =>
     apiClient.post('/v1/login', data),
 
+  // 两步登录：提交验证码或恢复码
+  loginTwoFactor: (data: { mfa_token: string; code: string }) =>
+    apiClient.post('/v1/login/2fa', data),
+
+  // 两步验证状态
+  getTwoFactorStatus: () => apiClient.get('/v1/user/2fa'),
+
+  // 生成两步验证密钥和二维码
+  setupTwoFactor: () => apiClient.post('/v1/user/2fa/setup'),
+
+  // 确认验证码并启用两步验证
+  enableTwoFactor: (code: string) => apiClient.post('/v1/user/2fa/enable', { code }),
+
+  // 关闭两步验证
+  disableTwoFactor: (data: { password: string; code: string }) =>
+    apiClient.post('/v1/user/2fa/disable', data),
+
+  // 重新生成恢复码
+  regenerateRecoveryCodes: (code: string) =>
+    apiClient.post('/v1/user/2fa/recovery-codes', { code }),
+
+  // 重置用户的两步验证（管理员操作）
+  resetTwoFactor: (id: number) => apiClient.delete(`/v1/user/${id}/2fa`),
+
+  // 修改自己的密码（成功后返回新的令牌）
+  changePassword: (data: { old_password: string; new_password: string }) =>
+    apiClient.put('/v1/user/password', data),
+
+  // 退出登录（撤销当前会话）
+  logout: () => apiClient.post('/v1/logout'),
+
+  // 撤销用户的全部会话（强制下线）
+  revokeSessions: (id: number) => apiClient.post(`/v1/user/${id}/sessions/revoke`),
+
   // 获取用户列表
   getUsers: (params: { pagesize: number; pagenum: number }) =>
     apiClient.get('/v1/users', { params }),
@@ -104,9 +231,25 @@ export const fileApi = {
   moveFile: (sourcePath: string, targetPath: string) =>
     apiClient.post('/v1/files/move', { sourcePath, targetPath }),
 
-  // 复制文件
+  // 复制文件/目录（后台任务，返回任务 ID）
   copyFile: (sourcePath: string, targetPath: string) =>
-    apiClient.post('/v1/files/copy', { sourcePath, targetPath }),
+    apiClient.post('/v1/files/copy', { sourcePath, targetPath }, { params: { async: 1 } }),
+
+  // 压缩文件/目录，格式由文件名的扩展名决定（后台任务，返回任务 ID）
+  compressFiles: (paths: string[], zipName: string, zipPath: string = '') =>
+    apiClient.post('/v1/files/v2/compress', { paths, zip_name: zipName, zip_path: zipPath }, { params: { async: 1 } }),
+
+  // 解压压缩包，extractTo 为空时解压到压缩包所在目录（后台任务，返回任务 ID）
+  extractArchive: (zipPath: string, extractTo: string = '') =>
+    apiClient.post('/v1/files/v2/extract', { zip_path: zipPath, extract_to: extractTo }, { params: { async: 1 } }),
+
+  // 后台任务的进度和结果
+  getFileJob: (id: string) =>
+    apiClient.get(`/v1/files/jobs/${id}`),
+
+  // 取消后台任务
+  cancelFileJob: (id: string) =>
+    apiClient.delete(`/v1/files/jobs/${id}`),
 
   // 批量删除
   batchDeleteFiles: (paths: string[]) =>
@@ -120,7 +263,15 @@ export const fileApi = {
 
   // 获取存储统计
   getStorageStats: () =>
-    apiClient.get('/v1/files/stats')
+    apiClient.get('/v1/files/stats'),
+
+  // 为已有图片补生成缩略图和不同宽度（后台执行）
+  startImageBackfill: (force: boolean = false, stripExif: boolean = false) =>
+    apiClient.post('/v1/files/images/backfill', { force, strip_exif: stripExif }),
+
+  // 补生成进度
+  getImageBackfill: () =>
+    apiClient.get('/v1/files/images/backfill')
 }
 
 // 分类相关API
@@ -134,11 +285,11 @@ export const categoryApi = {
     apiClient.get('/v1/category/search', { params }),
 
   // 创建分类
-  createCategory: (data: { name: string; img: string; top: number }) =>
+  createCategory: (data: { name: string; slug?: string; img: string; top: number }) =>
     apiClient.post('/v1/category/add', data),
 
   // 更新分类
-  updateCategory: (id: number, data: { name: string; img: string; top: number }) =>
+  updateCategory: (id: number, data: { name: string; slug?: string; img: string; top: number }) =>
     apiClient.put(`/v1/category/${id}`, data),
 
   // 删除分类
@@ -167,6 +318,7 @@ export const articleApi = {
   // 创建文章
   createArticle: (data: {
     title: string;
+    slug?: string;
     cid: number;
     desc: string;
     content: string;
@@ -179,7 +331,7 @@ export const articleApi = {
   }) =>
     apiClient.post('/v1/article/add', data),
 
-  // 上传ZIP发布文章（单个）
+  // 上传ZIP发布文章（单个），返回 task_id，文章在后台导入
   uploadZip: (file: File) => {
     const formData = new FormData()
     formData.append('file', file)
@@ -188,16 +340,49 @@ export const articleApi = {
     })
   },
 
-  // 批量上传ZIP
-  uploadZipBatch: (formData: FormData) => {
+  // 批量上传ZIP（大文件先分片上传，再按 upload_id 引用），每个文件返回一个 task_id，用 getUploadProgress 查询导入进度
+  uploadZipBatch: async (files: File[], mode: string) => {
+    const formData = new FormData()
+    for (const file of files) {
+      if (file.size > CHUNKED_UPLOAD_THRESHOLD) {
+        const { upload_id } = await uploadChunked(file, { type: 'import' })
+        formData.append('upload_id', upload_id)
+      } else {
+        formData.append('files', file)
+      }
+    }
+    formData.append('mode', mode)
     return apiClient.post('/v1/article/zip', formData, {
-      headers: { 'Content-Type': 'multipart/form-data' }
+      headers: { 'Content-Type': 'multipart/form-data' },
+      timeout: 0
     })
   },
 
+  // 导入 Hexo / Hugo / Jekyll 源码 ZIP 或 WordPress 导出的 XML，返回任务 ID
+  importBlog: async (file: File, format: string, mode: string) => {
+    const formData = new FormData()
+    if (file.size > CHUNKED_UPLOAD_THRESHOLD) {
+      const { upload_id } = await uploadChunked(file, { type: 'import' })
+      formData.append('upload_id', upload_id)
+    } else {
+      formData.append('file', file)
+    }
+    formData.append('format', format)
+    formData.append('mode', mode)
+    return apiClient.post('/v1/article/import', formData, {
+      headers: { 'Content-Type': 'multipart/form-data' },
+      timeout: 0
+    })
+  },
+
+  // 查询上传 / 导入任务进度
+  getUploadProgress: (taskId: string) =>
+    apiClient.get(`/v1/article/upload/${taskId}`),
+
   // 更新文章
   updateArticle: (id: number, data: {
     title: string;
+    slug?: string;
     cid: number;
     desc: string;
     content: string;
@@ -215,7 +400,18 @@ export const articleApi = {
 
   // 批量删除文章
   batchDeleteArticles: (ids: number[]) =>
-    apiClient.post('/v1/article/batch-delete', { ids })
+    apiClient.post('/v1/article/batch-delete', { ids }),
+
+  // 导出单篇文章为 Markdown ZIP
+  exportArticle: (id: number) =>
+    apiClient.get(`/v1/article/${id}/export`, { responseType: 'blob' }),
+
+  // 批量导出文章为 Markdown ZIP（ids 为空时导出全部）
+  exportArticles: (ids: number[] = []) =>
+    apiClient.get('/v1/article/export', {
+      params: ids.length ? { ids: ids.join(',') } : {},
+      responseType: 'blob'
+    })
 }
 
 // 文件上传API
@@ -252,4 +448,47 @@ export const systemApi = {
     apiClient.get('/v1/system/status'),
 }
 
+// 审计日志查询条件
+export interface AuditQuery {
+  username?: string
+  action?: string
+  keyword?: string
+  result?: string
+  start?: string
+  end?: string
+}
+
+// 审计日志API
+export const auditApi = {
+  // 分页查询审计日志
+  getLogs: (params: AuditQuery & { pagesize: number; pagenum: number }) =>
+    apiClient.get('/v1/audit', { params }),
+
+  // 导出 CSV
+  exportLogs: (params: AuditQuery) =>
+    apiClient.get('/v1/audit/export', { params, responseType: 'blob' }),
+}
+
+// 全站备份与恢复API（备份文件可能很大，不设置超时）
+export const backupApi = {
+  // 下载全站备份
+  createBackup: () =>
+    apiClient.post('/v1/system/backup', null, { responseType: 'blob', timeout: 0 }),
+
+  // 上传备份并预览差异（不修改数据）
+  uploadRestore: (formData: FormData) =>
+    apiClient.post('/v1/system/backup/restore', formData, {
+      headers: { 'Content-Type': 'multipart/form-data' },
+      timeout: 0
+    }),
+
+  // 确认恢复
+  applyRestore: (id: string) =>
+    apiClient.post(`/v1/system/backup/restore/${id}`, null, { timeout: 0 }),
+
+  // 放弃恢复
+  discardRestore: (id: string) =>
+    apiClient.delete(`/v1/system/backup/restore/${id}`),
+}
+
 export default apiClient
\ No newline at end of file
@@ -4,7 +4,7 @@
 export interface User {
   id: number
   username: string
-  role: number // 1: 管理员, 2: 普通用户
+  role: number // 1: 超级管理员, 2: 管理员, 3: 普通用户, 4: 编辑
   createdAt: string
 }
 
@@ -58,6 +58,10 @@ export default defineConfig({
         target: 'http://localhost:8080',
         changeOrigin: true,
       },
+      '/img': {
+        target: 'http://localhost:8080',
+        changeOrigin: true,
+      },
       '/assets': {
         target: 'http://localhost:8080',
         changeOrigin: true,
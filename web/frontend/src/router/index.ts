@@ -59,9 +59,9 @@ const router = createRouter({
 
 // 全局前置守卫：参数校验 + 权限控制
 router.beforeEach((to, _from, next) => {
-  // 校验文章ID等动态参数是否为合法数字
-  if (to.params.id && !/^\d+$/.test(to.params.id as string)) {
-    // 非法ID，重定向到404或首页
+  // 校验文章、分类的动态参数：数字 ID 或 slug（小写字母、数字和连字符）
+  if (to.params.id && !/^[a-z0-9-]+$/.test(to.params.id as string)) {
+    // 非法参数，重定向到404或首页
     next({ name: 'not-found', params: [to.path] })
     return
   }
@@ -82,6 +82,10 @@ export const articleApi = {
   getArticle: (id: number) =>
     apiClient.get(`/article/info/${id}`),
 
+  // 通过 slug 获取文章详情（旧 slug 由后端 301 跳转到当前 slug）
+  getArticleBySlug: (slug: string) =>
+    apiClient.get(`/article/slug/${encodeURIComponent(slug)}`),
+
   // 获取置顶文章
   getTopArticles: (params?: { num: number }) =>
     apiClient.get('/article/top', { params }),
@@ -111,7 +115,11 @@ export const categoryApi = {
 
   // 获取分类信息
   getCategoryInfo: (id: number) =>
-    apiClient.get(`/category/info/${id}`)
+    apiClient.get(`/category/info/${id}`),
+
+  // 通过 slug 获取分类信息（旧 slug 由后端 301 跳转到当前 slug）
+  getCategoryBySlug: (slug: string) =>
+    apiClient.get(`/category/slug/${encodeURIComponent(slug)}`)
 }
 
 // 标签相关API
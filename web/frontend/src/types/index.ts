@@ -6,6 +6,7 @@
 // 文章类型
 export interface Article {
   id: number
+  slug?: string
   title: string
   categoryId: number
   categoryName: string
@@ -24,6 +25,7 @@ export interface Article {
 // 分类类型
 export interface Category {
   id: number
+  slug?: string
   name: string
 }
 
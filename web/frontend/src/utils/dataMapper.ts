@@ -12,6 +12,7 @@ import type { Article, Category } from '@/types'
  */
 export const mapArticle = (item: any): Article => ({
   id: item.ID,
+  slug: item.slug,
   title: item.title,
   categoryId: item.cid,
   categoryName: item.Category?.name || '未分类',
@@ -33,9 +34,22 @@ export const mapArticle = (item: any): Article => ({
  */
 export const mapCategory = (item: any): Category => ({
   id: item.ID,
+  slug: item.slug,
   name: item.name
 })
 
+/**
+ * 文章详情页链接（优先使用 slug）
+ */
+export const articlePath = (article: { id: number; slug?: string }): string =>
+  `/article/${article.slug || article.id}`
+
+/**
+ * 分类文章列表页链接（优先使用 slug）
+ */
+export const categoryPath = (category: { id: number; slug?: string }): string =>
+  `/category/${category.slug || category.id}`
+
 /**
  * 批量映射文章列表
  */
@@ -67,6 +67,10 @@ export default defineConfig({
       '/uploads': {
         target: 'http://localhost:8080',
         changeOrigin: true,
+      },
+      '/img': {
+        target: 'http://localhost:8080',
+        changeOrigin: true,
       }
     },
     // 增加超时时间